@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/ast"
+)
+
+func astCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: mbasic ast file.bas...")
+		return 2
+	}
+
+	code := 0
+	for _, path := range args {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "mbasic: %v\n", err)
+			return 1
+		}
+
+		prog, err := ast.ParseFS(os.DirFS(filepath.Dir(path)), string(source))
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			code = 1
+			continue
+		}
+		if len(args) > 1 {
+			fmt.Fprintf(stdout, "# %s\n", path)
+		}
+		for _, stmt := range prog.Statements {
+			dumpNode(stdout, "", reflect.ValueOf(stmt), 0)
+		}
+	}
+	return code
+}
+
+// dumpNode writes a node as its type, position and plain fields on one
+// line, followed by its child nodes indented beneath it, each labelled with
+// the field it is in
+func dumpNode(w io.Writer, label string, v reflect.Value, depth int) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	line := strings.Repeat("  ", depth) + label + v.Type().Name()
+	if pos, ok := v.Addr().Interface().(interface{ Position() (int, int) }); ok {
+		l, c := pos.Position()
+		line += fmt.Sprintf(" %d:%d", l, c)
+	}
+
+	type child struct {
+		label string
+		value reflect.Value
+	}
+	var children []child
+	for idx := 0; idx < v.NumField(); idx++ {
+		field, value := v.Type().Field(idx), v.Field(idx)
+		if field.Anonymous { // Pos
+			continue
+		}
+		if text, ok := plainField(value); ok {
+			if text != "" {
+				line += " " + field.Name + "=" + text
+			}
+			continue
+		}
+		if value.Kind() == reflect.Slice {
+			for elem := 0; elem < value.Len(); elem++ {
+				children = append(children, child{fmt.Sprintf("%s[%d]: ", field.Name, elem), value.Index(elem)})
+			}
+			continue
+		}
+		children = append(children, child{field.Name + ": ", value})
+	}
+
+	fmt.Fprintln(w, line)
+	for _, c := range children {
+		dumpNode(w, c.label, c.value, depth+1)
+	}
+}
+
+// plainField returns a field that isn't a node or a list of nodes as text,
+// "" when it is empty
+func plainField(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() == "" {
+			return "", true
+		}
+		return fmt.Sprintf("%q", v.String()), true
+	case reflect.Bool, reflect.Int, reflect.Float64:
+		return fmt.Sprint(v.Interface()), true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return "", false
+		}
+		if v.Len() == 0 {
+			return "", true
+		}
+		return fmt.Sprintf("%q", v.Interface()), true
+	}
+	return "", false
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestASTCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "tree.bas", "if hp < 10 then\n    print \"low\";\nendif\nlet spawn = iif(hp, 1, 2)\n")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"ast", filepath.Join(dir, "tree.bas")}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	expected := `IfStatement 1:1
+  Condition: BinaryExpr 1:4 Operator=LT
+    Left: Identifier 1:4 Name="hp"
+    Right: IntLiteral 1:9 Value=10
+  ThenBlock[0]: PrintStatement 2:5 NoNewline=true
+    Values[0]: StringLiteral 2:11 Value="low"
+LetStatement 4:1 Name="spawn"
+  Value: ConditionalExpr 4:13
+    Condition: Identifier 4:17 Name="hp"
+    Then: IntLiteral 4:21 Value=1
+    Else: IntLiteral 4:24 Value=2
+`
+	if stdout.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+
+	writeScript(t, dir, "bad.bas", "for i = 1\n")
+	if code := run([]string{"ast", filepath.Join(dir, "bad.bas")}, strings.NewReader(""), &stdout, &stderr); code != 1 {
+		t.Errorf("expected exit code 1 for a script that doesn't parse, got %d", code)
+	}
+}
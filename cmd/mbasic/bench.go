@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func benchCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	n := flags.Int("n", 1000, "number of runs to time in each mode")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: mbasic bench [-n runs] file.bas")
+		return 2
+	}
+
+	path := flags.Arg(0)
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "mbasic: %v\n", err)
+		return 1
+	}
+
+	// Discard script output so printing doesn't dominate the timings
+	mb := newMechBasic(path)
+	mb.SetPrintFunc(func(value any) {})
+
+	result, err := mb.RunBenchmark(string(source), *n)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", path, err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "runs:      %d\n", result.Iterations)
+	fmt.Fprintf(stdout, "uncached:  %v/run\n", result.Uncached)
+	fmt.Fprintf(stdout, "cached:    %v/run\n", result.Cached)
+	fmt.Fprintf(stdout, "parse:     %v/run\n", result.ParseTime())
+	fmt.Fprintf(stdout, "speedup:   %.1fx\n", result.Speedup())
+	return 0
+}
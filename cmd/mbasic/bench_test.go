@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBenchCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "loop.bas", "let total = 0\nfor i = 1 to 10\n    total = total + i\nnext i\nprint total\n")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"bench", "-n", "5", filepath.Join(dir, "loop.bas")}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"runs:      5\n", "uncached:", "cached:", "speedup:"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "55") {
+		t.Error("expected script output to be discarded")
+	}
+
+	writeScript(t, dir, "broken.bas", "let x = \n")
+	if code := run([]string{"bench", filepath.Join(dir, "broken.bas")}, strings.NewReader(""), &stdout, &stderr); code != 1 {
+		t.Errorf("expected exit code 1 for a parse error, got %d", code)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/basic"
+)
+
+func checkCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	strict := flags.Bool("strict", false, "fail on warnings as well as errors")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: mbasic check [-strict] file.bas...")
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "mbasic: %v\n", err)
+			return 1
+		}
+
+		for _, diag := range newMechBasic(path).ValidateAll(string(source)) {
+			file := path
+			if diag.File != "" {
+				file = filepath.Join(filepath.Dir(path), diag.File)
+			}
+			fmt.Fprintf(stdout, "%s:%d:%d: %s: %s\n", file, diag.Line, diag.Column, diag.Severity, diag.Message)
+			if diag.Severity == basic.SeverityError || *strict {
+				code = 1
+			}
+		}
+	}
+	return code
+}
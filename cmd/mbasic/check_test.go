@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "good.bas", "let hp = 10\nprint hp\n")
+	writeScript(t, dir, "broken.bas", "let hp = 10\nif hp > 5\n    print hp\nendif\n")
+	writeScript(t, dir, "unused.bas", "function f(n):\n    let spare = n\n    return n\nendfunction\nprint f(1)\n")
+	good, broken, unused := filepath.Join(dir, "good.bas"), filepath.Join(dir, "broken.bas"), filepath.Join(dir, "unused.bas")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"check", good, broken}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), broken+":2:") || !strings.Contains(stdout.String(), ": error: ") {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+
+	stdout.Reset()
+	code = run([]string{"check", good, unused}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 || !strings.Contains(stdout.String(), unused+":2:") || !strings.Contains(stdout.String(), ": warning: ") {
+		t.Errorf("expected a warning that doesn't fail the check, got %d: %q", code, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"check", "-strict", unused}, strings.NewReader(""), &stdout, &stderr); code != 1 {
+		t.Errorf("expected -strict to fail on warnings, got %d", code)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/basic"
+)
+
+// errQuit aborts the script when the user quits the debugger
+var errQuit = errors.New("debugger: quit")
+
+// stepMode decides where the debugger pauses next
+type stepMode int
+
+const (
+	modeStep     stepMode = iota // Pause at the next statement
+	modeNext                     // Pause at the next statement at or above a call depth
+	modeContinue                 // Pause only at breakpoints
+)
+
+// lineList collects repeated -b flags
+type lineList []int
+
+func (l *lineList) String() string { return fmt.Sprint(*l) }
+
+func (l *lineList) Set(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid line number %q", value)
+	}
+	*l = append(*l, n)
+	return nil
+}
+
+func debugCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var breaks lineList
+	flags.Var(&breaks, "b", "set a breakpoint at `line` (repeatable)")
+	cont := flags.Bool("c", false, "run until the first breakpoint instead of pausing at the first statement")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: mbasic debug [-b line]... [-c] file.bas")
+		return 2
+	}
+
+	path := flags.Arg(0)
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "mbasic: %v\n", err)
+		return 1
+	}
+
+	mb := newMechBasic(path)
+	mb.SetRawPrintFunc(func(value any, newline bool) {
+		printValue(stdout, value, newline)
+	})
+
+	d := newDebugger(mb, string(source), stdin, stdout)
+	for _, line := range breaks {
+		d.breakpoints[line] = true
+	}
+	if *cont {
+		d.mode = modeContinue
+	}
+	mb.SetDebugHook(d.hook)
+
+	err = mb.Run(string(source))
+	switch {
+	case errors.Is(err, errQuit):
+		fmt.Fprintln(stdout, "quit")
+		return 0
+	case err != nil:
+		fmt.Fprintf(stderr, "%s: %v\n", path, err)
+		return 1
+	}
+
+	fmt.Fprintln(stdout, "program finished")
+	return 0
+}
+
+// debugger is an interactive debug hook driven by line commands
+type debugger struct {
+	mb          *basic.MechBasic
+	lines       []string
+	in          *bufio.Scanner
+	out         io.Writer
+	breakpoints map[int]bool
+	mode        stepMode
+	depth       int // Call depth for modeNext
+}
+
+func newDebugger(mb *basic.MechBasic, source string, in io.Reader, out io.Writer) *debugger {
+	return &debugger{
+		mb:          mb,
+		lines:       strings.Split(source, "\n"),
+		in:          bufio.NewScanner(in),
+		out:         out,
+		breakpoints: make(map[int]bool),
+	}
+}
+
+// hook decides whether to pause before a statement and, if so, runs the
+// command prompt until the user resumes
+func (d *debugger) hook(frame basic.DebugFrame) error {
+	pause := d.breakpoints[frame.Line]
+	switch d.mode {
+	case modeStep:
+		pause = true
+	case modeNext:
+		pause = pause || frame.Depth <= d.depth
+	}
+	if !pause {
+		return nil
+	}
+
+	d.showLine(frame.Line, frame)
+	for {
+		fmt.Fprint(d.out, "(mbasic) ")
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			return errQuit
+		}
+
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(d.in.Text()), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch strings.ToLower(cmd) {
+		case "s", "step":
+			d.mode = modeStep
+			return nil
+		case "n", "next":
+			d.mode, d.depth = modeNext, frame.Depth
+			return nil
+		case "o", "out":
+			d.mode, d.depth = modeNext, frame.Depth-1
+			return nil
+		case "c", "continue":
+			d.mode = modeContinue
+			return nil
+		case "b", "break":
+			d.setBreakpoint(arg, true)
+		case "d", "delete":
+			d.setBreakpoint(arg, false)
+		case "bl", "breakpoints":
+			d.listBreakpoints()
+		case "p", "print":
+			d.evaluate(arg)
+		case "v", "vars":
+			d.showVariables()
+		case "w", "where":
+			d.showStack(frame)
+		case "l", "list":
+			d.listSource(frame.Line)
+		case "q", "quit":
+			return errQuit
+		case "h", "help", "":
+			d.help()
+		default:
+			fmt.Fprintf(d.out, "unknown command %q (type help)\n", cmd)
+		}
+	}
+}
+
+func (d *debugger) showLine(line int, frame basic.DebugFrame) {
+	where := "top level"
+	if frame.Function != "" {
+		where = frame.Function
+	}
+	fmt.Fprintf(d.out, "line %d (%s): %s\n", line, where, strings.TrimSpace(d.source(line)))
+}
+
+func (d *debugger) source(line int) string {
+	if line < 1 || line > len(d.lines) {
+		return ""
+	}
+	return d.lines[line-1]
+}
+
+func (d *debugger) setBreakpoint(arg string, enabled bool) {
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 {
+		fmt.Fprintf(d.out, "expected a line number, got %q\n", arg)
+		return
+	}
+
+	if enabled {
+		d.breakpoints[line] = true
+		fmt.Fprintf(d.out, "breakpoint set at line %d\n", line)
+		return
+	}
+
+	delete(d.breakpoints, line)
+	fmt.Fprintf(d.out, "breakpoint cleared at line %d\n", line)
+}
+
+func (d *debugger) listBreakpoints() {
+	if len(d.breakpoints) == 0 {
+		fmt.Fprintln(d.out, "no breakpoints")
+		return
+	}
+
+	lines := make([]int, 0, len(d.breakpoints))
+	for line := range d.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	for _, line := range lines {
+		fmt.Fprintf(d.out, "line %d: %s\n", line, strings.TrimSpace(d.source(line)))
+	}
+}
+
+func (d *debugger) evaluate(expr string) {
+	if expr == "" {
+		fmt.Fprintln(d.out, "usage: print <expression>")
+		return
+	}
+
+	value, err := d.mb.EvalInFrame(expr)
+	if err != nil {
+		fmt.Fprintf(d.out, "error: %v\n", err)
+		return
+	}
+	fmt.Fprintln(d.out, formatValue(value))
+}
+
+func (d *debugger) showVariables() {
+	vars := d.mb.Variables()
+	if len(vars) == 0 {
+		fmt.Fprintln(d.out, "no variables")
+		return
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(d.out, "%s = %s\n", name, formatValue(vars[name]))
+	}
+}
+
+func (d *debugger) showStack(frame basic.DebugFrame) {
+	stack := d.mb.CallStack()
+	for idx := len(stack) - 1; idx >= 0; idx-- {
+		fmt.Fprintf(d.out, "  in %s\n", stack[idx])
+	}
+	fmt.Fprintf(d.out, "  at line %d, column %d\n", frame.Line, frame.Column)
+}
+
+func (d *debugger) listSource(current int) {
+	start := max(current-3, 1)
+	end := min(current+3, len(d.lines))
+	for line := start; line <= end; line++ {
+		marker := "  "
+		if line == current {
+			marker = "->"
+		} else if d.breakpoints[line] {
+			marker = " *"
+		}
+		fmt.Fprintf(d.out, "%s %4d  %s\n", marker, line, d.lines[line-1])
+	}
+}
+
+func (d *debugger) help() {
+	fmt.Fprintln(d.out, `commands:
+  s, step           run to the next statement, entering function calls
+  n, next           run to the next statement in this function or its callers
+  o, out            run until the current function returns
+  c, continue       run until the next breakpoint
+  b, break <line>   set a breakpoint
+  d, delete <line>  clear a breakpoint
+  bl, breakpoints   list breakpoints
+  p, print <expr>   evaluate an expression in the current frame
+  v, vars           show visible variables
+  w, where          show the call stack
+  l, list           show source around the current line
+  q, quit           stop the script`)
+}
+
+// formatValue renders a value for display, quoting strings
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	if value == nil {
+		return "null"
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const debugScript = `let total = 0
+function add(a, b):
+    let sum = a + b
+    return sum
+endfunction
+for i = 1 to 3
+    total = add(total, i)
+next i
+print total
+`
+
+func runDebug(t *testing.T, input string, args ...string) (string, string, int) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "script.bas")
+	if err := os.WriteFile(path, []byte(debugScript), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(append(append([]string{"debug"}, args...), path), strings.NewReader(input), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestDebugBreakpointAndPrint(t *testing.T) {
+	out, errOut, code := runDebug(t, "b 3\nc\np a + b\nw\nc\nd 3\nc\n")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+
+	for _, want := range []string{
+		"line 1 (top level): let total = 0",
+		"breakpoint set at line 3",
+		"line 3 (add): let sum = a + b",
+		"(mbasic) 1\n",
+		"  in add\n",
+		"breakpoint cleared at line 3",
+		"6\nprogram finished",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestDebugStepping(t *testing.T) {
+	out, errOut, code := runDebug(t, "s\ns\ns\ns\nv\nn\nn\nq\n")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+
+	// step enters add(), next stays in it and then returns to the loop
+	for _, want := range []string{
+		"line 6 (top level)",
+		"line 7 (top level)",
+		"line 3 (add)",
+		"a = 0\nb = 1\nsum = 1\ntotal = 0\n",
+		"line 4 (add)",
+		"line 7 (top level)",
+		"quit",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "program finished") {
+		t.Error("expected quit to stop the script")
+	}
+}
+
+func TestDebugRunToBreakpointFlag(t *testing.T) {
+	out, errOut, code := runDebug(t, "p total\nc\n", "-c", "-b", "9")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	if !strings.HasPrefix(out, "line 9 (top level): print total\n(mbasic) 6\n") {
+		t.Errorf("expected to stop first at line 9, got:\n%s", out)
+	}
+}
+
+func TestDebugUsageErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"debug"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit code 2 without a file, got %d", code)
+	}
+	if code := run([]string{"bogus"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit code 2 for an unknown command, got %d", code)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/basic"
+)
+
+func docCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("doc", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: mbasic doc file.bas...")
+		return 2
+	}
+
+	for idx, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "mbasic: %v\n", err)
+			return 1
+		}
+
+		docs, err := newMechBasic(path).DescribeScript(string(source))
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			return 1
+		}
+
+		if idx > 0 {
+			fmt.Fprintln(stdout)
+		}
+		writeMarkdown(stdout, filepath.Base(path), docs)
+	}
+	return 0
+}
+
+// writeMarkdown renders a script's function docs as a Markdown section
+func writeMarkdown(w io.Writer, title string, docs []basic.FunctionDoc) {
+	fmt.Fprintf(w, "# %s\n", title)
+	if len(docs) == 0 {
+		fmt.Fprintln(w, "\nNo public functions.")
+		return
+	}
+
+	for _, doc := range docs {
+		kind := "function"
+		if doc.IsSub {
+			kind = "sub"
+		}
+		fmt.Fprintf(w, "\n## %s\n\n", doc.Name)
+		// Parameters with a default value may be left out
+		params := make([]string, len(doc.Params))
+		for idx, param := range doc.Params {
+			if doc.Types[idx] != "" {
+				param += " AS " + doc.Types[idx]
+			}
+			if idx >= doc.Required {
+				param = "[" + param + "]"
+			}
+			params[idx] = param
+		}
+		fmt.Fprintf(w, "```basic\n%s %s(%s)\n```\n", kind, doc.Name, strings.Join(params, ", "))
+		if doc.Doc != "" {
+			fmt.Fprintf(w, "\n%s\n", doc.Doc)
+		}
+	}
+}
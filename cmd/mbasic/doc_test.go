@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "combat.bas", `## Applies damage after armor.
+function take_damage(hp AS INTEGER, amount = 1):
+    return hp - amount
+endfunction
+
+sub reset():
+endsub
+
+function _roll():
+    return 4
+endfunction
+`)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"doc", filepath.Join(dir, "combat.bas")}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+
+	expected := "# combat.bas\n\n" +
+		"## take_damage\n\n```basic\nfunction take_damage(hp AS INTEGER, [amount])\n```\n\nApplies damage after armor.\n\n" +
+		"## reset\n\n```basic\nsub reset()\n```\n"
+	if stdout.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+}
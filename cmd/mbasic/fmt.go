@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/format"
+)
+
+func fmtCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result back to each file")
+	list := flags.Bool("l", false, "list the files whose formatting differs")
+	minify := flags.Bool("minify", false, "strip comments and spaces instead")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: mbasic fmt [-l] [-w] [-minify] file.bas...")
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "mbasic: %v\n", err)
+			return 1
+		}
+
+		process := format.Format
+		if *minify {
+			process = format.Minify
+		}
+		formatted, err := process(string(source))
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			code = 1
+			continue
+		}
+
+		changed := formatted != string(source)
+		if *list && changed {
+			fmt.Fprintln(stdout, path)
+		}
+		if *write && changed {
+			if err := os.WriteFile(path, []byte(formatted), 0o644); err != nil {
+				fmt.Fprintf(stderr, "mbasic: %v\n", err)
+				return 1
+			}
+		}
+		if !*list && !*write {
+			fmt.Fprint(stdout, formatted)
+		}
+	}
+	return code
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFmtCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "messy.bas", "IF x>1 THEN\nPRINT x   # big\nENDIF\n")
+	writeScript(t, dir, "tidy.bas", "print 1\n")
+	messy, tidy := filepath.Join(dir, "messy.bas"), filepath.Join(dir, "tidy.bas")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"fmt", messy}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	expected := "if x > 1 then\n    print x  # big\nendif\n"
+	if stdout.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	code = run([]string{"fmt", "-l", "-w", messy, tidy}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if stdout.String() != messy+"\n" {
+		t.Errorf("expected only messy.bas to be listed, got %q", stdout.String())
+	}
+	written, err := os.ReadFile(messy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != expected {
+		t.Errorf("expected the file to be rewritten, got:\n%s", written)
+	}
+
+	stdout.Reset()
+	code = run([]string{"fmt", "-minify", messy}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "if x>1then\nprint x\nendif\n" {
+		t.Errorf("unexpected minified script %q", stdout.String())
+	}
+}
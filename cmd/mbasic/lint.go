@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/basic"
+	"github.com/mechanical-lich/mechanical-basic/pkg/lint"
+)
+
+func lintCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	disable := flags.String("disable", "", "comma-separated rules to skip: "+strings.Join(lint.Rules, ", "))
+	funcs := flags.String("func", "", "comma-separated functions the host registers")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: mbasic lint [-disable rules] [-func names] file.bas...")
+		return 2
+	}
+
+	var cfg lint.Config
+	if *disable != "" {
+		cfg.Disable = strings.Split(*disable, ",")
+	}
+	if *funcs != "" {
+		cfg.Functions = strings.Split(*funcs, ",")
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "mbasic: %v\n", err)
+			return 1
+		}
+
+		// Includes and imports are found next to the script, as when it runs
+		dir := os.DirFS(filepath.Dir(path))
+		cfg.SourceFS = dir
+		cfg.ModuleResolver = basic.FSResolver(dir)
+
+		issues, err := lint.Lint(string(source), cfg)
+		for _, issue := range issues {
+			file := path
+			if issue.File != "" {
+				file = filepath.Join(filepath.Dir(path), issue.File)
+			}
+			fmt.Fprintf(stdout, "%s:%d:%d: %s (%s)\n", file, issue.Line, issue.Column, issue.Message, issue.Rule)
+			code = 1
+		}
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: %v\n", path, err)
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "quest.bas", `include "lib.bas"
+let done = finished()
+if true then
+    give_reward()
+endif
+`)
+	writeScript(t, dir, "lib.bas", `function finished():
+    return true
+    print "unreachable"
+endfunction
+`)
+
+	path := filepath.Join(dir, "quest.bas")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"lint", path}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	expected := path + ":3:4: IF condition is constant (constant-condition)\n" +
+		path + ":4:5: call to undefined function give_reward (undefined-function)\n" +
+		filepath.Join(dir, "lib.bas") + ":3:5: unreachable code after RETURN (unreachable-code)\n"
+	if stdout.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	code = run([]string{"lint", "-disable", "unreachable-code,constant-condition", "-func", "give_reward", path},
+		strings.NewReader(""), &stdout, &stderr)
+	if code != 0 || stdout.Len() != 0 {
+		t.Errorf("expected no issues, got exit code %d: %s", code, stdout.String())
+	}
+}
@@ -0,0 +1,105 @@
+// Command mbasic runs and inspects MechanicalBasic scripts from the terminal.
+//
+// Usage:
+//
+//	mbasic <command> [arguments]
+//
+// The commands are:
+//
+//	ast      print the parsed syntax tree of scripts
+//	bench    time a script with and without the AST cache
+//	check    report syntax errors and warnings without running scripts
+//	debug    step through a script with breakpoints
+//	doc      print Markdown documentation for a script's functions
+//	fmt      print scripts in the canonical layout
+//	lint     check scripts for likely mistakes without running them
+//	run      run a script
+//	test     run the test_ functions of scripts
+//
+// Every command exits with status 0 on success, 1 when a script fails or a
+// problem is found, and 2 when it is used incorrectly, so they can gate a
+// CI pipeline.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/basic"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run dispatches a subcommand and returns the process exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	switch args[0] {
+	case "ast":
+		return astCommand(args[1:], stdout, stderr)
+	case "bench":
+		return benchCommand(args[1:], stdout, stderr)
+	case "check":
+		return checkCommand(args[1:], stdout, stderr)
+	case "debug":
+		return debugCommand(args[1:], stdin, stdout, stderr)
+	case "doc":
+		return docCommand(args[1:], stdout, stderr)
+	case "fmt":
+		return fmtCommand(args[1:], stdout, stderr)
+	case "lint":
+		return lintCommand(args[1:], stdout, stderr)
+	case "run":
+		return runCommand(args[1:], stdout, stderr)
+	case "test":
+		return testCommand(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		usage(stdout)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "mbasic: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: mbasic <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	fmt.Fprintln(w, "  ast file.bas...                 print the parsed syntax tree of scripts")
+	fmt.Fprintln(w, "  bench [-n runs] file.bas        time a script with and without the AST cache")
+	fmt.Fprintln(w, "  check [-strict] file.bas...     report errors and warnings without running")
+	fmt.Fprintln(w, "  debug [-b line]... file.bas     step through a script with breakpoints")
+	fmt.Fprintln(w, "  doc file.bas...                 print Markdown docs for a script's functions")
+	fmt.Fprintln(w, "  fmt [-l] [-w] file.bas...       print scripts in the canonical layout")
+	fmt.Fprintln(w, "  lint file.bas...                check scripts for likely mistakes")
+	fmt.Fprintln(w, "  run file.bas                    run a script")
+	fmt.Fprintln(w, "  test [-v] dir|file.bas...       run the test_ functions of scripts")
+}
+
+// newMechBasic creates an interpreter for the script at path, which imports
+// modules from and includes the files in its directory
+func newMechBasic(path string) *basic.MechBasic {
+	dir := os.DirFS(filepath.Dir(path))
+	mb := basic.NewMechanicalBasic()
+	mb.SetModuleResolver(basic.FSResolver(dir))
+	mb.SetSourceFS(dir)
+	return mb
+}
+
+// printValue writes the output of one PRINT statement
+func printValue(w io.Writer, value any, newline bool) {
+	if newline {
+		fmt.Fprintln(w, value)
+	} else {
+		fmt.Fprint(w, value)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: mbasic run file.bas")
+		return 2
+	}
+
+	path := args[0]
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "mbasic: %v\n", err)
+		return 1
+	}
+
+	mb := newMechBasic(path)
+	mb.SetRawPrintFunc(func(value any, newline bool) {
+		printValue(stdout, value, newline)
+	})
+	if err := mb.Run(string(source)); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "greet.bas", "include \"lib.bas\"\nprint \"hello\";\nprint \" \" + name()\n")
+	writeScript(t, dir, "lib.bas", "function name():\n    return \"world\"\nendfunction\n")
+	writeScript(t, dir, "fail.bas", "print 1\nthrow \"boom\"\n")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"run", filepath.Join(dir, "greet.bas")}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "hello world\n" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+
+	stdout.Reset()
+	path := filepath.Join(dir, "fail.bas")
+	code = run([]string{"run", path}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if stdout.String() != "1\n" || !strings.HasPrefix(stderr.String(), path+": ") || !strings.Contains(stderr.String(), "boom") {
+		t.Errorf("unexpected output %q, errors %q", stdout.String(), stderr.String())
+	}
+
+	if code := run([]string{"run"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit code 2 without a script, got %d", code)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/basic"
+)
+
+// testPrefix marks the script functions run by mbasic test
+const testPrefix = "test_"
+
+func testCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	verbose := flags.Bool("v", false, "show script output and passing tests")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: mbasic test [-v] dir|file.bas...")
+		return 2
+	}
+
+	files, err := findScripts(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "mbasic: %v\n", err)
+		return 1
+	}
+
+	r := &testRunner{out: stdout, verbose: *verbose}
+	for _, path := range files {
+		r.runFile(path)
+	}
+
+	switch {
+	case r.broken > 0:
+		fmt.Fprintf(stdout, "FAIL: %d of %d tests failed, %d scripts failed to load\n", r.failed, r.run, r.broken)
+		return 1
+	case r.failed > 0:
+		fmt.Fprintf(stdout, "FAIL: %d of %d tests failed\n", r.failed, r.run)
+		return 1
+	case r.run == 0:
+		fmt.Fprintln(stdout, "no tests found")
+		return 0
+	}
+
+	fmt.Fprintf(stdout, "ok: %d tests passed\n", r.run)
+	return 0
+}
+
+// findScripts expands the arguments into a sorted list of .bas files,
+// walking directories recursively
+func findScripts(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(file), ".bas") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// testRunner runs the test functions of each script and tallies the results
+type testRunner struct {
+	out     io.Writer
+	verbose bool
+	run     int
+	failed  int
+	broken  int // Scripts that couldn't be read or loaded
+}
+
+// runFile runs every test_ function in a script, each in a fresh interpreter
+// so state left behind by one test can't leak into the next
+func (r *testRunner) runFile(path string) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		r.broken++
+		r.report(path, err)
+		return
+	}
+
+	mb, err := r.load(path, string(source))
+	if err != nil {
+		r.broken++
+		r.report(path, err)
+		return
+	}
+
+	for _, name := range mb.Functions() {
+		if !strings.HasPrefix(name, testPrefix) {
+			continue
+		}
+
+		r.run++
+		label := path + ":" + name
+		if err := r.runTest(path, string(source), name); err != nil {
+			r.failed++
+			r.report(label, err)
+			continue
+		}
+		if r.verbose {
+			fmt.Fprintf(r.out, "PASS %s\n", label)
+		}
+	}
+}
+
+func (r *testRunner) runTest(path, source, name string) error {
+	mb, err := r.load(path, source)
+	if err != nil {
+		return err
+	}
+	_, err = mb.Call(name)
+	return err
+}
+
+// load creates an interpreter with the script's functions and top-level
+// variables in place
+func (r *testRunner) load(path, source string) (*basic.MechBasic, error) {
+	mb := newMechBasic(path)
+	mb.SetRawPrintFunc(func(value any, newline bool) {
+		if r.verbose {
+			printValue(r.out, value, newline)
+		}
+	})
+	return mb, mb.Load(source)
+}
+
+func (r *testRunner) report(label string, err error) {
+	fmt.Fprintf(r.out, "FAIL %s\n    %v\n", label, err)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeScript(t *testing.T, dir, name, source string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTestCommandReportsFailures(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "inventory.bas", `let count = 0
+function add_item(n):
+    count = count + n
+    return count
+endfunction
+sub test_add():
+    expect_eq(add_item(2), 2)
+endsub
+sub test_isolated():
+    add_item(1)
+    expect_eq(count, 2, "count")
+endsub
+sub helper():
+    assert(false)
+endsub
+`)
+	writeScript(t, dir, "notes.txt", "sub test_ignored():\nendsub\n")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"test", "-v", dir}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d: %s", code, stderr.String())
+	}
+
+	out := stdout.String()
+	for _, want := range []string{
+		"PASS " + filepath.Join(dir, "inventory.bas") + ":test_add\n",
+		"FAIL " + filepath.Join(dir, "inventory.bas") + ":test_isolated\n",
+		"line 11, column 5: expect_eq: count: got 1, expected 2",
+		"FAIL: 1 of 2 tests failed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "ignored") {
+		t.Error("expected non-.bas files to be skipped")
+	}
+}
+
+func TestTestCommandPasses(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "math.bas", "sub test_abs():\n    assert(abs(-2) = 2)\nendsub\n")
+	writeScript(t, dir, "broken.bas", "let x = \n")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"test", filepath.Join(dir, "math.bas")}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d:\n%s", code, stdout.String())
+	}
+	if stdout.String() != "ok: 1 tests passed\n" {
+		t.Errorf("unexpected output:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	code = run([]string{"test", dir}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 || !strings.Contains(stdout.String(), "FAIL "+filepath.Join(dir, "broken.bas")) ||
+		!strings.Contains(stdout.String(), "FAIL: 0 of 1 tests failed, 1 scripts failed to load") {
+		t.Errorf("expected a script that fails to load to be reported, got %d:\n%s", code, stdout.String())
+	}
+}
+
+func TestTestCommandImportsSiblingModules(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "mathutil.bas", "function double(x):\n    return x * 2\nendfunction\n")
+	writeScript(t, dir, "game.bas", "import \"mathutil\"\nsub test_double():\n    assert(double(2) = 4)\nendsub\n")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"test", filepath.Join(dir, "game.bas")}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 || stdout.String() != "ok: 1 tests passed\n" {
+		t.Errorf("expected the import to resolve next to the script, got %d:\n%s", code, stdout.String())
+	}
+}
+
+func TestTestCommandIncludesSiblingFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "mathutil.bas", "function double(x):\n    return x * 2\nendfunction\n")
+	writeScript(t, dir, "game.bas", "include \"mathutil.bas\"\nsub test_double():\n    assert(double(2) = 4)\nendsub\n")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"test", filepath.Join(dir, "game.bas")}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 || stdout.String() != "ok: 1 tests passed\n" {
+		t.Errorf("expected the include to resolve next to the script, got %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+}
@@ -1,13 +1,14 @@
 package main
 
 import (
-	"time"
+	"fmt"
 
 	"github.com/mechanical-lich/mechanical-basic/pkg/basic"
 )
 
 func main() {
 	mBasic := basic.NewMechanicalBasic()
+	mBasic.SetPrintFunc(func(value any) {}) // Keep output out of the timings
 
 	code := `
 	let x = 10
@@ -19,30 +20,12 @@ func main() {
 	print "Final: " + x
 	`
 
-	// First run
-	startTime := time.Now()
-	err := mBasic.Run(code)
+	result, err := mBasic.RunBenchmark(code, 1000)
 	if err != nil {
 		panic(err)
 	}
-	elapsed := time.Since(startTime)
-	println("First run took:", elapsed.String())
 
-	// Second run
-	startTime = time.Now()
-	err = mBasic.Run(code)
-	if err != nil {
-		panic(err)
-	}
-	elapsed = time.Since(startTime)
-	println("Second run took:", elapsed.String())
-
-	// Third run
-	startTime = time.Now()
-	err = mBasic.Run(code)
-	if err != nil {
-		panic(err)
-	}
-	elapsed = time.Since(startTime)
-	println("Second run took:", elapsed.String())
+	fmt.Println("Uncached run took:", result.Uncached)
+	fmt.Println("Cached run took:  ", result.Cached)
+	fmt.Printf("Caching is %.1fx faster\n", result.Speedup())
 }
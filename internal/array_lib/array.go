@@ -0,0 +1,185 @@
+package arraylib
+
+import (
+	"fmt"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Caller invokes a script or external function by name. It is used by the
+// helpers that accept a function reference, such as CountIf and Filter.
+type Caller func(name string, args ...interface{}) (interface{}, error)
+
+// Adder adds two numbers as the interpreter's + operator does, handling
+// integer overflow the same way. It is used by Sum.
+type Adder func(a, b interface{}) (interface{}, error)
+
+// Sum returns a builtin that sums a numeric array, adding with add. The
+// result is an int when every element is an int, otherwise a float.
+func Sum(add Adder) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("sum requires 1 argument")
+		}
+
+		arr, err := basic.EnsureArray(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("sum: %v", err)
+		}
+
+		var total interface{} = 0
+		for idx, elem := range arr {
+			if _, err := basic.EnsureFloat(elem); err != nil {
+				return nil, fmt.Errorf("sum: element %d must be numeric: %v", idx, err)
+			}
+			if total, err = add(total, elem); err != nil {
+				return nil, fmt.Errorf("sum: %w", err)
+			}
+		}
+
+		return total, nil
+	}
+}
+
+// Avg returns the arithmetic mean of a numeric array as a float
+func Avg(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("avg requires 1 argument")
+	}
+
+	arr, err := basic.EnsureArray(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("avg: %v", err)
+	}
+
+	if len(arr) == 0 {
+		return nil, fmt.Errorf("avg: array is empty")
+	}
+
+	total := 0.0
+	for idx, elem := range arr {
+		v, err := basic.EnsureFloat(elem)
+		if err != nil {
+			return nil, fmt.Errorf("avg: element %d must be numeric: %v", idx, err)
+		}
+		total += v
+	}
+
+	return total / float64(len(arr)), nil
+}
+
+// Min returns the smallest element of a numeric array, keeping its type.
+// It also accepts the values directly: min(3, 1, 2).
+func Min(args ...interface{}) (interface{}, error) {
+	return extreme("min", args, func(a, b float64) bool { return a < b })
+}
+
+// Max returns the largest element of a numeric array, keeping its type.
+// It also accepts the values directly: max(3, 1, 2).
+func Max(args ...interface{}) (interface{}, error) {
+	return extreme("max", args, func(a, b float64) bool { return a > b })
+}
+
+// CountIf returns a builtin that counts the elements of an array for which
+// the named predicate function returns a truthy value:
+// count_if(values, "isEven")
+func CountIf(call Caller) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		arr, predicate, err := arrayAndCallback("count_if", args)
+		if err != nil {
+			return nil, err
+		}
+
+		count := 0
+		for _, elem := range arr {
+			result, err := call(predicate, elem)
+			if err != nil {
+				return nil, err
+			}
+			if basic.IsTruthy(result) {
+				count++
+			}
+		}
+
+		return count, nil
+	}
+}
+
+// Filter returns a builtin that builds a new array holding the elements for
+// which the named predicate function returns a truthy value:
+// filter(values, "isEven")
+func Filter(call Caller) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		arr, predicate, err := arrayAndCallback("filter", args)
+		if err != nil {
+			return nil, err
+		}
+
+		result := []interface{}{}
+		for _, elem := range arr {
+			keep, err := call(predicate, elem)
+			if err != nil {
+				return nil, err
+			}
+			if basic.IsTruthy(keep) {
+				result = append(result, elem)
+			}
+		}
+
+		return result, nil
+	}
+}
+
+// extreme implements Min and Max, returning the element that wins the comparison
+func extreme(name string, args []interface{}, better func(a, b float64) bool) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s requires at least 1 argument", name)
+	}
+
+	arr := args
+	if len(args) == 1 {
+		var err error
+		arr, err = basic.EnsureArray(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	if len(arr) == 0 {
+		return nil, fmt.Errorf("%s: array is empty", name)
+	}
+
+	var best interface{}
+	bestVal := 0.0
+	for idx, elem := range arr {
+		v, err := basic.EnsureFloat(elem)
+		if err != nil {
+			return nil, fmt.Errorf("%s: element %d must be numeric: %v", name, idx, err)
+		}
+		if best == nil || better(v, bestVal) {
+			best = elem
+			bestVal = v
+		}
+	}
+
+	return best, nil
+}
+
+// arrayAndCallback validates the (array, function name) argument pair
+func arrayAndCallback(name string, args []interface{}) ([]interface{}, string, error) {
+	if len(args) != 2 {
+		return nil, "", fmt.Errorf("%s requires 2 arguments", name)
+	}
+
+	arr, err := basic.EnsureArray(args[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: first argument: %v", name, err)
+	}
+
+	callback, err := basic.EnsureString(args[1])
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: second argument must be a function name: %v", name, err)
+	}
+
+	return arr, callback, nil
+}
@@ -0,0 +1,130 @@
+package arraylib
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// add adds ints and floats, failing on int overflow
+func add(a, b interface{}) (interface{}, error) {
+	if x, ok := a.(int); ok {
+		if y, ok := b.(int); ok {
+			if sum := x + y; (x^sum)&(y^sum) >= 0 {
+				return sum, nil
+			}
+			return nil, fmt.Errorf("integer overflow: %d + %d", x, y)
+		}
+	}
+	x, _ := a.(float64)
+	if n, ok := a.(int); ok {
+		x = float64(n)
+	}
+	y, _ := b.(float64)
+	if n, ok := b.(int); ok {
+		y = float64(n)
+	}
+	return x + y, nil
+}
+
+func TestSum(t *testing.T) {
+	sum := Sum(add)
+	result, err := sum([]interface{}{1, 2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 6 {
+		t.Errorf("expected 6, got %v", result)
+	}
+
+	result, err = sum([]interface{}{1, 2.5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 3.5 {
+		t.Errorf("expected 3.5, got %v", result)
+	}
+
+	if _, err := sum([]interface{}{1, "x"}); err == nil {
+		t.Error("expected error for non-numeric element")
+	}
+	if _, err := sum([]interface{}{math.MaxInt, 1}); err == nil {
+		t.Error("expected error for an overflowing total")
+	}
+}
+
+func TestAvg(t *testing.T) {
+	result, err := Avg([]interface{}{1, 2, 3, 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 2.5 {
+		t.Errorf("expected 2.5, got %v", result)
+	}
+
+	if _, err := Avg([]interface{}{}); err == nil {
+		t.Error("expected error for empty array")
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	arr := []interface{}{3, 1.5, 7, -2}
+
+	result, err := Min(arr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != -2 {
+		t.Errorf("expected -2, got %v", result)
+	}
+
+	result, err = Max(arr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 7 {
+		t.Errorf("expected 7, got %v", result)
+	}
+
+	result, err = Max(4, 9.5, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 9.5 {
+		t.Errorf("expected 9.5, got %v", result)
+	}
+
+	if _, err := Max("not an array"); err == nil {
+		t.Error("expected error for non-array argument")
+	}
+}
+
+func TestCountIfAndFilter(t *testing.T) {
+	isEven := func(name string, args ...interface{}) (interface{}, error) {
+		if name != "iseven" {
+			return nil, fmt.Errorf("undefined function: %s", name)
+		}
+		return args[0].(int)%2 == 0, nil
+	}
+	arr := []interface{}{1, 2, 3, 4, 6}
+
+	count, err := CountIf(isEven)(arr, "iseven")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3, got %v", count)
+	}
+
+	filtered, err := Filter(isEven)(arr, "iseven")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(filtered) != "[2 4 6]" {
+		t.Errorf("expected [2 4 6], got %v", filtered)
+	}
+
+	if _, err := CountIf(isEven)(arr, "missing"); err == nil {
+		t.Error("expected error from unknown predicate")
+	}
+}
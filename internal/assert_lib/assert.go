@@ -0,0 +1,107 @@
+package assertlib
+
+import (
+	"bytes"
+	"fmt"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Assert fails with an error unless its condition is truthy:
+// assert(cond[, message])
+func Assert(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("assert requires 1 or 2 arguments")
+	}
+
+	if basic.IsTruthy(args[0]) {
+		return nil, nil
+	}
+
+	if len(args) == 2 {
+		return nil, fmt.Errorf("assertion failed: %s", basic.ToString(args[1]))
+	}
+	return nil, fmt.Errorf("assertion failed")
+}
+
+// ExpectEq fails with an error unless both values are equal:
+// expect_eq(actual, expected[, message]). Numbers compare by value, so 1 and
+// 1.0 are equal; arrays and maps compare element by element.
+func ExpectEq(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("expect_eq requires 2 or 3 arguments")
+	}
+
+	if Equal(args[0], args[1]) {
+		return nil, nil
+	}
+
+	if len(args) == 3 {
+		return nil, fmt.Errorf("expect_eq: %s: got %s, expected %s", basic.ToString(args[2]), describe(args[0]), describe(args[1]))
+	}
+	return nil, fmt.Errorf("expect_eq: got %s, expected %s", describe(args[0]), describe(args[1]))
+}
+
+// Equal reports whether two script values are equal
+func Equal(a, b interface{}) bool {
+	if af, ok := number(a); ok {
+		bf, ok := number(b)
+		return ok && af == bf
+	}
+
+	switch av := a.(type) {
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for idx := range av {
+			if !Equal(av[idx], bv[idx]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, elem := range av {
+			other, ok := bv[key]
+			if !ok || !Equal(elem, other) {
+				return false
+			}
+		}
+		return true
+	case []byte:
+		bv, ok := b.([]byte)
+		return ok && bytes.Equal(av, bv)
+	case string, bool, nil:
+		return a == b
+	default:
+		return false
+	}
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// describe renders a value for a failure message, quoting strings
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,63 @@
+package assertlib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssert(t *testing.T) {
+	if _, err := Assert(true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := Assert(1, "nonzero is truthy"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err := Assert(false)
+	if err == nil || err.Error() != "assertion failed" {
+		t.Errorf("expected plain failure, got %v", err)
+	}
+
+	_, err = Assert(0, "hp must be positive")
+	if err == nil || err.Error() != "assertion failed: hp must be positive" {
+		t.Errorf("expected failure with message, got %v", err)
+	}
+
+	if _, err := Assert(); err == nil {
+		t.Error("expected error for missing argument")
+	}
+}
+
+func TestExpectEq(t *testing.T) {
+	passing := [][]interface{}{
+		{1, 1},
+		{1, 1.0},
+		{"a", "a"},
+		{nil, nil},
+		{[]interface{}{1, "x"}, []interface{}{1.0, "x"}},
+		{map[string]interface{}{"k": 2}, map[string]interface{}{"k": 2}},
+	}
+	for _, args := range passing {
+		if _, err := ExpectEq(args...); err != nil {
+			t.Errorf("%v: unexpected error: %v", args, err)
+		}
+	}
+
+	failing := [][]interface{}{
+		{1, 2},
+		{"1", 1},
+		{true, 1},
+		{[]interface{}{1}, []interface{}{1, 2}},
+		{map[string]interface{}{"k": 2}, map[string]interface{}{"j": 2}},
+	}
+	for _, args := range failing {
+		if _, err := ExpectEq(args...); err == nil {
+			t.Errorf("%v: expected failure", args)
+		}
+	}
+
+	_, err := ExpectEq("hi", "ho", "greeting")
+	if err == nil || !strings.Contains(err.Error(), `greeting: got "hi", expected "ho"`) {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
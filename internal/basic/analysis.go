@@ -0,0 +1,594 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Warning is a non-fatal diagnostic produced when a program is parsed.
+// Warnings never stop a script from running.
+type Warning struct {
+	File    string // Included file the warning is about, "" for the script itself
+	Line    int
+	Column  int
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", positionText(w.File, w.Line, w.Column), w.Message)
+}
+
+// Warnings returns the diagnostics for the program most recently run,
+// loaded, evaluated or validated
+func (i *Interpreter) Warnings() []Warning {
+	warnings := make([]Warning, len(i.warnings))
+	copy(warnings, i.warnings)
+	return warnings
+}
+
+// analyzer checks a parsed program for misuse that the grammar alone can't
+// catch. Errors reject the program; warnings are reported alongside it.
+type analyzer struct {
+	funcs    map[string]*FunctionStatement
+	types    map[string]*TypeStatement
+	consts   map[string]*ConstStatement
+	eval     bool
+	caseSens bool // Identifiers are case-sensitive
+	warnings []Warning
+	err      error   // The first of errs
+	errs     []error // Every error found, for ValidateAll
+
+	// GOTO may only target a label in its own block or an enclosing one,
+	// within the same function
+	labels     map[string]*LabelStatement // Labels of the function being checked
+	visible    []map[string]bool          // Labels of each enclosing block, innermost last
+	unresolved []*GotoStatement           // GOTOs whose target isn't in scope
+
+	fn      *FunctionStatement // Function being checked, nil at the top level
+	globals map[string]bool    // Names the function has declared GLOBAL
+	loops   []Statement        // Loops enclosing the statement being checked, innermost last
+
+	// Variables are only known to be unused once the whole program is seen
+	reads       map[string]bool // Names read anywhere in the program
+	locals      []variableDecl  // Variables declared inside functions, first declaration each
+	globalNames map[string]Node // Variables assigned at the top level, first assignment each
+	params      []variableDecl  // Parameters of every function
+}
+
+// variableDecl is a variable or parameter declared in a function
+type variableDecl struct {
+	node Node
+	name string
+	fn   *FunctionStatement
+}
+
+// analyze runs the static checks over a program. In eval mode top-level
+// expression statements produce the result, so they are never flagged.
+func analyze(prog *Program, eval, caseSensitive bool) ([]Warning, error) {
+	a := runAnalysis(prog, eval, caseSensitive)
+	return a.warnings, a.err
+}
+
+// runAnalysis runs the static checks and returns the analyzer holding the
+// warnings and errors found
+func runAnalysis(prog *Program, eval, caseSensitive bool) *analyzer {
+	a := &analyzer{
+		funcs:    make(map[string]*FunctionStatement),
+		types:    make(map[string]*TypeStatement),
+		consts:   make(map[string]*ConstStatement),
+		eval:     eval,
+		caseSens: caseSensitive,
+		labels:   make(map[string]*LabelStatement),
+
+		reads:       make(map[string]bool),
+		globalNames: make(map[string]Node),
+	}
+	for _, stmt := range prog.Statements {
+		switch s := stmt.(type) {
+		case *FunctionStatement:
+			a.funcs[a.ident(s.Name)] = s
+		case *ConstStatement:
+			name := a.ident(s.Name)
+			if prev, dup := a.consts[name]; dup {
+				a.fail(s, "constant %s is already defined at line %d", s.Name, prev.Line)
+			}
+			a.consts[name] = s
+		case *TypeStatement:
+			name := a.ident(s.Name)
+			if prev, dup := a.types[name]; dup {
+				a.fail(s, "type %s is already defined at line %d", s.Name, prev.Line)
+			}
+			a.types[name] = s
+		}
+	}
+	for _, stmt := range prog.Statements {
+		if typ, ok := stmt.(*TypeStatement); ok {
+			if fn, clash := a.funcs[a.ident(typ.Name)]; clash {
+				a.fail(typ, "type %s has the same name as the function defined at line %d", typ.Name, fn.Line)
+			}
+		}
+	}
+
+	a.statements(prog.Statements, true)
+	a.resolveGotos()
+	a.checkVariables()
+	return a
+}
+
+func (a *analyzer) warn(node Node, format string, args ...interface{}) {
+	line, col := node.Position()
+	a.warnings = append(a.warnings, Warning{File: node.SourceFile(), Line: line, Column: col, Message: fmt.Sprintf(format, args...)})
+}
+
+func (a *analyzer) fail(node Node, format string, args ...interface{}) {
+	line, col := node.Position()
+	a.errs = append(a.errs, parseError(ErrorCheck, node.SourceFile(), line, col, format, args...))
+	if a.err == nil {
+		a.err = a.errs[0]
+	}
+}
+
+// ident and sameIdent fold identifiers like the interpreter does
+func (a *analyzer) ident(name string) string {
+	if a.caseSens {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+func (a *analyzer) sameIdent(x, y string) bool {
+	if a.caseSens {
+		return x == y
+	}
+	return strings.EqualFold(x, y)
+}
+
+func (a *analyzer) statements(stmts []Statement, topLevel bool) {
+	visible := make(map[string]bool)
+	for _, stmt := range stmts {
+		label, ok := stmt.(*LabelStatement)
+		if !ok {
+			continue
+		}
+		name := a.ident(label.Name)
+		if prev, dup := a.labels[name]; dup {
+			a.fail(label, "label %s is already defined at line %d", label.Name, prev.Line)
+		}
+		a.labels[name] = label
+		visible[name] = true
+	}
+
+	a.visible = append(a.visible, visible)
+	for _, stmt := range stmts {
+		a.statement(stmt, topLevel)
+	}
+	a.visible = a.visible[:len(a.visible)-1]
+}
+
+// block checks the body of an IF, loop or TRY. Functions may only be defined
+// at the top level of the script or of another function's body.
+func (a *analyzer) block(stmts []Statement) {
+	for _, stmt := range stmts {
+		if fn, ok := stmt.(*FunctionStatement); ok {
+			a.fail(fn, "function %s must be defined at the top level of the script or of a function", fn.Name)
+		}
+	}
+	a.statements(stmts, false)
+}
+
+// loopBody checks the body of a loop, which EXIT statements in it may leave
+func (a *analyzer) loopBody(loop Statement, body []Statement) {
+	a.loops = append(a.loops, loop)
+	a.block(body)
+	a.loops = a.loops[:len(a.loops)-1]
+}
+
+// checkExit rejects an EXIT that has nothing to leave: a loop of its kind in
+// the same function, or a function or SUB of its kind
+func (a *analyzer) checkExit(stmt *ExitStatement) {
+	switch stmt.Kind {
+	case TOKEN_FUNCTION, TOKEN_SUB:
+		if a.fn == nil {
+			a.fail(stmt, "EXIT %s is only allowed inside a %s", stmt.Kind, stmt.Kind)
+		} else if a.fn.IsSub != (stmt.Kind == TOKEN_SUB) {
+			want := TOKEN_FUNCTION
+			if a.fn.IsSub {
+				want = TOKEN_SUB
+			}
+			a.fail(stmt, "EXIT %s cannot leave %s; use EXIT %s", stmt.Kind, a.fn.Name, want)
+		}
+	case TOKEN_FOR:
+		for _, loop := range a.loops {
+			if s, ok := loop.(*ForStatement); ok && (stmt.Variable == "" || a.sameIdent(s.Variable, stmt.Variable)) {
+				return
+			}
+		}
+		if stmt.Variable != "" {
+			a.fail(stmt, "EXIT FOR %s is not inside a FOR %s loop", stmt.Variable, stmt.Variable)
+		} else {
+			a.fail(stmt, "EXIT FOR is not inside a FOR loop")
+		}
+	default:
+		for _, loop := range a.loops {
+			if _, ok := loop.(*DoLoopStatement); ok {
+				return
+			}
+		}
+		a.fail(stmt, "EXIT %s is not inside a DO loop", stmt.Kind)
+	}
+}
+
+// checkAssignable rejects a statement that assigns or declares a constant's name
+func (a *analyzer) checkAssignable(node Node, name string) {
+	if _, ok := a.consts[a.ident(name)]; ok {
+		a.fail(node, "cannot assign to constant %s", name)
+	}
+}
+
+// checkLocal rejects declaring a local variable the function has already
+// declared GLOBAL
+func (a *analyzer) checkLocal(node Node, name string) {
+	if a.globals[a.ident(name)] {
+		a.fail(node, "%s is declared GLOBAL and cannot also be a local variable", name)
+	}
+}
+
+// checkGlobal validates a GLOBAL declaration and records its names
+func (a *analyzer) checkGlobal(stmt *GlobalStatement) {
+	if a.fn == nil {
+		a.fail(stmt, "GLOBAL is only allowed inside a function")
+		return
+	}
+	for _, name := range stmt.Names {
+		for _, param := range a.fn.Params {
+			if a.sameIdent(param, name) {
+				a.fail(stmt, "%s is a parameter of %s and cannot be declared GLOBAL", name, a.fn.Name)
+			}
+		}
+		a.globals[a.ident(name)] = true
+	}
+}
+
+// checkGoto records a GOTO whose target isn't a label in scope
+func (a *analyzer) checkGoto(stmt *GotoStatement) {
+	name := a.ident(stmt.Label)
+	for _, visible := range a.visible {
+		if visible[name] {
+			return
+		}
+	}
+	a.unresolved = append(a.unresolved, stmt)
+}
+
+// resolveGotos reports the unresolved GOTOs of a function once all of its
+// labels are known
+func (a *analyzer) resolveGotos() {
+	for _, stmt := range a.unresolved {
+		if _, ok := a.labels[a.ident(stmt.Label)]; ok {
+			a.fail(stmt, "GOTO %s jumps into a block; the label must be in the same block or an enclosing one", stmt.Label)
+		} else {
+			a.fail(stmt, "GOTO %s: label not defined", stmt.Label)
+		}
+	}
+	a.unresolved = nil
+}
+
+func (a *analyzer) statement(stmt Statement, topLevel bool) {
+	switch s := stmt.(type) {
+	case *ConstStatement:
+		if !topLevel {
+			a.fail(s, "CONST %s must be declared at the top level", s.Name)
+		}
+		a.expression(s.Value)
+	case *ImportStatement:
+		if !topLevel {
+			a.fail(s, "IMPORT %q must be at the top level", s.Module)
+		}
+	case *OptionStatement:
+		if !topLevel {
+			a.fail(s, "OPTION %s must be at the top level of the script", s.Name)
+		}
+	case *IncludeStatement:
+		if !topLevel {
+			a.fail(s, "INCLUDE %q must be at the top level of the script", s.Path)
+		}
+	case *TypeStatement:
+		if !topLevel {
+			a.fail(s, "TYPE %s must be defined at the top level of the script", s.Name)
+		}
+		seen := make(map[string]bool)
+		for _, field := range s.Fields {
+			if seen[a.ident(field)] {
+				a.fail(s, "field %s is repeated in TYPE %s", field, s.Name)
+			}
+			seen[a.ident(field)] = true
+		}
+	case *LetStatement:
+		a.checkAssignable(s, s.Name)
+		a.checkLocal(s, s.Name)
+		a.declare(s, s.Name)
+		a.expression(s.Value)
+	case *LocalStatement:
+		if a.fn == nil {
+			a.fail(s, "LOCAL is only allowed inside a function")
+		}
+		a.checkAssignable(s, s.Name)
+		a.checkLocal(s, s.Name)
+		a.declare(s, s.Name)
+		a.expression(s.Value)
+	case *DimStatement:
+		a.checkAssignable(s, s.Name)
+		a.checkLocal(s, s.Name)
+		a.declare(s, s.Name)
+		for _, size := range s.Sizes {
+			a.expression(size)
+		}
+	case *MultiAssignStatement:
+		for _, target := range s.Targets {
+			a.checkAssignable(target, target.Name)
+			if s.Let {
+				a.checkLocal(target, target.Name)
+				a.declare(target, target.Name)
+			} else {
+				a.assign(target)
+			}
+			for _, index := range target.Indices {
+				a.expression(index)
+			}
+		}
+		for _, value := range s.Values {
+			a.expression(value)
+		}
+	case *AssignStatement:
+		a.checkAssignable(s, s.Name)
+		a.assign(s)
+		for _, index := range s.Indices {
+			a.expression(index)
+		}
+		if s.Value != nil {
+			a.expression(s.Value)
+		}
+	case *IfStatement:
+		a.expression(s.Condition)
+		a.block(s.ThenBlock)
+		for _, clause := range s.ElseIfClauses {
+			a.expression(clause.Condition)
+			a.block(clause.Block)
+		}
+		a.block(s.ElseBlock)
+	case *ForStatement:
+		a.checkAssignable(s, s.Variable)
+		a.checkLocal(s, s.Variable)
+		a.assignGlobal(s, s.Variable)
+		a.expression(s.Start)
+		a.expression(s.End)
+		a.loopBody(s, s.Body)
+	case *DoLoopStatement:
+		if s.Condition != nil {
+			a.expression(s.Condition)
+		}
+		a.loopBody(s, s.Body)
+	case *ExitStatement:
+		a.checkExit(s)
+	case *FunctionStatement:
+		if strings.EqualFold(s.Name, "iif") {
+			a.fail(s, "iif is built into the language and cannot be redefined")
+		}
+		for _, param := range s.Params {
+			a.checkAssignable(s, param)
+			a.params = append(a.params, variableDecl{node: s, name: param, fn: s})
+		}
+		for idx, typ := range s.Types {
+			if _, ok := a.types[a.ident(typ)]; typ != "" && !ok && !paramTypes[strings.ToLower(typ)] {
+				a.fail(s, "parameter %s of %s has unknown type %s", s.Params[idx], s.Name, typ)
+			}
+		}
+		for idx, def := range s.Defaults {
+			if def != nil {
+				a.expression(def)
+				a.checkArgType(def, s, idx)
+			}
+		}
+
+		// Each function has its own labels and GLOBAL declarations
+		labels, visible, unresolved := a.labels, a.visible, a.unresolved
+		fn, globals, loops := a.fn, a.globals, a.loops
+		a.labels, a.visible, a.unresolved = make(map[string]*LabelStatement), nil, nil
+		a.fn, a.globals, a.loops = s, make(map[string]bool), nil
+		a.statements(s.Body, false)
+		a.resolveGotos()
+		a.labels, a.visible, a.unresolved = labels, visible, unresolved
+		a.fn, a.globals, a.loops = fn, globals, loops
+	case *TryStatement:
+		if s.ErrorVar != "" {
+			a.checkAssignable(s, s.ErrorVar)
+			a.checkLocal(s, s.ErrorVar)
+		}
+		a.block(s.Body)
+		a.block(s.Handler)
+	case *GotoStatement:
+		a.checkGoto(s)
+	case *GlobalStatement:
+		a.checkGlobal(s)
+	case *ThrowStatement:
+		a.expression(s.Message)
+	case *OnErrorStatement:
+		// The handler may also be a host function, which can't be checked here
+		if fn, ok := a.funcs[a.ident(s.Handler)]; ok && (len(fn.Params) < 2 || requiredParams(fn) > 2) {
+			a.fail(s, "ON ERROR handler %s must take 2 parameters (message, line)", s.Handler)
+		}
+	case *ReturnStatement:
+		if s.Value != nil {
+			a.expression(s.Value)
+		}
+	case *PrintStatement:
+		for _, value := range s.Values {
+			a.expression(value)
+		}
+	case *ExpressionStatement:
+		call, ok := s.Expr.(*CallExpr)
+		if !ok {
+			a.expression(s.Expr)
+			return
+		}
+
+		// A call used as a statement may name a SUB; its arguments may not
+		a.reads[a.ident(call.Name)] = true // Or index an array held in a variable
+		for _, arg := range call.Args {
+			a.expression(arg)
+		}
+		for _, arg := range call.Named {
+			a.expression(arg.Value)
+		}
+		a.checkCallTypes(call)
+
+		if a.eval && topLevel {
+			return
+		}
+		if fn, ok := a.funcs[a.ident(call.Name)]; ok && !fn.IsSub {
+			a.warn(call, "result of function %s is discarded; declare it with SUB if it returns nothing", call.Name)
+		}
+	}
+}
+
+func (a *analyzer) expression(expr Expression) {
+	switch e := expr.(type) {
+	case *Identifier:
+		a.reads[a.ident(e.Name)] = true
+	case *BinaryExpr:
+		a.expression(e.Left)
+		a.expression(e.Right)
+	case *UnaryExpr:
+		a.expression(e.Operand)
+	case *CallExpr:
+		if fn, ok := a.funcs[a.ident(e.Name)]; ok && fn.IsSub {
+			a.fail(e, "SUB %s has no value and cannot be used in an expression", e.Name)
+		}
+		a.reads[a.ident(e.Name)] = true // Or index an array held in a variable
+		for _, arg := range e.Args {
+			a.expression(arg)
+		}
+		for _, arg := range e.Named {
+			a.expression(arg.Value)
+		}
+		a.checkCallTypes(e)
+	case *IndexExpr:
+		a.reads[a.ident(e.Name)] = true
+		for _, index := range e.Indices {
+			a.expression(index)
+		}
+	case *ConditionalExpr:
+		a.expression(e.Condition)
+		a.expression(e.Then)
+		a.expression(e.Else)
+	case *MemberExpr:
+		a.expression(e.Target)
+	case *SliceExpr:
+		a.expression(e.Target)
+		if e.Start != nil {
+			a.expression(e.Start)
+		}
+		if e.End != nil {
+			a.expression(e.End)
+		}
+	case *YieldExpr:
+		if e.Value != nil {
+			a.expression(e.Value)
+		}
+	}
+}
+
+// checkCallTypes rejects a literal argument that can't be passed to a typed
+// parameter of a script function
+func (a *analyzer) checkCallTypes(call *CallExpr) {
+	fn, ok := a.funcs[a.ident(call.Name)]
+	if !ok {
+		return
+	}
+	for idx, arg := range call.Args {
+		a.checkArgType(arg, fn, idx)
+	}
+	for _, arg := range call.Named {
+		for idx, param := range fn.Params {
+			if a.sameIdent(param, arg.Name) {
+				a.checkArgType(arg.Value, fn, idx)
+			}
+		}
+	}
+}
+
+// checkArgType checks a value for a typed parameter when it is a literal, so
+// its type is known before the script runs
+func (a *analyzer) checkArgType(value Expression, fn *FunctionStatement, idx int) {
+	var literal interface{}
+	switch v := value.(type) {
+	case *IntLiteral:
+		literal = v.Value
+	case *FloatLiteral:
+		literal = v.Value
+	case *StringLiteral:
+		literal = v.Value
+	case *BoolLiteral:
+		literal = v.Value
+	case *NullLiteral:
+		literal = nil
+	default:
+		return
+	}
+	if _, err := coerceParam(fn, idx, literal); err != nil {
+		a.fail(value, "%v", err)
+	}
+}
+
+// declare records a variable declared with LET, LOCAL or DIM
+func (a *analyzer) declare(node Node, name string) {
+	if a.fn == nil || a.globals[a.ident(name)] {
+		a.assignGlobal(node, name)
+		return
+	}
+	for _, local := range a.locals {
+		if local.fn == a.fn && a.sameIdent(local.name, name) {
+			return
+		}
+	}
+	a.locals = append(a.locals, variableDecl{node: node, name: name, fn: a.fn})
+}
+
+// assign records a plain assignment. Changing an element or field counts as
+// a use of the variable, since the array or record may be shared. Inside a
+// function the assignment may reach a variable of the caller, so only
+// top-level assignments are known to set globals.
+func (a *analyzer) assign(s *AssignStatement) {
+	if len(s.Indices) > 0 || len(s.Fields) > 0 {
+		a.reads[a.ident(s.Name)] = true
+		return
+	}
+	a.assignGlobal(s, s.Name)
+}
+
+// assignGlobal records a variable set at the top level
+func (a *analyzer) assignGlobal(node Node, name string) {
+	if a.fn != nil && !a.globals[a.ident(name)] {
+		return
+	}
+	if _, ok := a.globalNames[a.ident(name)]; !ok {
+		a.globalNames[a.ident(name)] = node
+	}
+}
+
+// checkVariables warns about variables declared in a function that nothing
+// in the program reads, and parameters that shadow a global variable. Globals
+// themselves are never reported unused, since the host may read them.
+func (a *analyzer) checkVariables() {
+	for _, local := range a.locals {
+		if !a.reads[a.ident(local.name)] {
+			a.warn(local.node, "variable %s is assigned but never read", local.name)
+		}
+	}
+	for _, param := range a.params {
+		if global, ok := a.globalNames[a.ident(param.name)]; ok {
+			line, _ := global.Position()
+			a.warn(param.node, "parameter %s of %s shadows the global variable %s set at line %d", param.name, param.fn.Name, param.name, line)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package basic
 type Node interface {
 	node()
 	Position() (line, column int)
+	SourceFile() string
 }
 
 // Statement represents an executable statement
@@ -24,6 +25,7 @@ type Expression interface {
 
 // Pos holds line and column information for error reporting
 type Pos struct {
+	File   string // Included file the node comes from, "" for the script itself
 	Line   int
 	Column int
 }
@@ -32,6 +34,10 @@ func (p Pos) Position() (line, column int) {
 	return p.Line, p.Column
 }
 
+func (p Pos) SourceFile() string {
+	return p.File
+}
+
 // -----------------------------------------------------------------------------
 // Program (root node)
 // -----------------------------------------------------------------------------
@@ -49,6 +55,10 @@ func (p *Program) Position() (line, column int) {
 	return 1, 1
 }
 
+func (p *Program) SourceFile() string {
+	return ""
+}
+
 // -----------------------------------------------------------------------------
 // Statements
 // -----------------------------------------------------------------------------
@@ -67,13 +77,104 @@ func (s *LetStatement) statement() {}
 type AssignStatement struct {
 	Pos
 	Name     string
-	Operator TokenType  // TOKEN_EQ, TOKEN_PLUS_EQ, TOKEN_MINUS_EQ, TOKEN_PLUS_PLUS, TOKEN_MINUS_MINUS
-	Value    Expression // nil for ++ and --
+	Indices  []Expression // Set when assigning an array element: a(i) = expr
+	Fields   []string     // Set when assigning a record field: v.x = expr, a(i).pos.x = expr
+	Operator TokenType    // TOKEN_EQ, TOKEN_PLUS_EQ, TOKEN_MINUS_EQ, TOKEN_PLUS_PLUS, TOKEN_MINUS_MINUS
+	Value    Expression   // nil for ++ and --
 }
 
 func (s *AssignStatement) node()      {}
 func (s *AssignStatement) statement() {}
 
+// MultiAssignStatement represents: [LET] a, b = expr, expr
+// Every value is evaluated before the targets are assigned, so a, b = b, a
+// swaps. A single array value is unpacked across the targets.
+type MultiAssignStatement struct {
+	Pos
+	Let     bool               // Declares the targets in the current scope, like LET
+	Targets []*AssignStatement // Plain (TOKEN_EQ) assignments without a Value
+	Values  []Expression
+}
+
+func (s *MultiAssignStatement) node()      {}
+func (s *MultiAssignStatement) statement() {}
+
+// ConstStatement represents: CONST NAME = expr
+type ConstStatement struct {
+	Pos
+	Name  string
+	Value Expression
+}
+
+func (s *ConstStatement) node()      {}
+func (s *ConstStatement) statement() {}
+
+// GlobalStatement represents: GLOBAL name[, name...]
+type GlobalStatement struct {
+	Pos
+	Names []string
+}
+
+func (s *GlobalStatement) node()      {}
+func (s *GlobalStatement) statement() {}
+
+// LocalStatement represents: LOCAL x = expr
+type LocalStatement struct {
+	Pos
+	Name  string
+	Value Expression
+}
+
+func (s *LocalStatement) node()      {}
+func (s *LocalStatement) statement() {}
+
+// TypeStatement represents: TYPE name, its field names one per line, ENDTYPE
+type TypeStatement struct {
+	Pos
+	Name   string
+	Fields []string
+}
+
+func (s *TypeStatement) node()      {}
+func (s *TypeStatement) statement() {}
+
+// IncludeStatement represents: INCLUDE "file.bas"
+type IncludeStatement struct {
+	Pos
+	Path string
+}
+
+func (s *IncludeStatement) node()      {}
+func (s *IncludeStatement) statement() {}
+
+// OptionStatement represents: OPTION EXPLICIT
+type OptionStatement struct {
+	Pos
+	Name string
+}
+
+func (s *OptionStatement) node()      {}
+func (s *OptionStatement) statement() {}
+
+// ImportStatement represents: IMPORT "module"
+type ImportStatement struct {
+	Pos
+	Module string
+}
+
+func (s *ImportStatement) node()      {}
+func (s *ImportStatement) statement() {}
+
+// DimStatement represents: DIM name(size[, size...])
+type DimStatement struct {
+	Pos
+	Name  string
+	Sizes []Expression // One per dimension
+}
+
+func (s *DimStatement) node()      {}
+func (s *DimStatement) statement() {}
+
 // IfStatement represents: IF cond THEN ... [ELSEIF cond THEN ...] [ELSE ...] ENDIF
 type IfStatement struct {
 	Pos
@@ -105,6 +206,20 @@ type ForStatement struct {
 func (s *ForStatement) node()      {}
 func (s *ForStatement) statement() {}
 
+// DoLoopStatement represents DO ... LOOP with an optional WHILE or UNTIL
+// condition, tested either after DO (before each pass) or after LOOP (after
+// each pass, so the body always runs at least once)
+type DoLoopStatement struct {
+	Pos
+	Condition Expression // nil for an unconditional DO ... LOOP
+	Until     bool       // UNTIL: loop while the condition is false
+	PreTest   bool       // Condition written after DO rather than LOOP
+	Body      []Statement
+}
+
+func (s *DoLoopStatement) node()      {}
+func (s *DoLoopStatement) statement() {}
+
 // BreakStatement represents: BREAK
 type BreakStatement struct {
 	Pos
@@ -113,12 +228,75 @@ type BreakStatement struct {
 func (s *BreakStatement) node()      {}
 func (s *BreakStatement) statement() {}
 
+// ExitStatement represents: EXIT FOR [var], EXIT DO, EXIT WHILE, EXIT FUNCTION
+// or EXIT SUB
+type ExitStatement struct {
+	Pos
+	Kind     TokenType // TOKEN_FOR, TOKEN_DO, TOKEN_WHILE, TOKEN_FUNCTION or TOKEN_SUB
+	Variable string    // Loop variable naming the FOR loop to leave; may be empty
+}
+
+func (s *ExitStatement) node()      {}
+func (s *ExitStatement) statement() {}
+
+// TryStatement represents: TRY ... CATCH [var] ... ENDTRY
+type TryStatement struct {
+	Pos
+	Body     []Statement
+	ErrorVar string // Receives the error message in the CATCH block; may be empty
+	Handler  []Statement
+}
+
+func (s *TryStatement) node()      {}
+func (s *TryStatement) statement() {}
+
+// OnErrorStatement represents: ON ERROR CALL handler
+type OnErrorStatement struct {
+	Pos
+	Handler string // Function called with (message, line) when a statement fails
+}
+
+func (s *OnErrorStatement) node()      {}
+func (s *OnErrorStatement) statement() {}
+
+// ThrowStatement represents: THROW message
+type ThrowStatement struct {
+	Pos
+	Message Expression
+}
+
+func (s *ThrowStatement) node()      {}
+func (s *ThrowStatement) statement() {}
+
+// LabelStatement marks a GOTO target: name:
+type LabelStatement struct {
+	Pos
+	Name string
+}
+
+func (s *LabelStatement) node()      {}
+func (s *LabelStatement) statement() {}
+
+// GotoStatement represents: GOTO label
+type GotoStatement struct {
+	Pos
+	Label string
+}
+
+func (s *GotoStatement) node()      {}
+func (s *GotoStatement) statement() {}
+
 // FunctionStatement represents: FUNCTION name(params): ... ENDFUNCTION
+// or SUB name(params): ... ENDSUB
 type FunctionStatement struct {
 	Pos
-	Name   string
-	Params []string
-	Body   []Statement
+	Name     string
+	Params   []string
+	Defaults []Expression // Default value of each parameter, nil where the caller must pass one
+	Types    []string     // Type each parameter is declared AS, "" where it has none
+	Body     []Statement
+	IsSub    bool   // SUBs return no value and may only be called as statements
+	Doc      string // ## doc comment above the definition, one line per comment
 }
 
 func (s *FunctionStatement) node()      {}
@@ -133,10 +311,12 @@ type ReturnStatement struct {
 func (s *ReturnStatement) node()      {}
 func (s *ReturnStatement) statement() {}
 
-// PrintStatement represents: PRINT expr
+// PrintStatement represents: PRINT [USING format;] expr[, expr...][;]
 type PrintStatement struct {
 	Pos
-	Value Expression
+	Using     Expression // Format of PRINT USING format; values, or nil
+	Values    []Expression
+	NoNewline bool // A trailing ; keeps the next PRINT on the same line
 }
 
 func (s *PrintStatement) node()      {}
@@ -191,6 +371,14 @@ type BoolLiteral struct {
 func (e *BoolLiteral) node()       {}
 func (e *BoolLiteral) expression() {}
 
+// NullLiteral represents: null (or nil)
+type NullLiteral struct {
+	Pos
+}
+
+func (e *NullLiteral) node()       {}
+func (e *NullLiteral) expression() {}
+
 // Identifier represents a variable reference: x, foo
 type Identifier struct {
 	Pos
@@ -224,9 +412,75 @@ func (e *UnaryExpr) expression() {}
 // CallExpr represents a function call: pow(2, 3), getX()
 type CallExpr struct {
 	Pos
-	Name string
-	Args []Expression
+	Name  string
+	Args  []Expression
+	Named []*NamedArg // Arguments passed by parameter name, after the positional ones
 }
 
 func (e *CallExpr) node()       {}
 func (e *CallExpr) expression() {}
+
+// NamedArg is a call argument passed by parameter name: name = expr
+type NamedArg struct {
+	Pos
+	Name  string
+	Value Expression
+}
+
+func (a *NamedArg) node() {}
+
+// ConditionalExpr represents iif(cond, a, b). Only the chosen branch is
+// evaluated, so it is parsed as an expression rather than called as a function.
+type ConditionalExpr struct {
+	Pos
+	Condition Expression
+	Then      Expression
+	Else      Expression
+}
+
+func (e *ConditionalExpr) node()       {}
+func (e *ConditionalExpr) expression() {}
+
+// IndexExpr represents an element of an array declared with DIM: a(i), grid(x, y)
+type IndexExpr struct {
+	Pos
+	Name    string
+	Indices []Expression
+}
+
+func (e *IndexExpr) node()       {}
+func (e *IndexExpr) expression() {}
+
+// SliceExpr represents a character or substring of a string: s[i], s[a:b].
+// Start and End are nil when omitted from a slice (s[:b], s[a:]).
+type SliceExpr struct {
+	Pos
+	Target Expression
+	Start  Expression
+	End    Expression
+	Slice  bool // true for s[a:b], false for s[i]
+}
+
+func (e *SliceExpr) node()       {}
+func (e *SliceExpr) expression() {}
+
+// MemberExpr represents a field of a record: v.x
+type MemberExpr struct {
+	Pos
+	Target Expression
+	Field  string
+}
+
+func (e *MemberExpr) node()       {}
+func (e *MemberExpr) expression() {}
+
+// YieldExpr represents: YIELD [expr]. It pauses the coroutine running it,
+// handing the value to the host, and evaluates to the value the host
+// resumes it with.
+type YieldExpr struct {
+	Pos
+	Value Expression // nil for a bare YIELD
+}
+
+func (e *YieldExpr) node()       {}
+func (e *YieldExpr) expression() {}
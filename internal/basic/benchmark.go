@@ -0,0 +1,70 @@
+package basic
+
+import (
+	"fmt"
+	"time"
+)
+
+// BenchmarkResult holds the average time per run of a benchmarked script
+type BenchmarkResult struct {
+	Iterations int           // Runs timed for each mode
+	Uncached   time.Duration // Average per run when the code is parsed every time
+	Cached     time.Duration // Average per run with the AST served from the cache
+}
+
+// Speedup returns how many times faster cached runs are than uncached ones
+func (r BenchmarkResult) Speedup() float64 {
+	if r.Cached <= 0 {
+		return 0
+	}
+	return float64(r.Uncached) / float64(r.Cached)
+}
+
+// ParseTime estimates the time spent tokenizing and parsing per run
+func (r BenchmarkResult) ParseTime() time.Duration {
+	if r.Uncached < r.Cached {
+		return 0
+	}
+	return r.Uncached - r.Cached
+}
+
+// RunBenchmark runs the code n times parsing it afresh each time, then n
+// times from the AST cache, and reports the average time per run of each.
+// Output from PRINT goes to the interpreter's print function as usual.
+func (i *Interpreter) RunBenchmark(code string, n int) (BenchmarkResult, error) {
+	if n < 1 {
+		return BenchmarkResult{}, fmt.Errorf("benchmark: iterations must be at least 1, got %d", n)
+	}
+
+	result := BenchmarkResult{Iterations: n}
+
+	start := time.Now()
+	for run := 0; run < n; run++ {
+		prog, _, err := i.parseProgram("", code, false)
+		if err != nil {
+			return BenchmarkResult{}, err
+		}
+		if prog, err = i.expandIncludes(prog, false); err != nil {
+			return BenchmarkResult{}, err
+		}
+		if _, err := i.executeProgram(prog); err != nil {
+			return BenchmarkResult{}, err
+		}
+	}
+	result.Uncached = time.Since(start) / time.Duration(n)
+
+	// Warm the cache so the first timed run doesn't pay for parsing
+	if _, err := i.getOrParseProgram(code); err != nil {
+		return BenchmarkResult{}, err
+	}
+
+	start = time.Now()
+	for run := 0; run < n; run++ {
+		if err := i.Interpret(code); err != nil {
+			return BenchmarkResult{}, err
+		}
+	}
+	result.Cached = time.Since(start) / time.Duration(n)
+
+	return result, nil
+}
@@ -0,0 +1,40 @@
+package basic
+
+import (
+	"fmt"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// coreFuncs are the functions every interpreter provides, because they belong
+// to the language rather than to a library. A host can replace one by
+// registering a function with the same name.
+var coreFuncs = map[string]ExternalFunc{
+	"isnull": isNull,
+	"typeof": typeOf,
+}
+
+// coreFunctions returns a new function table holding only the core functions
+func coreFunctions() map[string]ExternalFunc {
+	funcs := make(map[string]ExternalFunc, len(coreFuncs))
+	for name, fn := range coreFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// isNull reports whether a value is NULL: isnull(x)
+func isNull(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("isnull requires 1 argument")
+	}
+	return args[0] == nil, nil
+}
+
+// typeOf returns the name of a value's type: typeof(x). See functions.TypeName.
+func typeOf(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("typeof requires 1 argument")
+	}
+	return functions.TypeName(args[0]), nil
+}
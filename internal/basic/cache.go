@@ -0,0 +1,179 @@
+package basic
+
+import (
+	"container/list"
+	"time"
+)
+
+// CacheEntry describes a parsed program held in the AST cache
+type CacheEntry struct {
+	Hash     string    // Cache key derived from the source code
+	Size     int       // Length of the source code in bytes (a proxy for AST memory)
+	LastUsed time.Time // Last time the entry was parsed or served from the cache
+	Pinned   bool      // Pinned entries are never evicted
+}
+
+// programCache holds parsed programs by hash, evicting the least recently
+// used unpinned program once it holds more than maxSize. Interpreters made
+// with Spawn share one.
+type programCache struct {
+	programs map[string]*cachedProgram
+	recent   *list.List // The cached programs, most recently used first
+	maxSize  int        // Most programs held at once, 0 for no limit
+}
+
+// cachedProgram is a single AST cache slot
+type cachedProgram struct {
+	hash     string
+	program  *Program
+	warnings []Warning
+	size     int
+	lastUsed time.Time
+	pinned   bool
+	elem     *list.Element // Position in programCache.recent
+}
+
+func newProgramCache(maxSize int) *programCache {
+	return &programCache{
+		programs: make(map[string]*cachedProgram),
+		recent:   list.New(),
+		maxSize:  maxSize,
+	}
+}
+
+// add stores a program in the cache as the most recently used one, evicting
+// others if the cache is full
+func (c *programCache) add(cached *cachedProgram) {
+	c.programs[cached.hash] = cached
+	cached.elem = c.recent.PushFront(cached)
+	c.trim()
+}
+
+// remove drops a program from the cache
+func (c *programCache) remove(cached *cachedProgram) {
+	delete(c.programs, cached.hash)
+	c.recent.Remove(cached.elem)
+}
+
+// trim evicts the least recently used unpinned programs until the cache is
+// within its size. Pinned programs are kept even if that leaves it over.
+func (c *programCache) trim() {
+	elem := c.recent.Back()
+	for c.maxSize > 0 && len(c.programs) > c.maxSize && elem != nil {
+		prev := elem.Prev()
+		if cached := elem.Value.(*cachedProgram); !cached.pinned {
+			c.remove(cached)
+		}
+		elem = prev
+	}
+}
+
+// touch records a use of the cache slot
+func (i *Interpreter) touch(cached *cachedProgram) {
+	cached.lastUsed = time.Now()
+	if cached.elem != nil {
+		i.astCache.recent.MoveToFront(cached.elem)
+	}
+}
+
+// SetCacheSize caps the number of programs the AST cache holds. Once it is
+// full, caching another program evicts the least recently used one that
+// isn't pinned. Lowering the size evicts programs right away. 0, the
+// default, means no limit.
+func (i *Interpreter) SetCacheSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	i.astCache.maxSize = n
+	i.astCache.trim()
+}
+
+// SetCaching turns the AST cache on or off. While it is off every script is
+// parsed afresh and the cache is neither consulted nor added to, which saves
+// the work for scripts that only run once. Caching is on by default.
+func (i *Interpreter) SetCaching(enabled bool) {
+	i.noCache = !enabled
+}
+
+// Caching reports whether the AST cache is in use
+func (i *Interpreter) Caching() bool {
+	return !i.noCache
+}
+
+// CacheKey returns the key under which the given code is cached when it is
+// run by Interpret or Load. Code run by Evaluate is cached under EvalCacheKey.
+func (i *Interpreter) CacheKey(code string) string {
+	return i.hashCode(code)
+}
+
+// EvalCacheKey returns the key under which the given code is cached when it
+// is run by Evaluate, which parses it differently
+func (i *Interpreter) EvalCacheKey(code string) string {
+	return i.hashCode("eval:" + code)
+}
+
+// CachedPrograms lists the programs currently held in the AST cache,
+// most recently used first
+func (i *Interpreter) CachedPrograms() []CacheEntry {
+	slots := make([]*cachedProgram, 0, len(i.astCache.programs))
+	for elem := i.astCache.recent.Front(); elem != nil; elem = elem.Next() {
+		slots = append(slots, elem.Value.(*cachedProgram))
+	}
+
+	entries := make([]CacheEntry, len(slots))
+	for idx, cached := range slots {
+		entries[idx] = CacheEntry{
+			Hash:     cached.hash,
+			Size:     cached.size,
+			LastUsed: cached.lastUsed,
+			Pinned:   cached.pinned,
+		}
+	}
+	return entries
+}
+
+// PinProgram marks a cached program so it is never evicted.
+// Returns false if no program is cached under the hash.
+func (i *Interpreter) PinProgram(hash string) bool {
+	cached, ok := i.astCache.programs[hash]
+	if !ok {
+		return false
+	}
+	cached.pinned = true
+	return true
+}
+
+// UnpinProgram clears the pinned flag of a cached program.
+// Returns false if no program is cached under the hash.
+func (i *Interpreter) UnpinProgram(hash string) bool {
+	cached, ok := i.astCache.programs[hash]
+	if !ok {
+		return false
+	}
+	cached.pinned = false
+	return true
+}
+
+// EvictProgram removes a program from the cache.
+// Returns false if the hash is not cached or the entry is pinned.
+func (i *Interpreter) EvictProgram(hash string) bool {
+	cached, ok := i.astCache.programs[hash]
+	if !ok || cached.pinned {
+		return false
+	}
+	i.astCache.remove(cached)
+	return true
+}
+
+// ClearCache evicts every unpinned program and returns the number removed
+func (i *Interpreter) ClearCache() int {
+	removed := 0
+	for _, cached := range i.astCache.programs {
+		if cached.pinned {
+			continue
+		}
+		i.astCache.remove(cached)
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,151 @@
+package basic
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// cacheFileVersion identifies the layout of the AST written by ExportCache.
+// Bump it whenever a node type changes, so stale files are rejected rather
+// than decoded into programs that are missing fields.
+const cacheFileVersion = 2
+
+// cacheFile is the content of a file written by ExportCache
+type cacheFile struct {
+	Version  int
+	Programs []cacheFileEntry // Least recently used first
+}
+
+// cacheFileEntry is a cached program as written by ExportCache
+type cacheFileEntry struct {
+	Hash     string
+	Program  *Program
+	Warnings []Warning
+	Size     int
+	Pinned   bool
+}
+
+func init() {
+	for _, node := range []interface{}{
+		&LetStatement{}, &AssignStatement{}, &MultiAssignStatement{}, &ConstStatement{},
+		&GlobalStatement{}, &LocalStatement{}, &TypeStatement{}, &IncludeStatement{},
+		&OptionStatement{}, &ImportStatement{}, &DimStatement{}, &IfStatement{},
+		&ForStatement{}, &DoLoopStatement{}, &BreakStatement{}, &ExitStatement{},
+		&TryStatement{}, &OnErrorStatement{}, &ThrowStatement{}, &LabelStatement{},
+		&GotoStatement{}, &FunctionStatement{}, &ReturnStatement{}, &PrintStatement{},
+		&ExpressionStatement{},
+
+		&IntLiteral{}, &FloatLiteral{}, &StringLiteral{}, &BoolLiteral{}, &NullLiteral{},
+		&Identifier{}, &BinaryExpr{}, &UnaryExpr{}, &CallExpr{}, &ConditionalExpr{},
+		&IndexExpr{}, &SliceExpr{}, &MemberExpr{}, &YieldExpr{},
+	} {
+		gob.Register(node)
+	}
+}
+
+// ExportCache writes the programs in the AST cache to w, so a later run can
+// load them with ImportCache instead of parsing the scripts again. The file
+// can only be read by the same version of the library.
+func (i *Interpreter) ExportCache(w io.Writer) error {
+	file := cacheFile{Version: cacheFileVersion}
+	for elem := i.astCache.recent.Back(); elem != nil; elem = elem.Prev() {
+		cached := elem.Value.(*cachedProgram)
+		file.Programs = append(file.Programs, cacheFileEntry{
+			Hash:     cached.hash,
+			Program:  cached.program,
+			Warnings: cached.warnings,
+			Size:     cached.size,
+			Pinned:   cached.pinned,
+		})
+	}
+
+	if err := gob.NewEncoder(w).Encode(file); err != nil {
+		return fmt.Errorf("cannot export the AST cache: %w", err)
+	}
+	return nil
+}
+
+// ImportCache adds the programs written by ExportCache to the AST cache, so
+// scripts with the same source run without being parsed. Programs that are
+// already cached are kept. Nothing is added if r can't be read or holds
+// anything other than a cache file from this version of the library.
+func (i *Interpreter) ImportCache(r io.Reader) error {
+	var file cacheFile
+	if err := gob.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("invalid cache file: %w", err)
+	}
+	if file.Version != cacheFileVersion {
+		return fmt.Errorf("invalid cache file: version %d, expected %d", file.Version, cacheFileVersion)
+	}
+
+	for _, entry := range file.Programs {
+		if _, ok := i.astCache.programs[entry.Hash]; ok {
+			continue
+		}
+		prog := entry.Program
+		if prog == nil {
+			prog = &Program{} // gob leaves out a program with no statements
+		}
+		cached := &cachedProgram{
+			hash:     entry.Hash,
+			program:  prog,
+			warnings: entry.Warnings,
+			size:     entry.Size,
+			pinned:   entry.Pinned,
+		}
+		i.touch(cached)
+		i.astCache.add(cached)
+	}
+	return nil
+}
+
+// functionFields is FunctionStatement without its gob methods
+type functionFields FunctionStatement
+
+// defaultValue holds one entry of FunctionStatement.Defaults. gob can't write
+// a nil entry of a slice of interfaces, so the entries are boxed.
+type defaultValue struct {
+	Value Expression
+}
+
+// GobEncode writes the function for ExportCache, with its defaults boxed
+func (s *FunctionStatement) GobEncode() ([]byte, error) {
+	fields := functionFields(*s)
+	fields.Defaults = nil
+	defaults := make([]defaultValue, len(s.Defaults))
+	for idx, def := range s.Defaults {
+		defaults[idx].Value = def
+	}
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(fields); err != nil {
+		return nil, err
+	}
+	if err := enc.Encode(defaults); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode reads a function written by GobEncode
+func (s *FunctionStatement) GobDecode(data []byte) error {
+	var fields functionFields
+	var defaults []defaultValue
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&fields); err != nil {
+		return err
+	}
+	if err := dec.Decode(&defaults); err != nil {
+		return err
+	}
+
+	*s = FunctionStatement(fields)
+	s.Defaults = make([]Expression, len(defaults))
+	for idx, def := range defaults {
+		s.Defaults[idx] = def.Value
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package basic
+
+import (
+	"context"
+	"time"
+)
+
+// CallOptions overrides the interpreter's limits for a single call, so a
+// per-frame handler can get a much smaller budget than a script's setup.
+// Zero fields keep the interpreter's settings.
+type CallOptions struct {
+	MaxStatements int           // Statements the call may execute; see SetMaxStatements
+	MaxIterations int           // Loop iterations the call may run; see SetMaxIterations
+	Timeout       time.Duration // How long the call may run before it is stopped
+}
+
+// CallWithOptions is Call with the limits in opts applied for this call
+// only. A call that runs out of time fails with an error matching
+// context.DeadlineExceeded, as CallContext does.
+func (i *Interpreter) CallWithOptions(funcName string, opts CallOptions, args ...interface{}) (interface{}, error) {
+	if opts.MaxStatements > 0 {
+		defer func(max int) { i.maxStatements = max }(i.maxStatements)
+		i.maxStatements = opts.MaxStatements
+	}
+	if opts.MaxIterations > 0 {
+		defer func(max int) { i.maxIterations = max }(i.maxIterations)
+		i.maxIterations = opts.MaxIterations
+	}
+
+	if opts.Timeout <= 0 {
+		return i.Call(funcName, args...)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	return i.CallContext(ctx, funcName, args...)
+}
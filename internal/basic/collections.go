@@ -0,0 +1,338 @@
+package basic
+
+import "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+
+// MaxArrayElements limits the total number of elements a single DIM may
+// allocate, so a script can't exhaust the host's memory
+const MaxArrayElements = 1 << 20
+
+// executeDimStatement creates a zero-filled array in the current scope.
+// Multi-dimensional arrays are arrays of arrays.
+func (i *Interpreter) executeDimStatement(stmt *DimStatement) error {
+	if err := i.checkAssignable(stmt, stmt.Name); err != nil {
+		return err
+	}
+
+	sizes := make([]int, len(stmt.Sizes))
+	total := 1
+	for idx, sizeExpr := range stmt.Sizes {
+		value, err := i.evaluateExpression(sizeExpr)
+		if err != nil {
+			return err
+		}
+
+		size, ok := value.(int)
+		if !ok {
+			return i.runtimeError(sizeExpr, "DIM %s: size must be an integer, got %s", stmt.Name, functions.TypeName(value))
+		}
+		if size < 0 {
+			return i.runtimeError(sizeExpr, "DIM %s: size must not be negative, got %d", stmt.Name, size)
+		}
+
+		// Compare before multiplying, which could overflow
+		if size > 0 && total > MaxArrayElements/size {
+			return i.runtimeError(stmt, "DIM %s: more than %d elements", stmt.Name, MaxArrayElements)
+		}
+		total *= size
+		if err := i.checkCollectionSize(sizeExpr, stmt.Name, size); err != nil {
+			return err
+		}
+		sizes[idx] = size
+	}
+
+	arr := makeArray(sizes)
+	if err := i.checkLocalStore(stmt, stmt.Name, arr); err != nil {
+		return err
+	}
+	i.currentScope()[i.ident(stmt.Name)] = arr
+	return nil
+}
+
+func makeArray(sizes []int) []interface{} {
+	arr := make([]interface{}, sizes[0])
+	for idx := range arr {
+		if len(sizes) > 1 {
+			arr[idx] = makeArray(sizes[1:])
+		} else {
+			arr[idx] = 0
+		}
+	}
+	return arr
+}
+
+func (i *Interpreter) evaluateIndexExpr(expr *IndexExpr) (interface{}, error) {
+	value, err := i.getVariable(i.ident(expr.Name))
+	if err != nil {
+		return nil, i.runtimeError(expr, "%v", err)
+	}
+
+	indices, err := i.evaluateAll(expr.Indices)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.indexValue(expr, expr.Name, value, indices)
+}
+
+// evaluateSliceExpr returns a character (s[i]) or substring (s[a:b]) of a
+// string. Indices count characters rather than bytes and start at 0; slices
+// include the start and exclude the end, like Go.
+func (i *Interpreter) evaluateSliceExpr(expr *SliceExpr) (interface{}, error) {
+	value, err := i.evaluateExpression(expr.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, i.runtimeError(expr, "[] requires a string, got %s", functions.TypeName(value))
+	}
+	runes := []rune(s)
+
+	if !expr.Slice {
+		idx, err := i.sliceBound(expr.Start, 0)
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || idx >= len(runes) {
+			return nil, i.runtimeError(expr, "string index %d out of range (length %d)", idx, len(runes))
+		}
+		return string(runes[idx]), nil
+	}
+
+	start, err := i.sliceBound(expr.Start, 0)
+	if err != nil {
+		return nil, err
+	}
+	end, err := i.sliceBound(expr.End, len(runes))
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || end > len(runes) || start > end {
+		return nil, i.runtimeError(expr, "slice [%d:%d] out of range (length %d)", start, end, len(runes))
+	}
+	return string(runes[start:end]), nil
+}
+
+// sliceBound evaluates an index or slice bound, returning def when it was
+// omitted
+func (i *Interpreter) sliceBound(bound Expression, def int) (int, error) {
+	if bound == nil {
+		return def, nil
+	}
+
+	value, err := i.evaluateExpression(bound)
+	if err != nil {
+		return 0, err
+	}
+
+	idx, ok := value.(int)
+	if !ok {
+		return 0, i.runtimeError(bound, "string index must be an integer, got %s", functions.TypeName(value))
+	}
+	return idx, nil
+}
+
+// evaluateAll evaluates a list of expressions in order
+func (i *Interpreter) evaluateAll(exprs []Expression) ([]interface{}, error) {
+	values := make([]interface{}, len(exprs))
+	for idx, expr := range exprs {
+		val, err := i.evaluateExpression(expr)
+		if err != nil {
+			return nil, err
+		}
+		values[idx] = val
+	}
+	return values, nil
+}
+
+// indexValue reads the element of an array or map (or of nested ones, one
+// index or key per level) at the given indices
+func (i *Interpreter) indexValue(node Node, name string, value interface{}, indices []interface{}) (interface{}, error) {
+	for _, index := range indices {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			key, err := i.mapKey(node, name, index)
+			if err != nil {
+				return nil, err
+			}
+			elem, ok := container[key]
+			if !ok {
+				return nil, i.runtimeError(node, "%s: no key %q", name, key)
+			}
+			value = elem
+		case []interface{}:
+			idx, err := i.elementIndex(node, name, len(container), index)
+			if err != nil {
+				return nil, err
+			}
+			value = container[idx]
+		case []byte:
+			idx, err := i.elementIndex(node, name, len(container), index)
+			if err != nil {
+				return nil, err
+			}
+			value = int(container[idx])
+		default:
+			return nil, i.runtimeError(node, "%s is not an array or map (got %s)", name, functions.TypeName(value))
+		}
+	}
+	return value, nil
+}
+
+// isIndexable reports whether a value can be indexed with name(i)
+func isIndexable(value interface{}) bool {
+	switch value.(type) {
+	case []interface{}, []byte, map[string]interface{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// mapKey validates a map key
+func (i *Interpreter) mapKey(node Node, name string, key interface{}) (string, error) {
+	s, ok := key.(string)
+	if !ok {
+		return "", i.runtimeError(node, "%s: key must be a string, got %s", name, functions.TypeName(key))
+	}
+	return s, nil
+}
+
+// elementIndex validates an index against an array length
+func (i *Interpreter) elementIndex(node Node, name string, length int, index interface{}) (int, error) {
+	idx, ok := index.(int)
+	if !ok {
+		return 0, i.runtimeError(node, "%s: index must be an integer, got %s", name, functions.TypeName(index))
+	}
+	if idx < 0 || idx >= length {
+		return 0, i.runtimeError(node, "%s: index %d out of range (length %d)", name, idx, length)
+	}
+	return idx, nil
+}
+
+// executeMultiAssignStatement evaluates every value, unpacking a single
+// array, and then assigns the targets from left to right
+func (i *Interpreter) executeMultiAssignStatement(stmt *MultiAssignStatement) error {
+	values, err := i.evaluateAll(stmt.Values)
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 1 {
+		arr, ok := values[0].([]interface{})
+		if !ok {
+			return i.runtimeError(stmt, "cannot unpack %s into %d variables", functions.TypeName(values[0]), len(stmt.Targets))
+		}
+		if len(arr) != len(stmt.Targets) {
+			return i.runtimeError(stmt, "cannot unpack %d values into %d variables", len(arr), len(stmt.Targets))
+		}
+		values = arr
+	}
+
+	for idx, target := range stmt.Targets {
+		if err := i.checkAssignable(target, target.Name); err != nil {
+			return err
+		}
+
+		if stmt.Let {
+			if err := i.checkLocalStore(target, target.Name, values[idx]); err != nil {
+				return err
+			}
+			i.currentScope()[i.ident(target.Name)] = values[idx]
+			continue
+		}
+
+		_, set, err := i.assignTarget(target)
+		if err != nil {
+			return err
+		}
+		if err := set(values[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignTarget returns accessors for the variable, array element or record
+// field an assignment writes to
+func (i *Interpreter) assignTarget(stmt *AssignStatement) (get func() (interface{}, error), set func(interface{}) error, err error) {
+	if len(stmt.Fields) > 0 {
+		return i.fieldTarget(stmt)
+	}
+
+	name := i.ident(stmt.Name)
+	if len(stmt.Indices) == 0 {
+		if i.explicit && !i.isDeclared(name) {
+			return nil, nil, i.runtimeError(stmt, "variable %s is not declared; declare it with LET (OPTION EXPLICIT)", stmt.Name)
+		}
+		get = func() (interface{}, error) { return i.getVariable(name) }
+		set = func(value interface{}) error {
+			if i.limits != (MemoryLimits{}) {
+				if err := i.checkStore(stmt, stmt.Name, value, at(i.variableScope(name), name), !i.isDeclared(name)); err != nil {
+					return err
+				}
+			}
+			i.setVariable(name, value)
+			return nil
+		}
+		return get, set, nil
+	}
+
+	root, err := i.getVariable(name)
+	if err != nil {
+		return nil, nil, i.runtimeError(stmt, "%v", err)
+	}
+
+	indices, err := i.evaluateAll(stmt.Indices)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Walk to the array or map holding the element
+	last := len(indices) - 1
+	container, err := i.indexValue(stmt, stmt.Name, root, indices[:last])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch c := container.(type) {
+	case []interface{}:
+		idx, err := i.elementIndex(stmt, stmt.Name, len(c), indices[last])
+		if err != nil {
+			return nil, nil, err
+		}
+		get = func() (interface{}, error) { return c[idx], nil }
+		set = func(value interface{}) error {
+			if err := i.checkStore(stmt, stmt.Name, value, at(c, idx), false); err != nil {
+				return err
+			}
+			c[idx] = value
+			return nil
+		}
+
+	case map[string]interface{}:
+		key, err := i.mapKey(stmt, stmt.Name, indices[last])
+		if err != nil {
+			return nil, nil, err
+		}
+		// Plain assignment adds the key; +=, ++ etc. need it to exist
+		get = func() (interface{}, error) { return i.indexValue(stmt, stmt.Name, c, indices[last:]) }
+		set = func(value interface{}) error {
+			if _, ok := c[key]; !ok {
+				if err := i.checkCollectionSize(stmt, stmt.Name, len(c)+1); err != nil {
+					return err
+				}
+			}
+			if err := i.checkStore(stmt, stmt.Name, value, at(c, key), false); err != nil {
+				return err
+			}
+			c[key] = value
+			return nil
+		}
+
+	default:
+		return nil, nil, i.runtimeError(stmt, "cannot assign an element of %s (got %s)", stmt.Name, functions.TypeName(container))
+	}
+	return get, set, nil
+}
@@ -0,0 +1,21 @@
+package basic
+
+// Compile parses the code and expands its INCLUDEs into a program that
+// InterpretProgram and LoadProgram run without parsing again. Running a
+// program never changes it, so one compiled program can drive any number of
+// interpreters at once, each used from its own goroutine. The interpreters
+// should share the case sensitivity setting of the one that compiled it.
+func (i *Interpreter) Compile(code string) (*Program, error) {
+	return i.getOrParseProgram(code)
+}
+
+// InterpretProgram seeds the global scope with vars, which may be nil, and
+// executes a program made by Compile. The AST cache isn't consulted.
+func (i *Interpreter) InterpretProgram(prog *Program, vars map[string]interface{}) error {
+	if err := i.seedGlobals(vars); err != nil {
+		return err
+	}
+
+	_, err := i.executeProgram(prog)
+	return err
+}
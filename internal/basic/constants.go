@@ -0,0 +1,30 @@
+package basic
+
+import (
+	"fmt"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// RegisterConstant defines a read-only value, such as PI or SCREEN_W, that
+// scripts see in every scope. Unlike a variable set with SetVariable, it
+// survives loading a new script and resetting the interpreter, and scripts
+// can't assign to it or declare a variable or constant with its name.
+// Registering the name again replaces the value.
+func (i *Interpreter) RegisterConstant(name string, value interface{}) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid constant name %q", name)
+	}
+	normalized, err := functions.Normalize(value)
+	if err != nil {
+		return fmt.Errorf("constant %s: %v", name, err)
+	}
+	i.hostConstants[i.ident(name)] = normalized
+	return nil
+}
+
+// hostConstant returns the value of a constant registered by the host
+func (i *Interpreter) hostConstant(name string) (interface{}, bool) {
+	value, ok := i.hostConstants[name]
+	return value, ok
+}
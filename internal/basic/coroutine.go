@@ -0,0 +1,206 @@
+package basic
+
+import (
+	"errors"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// ErrCoroutineDone is returned by Resume once the coroutine's function has
+// returned, failed or been closed
+var ErrCoroutineDone = errors.New("coroutine has finished")
+
+// errCoroutineClosed unwinds a paused coroutine when the host closes it
+var errCoroutineClosed = errors.New("coroutine closed")
+
+// Coroutine is a call of a script function that can pause at YIELD and be
+// resumed by the host later, such as a behavior that waits a frame in the
+// middle of a function. Start one with StartCall.
+//
+// The call runs on its own goroutine, but only ever while the host waits in
+// StartCall, Resume or Close, so the interpreter is never used by two
+// goroutines at once. While it is paused the host may run other code on the
+// interpreter; the coroutine sees the global variables as they are when it
+// resumes.
+type Coroutine struct {
+	interp *Interpreter
+	state  execState // Execution state of the call while it is paused
+	resume chan resumeMsg
+	pause  chan coroutineStep
+	value  interface{}
+	done   bool
+}
+
+// resumeMsg is sent to a paused coroutine
+type resumeMsg struct {
+	value  interface{}
+	closed bool // Unwind instead of continuing
+}
+
+// coroutineStep is sent to the host when the coroutine pauses or finishes
+type coroutineStep struct {
+	value interface{}
+	err   error
+	done  bool
+}
+
+// execState is the state of an execution in progress, which the interpreter
+// swaps out while a coroutine is paused
+type execState struct {
+	scopes         []map[string]interface{}
+	callerScopes   [][]map[string]interface{}
+	callStack      []string
+	frames         []callFrame
+	iterationCount int
+	statementCount int
+	exprDepth      int
+	breakFlag      bool
+	gotoLabel      string
+	hookErr        error
+	tryDepth       int
+	inErrorHandler bool
+	returnFlag     bool
+	returnValue    interface{}
+	exiting        *ExitStatement
+	coroutine      *Coroutine
+}
+
+func (i *Interpreter) saveState() execState {
+	return execState{
+		scopes:         i.scopes,
+		callerScopes:   i.callerScopes,
+		callStack:      i.callStack,
+		frames:         i.frames,
+		iterationCount: i.iterationCount,
+		statementCount: i.statementCount,
+		exprDepth:      i.exprDepth,
+		breakFlag:      i.breakFlag,
+		gotoLabel:      i.gotoLabel,
+		hookErr:        i.hookErr,
+		tryDepth:       i.tryDepth,
+		inErrorHandler: i.inErrorHandler,
+		returnFlag:     i.returnFlag,
+		returnValue:    i.returnValue,
+		exiting:        i.exiting,
+		coroutine:      i.coroutine,
+	}
+}
+
+func (i *Interpreter) restoreState(s execState) {
+	i.scopes = s.scopes
+	i.callerScopes = s.callerScopes
+	i.callStack = s.callStack
+	i.frames = s.frames
+	i.iterationCount = s.iterationCount
+	i.statementCount = s.statementCount
+	i.exprDepth = s.exprDepth
+	i.breakFlag = s.breakFlag
+	i.gotoLabel = s.gotoLabel
+	i.hookErr = s.hookErr
+	i.tryDepth = s.tryDepth
+	i.inErrorHandler = s.inErrorHandler
+	i.returnFlag = s.returnFlag
+	i.returnValue = s.returnValue
+	i.exiting = s.exiting
+	i.coroutine = s.coroutine
+}
+
+// StartCall calls a script function as a coroutine, running it until its
+// first YIELD or its end. An error from the function before it yields is
+// returned along with the finished coroutine.
+func (i *Interpreter) StartCall(funcName string, args ...interface{}) (*Coroutine, error) {
+	c := &Coroutine{
+		interp: i,
+		resume: make(chan resumeMsg),
+		pause:  make(chan coroutineStep),
+	}
+	return c, c.run(func() {
+		go func() {
+			i.coroutine = c
+			result, err := i.Call(funcName, args...)
+			c.pause <- coroutineStep{value: result, err: err, done: true}
+		}()
+	})
+}
+
+// Resume continues the coroutine from its YIELD, which evaluates to value
+// in the script, and runs it until the next YIELD or its end. Each resume
+// starts with a fresh statement budget and iteration count.
+func (c *Coroutine) Resume(value interface{}) (interface{}, error) {
+	if c.done {
+		return nil, ErrCoroutineDone
+	}
+	err := c.run(func() {
+		c.resume <- resumeMsg{value: value}
+	})
+	return c.value, err
+}
+
+// Close stops a paused coroutine the host no longer needs, ending its
+// goroutine. Closing a finished coroutine does nothing.
+func (c *Coroutine) Close() {
+	if c.done {
+		return
+	}
+	c.run(func() {
+		c.resume <- resumeMsg{closed: true}
+	})
+}
+
+// Done reports whether the coroutine's function has returned, failed or
+// been closed
+func (c *Coroutine) Done() bool {
+	return c.done
+}
+
+// Value returns the value given to the last YIELD, or the function's result
+// once it has returned
+func (c *Coroutine) Value() interface{} {
+	return c.value
+}
+
+// run hands control to the coroutine with send and waits for it to pause or
+// finish, keeping the host's execution state aside meanwhile
+func (c *Coroutine) run(send func()) error {
+	outer := c.interp.saveState()
+	send()
+	step := <-c.pause
+	c.interp.restoreState(outer)
+
+	c.value, c.done = step.value, step.done
+	return step.err
+}
+
+// evaluateYield pauses the running coroutine, handing the value to the host
+func (i *Interpreter) evaluateYield(expr *YieldExpr) (interface{}, error) {
+	c := i.coroutine
+	if c == nil {
+		return nil, i.runtimeError(expr, "YIELD outside a coroutine; start the function with StartCall")
+	}
+
+	var value interface{}
+	if expr.Value != nil {
+		var err error
+		if value, err = i.evaluateExpression(expr.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	c.state = i.saveState()
+	c.pause <- coroutineStep{value: i.exportValue(value)}
+	msg := <-c.resume
+	i.restoreState(c.state)
+
+	// Pick up globals replaced while the coroutine was paused, and start the
+	// new step's budget
+	i.scopes[0] = i.globalScope
+	i.resetInterrupt()
+	i.iterationCount = 0
+	i.statementCount = 0
+	i.usage = memoryUsage{}
+
+	if msg.closed {
+		return nil, errCoroutineClosed
+	}
+	return functions.Normalize(msg.value)
+}
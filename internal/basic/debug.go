@@ -0,0 +1,99 @@
+package basic
+
+import "fmt"
+
+// DebugFrame describes the statement the interpreter is about to execute
+type DebugFrame struct {
+	File     string // Included file of the statement, "" for the script itself
+	Line     int    // Line of the statement (1-indexed)
+	Column   int    // Column of the statement (1-indexed)
+	Function string // Innermost script function, or "" at top level
+	Depth    int    // Number of script function calls on the stack
+}
+
+// DebugHook is called before every statement while a hook is installed.
+// It runs on the interpreter's goroutine, so it may block to wait for user
+// input; returning an error aborts the execution with that error.
+type DebugHook func(frame DebugFrame) error
+
+// SetDebugHook installs a hook that is called before each statement.
+// Pass nil to remove it.
+func (i *Interpreter) SetDebugHook(hook DebugHook) {
+	i.debugHook = hook
+}
+
+// debugStep reports a statement to the debug hook, if one is installed
+func (i *Interpreter) debugStep(stmt Statement) error {
+	if i.debugHook == nil {
+		return nil
+	}
+
+	line, col := stmt.Position()
+	frame := DebugFrame{File: stmt.SourceFile(), Line: line, Column: col, Depth: len(i.callStack)}
+	if frame.Depth > 0 {
+		frame.Function = i.callStack[frame.Depth-1]
+	}
+
+	// Don't report statements run by the hook itself (e.g. EvalInFrame)
+	hook := i.debugHook
+	i.debugHook = nil
+	defer func() { i.debugHook = hook }()
+
+	err := hook(frame)
+	if err != nil {
+		i.hookErr = err
+	}
+	return err
+}
+
+// CallStack returns the names of the script functions currently executing,
+// outermost first
+func (i *Interpreter) CallStack() []string {
+	stack := make([]string, len(i.callStack))
+	copy(stack, i.callStack)
+	return stack
+}
+
+// Variables returns the variables visible at the current point of execution,
+// with inner scopes hiding outer ones. Intended for use from a debug hook.
+func (i *Interpreter) Variables() map[string]interface{} {
+	vars := make(map[string]interface{})
+	for _, scope := range i.scopes {
+		for name, value := range scope {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+// EvalInFrame evaluates a single expression against the variables visible at
+// the current point of execution, without resetting any execution state.
+// Intended for use from a debug hook while a script is paused.
+func (i *Interpreter) EvalInFrame(code string) (interface{}, error) {
+	tokens, err := Tokenize(code)
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewParser(tokens)
+	p.allowExpressions = true
+	p.maxDepth = i.maxExprDepth
+	prog, err := p.ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(prog.Statements) != 1 {
+		return nil, fmt.Errorf("expected a single expression")
+	}
+	stmt, ok := prog.Statements[0].(*ExpressionStatement)
+	if !ok {
+		return nil, fmt.Errorf("expected an expression, not a statement")
+	}
+
+	// Calls made by the expression must not disturb a pending RETURN
+	returnFlag, returnValue := i.returnFlag, i.returnValue
+	defer func() { i.returnFlag, i.returnValue = returnFlag, returnValue }()
+
+	return i.evaluateExpression(stmt.Expr)
+}
@@ -0,0 +1,72 @@
+package basic
+
+import (
+	"sort"
+	"strings"
+)
+
+// FunctionDoc describes a script function for documentation tools
+type FunctionDoc struct {
+	Name     string   // Name as written in the definition
+	Params   []string // Parameter names in order
+	Types    []string // Type each parameter is declared AS, "" where it has none
+	Required int      // Number of leading parameters without a default value
+	Doc      string   // Text of the ## comments above the definition
+	IsSub    bool     // Declared with SUB rather than FUNCTION
+	Line     int      // Line of the definition (1-indexed)
+}
+
+// DescribeFunctions documents the public functions of the loaded script, in
+// source order. Functions whose names start with an underscore are private
+// and left out.
+func (i *Interpreter) DescribeFunctions() []FunctionDoc {
+	fns := make([]*FunctionStatement, 0, len(i.userFuncs))
+	for _, fn := range i.userFuncs {
+		fns = append(fns, fn)
+	}
+	return describeFunctions(fns)
+}
+
+// DescribeScript documents the public functions of a script without running
+// any of its code
+func (i *Interpreter) DescribeScript(code string) ([]FunctionDoc, error) {
+	prog, err := i.getOrParseProgram(code)
+	if err != nil {
+		return nil, err
+	}
+
+	var fns []*FunctionStatement
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*FunctionStatement); ok {
+			fns = append(fns, fn)
+		}
+	}
+	return describeFunctions(fns), nil
+}
+
+func describeFunctions(fns []*FunctionStatement) []FunctionDoc {
+	docs := []FunctionDoc{}
+	for _, fn := range fns {
+		if strings.HasPrefix(fn.Name, "_") {
+			continue
+		}
+		params := make([]string, len(fn.Params))
+		copy(params, fn.Params)
+		types := make([]string, len(fn.Params))
+		copy(types, fn.Types)
+		docs = append(docs, FunctionDoc{
+			Name:     fn.Name,
+			Params:   params,
+			Types:    types,
+			Required: requiredParams(fn),
+			Doc:      fn.Doc,
+			IsSub:    fn.IsSub,
+			Line:     fn.Line,
+		})
+	}
+
+	sort.Slice(docs, func(a, b int) bool {
+		return docs[a].Line < docs[b].Line
+	})
+	return docs
+}
@@ -0,0 +1,112 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Severity says whether a Diagnostic stops the script from running
+type Severity int
+
+const (
+	SeverityError   Severity = iota // The script can't run
+	SeverityWarning                 // Likely a mistake, but the script runs
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is one problem found by ValidateAll
+type Diagnostic struct {
+	Severity Severity
+	Kind     ErrorKind // ErrorSyntax, ErrorCheck or ErrorInclude; ErrorCheck for warnings
+	Message  string
+	File     string // Included file the problem is in, "" for the script itself
+	Line     int
+	Column   int
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", positionText(d.File, d.Line, d.Column), d.Severity, d.Message)
+}
+
+// ValidateAll checks the code without executing it, like Validate, but
+// returns every problem found rather than stopping at the first, for editors
+// that underline all of them in one pass. A statement that fails to parse is
+// skipped, so the lines after it are still checked; when it is the first line
+// of a block, such as a FOR missing its TO, the block's body is still checked
+// and its ending line skipped. A bad token, such as an unterminated string,
+// ends the check. Warnings are included. The result is
+// sorted by position and empty for a script that is valid and has no
+// warnings.
+func (i *Interpreter) ValidateAll(code string) []Diagnostic {
+	tokens, err := Tokenize(code)
+	if err != nil {
+		return []Diagnostic{errorDiagnostic(err)}
+	}
+
+	p := NewParser(tokens)
+	p.maxDepth = i.maxExprDepth
+	p.recovering = true
+	prog, err := p.ParseProgram()
+	if err != nil {
+		return []Diagnostic{errorDiagnostic(err)}
+	}
+
+	var diags []Diagnostic
+	for _, err := range p.errs {
+		diags = append(diags, errorDiagnostic(err))
+	}
+	a := runAnalysis(prog, false, i.caseSensitive)
+	for _, err := range a.errs {
+		diags = append(diags, errorDiagnostic(err))
+	}
+	for _, w := range a.warnings {
+		diags = append(diags, Diagnostic{
+			Severity: SeverityWarning,
+			Kind:     ErrorCheck,
+			Message:  w.Message,
+			File:     w.File,
+			Line:     w.Line,
+			Column:   w.Column,
+		})
+	}
+	if len(p.errs)+len(a.errs) == 0 {
+		// Included files are only checked once the script itself is valid
+		if _, err := i.expandIncludes(prog, false); err != nil {
+			diags = append(diags, errorDiagnostic(err))
+		}
+	}
+
+	sort.SliceStable(diags, func(x, y int) bool {
+		if diags[x].File != diags[y].File {
+			return diags[x].File < diags[y].File
+		}
+		if diags[x].Line != diags[y].Line {
+			return diags[x].Line < diags[y].Line
+		}
+		return diags[x].Column < diags[y].Column
+	})
+	return diags
+}
+
+// errorDiagnostic converts an error found while checking a script
+func errorDiagnostic(err error) Diagnostic {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return Diagnostic{
+			Severity: SeverityError,
+			Kind:     parseErr.Kind,
+			Message:  parseErr.Message,
+			File:     parseErr.File,
+			Line:     parseErr.Line,
+			Column:   parseErr.Column,
+		}
+	}
+	return Diagnostic{Severity: SeverityError, Kind: ErrorSyntax, Message: err.Error()}
+}
@@ -0,0 +1,217 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrFunctionRegistered is returned, wrapped with the names, when strict
+// registration is on and a function is registered under a name already taken
+var ErrFunctionRegistered = errors.New("function already registered")
+
+// ErrorKind classifies a ParseError or RuntimeError, so hosts can tell a
+// syntax error from a script bug without matching the message
+type ErrorKind int
+
+const (
+	ErrorSyntax      ErrorKind = iota // The script isn't valid BASIC
+	ErrorCheck                        // Rejected before running, such as an assignment to a constant
+	ErrorInclude                      // An INCLUDE failed
+	ErrorRuntime                      // The script failed while running
+	ErrorHost                         // An external function returned an error
+	ErrorThrow                        // The script raised the error with THROW
+	ErrorBudget                       // The statement budget or iteration limit ran out
+	ErrorMemory                       // The script would store more than the memory limits allow
+	ErrorInterrupted                  // The run was stopped or its context cancelled
+)
+
+var errorKindNames = [...]string{"syntax", "check", "include", "runtime", "host", "throw", "budget", "memory", "interrupted"}
+
+func (k ErrorKind) String() string {
+	if k < 0 || int(k) >= len(errorKindNames) {
+		return fmt.Sprintf("ErrorKind(%d)", int(k))
+	}
+	return errorKindNames[k]
+}
+
+// ParseError is returned for a script that is rejected before it runs
+type ParseError struct {
+	Kind    ErrorKind // ErrorSyntax, ErrorCheck or ErrorInclude
+	Message string
+	File    string // Included file with the error, "" for the script itself
+	Line    int
+	Column  int
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", positionText(e.File, e.Line, e.Column), e.Message)
+}
+
+// parseError builds a ParseError at a position
+func parseError(kind ErrorKind, file string, line, column int, format string, args ...interface{}) *ParseError {
+	return &ParseError{Kind: kind, Message: fmt.Sprintf(format, args...), File: file, Line: line, Column: column}
+}
+
+// RuntimeError is returned for a script that fails while running. It wraps
+// the cause, such as a *ScriptError or ErrInterrupted, with the position
+// reached.
+type RuntimeError struct {
+	Kind   ErrorKind
+	File   string // Included file with the error, "" for the script itself
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *RuntimeError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("runtime error in %s at line %d, column %d: %v", e.File, e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("runtime error at line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// runtimeErrorKind classifies the cause of a RuntimeError
+func runtimeErrorKind(err error) ErrorKind {
+	var script *ScriptError
+	var budget *BudgetExceededError
+	switch {
+	case errors.As(err, &script):
+		return ErrorThrow
+	case errors.As(err, &budget), errors.Is(err, errIterationLimit):
+		return ErrorBudget
+	case errors.Is(err, ErrMemoryLimit):
+		return ErrorMemory
+	case errors.Is(err, ErrInterrupted), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrorInterrupted
+	}
+	return ErrorRuntime
+}
+
+// ScriptError is the error raised by a THROW statement. It is returned
+// wrapped with its position, so hosts can tell errors a script signals on
+// purpose from other failures with errors.As.
+type ScriptError struct {
+	Message string
+	File    string // Included file of the THROW, "" for the script itself
+	Line    int
+	Column  int
+}
+
+func (e *ScriptError) Error() string {
+	return e.Message
+}
+
+// BudgetExceededError is returned, wrapped with the position reached, when a
+// run executes more statements than SetMaxStatements allows
+type BudgetExceededError struct {
+	Limit int
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("statement budget exceeded (%d)", e.Limit)
+}
+
+// executeThrowStatement raises a ScriptError with the message
+func (i *Interpreter) executeThrowStatement(stmt *ThrowStatement) error {
+	value, err := i.evaluateExpression(stmt.Message)
+	if err != nil {
+		return err
+	}
+	return i.positionError(stmt, &ScriptError{
+		Message: i.toString(value),
+		File:    stmt.File,
+		Line:    stmt.Line,
+		Column:  stmt.Column,
+	})
+}
+
+// executeTryStatement runs the TRY block and, if it fails with a catchable
+// error, the CATCH block with the error message bound to the CATCH variable
+func (i *Interpreter) executeTryStatement(stmt *TryStatement) error {
+	i.tryDepth++
+	err := i.executeBlock(stmt.Body)
+	i.tryDepth--
+	if err == nil || !i.catchable(err) {
+		return err
+	}
+
+	i.pushScope()
+	defer i.popScope()
+
+	if stmt.ErrorVar != "" {
+		name, message := i.ident(stmt.ErrorVar), errorMessage(err)
+		if err := i.checkTotals(stmt, stmt.ErrorVar, message, at(i.currentScope(), name)); err != nil {
+			return err
+		}
+		i.currentScope()[name] = message
+	}
+	return i.executeBlock(stmt.Handler)
+}
+
+// executeOnErrorStatement sets the function that handles errors from now on
+func (i *Interpreter) executeOnErrorStatement(stmt *OnErrorStatement) error {
+	name := i.ident(stmt.Handler)
+	if _, ok := i.userFuncs[name]; !ok {
+		if _, ok := i.externalFuncs[name]; !ok {
+			return i.runtimeError(stmt, "ON ERROR: undefined function: %s", stmt.Handler)
+		}
+	}
+	i.errorHandler = stmt.Handler
+	return nil
+}
+
+// handleError passes the error from a failed statement to the ON ERROR
+// handler, if there is one, so the script continues with the next statement.
+// TRY blocks take precedence, and errors from the handler itself propagate.
+func (i *Interpreter) handleError(stmt Statement, err error) error {
+	if i.errorHandler == "" || i.tryDepth > 0 || i.inErrorHandler || !i.catchable(err) {
+		return err
+	}
+
+	line, _ := stmt.Position()
+	var positioned *RuntimeError
+	if errors.As(err, &positioned) {
+		line = positioned.Line
+	}
+
+	i.inErrorHandler = true
+	defer func() { i.inErrorHandler = false }()
+
+	if _, herr := i.Invoke(i.errorHandler, errorMessage(err), line); herr != nil {
+		return herr
+	}
+	return nil
+}
+
+// catchable reports whether a script may recover from an error. Stop, a
+// cancelled context, the iteration limit, the statement budget, debug hook
+// errors and closing a coroutine always end the run, so a script can't
+// ignore them.
+func (i *Interpreter) catchable(err error) bool {
+	if i.hookErr != nil && errors.Is(err, i.hookErr) {
+		return false
+	}
+	if i.ctxDone.Load() {
+		return false
+	}
+	var budget *BudgetExceededError
+	if errors.As(err, &budget) {
+		return false
+	}
+	return !errors.Is(err, ErrInterrupted) && !errors.Is(err, errIterationLimit) && !errors.Is(err, errCoroutineClosed)
+}
+
+// errorMessage returns the text a script sees for an error, without the
+// position prefix added for the host
+func errorMessage(err error) string {
+	var positioned *RuntimeError
+	if errors.As(err, &positioned) {
+		return positioned.Err.Error()
+	}
+	return err.Error()
+}
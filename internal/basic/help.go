@@ -0,0 +1,110 @@
+package basic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FunctionInfo documents a registered host function
+type FunctionInfo struct {
+	Name        string // Name the function is called by
+	Signature   string // How to call it, e.g. "pow(x, y)"; "" if not documented
+	Description string // What it does; "" if not documented
+}
+
+// DocumentFunction attaches a signature and description to a registered host
+// function, for ListFunctions and help(). Registering the name again clears
+// them. Returns false if no function is registered under the name.
+func (i *Interpreter) DocumentFunction(name, signature, description string) bool {
+	if !i.HasExternalFunction(name) {
+		return false
+	}
+	key := i.ident(name)
+	i.externalDocs[key] = FunctionInfo{Name: key, Signature: signature, Description: description}
+	return true
+}
+
+// ListFunctions lists the registered host functions, including the core and
+// library functions, sorted by name
+func (i *Interpreter) ListFunctions() []FunctionInfo {
+	infos := make([]FunctionInfo, 0, len(i.externalFuncs))
+	for name := range i.externalFuncs {
+		info, ok := i.externalDocs[name]
+		if !ok {
+			info = FunctionInfo{Name: name}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(a, b int) bool {
+		return infos[a].Name < infos[b].Name
+	})
+	return infos
+}
+
+// Help is the help builtin for script consoles. help("pow") prints how to
+// call a host or script function and what it does; help() prints the names
+// of every function that can be called.
+func (i *Interpreter) Help(args ...interface{}) (interface{}, error) {
+	switch len(args) {
+	case 0:
+		i.printFunc(strings.Join(i.callableNames(), ", "), true)
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("help requires 0 or 1 arguments")
+	}
+
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("help: argument must be a function name")
+	}
+	key := i.ident(name)
+
+	if _, ok := i.externalFuncs[key]; ok {
+		info := i.externalDocs[key]
+		i.printFunc(firstNonEmpty(info.Signature, key+"(...)"), true)
+		i.printFunc(firstNonEmpty(info.Description, "No description."), true)
+		return nil, nil
+	}
+	if fn, ok := i.userFuncs[key]; ok {
+		i.printFunc(scriptSignature(fn), true)
+		i.printFunc(firstNonEmpty(fn.Doc, "No description."), true)
+		return nil, nil
+	}
+	return nil, fmt.Errorf("help: unknown function %s", name)
+}
+
+// callableNames returns the names of the host and script functions, sorted
+func (i *Interpreter) callableNames() []string {
+	names := make([]string, 0, len(i.externalFuncs)+len(i.userFuncs))
+	for name := range i.externalFuncs {
+		names = append(names, name)
+	}
+	for name := range i.userFuncs {
+		if _, ok := i.externalFuncs[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scriptSignature describes how to call a script function: hit(base, armor AS integer)
+func scriptSignature(fn *FunctionStatement) string {
+	params := make([]string, len(fn.Params))
+	for idx, param := range fn.Params {
+		params[idx] = param
+		if idx < len(fn.Types) && fn.Types[idx] != "" {
+			params[idx] += " AS " + fn.Types[idx]
+		}
+	}
+	return fn.Name + "(" + strings.Join(params, ", ") + ")"
+}
+
+func firstNonEmpty(text, fallback string) string {
+	if text == "" {
+		return fallback
+	}
+	return text
+}
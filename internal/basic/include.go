@@ -0,0 +1,95 @@
+package basic
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// SetSourceFS sets the file system INCLUDE reads files from. Paths are
+// relative to its root, or to the including file for nested includes.
+// Without one every INCLUDE fails.
+func (i *Interpreter) SetSourceFS(fsys fs.FS) {
+	i.sourceFS = fsys
+}
+
+// expandIncludes returns the program with each top-level INCLUDE replaced by
+// the statements of the included file. Each file is included once, however
+// often it is named. The merged program is analyzed again so that checks
+// such as duplicate constants see every file.
+func (i *Interpreter) expandIncludes(prog *Program, eval bool) (*Program, error) {
+	hasInclude := false
+	for _, stmt := range prog.Statements {
+		if _, ok := stmt.(*IncludeStatement); ok {
+			hasInclude = true
+			break
+		}
+	}
+	if !hasInclude {
+		return prog, nil
+	}
+
+	statements, err := i.includeStatements(prog.Statements, nil, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &Program{Statements: statements}
+	warnings, err := analyze(merged, eval, i.caseSensitive)
+	if err != nil {
+		return nil, err
+	}
+	i.warnings = warnings
+	return merged, nil
+}
+
+// includeStatements expands the INCLUDEs among statements. open lists the
+// files being included, outermost first, to detect cycles.
+func (i *Interpreter) includeStatements(statements []Statement, open []string, included map[string]bool) ([]Statement, error) {
+	var expanded []Statement
+	for _, stmt := range statements {
+		inc, ok := stmt.(*IncludeStatement)
+		if !ok {
+			expanded = append(expanded, stmt)
+			continue
+		}
+
+		name := path.Join(path.Dir(inc.File), inc.Path)
+		for _, file := range open {
+			if file == name {
+				chain := strings.Join(append(open, name), " -> ")
+				return nil, i.includeError(inc, "include cycle: %s", chain)
+			}
+		}
+		if included[name] {
+			continue
+		}
+		included[name] = true
+
+		if i.sourceFS == nil {
+			return nil, i.includeError(inc, "no source file system is set")
+		}
+		source, err := fs.ReadFile(i.sourceFS, name)
+		if err != nil {
+			return nil, i.includeError(inc, "%v", err)
+		}
+
+		prog, err := i.cachedParse(i.hashCode("file:"+name+"\n"+string(source)), name, string(source), false)
+		if err != nil {
+			return nil, err
+		}
+
+		nested, err := i.includeStatements(prog.Statements, append(open, name), included)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, nested...)
+	}
+	return expanded, nil
+}
+
+// includeError reports a failed INCLUDE at its position
+func (i *Interpreter) includeError(stmt *IncludeStatement, format string, args ...interface{}) error {
+	return parseError(ErrorInclude, stmt.File, stmt.Line, stmt.Column, "INCLUDE %q: %s", stmt.Path, fmt.Sprintf(format, args...))
+}
@@ -1,64 +1,353 @@
 package basic
 
 import (
+	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io/fs"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/functions"
 )
 
 // MaxIterations is the default limit for loop iterations to prevent infinite loops
 const MaxIterations = 100000
 
+// MaxExpressionDepth is the default limit for expression nesting, applied when
+// parsing and when evaluating. Nested function calls count toward the limit.
+const MaxExpressionDepth = 5000
+
 // ExternalFunc is the signature for registered external functions
 type ExternalFunc func(args ...interface{}) (interface{}, error)
 
+// BoundFunc makes the external function for the interpreter calling it, for
+// builtins that need the running interpreter, such as ones taking a script
+// function as a callback
+type BoundFunc func(i *Interpreter) ExternalFunc
+
 // PrintFunc is the signature for custom print handlers
 type PrintFunc func(value interface{})
 
+// RawPrintFunc is the signature for print handlers that also need to know
+// whether to end the line. newline is false after PRINT x; (trailing semicolon).
+type RawPrintFunc func(value interface{}, newline bool)
+
+// NamedArgPolicy controls how CallNamed treats argument maps that don't line
+// up with the function's parameter list. The zero value ignores extra keys and
+// rejects missing parameters.
+type NamedArgPolicy struct {
+	RejectExtra  bool // Error on keys that don't name a parameter
+	AllowMissing bool // Bind parameters absent from the map to nil
+}
+
+// NumberFormat controls how floats are turned into text by PRINT and string
+// concatenation. The zero value keeps Go's shortest %g representation and
+// hands PRINT the raw value.
+type NumberFormat struct {
+	Precision int  // Significant digits, or decimal places when Fixed; 0 means shortest
+	Fixed     bool // Use fixed-point notation, never exponent form
+}
+
+// OverflowMode selects what happens when integer arithmetic overflows
+type OverflowMode int
+
+const (
+	OverflowWrap    OverflowMode = iota // Wrap around silently (default, no checks)
+	OverflowError                       // Fail with a runtime error
+	OverflowPromote                     // Redo the operation in float64
+)
+
+// ResultPolicy selects the numeric types handed back to the host by Call,
+// CallNamed, Evaluate and Globals. Inside the interpreter integers are always
+// int and floats float64.
+type ResultPolicy int
+
+const (
+	ResultNative  ResultPolicy = iota // int and float64 (default)
+	ResultInt64                       // Integers returned as int64
+	ResultFloat64                     // Every number returned as float64
+)
+
+// errIntegerOverflow is returned by the arithmetic helpers in OverflowError
+// mode; evaluateBinaryExpr adds the position
+var errIntegerOverflow = errors.New("integer overflow")
+
+// errIterationLimit is reported when a script exceeds the iteration limit
+var errIterationLimit = errors.New("maximum iterations exceeded")
+
 // Interpreter executes MechanicalBasic programs
 type Interpreter struct {
 	// External functions registered by the host application
 	externalFuncs map[string]ExternalFunc
+	boundFuncs    map[string]BoundFunc // Functions made for the interpreter calling them
 
-	// User-defined functions from the script
+	// User-defined functions and record types from the script
 	userFuncs map[string]*FunctionStatement
+	types     map[string]*TypeStatement
 
 	// Global scope for top-level variables (persists between calls)
 	globalScope map[string]interface{}
+	constants   map[string]bool // Names in the global scope declared with CONST
 
 	// Variable scopes (stack for function calls)
 	scopes []map[string]interface{}
 
+	// Names of the script functions being executed, for debugging
+	callStack []string
+	frames    []callFrame // Scope state of the functions being executed
+	debugHook DebugHook
+
+	// Diagnostics for the most recently parsed or cached program
+	warnings []Warning
+
+	// Signatures and descriptions of external functions, by name
+	externalDocs map[string]FunctionInfo
+
+	// Read-only values registered by the host, visible in every scope
+	hostConstants map[string]interface{}
+
 	// AST cache keyed by code hash
-	astCache map[string]*Program
+	astCache *programCache
 
 	// Configuration
-	maxIterations int       // Max loop iterations (infinite loop protection)
-	printFunc     PrintFunc // Custom print handler (defaults to fmt.Println)
+	maxIterations  int            // Max loop iterations (infinite loop protection)
+	maxStatements  int            // Max statements executed per run, 0 for no limit
+	maxExprDepth   int            // Max expression nesting (stack overflow protection)
+	printFunc      RawPrintFunc   // Custom print handler (defaults to fmt.Println)
+	printSeparator string         // Placed between the values of PRINT a, b
+	namedArgPolicy NamedArgPolicy // How CallNamed binds argument maps
+	rng            *rand.Rand     // Random source shared by the random builtins
+	numberFormat   NumberFormat   // How floats are converted to text
+	overflowMode   OverflowMode   // How integer overflow is handled
+	resultPolicy   ResultPolicy   // Numeric types returned to the host
+	scopeMode      ScopeMode      // Where assignments inside functions go
+	strictVars     bool           // Require every script to declare its variables
+	caseSensitive  bool           // Identifiers aren't lowercased
+	sourceFS       fs.FS          // Where INCLUDE reads files from
+	moduleResolver ModuleResolver // Finds the source of IMPORTed modules
+	limits         MemoryLimits   // Caps on the state a script may hold
+	noCache        bool           // Parse every script afresh, leaving the AST cache alone
+	strictRegister bool           // RegisterFunction fails on a name already registered
 
 	// Execution state
-	iterationCount int  // Current iteration count for loop protection
-	breakFlag      bool // Set when BREAK is encountered
-	returnFlag     bool // Set when RETURN is encountered
+	iterationCount int    // Current iteration count for loop protection
+	statementCount int    // Statements executed so far, for the statement budget
+	exprDepth      int    // Current expression nesting depth
+	breakFlag      bool   // Set when BREAK is encountered
+	gotoLabel      string // Set when GOTO is encountered, until its label is reached
+	hookErr        error  // Error returned by the debug hook, which TRY must not catch
+	errorHandler   string // Function set by ON ERROR CALL; persists across Call
+	tryDepth       int    // Number of TRY blocks being run, which take precedence over ON ERROR
+	inErrorHandler bool   // Set while the ON ERROR handler runs, so its own errors propagate
+	returnFlag     bool   // Set when RETURN is encountered
 	returnValue    interface{}
+	exiting        *ExitStatement     // Set by EXIT FOR or EXIT DO until the loop it leaves is reached
+	modules        map[string]*module // Modules imported by the current run, by name
+	explicit       bool               // Assignment to an undeclared variable is an error
+	session        bool               // Set by EvaluateSession: definitions outlive the run
+	coroutine      *Coroutine         // Coroutine being run, which YIELD pauses
+
+	// Scopes of the callers of the functions being run, and a bound on the
+	// state held, for the total memory limits
+	callerScopes [][]map[string]interface{}
+	usage        memoryUsage
+
+	// Line timings, while profiling is on, and those of the last session
+	profiler    *profiler
+	lastProfile Profile
+
+	// Set by Stop, possibly from another goroutine
+	interrupted atomic.Bool
+
+	// Context of the run in progress, if it has one, and whether it is done
+	ctx     context.Context
+	ctxDone atomic.Bool
 }
 
 // NewInterpreter creates a new interpreter instance
 func NewInterpreter() *Interpreter {
+	globalScope := make(map[string]interface{})
 	return &Interpreter{
-		externalFuncs: make(map[string]ExternalFunc),
-		userFuncs:     make(map[string]*FunctionStatement),
-		globalScope:   make(map[string]interface{}),
-		scopes:        []map[string]interface{}{make(map[string]interface{})},
-		astCache:      make(map[string]*Program),
-		maxIterations: MaxIterations,
-		printFunc:     func(v interface{}) { fmt.Println(v) },
+		externalFuncs:  coreFunctions(),
+		boundFuncs:     make(map[string]BoundFunc),
+		externalDocs:   make(map[string]FunctionInfo),
+		hostConstants:  make(map[string]interface{}),
+		userFuncs:      make(map[string]*FunctionStatement),
+		types:          make(map[string]*TypeStatement),
+		globalScope:    globalScope,
+		constants:      make(map[string]bool),
+		scopes:         []map[string]interface{}{globalScope},
+		astCache:       newProgramCache(0),
+		maxIterations:  MaxIterations,
+		maxExprDepth:   MaxExpressionDepth,
+		printFunc:      printStdout,
+		printSeparator: " ",
+		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Spawn creates an interpreter with the same settings that shares this one's
+// registered functions, AST cache and random source, so it costs little more
+// than its own empty global scope. Variables, script functions and execution
+// state are its own. Functions registered on either interpreter, and changes
+// to the cache, are seen by both, so neither may run while the other does.
+func (i *Interpreter) Spawn() *Interpreter {
+	globalScope := make(map[string]interface{})
+	return &Interpreter{
+		externalFuncs:  i.externalFuncs,
+		boundFuncs:     i.boundFuncs,
+		externalDocs:   i.externalDocs,
+		hostConstants:  i.hostConstants,
+		userFuncs:      make(map[string]*FunctionStatement),
+		types:          make(map[string]*TypeStatement),
+		globalScope:    globalScope,
+		constants:      make(map[string]bool),
+		scopes:         []map[string]interface{}{globalScope},
+		astCache:       i.astCache,
+		maxIterations:  i.maxIterations,
+		maxStatements:  i.maxStatements,
+		maxExprDepth:   i.maxExprDepth,
+		printFunc:      i.printFunc,
+		printSeparator: i.printSeparator,
+		namedArgPolicy: i.namedArgPolicy,
+		rng:            i.rng,
+		numberFormat:   i.numberFormat,
+		overflowMode:   i.overflowMode,
+		resultPolicy:   i.resultPolicy,
+		scopeMode:      i.scopeMode,
+		strictVars:     i.strictVars,
+		caseSensitive:  i.caseSensitive,
+		sourceFS:       i.sourceFS,
+		moduleResolver: i.moduleResolver,
+		limits:         i.limits,
+		noCache:        i.noCache,
+		strictRegister: i.strictRegister,
+	}
+}
+
+// Clone creates an independent copy of the interpreter with the same
+// settings, registered functions and loaded script functions and types, but
+// no variables and an empty AST cache. Functions registered on the clone
+// afterwards are its own. It gets its own random source, seeded from this
+// one's, so clones can run on separate goroutines.
+func (i *Interpreter) Clone() *Interpreter {
+	clone := i.Spawn()
+	clone.externalFuncs = make(map[string]ExternalFunc, len(i.externalFuncs))
+	for name, fn := range i.externalFuncs {
+		clone.externalFuncs[name] = fn
+	}
+	clone.boundFuncs = make(map[string]BoundFunc, len(i.boundFuncs))
+	for name, bind := range i.boundFuncs {
+		clone.boundFuncs[name] = bind
+	}
+	clone.externalDocs = make(map[string]FunctionInfo, len(i.externalDocs))
+	for name, info := range i.externalDocs {
+		clone.externalDocs[name] = info
+	}
+	clone.hostConstants = make(map[string]interface{}, len(i.hostConstants))
+	for name, value := range i.hostConstants {
+		clone.hostConstants[name] = value
+	}
+	for name, fn := range i.userFuncs {
+		clone.userFuncs[name] = fn
+	}
+	for name, typ := range i.types {
+		clone.types[name] = typ
+	}
+	clone.astCache = newProgramCache(i.astCache.maxSize)
+	clone.rng = rand.New(rand.NewSource(i.rng.Int63()))
+	clone.errorHandler = i.errorHandler
+	return clone
+}
+
+// RegisterFunction registers an external function that can be called from
+// scripts, replacing any function already registered under the name. With
+// strict registration on, it fails with ErrFunctionRegistered instead.
+func (i *Interpreter) RegisterFunction(name string, function ExternalFunc) error {
+	if i.strictRegister && i.HasExternalFunction(name) {
+		return fmt.Errorf("%w: %s", ErrFunctionRegistered, name)
+	}
+	i.externalFuncs[i.ident(name)] = function
+	delete(i.boundFuncs, i.ident(name))
+	delete(i.externalDocs, i.ident(name))
+	return nil
+}
+
+// RegisterBoundFunction registers an external function that bind makes for
+// the interpreter calling it, so that instances spawned or cloned from this
+// one each get a function bound to themselves
+func (i *Interpreter) RegisterBoundFunction(name string, bind BoundFunc) error {
+	if err := i.RegisterFunction(name, bind(i)); err != nil {
+		return err
+	}
+	i.boundFuncs[i.ident(name)] = bind
+	return nil
+}
+
+// RegisterFunctions registers several external functions at once. With
+// strict registration on, none are registered if any name is taken.
+func (i *Interpreter) RegisterFunctions(funcs map[string]ExternalFunc) error {
+	if i.strictRegister {
+		names := make([]string, 0, len(funcs))
+		for name := range funcs {
+			if i.HasExternalFunction(name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			return fmt.Errorf("%w: %s", ErrFunctionRegistered, strings.Join(names, ", "))
+		}
 	}
+	for name, function := range funcs {
+		i.externalFuncs[i.ident(name)] = function
+		delete(i.boundFuncs, i.ident(name))
+		delete(i.externalDocs, i.ident(name))
+	}
+	return nil
 }
 
-// RegisterFunction registers an external function that can be called from scripts
-func (i *Interpreter) RegisterFunction(name string, function ExternalFunc) {
-	i.externalFuncs[strings.ToLower(name)] = function
+// UnregisterFunction removes an external function. Returns false if none is
+// registered under the name.
+func (i *Interpreter) UnregisterFunction(name string) bool {
+	if !i.HasExternalFunction(name) {
+		return false
+	}
+	delete(i.externalFuncs, i.ident(name))
+	delete(i.boundFuncs, i.ident(name))
+	delete(i.externalDocs, i.ident(name))
+	return true
+}
+
+// externalFunc looks up an external function by identifier, binding it to
+// this interpreter if it was registered with RegisterBoundFunction
+func (i *Interpreter) externalFunc(name string) (ExternalFunc, bool) {
+	if bind, ok := i.boundFuncs[name]; ok {
+		return bind(i), true
+	}
+	fn, ok := i.externalFuncs[name]
+	return fn, ok
+}
+
+// HasExternalFunction reports whether an external function is registered
+// under the name
+func (i *Interpreter) HasExternalFunction(name string) bool {
+	_, ok := i.externalFuncs[i.ident(name)]
+	return ok
+}
+
+// SetStrictRegistration makes RegisterFunction fail rather than replace a
+// function already registered under the same name, so two bindings can't
+// silently shadow each other
+func (i *Interpreter) SetStrictRegistration(strict bool) {
+	i.strictRegister = strict
 }
 
 // SetMaxIterations sets the maximum loop iterations allowed
@@ -66,11 +355,111 @@ func (i *Interpreter) SetMaxIterations(max int) {
 	i.maxIterations = max
 }
 
-// SetPrintFunc sets a custom handler for PRINT statements
+// SetMaxStatements sets the maximum number of statements a run may execute,
+// counting those in loops and function calls. Exceeding it fails with a
+// BudgetExceededError. Zero, the default, means no limit.
+func (i *Interpreter) SetMaxStatements(max int) {
+	i.maxStatements = max
+}
+
+// SetMaxExpressionDepth sets the maximum expression nesting depth allowed
+// when parsing and evaluating. Programs already in the AST cache keep the
+// limit they were parsed with; the evaluation limit applies immediately.
+func (i *Interpreter) SetMaxExpressionDepth(max int) {
+	i.maxExprDepth = max
+}
+
+// SetNumberFormat sets how floats are formatted by PRINT and string
+// concatenation. With a non-zero format, PRINT passes floats to the print
+// handler as formatted strings.
+func (i *Interpreter) SetNumberFormat(format NumberFormat) {
+	i.numberFormat = format
+}
+
+// SetOverflowMode sets how integer overflow in +, -, * and / is handled.
+// OverflowWrap skips the checks entirely, so trusted scripts pay nothing.
+func (i *Interpreter) SetOverflowMode(mode OverflowMode) {
+	i.overflowMode = mode
+}
+
+// SetResultPolicy sets the numeric types returned to the host. Values passed
+// in by the host are always normalized to int and float64, whatever their Go
+// type, so Call("hit", int64(10)) and Call("hit", 10) behave the same.
+func (i *Interpreter) SetResultPolicy(policy ResultPolicy) {
+	i.resultPolicy = policy
+}
+
+// exportValue converts a script value to the types selected by the result
+// policy, copying arrays and maps that contain numbers
+func (i *Interpreter) exportValue(value interface{}) interface{} {
+	if i.resultPolicy == ResultNative {
+		return value
+	}
+
+	switch v := value.(type) {
+	case int:
+		if i.resultPolicy == ResultInt64 {
+			return int64(v)
+		}
+		return float64(v)
+	case []interface{}:
+		exported := make([]interface{}, len(v))
+		for idx, elem := range v {
+			exported[idx] = i.exportValue(elem)
+		}
+		return exported
+	case map[string]interface{}:
+		exported := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			exported[key] = i.exportValue(elem)
+		}
+		return exported
+	default:
+		return value
+	}
+}
+
+// SetPrintFunc sets a custom handler for PRINT statements. The handler is
+// called once per PRINT and can't tell whether it ended with a semicolon;
+// use SetRawPrintFunc for that.
 func (i *Interpreter) SetPrintFunc(fn PrintFunc) {
+	i.printFunc = func(value interface{}, newline bool) { fn(value) }
+}
+
+// SetRawPrintFunc sets a custom handler for PRINT statements that is also
+// told whether the line should end, replacing any handler set with
+// SetPrintFunc
+func (i *Interpreter) SetRawPrintFunc(fn RawPrintFunc) {
 	i.printFunc = fn
 }
 
+// printStdout is the default print handler
+func printStdout(value interface{}, newline bool) {
+	if newline {
+		fmt.Println(value)
+	} else {
+		fmt.Print(value)
+	}
+}
+
+// SetPrintSeparator sets the text placed between values when PRINT is given
+// more than one (PRINT a, b, c). The default is a single space.
+func (i *Interpreter) SetPrintSeparator(sep string) {
+	i.printSeparator = sep
+}
+
+// Seed reseeds the interpreter's random source, making scripts that use the
+// random builtins reproducible
+func (i *Interpreter) Seed(seed int64) {
+	i.rng.Seed(seed)
+}
+
+// Rand returns the interpreter's random source. Builtins that need
+// randomness should draw from it so Seed applies to them.
+func (i *Interpreter) Rand() *rand.Rand {
+	return i.rng
+}
+
 // Interpret executes the given code string
 func (i *Interpreter) Interpret(code string) error {
 	prog, err := i.getOrParseProgram(code)
@@ -78,7 +467,166 @@ func (i *Interpreter) Interpret(code string) error {
 		return err
 	}
 
-	return i.executeProgram(prog)
+	_, err = i.executeProgram(prog)
+	return err
+}
+
+// InterpretWithVars seeds the global scope with the given variables and then
+// executes the code. Seeded variables are visible to the script like any
+// top-level variable.
+func (i *Interpreter) InterpretWithVars(code string, vars map[string]interface{}) error {
+	prog, err := i.getOrParseProgram(code)
+	if err != nil {
+		return err
+	}
+
+	if err := i.seedGlobals(vars); err != nil {
+		return err
+	}
+
+	_, err = i.executeProgram(prog)
+	return err
+}
+
+// Evaluate executes the code in eval mode and returns the value of the last
+// top-level expression statement, or the value of an explicit top-level RETURN.
+// Bare expressions such as `2 + damage * 3` are valid statements in eval mode.
+func (i *Interpreter) Evaluate(code string) (interface{}, error) {
+	return i.EvaluateWithVars(code, nil)
+}
+
+// EvaluateWithVars seeds the global scope with the given variables and then
+// evaluates the code as Evaluate does
+func (i *Interpreter) EvaluateWithVars(code string, vars map[string]interface{}) (interface{}, error) {
+	prog, err := i.getOrParseEval(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := i.seedGlobals(vars); err != nil {
+		return nil, err
+	}
+
+	result, err := i.executeProgram(prog)
+	if err != nil {
+		return nil, err
+	}
+	return i.exportValue(result), nil
+}
+
+// EvaluateSession evaluates the code as Evaluate does, but keeps the
+// functions, record types, modules and ON ERROR handler set by earlier calls,
+// so code can be entered a piece at a time as in an interactive console. The
+// code is parsed without being added to the AST cache, which would otherwise
+// grow with every entry.
+func (i *Interpreter) EvaluateSession(code string) (interface{}, error) {
+	i.session = true
+	defer func() { i.session = false }()
+	return i.EvaluateWithVars(code, nil)
+}
+
+// seedGlobals copies host-provided variables into the global scope
+func (i *Interpreter) seedGlobals(vars map[string]interface{}) error {
+	for name, value := range vars {
+		normalized, err := functions.Normalize(value)
+		if err != nil {
+			return fmt.Errorf("variable %s: %v", name, err)
+		}
+		i.globalScope[i.ident(name)] = normalized
+	}
+	return nil
+}
+
+// HasVariable reports whether a global variable with the given name exists
+func (i *Interpreter) HasVariable(name string) bool {
+	_, ok := i.globalScope[i.ident(name)]
+	return ok
+}
+
+// VarType returns the type name of a global variable ("int", "float", "string",
+// "bool", "array", "map", "bytes", "null", "object" or the TYPE of a record),
+// or false if it doesn't exist
+func (i *Interpreter) VarType(name string) (string, bool) {
+	value, ok := i.globalScope[i.ident(name)]
+	if !ok {
+		return "", false
+	}
+	return functions.TypeName(value), true
+}
+
+// GetVariable returns the value of a global variable, converted as for Call
+// results, or false if it doesn't exist
+func (i *Interpreter) GetVariable(name string) (interface{}, bool) {
+	value, ok := i.globalScope[i.ident(name)]
+	if !ok {
+		return nil, false
+	}
+	if _, imported := value.(*closure); imported {
+		return nil, false
+	}
+	return i.exportValue(value), true
+}
+
+// SetVariable creates or replaces a global variable, which scripts then see
+// like any top-level variable. Constants can't be replaced.
+func (i *Interpreter) SetVariable(name string, value interface{}) error {
+	key := i.ident(name)
+	if _, host := i.hostConstant(key); host || i.constants[key] {
+		return fmt.Errorf("cannot assign to constant %s", name)
+	}
+	normalized, err := functions.Normalize(value)
+	if err != nil {
+		return fmt.Errorf("variable %s: %v", name, err)
+	}
+	i.globalScope[key] = normalized
+	return nil
+}
+
+// Globals returns a copy of the variables in the global scope. Arrays, maps
+// and records are copied too, so the result is a snapshot that later runs
+// don't change.
+func (i *Interpreter) Globals() map[string]interface{} {
+	globals := make(map[string]interface{}, len(i.globalScope))
+	snap := newSnapshot()
+	for name, value := range i.globalScope {
+		if _, ok := value.(*closure); ok {
+			continue // An imported function
+		}
+		globals[name] = i.exportValue(snap.copy(value))
+	}
+	return globals
+}
+
+// SetGlobals replaces the global variables with a copy of vars, such as a
+// snapshot taken earlier with Globals. Constants and imported modules are
+// kept, and entries naming a constant are ignored. If a value can't be
+// converted, such as an unsigned number too large for int, the globals are
+// left as they were.
+func (i *Interpreter) SetGlobals(vars map[string]interface{}) error {
+	snap := newSnapshot()
+	normalized := make(map[string]interface{}, len(vars))
+	for name, value := range vars {
+		key := i.ident(name)
+		if i.constants[key] {
+			continue
+		}
+		value, err := functions.Normalize(snap.copy(value))
+		if err != nil {
+			return fmt.Errorf("variable %s: %v", name, err)
+		}
+		normalized[key] = value
+	}
+
+	for name, value := range i.globalScope {
+		if _, ok := value.(*closure); ok || i.constants[name] {
+			continue
+		}
+		delete(i.globalScope, name)
+	}
+	for key, value := range normalized {
+		i.globalScope[key] = value
+	}
+	return nil
 }
 
 // Load parses the code, registers function definitions, and executes top-level code.
@@ -88,16 +636,26 @@ func (i *Interpreter) Load(code string) error {
 	if err != nil {
 		return err
 	}
+	return i.LoadProgram(prog)
+}
 
+// LoadProgram is Load for a program made by Compile
+func (i *Interpreter) LoadProgram(prog *Program) error {
 	// Reset state for new script
 	i.userFuncs = make(map[string]*FunctionStatement)
+	i.types = make(map[string]*TypeStatement)
 	i.globalScope = make(map[string]interface{})
+	i.constants = make(map[string]bool)
+	i.errorHandler = ""
+	i.modules = make(map[string]*module)
+	i.explicit = i.requiresDeclarations(prog.Statements)
 
 	// Collect top-level statements and function definitions
+	i.defineTypes(prog.Statements)
 	var topLevelStatements []Statement
 	for _, stmt := range prog.Statements {
 		if fn, ok := stmt.(*FunctionStatement); ok {
-			i.userFuncs[strings.ToLower(fn.Name)] = fn
+			i.userFuncs[i.ident(fn.Name)] = fn
 		} else {
 			topLevelStatements = append(topLevelStatements, stmt)
 		}
@@ -105,15 +663,29 @@ func (i *Interpreter) Load(code string) error {
 
 	// Execute top-level code now, storing variables in global scope
 	if len(topLevelStatements) > 0 {
+		i.resetInterrupt()
 		i.iterationCount = 0
+		i.statementCount = 0
+		i.usage = memoryUsage{}
 		i.breakFlag = false
+		i.exiting = nil
+		i.gotoLabel = ""
+		i.hookErr = nil
 		i.returnFlag = false
 		i.returnValue = nil
 		i.scopes = []map[string]interface{}{i.globalScope}
-
-		for _, stmt := range topLevelStatements {
-			if err := i.executeStatement(stmt); err != nil {
-				return fmt.Errorf("error in top-level code: %w", err)
+		i.callerScopes = nil
+		i.callStack = nil
+		i.frames = nil
+
+		for idx := 0; idx < len(topLevelStatements); idx++ {
+			if err := i.executeStatement(topLevelStatements[idx]); err != nil {
+				if err = i.handleError(topLevelStatements[idx], err); err != nil {
+					return fmt.Errorf("error in top-level code: %w", err)
+				}
+			}
+			if target, ok := i.jumpTarget(topLevelStatements); ok {
+				idx = target
 			}
 		}
 	}
@@ -125,45 +697,119 @@ func (i *Interpreter) Load(code string) error {
 // Global variables from top-level code persist between calls.
 // Function-local variables do not persist between calls.
 func (i *Interpreter) Call(funcName string, args ...interface{}) (interface{}, error) {
-	name := strings.ToLower(funcName)
+	name := i.ident(funcName)
 
 	fn, ok := i.userFuncs[name]
 	if !ok {
 		return nil, fmt.Errorf("undefined function: %s", funcName)
 	}
 
-	if len(args) != len(fn.Params) {
-		return nil, fmt.Errorf("function %s expects %d arguments, got %d", funcName, len(fn.Params), len(args))
+	if err := checkArgCount(funcName, fn, len(args)); err != nil {
+		return nil, err
 	}
 
 	// Reset execution state for this call
+	i.resetInterrupt()
 	i.iterationCount = 0
+	i.statementCount = 0
+	i.usage = memoryUsage{}
 	i.breakFlag = false
+	i.exiting = nil
+	i.gotoLabel = ""
+	i.hookErr = nil
 	i.returnFlag = false
 	i.returnValue = nil
 
 	// Start with global scope + fresh local scope for function
 	i.scopes = []map[string]interface{}{i.globalScope, make(map[string]interface{})}
+	i.callerScopes = nil
+	i.callStack = []string{fn.Name}
+	i.frames = []callFrame{{base: 1}}
 
 	// Bind parameters to the local scope (top of stack)
-	for idx, param := range fn.Params {
-		i.currentScope()[strings.ToLower(param)] = args[idx]
+	values := make([]interface{}, len(args))
+	for idx, arg := range args {
+		value, err := functions.Normalize(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %d: %v", fn.Name, idx+1, err)
+		}
+		values[idx] = value
+	}
+	if err := i.bindParams(fn, values); err != nil {
+		return nil, err
 	}
+	i.defineNested(fn.Body)
 
 	// Execute function body
 	if err := i.executeBlock(fn.Body); err != nil {
 		return nil, err
 	}
 
-	return i.returnValue, nil
+	return i.exportValue(i.returnValue), nil
+}
+
+// SetNamedArgPolicy sets how CallNamed handles extra and missing arguments
+func (i *Interpreter) SetNamedArgPolicy(policy NamedArgPolicy) {
+	i.namedArgPolicy = policy
+}
+
+// CallNamed invokes a script-defined function, binding arguments by parameter
+// name (case-insensitive) rather than position. Missing keys take the
+// parameter's default value; extra keys and missing keys without a default
+// are handled according to the interpreter's NamedArgPolicy.
+func (i *Interpreter) CallNamed(funcName string, args map[string]interface{}) (interface{}, error) {
+	fn, ok := i.userFuncs[i.ident(funcName)]
+	if !ok {
+		return nil, fmt.Errorf("undefined function: %s", funcName)
+	}
+
+	named := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		named[i.ident(key)] = value
+	}
+
+	positional := make([]interface{}, len(fn.Params))
+	for idx, param := range fn.Params {
+		key := i.ident(param)
+		value, ok := named[key]
+		if !ok && idx < len(fn.Defaults) && fn.Defaults[idx] != nil {
+			value = omittedArg{}
+		} else if !ok && !i.namedArgPolicy.AllowMissing {
+			return nil, fmt.Errorf("function %s: missing argument %s", funcName, param)
+		}
+		positional[idx] = value
+		delete(named, key)
+	}
+
+	if len(named) > 0 && i.namedArgPolicy.RejectExtra {
+		extra := make([]string, 0, len(named))
+		for key := range named {
+			extra = append(extra, key)
+		}
+		sort.Strings(extra)
+		return nil, fmt.Errorf("function %s: unknown arguments %s", funcName, strings.Join(extra, ", "))
+	}
+
+	return i.Call(funcName, positional...)
 }
 
 // HasFunction checks if a function with the given name exists
 func (i *Interpreter) HasFunction(funcName string) bool {
-	_, ok := i.userFuncs[strings.ToLower(funcName)]
+	_, ok := i.userFuncs[i.ident(funcName)]
 	return ok
 }
 
+// FunctionNames returns the names of the script functions defined by the
+// loaded program, in lowercase and sorted
+func (i *Interpreter) FunctionNames() []string {
+	names := make([]string, 0, len(i.userFuncs))
+	for name := range i.userFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Validate checks the given code for syntax errors without executing it
 func (i *Interpreter) Validate(code string) error {
 	_, err := i.getOrParseProgram(code)
@@ -172,63 +818,169 @@ func (i *Interpreter) Validate(code string) error {
 
 // getOrParseProgram returns a cached AST or parses and caches the code
 func (i *Interpreter) getOrParseProgram(code string) (*Program, error) {
-	hash := i.hashCode(code)
-
-	if prog, ok := i.astCache[hash]; ok {
-		return prog, nil
+	prog, err := i.cachedParse(i.hashCode(code), "", code, false)
+	if err != nil {
+		return nil, err
 	}
+	return i.expandIncludes(prog, false)
+}
 
-	tokens, err := Tokenize(code)
+// getOrParseEval returns a cached AST or parses and caches the code in eval mode.
+// Eval programs are cached under a separate key since they parse differently.
+func (i *Interpreter) getOrParseEval(code string) (*Program, error) {
+	prog, err := i.cachedParse(i.EvalCacheKey(code), "", code, true)
 	if err != nil {
 		return nil, err
 	}
+	return i.expandIncludes(prog, true)
+}
+
+// cachedParse returns the cached AST for the hash or parses and caches the
+// code. file names the included file the code comes from, if any.
+func (i *Interpreter) cachedParse(hash, file, code string, eval bool) (*Program, error) {
+	i.warnings = nil
+
+	if i.noCache || i.session {
+		prog, warnings, err := i.parseProgram(file, code, eval)
+		if err != nil {
+			return nil, err
+		}
+		i.warnings = warnings
+		return prog, nil
+	}
+
+	if cached, ok := i.astCache.programs[hash]; ok {
+		i.touch(cached)
+		i.warnings = cached.warnings
+		return cached.program, nil
+	}
 
-	prog, err := Parse(tokens)
+	prog, warnings, err := i.parseProgram(file, code, eval)
 	if err != nil {
 		return nil, err
 	}
+	i.warnings = warnings
 
-	i.astCache[hash] = prog
+	cached := &cachedProgram{hash: hash, program: prog, size: len(code), warnings: warnings}
+	i.touch(cached)
+	i.astCache.add(cached)
 	return prog, nil
 }
 
+// parseProgram tokenizes, parses and analyzes the code without consulting the cache
+func (i *Interpreter) parseProgram(file, code string, eval bool) (*Program, []Warning, error) {
+	tokens, err := Tokenize(code)
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			parseErr.File = file
+		}
+		return nil, nil, err
+	}
+
+	p := NewParser(tokens)
+	p.file = file
+	p.allowExpressions = eval
+	p.maxDepth = i.maxExprDepth
+	prog, err := p.ParseProgram()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	warnings, err := analyze(prog, eval, i.caseSensitive)
+	if err != nil {
+		return nil, nil, err
+	}
+	return prog, warnings, nil
+}
+
 func (i *Interpreter) hashCode(code string) string {
 	h := sha256.Sum256([]byte(code))
 	return fmt.Sprintf("%x", h[:8])
 }
 
-// executeProgram runs the program
-func (i *Interpreter) executeProgram(prog *Program) error {
+// executeProgram runs the program and returns the value of the last top-level
+// expression statement, or the value of a top-level RETURN
+func (i *Interpreter) executeProgram(prog *Program) (interface{}, error) {
 	// Reset execution state
+	i.resetInterrupt()
 	i.iterationCount = 0
+	i.statementCount = 0
+	i.usage = memoryUsage{}
 	i.breakFlag = false
+	i.exiting = nil
+	i.gotoLabel = ""
+	i.hookErr = nil
 	i.returnFlag = false
 	i.returnValue = nil
-	i.userFuncs = make(map[string]*FunctionStatement)
+	if i.session {
+		i.explicit = i.explicit || i.requiresDeclarations(prog.Statements)
+		if i.modules == nil {
+			i.modules = make(map[string]*module)
+		}
+	} else {
+		i.modules = make(map[string]*module)
+		i.explicit = i.requiresDeclarations(prog.Statements)
+		i.errorHandler = ""
+		i.userFuncs = make(map[string]*FunctionStatement)
+		i.types = make(map[string]*TypeStatement)
+	}
+	i.scopes = []map[string]interface{}{i.globalScope}
+	i.callerScopes = nil
+	i.callStack = nil
+	i.frames = nil
 
-	// First pass: collect function definitions
+	// First pass: collect function and type definitions
+	i.defineTypes(prog.Statements)
 	for _, stmt := range prog.Statements {
 		if fn, ok := stmt.(*FunctionStatement); ok {
-			i.userFuncs[strings.ToLower(fn.Name)] = fn
+			i.userFuncs[i.ident(fn.Name)] = fn
 		}
 	}
 
 	// Second pass: execute top-level statements
-	for _, stmt := range prog.Statements {
-		if _, ok := stmt.(*FunctionStatement); ok {
+	var result interface{}
+	for idx := 0; idx < len(prog.Statements); idx++ {
+		stmt := prog.Statements[idx]
+		switch s := stmt.(type) {
+		case *FunctionStatement:
 			continue // Skip function definitions
+		case *ExpressionStatement:
+			if err := i.checkInterrupt(s); err != nil {
+				return nil, err
+			}
+			if err := i.countStatement(s); err != nil {
+				return nil, err
+			}
+			if err := i.debugStep(s); err != nil {
+				return nil, err
+			}
+			val, err := i.evaluateTopLevel(s)
+			if err != nil {
+				if err = i.handleError(s, err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			result = val
+			continue
 		}
 
 		if err := i.executeStatement(stmt); err != nil {
-			return err
+			if err = i.handleError(stmt, err); err != nil {
+				return nil, err
+			}
 		}
 
 		if i.returnFlag {
-			break
+			return i.returnValue, nil
+		}
+		if target, ok := i.jumpTarget(prog.Statements); ok {
+			idx = target
 		}
 	}
 
-	return nil
+	return result, nil
 }
 
 // -----------------------------------------------------------------------------
@@ -236,18 +988,99 @@ func (i *Interpreter) executeProgram(prog *Program) error {
 // -----------------------------------------------------------------------------
 
 func (i *Interpreter) executeStatement(stmt Statement) error {
+	if err := i.checkInterrupt(stmt); err != nil {
+		return err
+	}
+	if err := i.countStatement(stmt); err != nil {
+		return err
+	}
+
+	if i.debugHook != nil {
+		switch stmt.(type) {
+		case *FunctionStatement, *LabelStatement:
+			// Definitions and labels aren't steps
+		default:
+			if err := i.debugStep(stmt); err != nil {
+				return err
+			}
+		}
+	}
+
+	if i.profiler != nil {
+		defer i.profiler.start(stmt)()
+	}
+
+	err := i.runStatement(stmt)
+	if err == nil || (i.hookErr != nil && errors.Is(err, i.hookErr)) {
+		return err // Debug hook errors reach the host unchanged
+	}
+	return i.positionError(stmt, err)
+}
+
+// evaluateTopLevel evaluates an expression statement at the top level of the
+// program, whose value Evaluate returns. Errors are given the statement's
+// position as executeStatement gives them.
+func (i *Interpreter) evaluateTopLevel(stmt *ExpressionStatement) (interface{}, error) {
+	if i.profiler != nil {
+		defer i.profiler.start(stmt)()
+	}
+	value, err := i.evaluateExpression(stmt.Expr)
+	if err == nil || (i.hookErr != nil && errors.Is(err, i.hookErr)) {
+		return value, err
+	}
+	return nil, i.positionError(stmt, err)
+}
+
+// runStatement executes one statement. Errors that don't carry a position
+// from deeper in the script are given the statement's by executeStatement.
+func (i *Interpreter) runStatement(stmt Statement) error {
 	switch s := stmt.(type) {
 	case *LetStatement:
 		return i.executeLetStatement(s)
+	case *ConstStatement:
+		return i.executeConstStatement(s)
+	case *DimStatement:
+		return i.executeDimStatement(s)
 	case *AssignStatement:
 		return i.executeAssignStatement(s)
+	case *MultiAssignStatement:
+		return i.executeMultiAssignStatement(s)
 	case *IfStatement:
 		return i.executeIfStatement(s)
 	case *ForStatement:
 		return i.executeForStatement(s)
+	case *DoLoopStatement:
+		return i.executeDoLoopStatement(s)
+	case *ExitStatement:
+		return i.executeExitStatement(s)
 	case *BreakStatement:
 		i.breakFlag = true
 		return nil
+	case *TryStatement:
+		return i.executeTryStatement(s)
+	case *GotoStatement:
+		return i.executeGotoStatement(s)
+	case *OnErrorStatement:
+		return i.executeOnErrorStatement(s)
+	case *GlobalStatement:
+		return i.executeGlobalStatement(s)
+	case *LocalStatement:
+		return i.executeLocalStatement(s)
+	case *ThrowStatement:
+		return i.executeThrowStatement(s)
+	case *ImportStatement:
+		return i.executeImportStatement(s)
+	case *IncludeStatement:
+		// Replaced by the included statements when the program is parsed
+		return nil
+	case *OptionStatement:
+		// Applied before the program runs
+		return nil
+	case *TypeStatement:
+		// Registered before the program runs
+		return nil
+	case *LabelStatement:
+		return nil
 	case *ReturnStatement:
 		return i.executeReturnStatement(s)
 	case *PrintStatement:
@@ -256,52 +1089,100 @@ func (i *Interpreter) executeStatement(stmt Statement) error {
 		_, err := i.evaluateExpression(s.Expr)
 		return err
 	case *FunctionStatement:
-		// Already collected in first pass
+		// Top-level functions are collected before the program runs and
+		// nested ones are bound when the enclosing function is called
 		return nil
 	default:
 		return fmt.Errorf("unknown statement type: %T", stmt)
 	}
 }
 
+// executeConstStatement defines a constant in the top-level scope of the
+// script or module. Running the same CONST again, as when a script is rerun,
+// redefines it.
+func (i *Interpreter) executeConstStatement(stmt *ConstStatement) error {
+	value, err := i.evaluateExpression(stmt.Value)
+	if err != nil {
+		return err
+	}
+
+	name := i.ident(stmt.Name)
+	if _, host := i.hostConstant(name); host {
+		return i.runtimeError(stmt, "constant %s is already defined by the host", stmt.Name)
+	}
+	_, exists := i.scopes[0][name]
+	if err := i.checkStore(stmt, stmt.Name, value, at(i.scopes[0], name), !exists); err != nil {
+		return err
+	}
+	i.scopes[0][name] = value
+	i.constants[name] = true
+	return nil
+}
+
 func (i *Interpreter) executeLetStatement(stmt *LetStatement) error {
+	if err := i.checkAssignable(stmt, stmt.Name); err != nil {
+		return err
+	}
+
 	value, err := i.evaluateExpression(stmt.Value)
 	if err != nil {
 		return err
 	}
 
+	if err := i.checkLocalStore(stmt, stmt.Name, value); err != nil {
+		return err
+	}
+
 	// LET always creates/overwrites in current scope
-	i.currentScope()[strings.ToLower(stmt.Name)] = value
+	i.currentScope()[i.ident(stmt.Name)] = value
 	return nil
 }
 
 func (i *Interpreter) executeAssignStatement(stmt *AssignStatement) error {
-	name := strings.ToLower(stmt.Name)
+	if err := i.checkAssignable(stmt, stmt.Name); err != nil {
+		return err
+	}
+
+	get, set, err := i.assignTarget(stmt)
+	if err != nil {
+		return err
+	}
 
 	switch stmt.Operator {
 	case TOKEN_PLUS_PLUS:
-		val, err := i.getVariable(name)
+		val, err := get()
 		if err != nil {
 			return err
 		}
 		newVal, err := i.addValues(val, 1)
+		if errors.Is(err, errIntegerOverflow) {
+			return i.runtimeError(stmt, "%v", err)
+		}
 		if err != nil {
 			return i.runtimeError(stmt, "cannot increment %T", val)
 		}
-		i.setVariable(name, newVal)
+		if err := set(newVal); err != nil {
+			return err
+		}
 
 	case TOKEN_MINUS_MINUS:
-		val, err := i.getVariable(name)
+		val, err := get()
 		if err != nil {
 			return err
 		}
 		newVal, err := i.subtractValues(val, 1)
+		if errors.Is(err, errIntegerOverflow) {
+			return i.runtimeError(stmt, "%v", err)
+		}
 		if err != nil {
 			return i.runtimeError(stmt, "cannot decrement %T", val)
 		}
-		i.setVariable(name, newVal)
+		if err := set(newVal); err != nil {
+			return err
+		}
 
 	case TOKEN_PLUS_EQ:
-		val, err := i.getVariable(name)
+		val, err := get()
 		if err != nil {
 			return err
 		}
@@ -310,13 +1191,18 @@ func (i *Interpreter) executeAssignStatement(stmt *AssignStatement) error {
 			return err
 		}
 		newVal, err := i.addValues(val, addend)
+		if errors.Is(err, errIntegerOverflow) {
+			return i.runtimeError(stmt, "%v", err)
+		}
 		if err != nil {
 			return i.runtimeError(stmt, "cannot add %T to %T", addend, val)
 		}
-		i.setVariable(name, newVal)
+		if err := set(newVal); err != nil {
+			return err
+		}
 
 	case TOKEN_MINUS_EQ:
-		val, err := i.getVariable(name)
+		val, err := get()
 		if err != nil {
 			return err
 		}
@@ -325,17 +1211,24 @@ func (i *Interpreter) executeAssignStatement(stmt *AssignStatement) error {
 			return err
 		}
 		newVal, err := i.subtractValues(val, subtrahend)
+		if errors.Is(err, errIntegerOverflow) {
+			return i.runtimeError(stmt, "%v", err)
+		}
 		if err != nil {
 			return i.runtimeError(stmt, "cannot subtract %T from %T", subtrahend, val)
 		}
-		i.setVariable(name, newVal)
+		if err := set(newVal); err != nil {
+			return err
+		}
 
 	case TOKEN_EQ:
 		value, err := i.evaluateExpression(stmt.Value)
 		if err != nil {
 			return err
 		}
-		i.setVariable(name, value)
+		if err := set(value); err != nil {
+			return err
+		}
 
 	default:
 		return i.runtimeError(stmt, "unknown assignment operator: %s", stmt.Operator)
@@ -379,35 +1272,87 @@ func (i *Interpreter) executeForStatement(stmt *ForStatement) error {
 		return err
 	}
 
-	end, err := i.evaluateExpression(stmt.End)
-	if err != nil {
-		return err
-	}
+	end, err := i.evaluateExpression(stmt.End)
+	if err != nil {
+		return err
+	}
+
+	startInt, ok := i.toInt(start)
+	if !ok {
+		return i.runtimeError(stmt, "FOR start value must be numeric")
+	}
+
+	endInt, ok := i.toInt(end)
+	if !ok {
+		return i.runtimeError(stmt, "FOR end value must be numeric")
+	}
+
+	// Create a new scope for the loop variable (doesn't leak)
+	i.pushScope()
+	defer i.popScope()
+
+	if err := i.checkAssignable(stmt, stmt.Variable); err != nil {
+		return err
+	}
+	if err := i.checkStore(stmt, stmt.Variable, startInt, location{}, true); err != nil {
+		return err
+	}
+	varName := i.ident(stmt.Variable)
+
+	for j := startInt; j <= endInt; j++ {
+		// Check infinite loop protection
+		if err := i.countIteration(stmt); err != nil {
+			return err
+		}
+		if err := i.checkInterrupt(stmt); err != nil {
+			return err
+		}
+
+		i.currentScope()[varName] = j
+
+		if err := i.executeBlock(stmt.Body); err != nil {
+			return err
+		}
+
+		if i.breakFlag {
+			i.breakFlag = false
+			break
+		}
+
+		if i.exiting != nil {
+			i.leaveLoop(stmt)
+			break
+		}
 
-	startInt, ok := i.toInt(start)
-	if !ok {
-		return i.runtimeError(stmt, "FOR start value must be numeric")
+		if i.returnFlag || i.gotoLabel != "" {
+			break
+		}
 	}
 
-	endInt, ok := i.toInt(end)
-	if !ok {
-		return i.runtimeError(stmt, "FOR end value must be numeric")
-	}
+	return nil
+}
 
-	// Create a new scope for the loop variable (doesn't leak)
+func (i *Interpreter) executeDoLoopStatement(stmt *DoLoopStatement) error {
+	// Variables declared in the body stay visible to the LOOP condition
+	// but don't leak out of the loop
 	i.pushScope()
 	defer i.popScope()
 
-	varName := strings.ToLower(stmt.Variable)
+	for {
+		if stmt.PreTest {
+			done, err := i.loopDone(stmt)
+			if err != nil || done {
+				return err
+			}
+		}
 
-	for j := startInt; j <= endInt; j++ {
 		// Check infinite loop protection
-		i.iterationCount++
-		if i.iterationCount > i.maxIterations {
-			return i.runtimeError(stmt, "maximum iterations exceeded (%d)", i.maxIterations)
+		if err := i.countIteration(stmt); err != nil {
+			return err
+		}
+		if err := i.checkInterrupt(stmt); err != nil {
+			return err
 		}
-
-		i.currentScope()[varName] = j
 
 		if err := i.executeBlock(stmt.Body); err != nil {
 			return err
@@ -415,17 +1360,67 @@ func (i *Interpreter) executeForStatement(stmt *ForStatement) error {
 
 		if i.breakFlag {
 			i.breakFlag = false
-			break
+			return nil
 		}
 
-		if i.returnFlag {
-			break
+		if i.exiting != nil {
+			i.leaveLoop(stmt)
+			return nil
+		}
+
+		if i.returnFlag || i.gotoLabel != "" {
+			return nil
+		}
+
+		if !stmt.PreTest {
+			done, err := i.loopDone(stmt)
+			if err != nil || done {
+				return err
+			}
 		}
 	}
+}
+
+// loopDone evaluates a DO loop's condition and reports whether to stop
+func (i *Interpreter) loopDone(stmt *DoLoopStatement) (bool, error) {
+	if stmt.Condition == nil {
+		return false, nil
+	}
+
+	cond, err := i.evaluateExpression(stmt.Condition)
+	if err != nil {
+		return false, err
+	}
+	return i.isTruthy(cond) == stmt.Until, nil
+}
 
+// executeExitStatement leaves the function like a RETURN without a value, or
+// starts leaving loops until the one the EXIT names is reached
+func (i *Interpreter) executeExitStatement(stmt *ExitStatement) error {
+	switch stmt.Kind {
+	case TOKEN_FUNCTION, TOKEN_SUB:
+		i.returnFlag = true
+	default:
+		i.exiting = stmt
+	}
 	return nil
 }
 
+// leaveLoop is called as a loop stops for a pending EXIT. The EXIT is done
+// once it reaches a loop of its kind, and otherwise carries on outward.
+func (i *Interpreter) leaveLoop(loop Statement) {
+	switch l := loop.(type) {
+	case *ForStatement:
+		if i.exiting.Kind == TOKEN_FOR && (i.exiting.Variable == "" || i.sameIdent(i.exiting.Variable, l.Variable)) {
+			i.exiting = nil
+		}
+	case *DoLoopStatement:
+		if i.exiting.Kind == TOKEN_DO || i.exiting.Kind == TOKEN_WHILE {
+			i.exiting = nil
+		}
+	}
+}
+
 func (i *Interpreter) executeReturnStatement(stmt *ReturnStatement) error {
 	if stmt.Value != nil {
 		val, err := i.evaluateExpression(stmt.Value)
@@ -438,21 +1433,97 @@ func (i *Interpreter) executeReturnStatement(stmt *ReturnStatement) error {
 	return nil
 }
 
+// executePrintStatement hands a single value to the print handler as is;
+// several values are converted to text and joined into one string
 func (i *Interpreter) executePrintStatement(stmt *PrintStatement) error {
-	val, err := i.evaluateExpression(stmt.Value)
+	if stmt.Using != nil {
+		return i.executePrintUsing(stmt)
+	}
+
+	values, err := i.evaluateAll(stmt.Values)
 	if err != nil {
 		return err
 	}
-	i.printFunc(val)
+
+	if len(values) > 1 {
+		parts := make([]string, len(values))
+		for idx, val := range values {
+			parts[idx] = i.toString(val)
+		}
+		i.printFunc(strings.Join(parts, i.printSeparator), !stmt.NoNewline)
+		return nil
+	}
+
+	val := values[0]
+	if f, ok := val.(float64); ok && i.numberFormat != (NumberFormat{}) {
+		i.printFunc(i.formatFloat(f), !stmt.NoNewline)
+		return nil
+	}
+	i.printFunc(val, !stmt.NoNewline)
+	return nil
+}
+
+// countIteration records one loop iteration or jump, failing once the
+// iteration limit is exceeded
+func (i *Interpreter) countIteration(node Node) error {
+	i.iterationCount++
+	if i.iterationCount > i.maxIterations {
+		return i.runtimeError(node, "%w (%d)", errIterationLimit, i.maxIterations)
+	}
+	return nil
+}
+
+// countStatement records one executed statement, failing once the
+// statement budget is exceeded
+func (i *Interpreter) countStatement(node Node) error {
+	if i.maxStatements <= 0 {
+		return nil
+	}
+	i.statementCount++
+	if i.statementCount > i.maxStatements {
+		return i.runtimeError(node, "%w", &BudgetExceededError{Limit: i.maxStatements})
+	}
+	return nil
+}
+
+// executeGotoStatement starts a jump. Each enclosing block looks for the
+// label as the jump unwinds; the analyzer has already checked that one has it.
+func (i *Interpreter) executeGotoStatement(stmt *GotoStatement) error {
+	// A GOTO can loop, so it counts toward the iteration limit
+	if err := i.countIteration(stmt); err != nil {
+		return err
+	}
+	i.gotoLabel = i.ident(stmt.Label)
 	return nil
 }
 
+// jumpTarget completes a pending GOTO whose label is in statements,
+// returning the label's index
+func (i *Interpreter) jumpTarget(statements []Statement) (int, bool) {
+	if i.gotoLabel == "" {
+		return 0, false
+	}
+	for idx, stmt := range statements {
+		if label, ok := stmt.(*LabelStatement); ok && i.sameIdent(label.Name, i.gotoLabel) {
+			i.gotoLabel = ""
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
 func (i *Interpreter) executeBlock(statements []Statement) error {
-	for _, stmt := range statements {
-		if err := i.executeStatement(stmt); err != nil {
-			return err
+	for idx := 0; idx < len(statements); idx++ {
+		if err := i.executeStatement(statements[idx]); err != nil {
+			if err = i.handleError(statements[idx], err); err != nil {
+				return err
+			}
+		}
+		if target, ok := i.jumpTarget(statements); ok {
+			idx = target
+			continue
 		}
-		if i.breakFlag || i.returnFlag {
+		if i.breakFlag || i.exiting != nil || i.returnFlag || i.gotoLabel != "" {
 			break
 		}
 	}
@@ -464,6 +1535,12 @@ func (i *Interpreter) executeBlock(statements []Statement) error {
 // -----------------------------------------------------------------------------
 
 func (i *Interpreter) evaluateExpression(expr Expression) (interface{}, error) {
+	i.exprDepth++
+	defer func() { i.exprDepth-- }()
+	if i.maxExprDepth > 0 && i.exprDepth > i.maxExprDepth {
+		return nil, i.runtimeError(expr, "expression too complex: nesting exceeds %d levels", i.maxExprDepth)
+	}
+
 	switch e := expr.(type) {
 	case *IntLiteral:
 		return e.Value, nil
@@ -473,14 +1550,33 @@ func (i *Interpreter) evaluateExpression(expr Expression) (interface{}, error) {
 		return e.Value, nil
 	case *BoolLiteral:
 		return e.Value, nil
+	case *NullLiteral:
+		return nil, nil
 	case *Identifier:
-		return i.getVariable(strings.ToLower(e.Name))
+		return i.getVariable(i.ident(e.Name))
 	case *BinaryExpr:
 		return i.evaluateBinaryExpr(e)
 	case *UnaryExpr:
 		return i.evaluateUnaryExpr(e)
 	case *CallExpr:
 		return i.evaluateCallExpr(e)
+	case *IndexExpr:
+		return i.evaluateIndexExpr(e)
+	case *SliceExpr:
+		return i.evaluateSliceExpr(e)
+	case *MemberExpr:
+		return i.evaluateMemberExpr(e)
+	case *YieldExpr:
+		return i.evaluateYield(e)
+	case *ConditionalExpr:
+		cond, err := i.evaluateExpression(e.Condition)
+		if err != nil {
+			return nil, err
+		}
+		if i.isTruthy(cond) {
+			return i.evaluateExpression(e.Then)
+		}
+		return i.evaluateExpression(e.Else)
 	default:
 		return nil, fmt.Errorf("unknown expression type: %T", expr)
 	}
@@ -497,16 +1593,28 @@ func (i *Interpreter) evaluateBinaryExpr(expr *BinaryExpr) (interface{}, error)
 		return nil, err
 	}
 
+	// NULL equals only itself and has no order
+	switch expr.Operator {
+	case TOKEN_LT, TOKEN_GT, TOKEN_LTE, TOKEN_GTE:
+		if left == nil || right == nil {
+			return nil, i.runtimeError(expr, "cannot order NULL; compare it with = or <> instead")
+		}
+	}
+
 	switch expr.Operator {
 	// Arithmetic
 	case TOKEN_PLUS:
-		return i.addValues(left, right)
+		result, err := i.addValues(left, right)
+		return result, i.positionOverflow(expr, err)
 	case TOKEN_MINUS:
-		return i.subtractValues(left, right)
+		result, err := i.subtractValues(left, right)
+		return result, i.positionOverflow(expr, err)
 	case TOKEN_STAR:
-		return i.multiplyValues(left, right)
+		result, err := i.multiplyValues(left, right)
+		return result, i.positionOverflow(expr, err)
 	case TOKEN_SLASH:
-		return i.divideValues(left, right)
+		result, err := i.divideValues(left, right)
+		return result, i.positionOverflow(expr, err)
 
 	// Comparison
 	case TOKEN_EQ:
@@ -522,6 +1630,10 @@ func (i *Interpreter) evaluateBinaryExpr(expr *BinaryExpr) (interface{}, error)
 	case TOKEN_GTE:
 		return i.compareValues(left, right) >= 0, nil
 
+	// Bitwise
+	case TOKEN_AMP, TOKEN_PIPE, TOKEN_XOR, TOKEN_SHL, TOKEN_SHR:
+		return i.bitwiseValues(expr, left, right)
+
 	// Logical
 	case TOKEN_AND:
 		return i.isTruthy(left) && i.isTruthy(right), nil
@@ -533,6 +1645,42 @@ func (i *Interpreter) evaluateBinaryExpr(expr *BinaryExpr) (interface{}, error)
 	}
 }
 
+// bitwiseSymbols spells the bitwise operators for error messages
+var bitwiseSymbols = map[TokenType]string{
+	TOKEN_AMP:  "&",
+	TOKEN_PIPE: "|",
+	TOKEN_XOR:  "XOR",
+	TOKEN_SHL:  "<<",
+	TOKEN_SHR:  ">>",
+}
+
+// bitwiseValues applies a bitwise or shift operator to two ints
+func (i *Interpreter) bitwiseValues(expr *BinaryExpr, left, right interface{}) (interface{}, error) {
+	a, aOK := left.(int)
+	b, bOK := right.(int)
+	if !aOK || !bOK {
+		return nil, i.runtimeError(expr, "operator %s requires integers, got %s and %s",
+			bitwiseSymbols[expr.Operator], functions.TypeName(left), functions.TypeName(right))
+	}
+
+	switch expr.Operator {
+	case TOKEN_AMP:
+		return a & b, nil
+	case TOKEN_PIPE:
+		return a | b, nil
+	case TOKEN_XOR:
+		return a ^ b, nil
+	}
+
+	if b < 0 {
+		return nil, i.runtimeError(expr, "negative shift count %d", b)
+	}
+	if expr.Operator == TOKEN_SHL {
+		return a << b, nil
+	}
+	return a >> b, nil
+}
+
 func (i *Interpreter) evaluateUnaryExpr(expr *UnaryExpr) (interface{}, error) {
 	operand, err := i.evaluateExpression(expr.Operand)
 	if err != nil {
@@ -543,6 +1691,12 @@ func (i *Interpreter) evaluateUnaryExpr(expr *UnaryExpr) (interface{}, error) {
 	case TOKEN_MINUS:
 		switch v := operand.(type) {
 		case int:
+			if v == math.MinInt && i.overflowMode != OverflowWrap {
+				if i.overflowMode == OverflowError {
+					return nil, i.runtimeError(expr, "integer overflow negating %d", v)
+				}
+				return -float64(v), nil
+			}
 			return -v, nil
 		case float64:
 			return -v, nil
@@ -559,7 +1713,7 @@ func (i *Interpreter) evaluateUnaryExpr(expr *UnaryExpr) (interface{}, error) {
 }
 
 func (i *Interpreter) evaluateCallExpr(expr *CallExpr) (interface{}, error) {
-	name := strings.ToLower(expr.Name)
+	name := i.ident(expr.Name)
 
 	// Evaluate arguments
 	args := make([]interface{}, len(expr.Args))
@@ -570,33 +1724,225 @@ func (i *Interpreter) evaluateCallExpr(expr *CallExpr) (interface{}, error) {
 		}
 		args[idx] = val
 	}
+	named := make([]interface{}, len(expr.Named))
+	for idx, arg := range expr.Named {
+		val, err := i.evaluateExpression(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		named[idx] = val
+	}
+
+	// Nested functions shadow everything else with the same name
+	if c := i.lookupClosure(name); c != nil {
+		placed, err := i.placeNamedArgs(expr, c.fn, args, named)
+		if err != nil {
+			return nil, err
+		}
+		return i.callFunction(expr, c.fn, c.env, placed)
+	}
+
+	// Calling a type's name constructs a record
+	if typ, ok := i.types[name]; ok {
+		return i.newRecord(expr, typ, args, named)
+	}
+
+	if len(expr.Named) > 0 {
+		fn, ok := i.userFuncs[name]
+		if !ok {
+			return nil, i.runtimeError(expr.Named[0], "%s does not take named arguments", expr.Name)
+		}
+		placed, err := i.placeNamedArgs(expr, fn, args, named)
+		if err != nil {
+			return nil, err
+		}
+		return i.callUserFunction(expr, fn, placed)
+	}
 
-	// Check external functions first
-	if fn, ok := i.externalFuncs[name]; ok {
-		return fn(args...)
+	// Check external functions next
+	if fn, ok := i.externalFunc(name); ok {
+		result, err := i.callExternal(fn, args)
+		if err != nil {
+			return nil, i.hostError(expr, err)
+		}
+		return result, nil
 	}
 
 	// Check user-defined functions
 	if fn, ok := i.userFuncs[name]; ok {
-		return i.callUserFunction(fn, args)
+		return i.callUserFunction(expr, fn, args)
+	}
+
+	// Maps, and arrays that weren't declared with DIM (such as ones passed in
+	// by the host), are indexed with the same syntax
+	if value, err := i.getVariable(name); err == nil && len(args) > 0 && isIndexable(value) {
+		return i.indexValue(expr, expr.Name, value, args)
 	}
 
 	return nil, i.runtimeError(expr, "undefined function: %s", expr.Name)
 }
 
-func (i *Interpreter) callUserFunction(fn *FunctionStatement, args []interface{}) (interface{}, error) {
-	if len(args) != len(fn.Params) {
-		return nil, fmt.Errorf("function %s expects %d arguments, got %d", fn.Name, len(fn.Params), len(args))
+// placeNamedArgs adds the values of a call's named arguments to the
+// positional ones, in parameter order. Parameters passed neither way take
+// their default values.
+func (i *Interpreter) placeNamedArgs(expr *CallExpr, fn *FunctionStatement, args, named []interface{}) ([]interface{}, error) {
+	if len(named) == 0 {
+		return args, nil
+	}
+	if len(args) > len(fn.Params) {
+		return nil, i.positionError(expr, checkArgCount(fn.Name, fn, len(args)+len(named)))
 	}
 
-	// Push new scope for function
-	i.pushScope()
-	defer i.popScope()
+	placed := make([]interface{}, len(fn.Params))
+	copy(placed, args)
+	for idx := len(args); idx < len(placed); idx++ {
+		placed[idx] = omittedArg{}
+	}
 
-	// Bind parameters
+	for idx, arg := range expr.Named {
+		param := -1
+		for pidx, name := range fn.Params {
+			if i.sameIdent(name, arg.Name) {
+				param = pidx
+				break
+			}
+		}
+		if param < 0 {
+			return nil, i.runtimeError(arg, "function %s has no parameter %s", fn.Name, arg.Name)
+		}
+		if param < len(args) {
+			return nil, i.runtimeError(arg, "argument %s is already passed by position", arg.Name)
+		}
+		placed[param] = named[idx]
+	}
+
+	for idx, value := range placed {
+		if _, omitted := value.(omittedArg); omitted && (idx >= len(fn.Defaults) || fn.Defaults[idx] == nil) {
+			return nil, i.runtimeError(expr, "function %s: missing argument %s", fn.Name, fn.Params[idx])
+		}
+	}
+	return placed, nil
+}
+
+// Invoke calls an external or script-defined function by name from within a
+// running script, sharing the current execution state. It lets builtins such
+// as count_if accept a function name as a callback.
+func (i *Interpreter) Invoke(funcName string, args ...interface{}) (interface{}, error) {
+	name := i.ident(funcName)
+
+	if c := i.lookupClosure(name); c != nil {
+		return i.callFunction(nil, c.fn, c.env, args)
+	}
+
+	if fn, ok := i.externalFunc(name); ok {
+		return i.callExternal(fn, args)
+	}
+
+	if fn, ok := i.userFuncs[name]; ok {
+		return i.callUserFunction(nil, fn, args)
+	}
+
+	return nil, fmt.Errorf("undefined function: %s", funcName)
+}
+
+// omittedArg marks a parameter CallNamed had no value for, so that it takes
+// its default value
+type omittedArg struct{}
+
+// requiredParams returns how many arguments a call of fn must pass: the
+// parameters before the first one with a default value
+func requiredParams(fn *FunctionStatement) int {
+	for idx := range fn.Params {
+		if idx < len(fn.Defaults) && fn.Defaults[idx] != nil {
+			return idx
+		}
+	}
+	return len(fn.Params)
+}
+
+// checkArgCount fails if a call of fn passes too few or too many arguments
+func checkArgCount(name string, fn *FunctionStatement, count int) error {
+	required := requiredParams(fn)
+	if count >= required && count <= len(fn.Params) {
+		return nil
+	}
+	if required == len(fn.Params) {
+		return fmt.Errorf("function %s expects %d arguments, got %d", name, required, count)
+	}
+	return fmt.Errorf("function %s expects %d to %d arguments, got %d", name, required, len(fn.Params), count)
+}
+
+// bindParams binds the arguments of a call to the function's parameters in
+// the current scope, converting them to the parameters' declared types.
+// Parameters without an argument take their default value, evaluated in
+// order inside the function so it can use earlier parameters.
+func (i *Interpreter) bindParams(fn *FunctionStatement, args []interface{}) error {
+	scope := i.currentScope()
 	for idx, param := range fn.Params {
-		i.currentScope()[strings.ToLower(param)] = args[idx]
+		var value interface{}
+		passed := false
+		if idx < len(args) {
+			_, omitted := args[idx].(omittedArg)
+			value, passed = args[idx], !omitted
+		}
+		if !passed {
+			value = nil
+			if idx < len(fn.Defaults) && fn.Defaults[idx] != nil {
+				var err error
+				value, err = i.evaluateExpression(fn.Defaults[idx])
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		value, err := coerceParam(fn, idx, value)
+		if err != nil {
+			return err
+		}
+		if err := i.checkTotals(fn, param, value, at(scope, i.ident(param))); err != nil {
+			return err
+		}
+		scope[i.ident(param)] = value
+	}
+	return nil
+}
+
+// callExternal calls a host function, normalizing the numbers it returns
+func (i *Interpreter) callExternal(fn ExternalFunc, args []interface{}) (interface{}, error) {
+	result, err := fn(args...)
+	if err != nil {
+		return nil, err
+	}
+	return functions.Normalize(result)
+}
+
+// callUserFunction calls a top-level function, whose body sees the globals
+func (i *Interpreter) callUserFunction(call Node, fn *FunctionStatement, args []interface{}) (interface{}, error) {
+	return i.callFunction(call, fn, []map[string]interface{}{i.globalScope}, args)
+}
+
+// callFunction runs a function body in a new scope on top of env, the scopes
+// visible where the function was defined. Errors in the arguments are
+// reported at call, the call expression, unless it is nil.
+func (i *Interpreter) callFunction(call Node, fn *FunctionStatement, env []map[string]interface{}, args []interface{}) (interface{}, error) {
+	if err := checkArgCount(fn.Name, fn, len(args)); err != nil {
+		return nil, i.callError(call, err)
+	}
+
+	defer i.enterFunction(env)()
+
+	i.callStack = append(i.callStack, fn.Name)
+	defer func() { i.callStack = i.callStack[:len(i.callStack)-1] }()
+
+	i.pushFrame()
+	defer i.popFrame()
+
+	// Bind parameters
+	if err := i.bindParams(fn, args); err != nil {
+		return nil, i.callError(call, err)
 	}
+	i.defineNested(fn.Body)
 
 	// Save and restore return state
 	oldReturnFlag := i.returnFlag
@@ -622,6 +1968,12 @@ func (i *Interpreter) callUserFunction(fn *FunctionStatement, args []interface{}
 // Value Operations
 // -----------------------------------------------------------------------------
 
+// Add adds two values as the + operator does, handling integer overflow as
+// set by SetOverflowMode. Builtins that total values, such as sum, use it.
+func (i *Interpreter) Add(left, right interface{}) (interface{}, error) {
+	return i.addValues(left, right)
+}
+
 func (i *Interpreter) addValues(left, right interface{}) (interface{}, error) {
 	// String concatenation
 	if ls, ok := left.(string); ok {
@@ -641,7 +1993,11 @@ func (i *Interpreter) addValues(left, right interface{}) (interface{}, error) {
 	// If both are ints, return int
 	if li, ok := left.(int); ok {
 		if ri, ok := right.(int); ok {
-			return li + ri, nil
+			sum := li + ri
+			if i.overflowMode != OverflowWrap && (li^sum)&(ri^sum) < 0 {
+				return i.overflowed("+", li, ri, lf+rf)
+			}
+			return sum, nil
 		}
 	}
 
@@ -657,7 +2013,11 @@ func (i *Interpreter) subtractValues(left, right interface{}) (interface{}, erro
 
 	if li, ok := left.(int); ok {
 		if ri, ok := right.(int); ok {
-			return li - ri, nil
+			diff := li - ri
+			if i.overflowMode != OverflowWrap && (li^ri)&(li^diff) < 0 {
+				return i.overflowed("-", li, ri, lf-rf)
+			}
+			return diff, nil
 		}
 	}
 
@@ -673,7 +2033,12 @@ func (i *Interpreter) multiplyValues(left, right interface{}) (interface{}, erro
 
 	if li, ok := left.(int); ok {
 		if ri, ok := right.(int); ok {
-			return li * ri, nil
+			product := li * ri
+			if i.overflowMode != OverflowWrap && li != 0 &&
+				(product/li != ri || (li == -1 && ri == math.MinInt)) {
+				return i.overflowed("*", li, ri, lf*rf)
+			}
+			return product, nil
 		}
 	}
 
@@ -693,6 +2058,9 @@ func (i *Interpreter) divideValues(left, right interface{}) (interface{}, error)
 
 	if li, ok := left.(int); ok {
 		if ri, ok := right.(int); ok {
+			if i.overflowMode != OverflowWrap && li == math.MinInt && ri == -1 {
+				return i.overflowed("/", li, ri, lf/rf)
+			}
 			return li / ri, nil
 		}
 	}
@@ -700,9 +2068,28 @@ func (i *Interpreter) divideValues(left, right interface{}) (interface{}, error)
 	return lf / rf, nil
 }
 
+// overflowed resolves an overflowing int operation according to the
+// overflow mode, given the result computed in float64
+func (i *Interpreter) overflowed(op string, left, right int, promoted float64) (interface{}, error) {
+	if i.overflowMode == OverflowPromote {
+		return promoted, nil
+	}
+	return nil, fmt.Errorf("%w: %d %s %d", errIntegerOverflow, left, op, right)
+}
+
+// positionOverflow attaches the expression's position to overflow errors
+func (i *Interpreter) positionOverflow(expr *BinaryExpr, err error) error {
+	if errors.Is(err, errIntegerOverflow) {
+		return i.runtimeError(expr, "%v", err)
+	}
+	return err
+}
+
 func (i *Interpreter) equalValues(left, right interface{}) bool {
 	// Type-aware comparison
 	switch lv := left.(type) {
+	case nil:
+		return right == nil
 	case int:
 		if rv, ok := right.(int); ok {
 			return lv == rv
@@ -760,20 +2147,7 @@ func (i *Interpreter) compareValues(left, right interface{}) int {
 // -----------------------------------------------------------------------------
 
 func (i *Interpreter) isTruthy(val interface{}) bool {
-	switch v := val.(type) {
-	case nil:
-		return false
-	case bool:
-		return v
-	case int:
-		return v != 0
-	case float64:
-		return v != 0
-	case string:
-		return v != ""
-	default:
-		return true
-	}
+	return functions.IsTruthy(val)
 }
 
 func (i *Interpreter) toFloat64(val interface{}) (float64, bool) {
@@ -798,24 +2172,29 @@ func (i *Interpreter) toInt(val interface{}) (int, bool) {
 	}
 }
 
+// ToString converts a value to text the way string concatenation does,
+// applying the interpreter's number format
+func (i *Interpreter) ToString(val interface{}) string {
+	return i.toString(val)
+}
+
 func (i *Interpreter) toString(val interface{}) string {
-	switch v := val.(type) {
-	case string:
-		return v
-	case int:
-		return fmt.Sprintf("%d", v)
-	case float64:
-		return fmt.Sprintf("%g", v)
-	case bool:
-		if v {
-			return "true"
-		}
-		return "false"
-	case nil:
-		return ""
-	default:
-		return fmt.Sprintf("%v", v)
+	if f, ok := val.(float64); ok {
+		return i.formatFloat(f)
+	}
+	return functions.ToString(val)
+}
+
+// formatFloat applies the interpreter's number format to a float
+func (i *Interpreter) formatFloat(f float64) string {
+	precision := i.numberFormat.Precision
+	if precision <= 0 {
+		precision = -1
 	}
+	if i.numberFormat.Fixed {
+		return strconv.FormatFloat(f, 'f', precision, 64)
+	}
+	return strconv.FormatFloat(f, 'g', precision, 64)
 }
 
 // -----------------------------------------------------------------------------
@@ -837,25 +2216,59 @@ func (i *Interpreter) popScope() {
 }
 
 func (i *Interpreter) getVariable(name string) (interface{}, error) {
-	// Search from innermost scope outward
-	for j := len(i.scopes) - 1; j >= 0; j-- {
-		if val, ok := i.scopes[j][name]; ok {
+	if i.isGlobalName(name) {
+		if val, ok := i.scopes[0][name]; ok {
 			return val, nil
 		}
+	} else {
+		// Search from innermost scope outward
+		for j := len(i.scopes) - 1; j >= 0; j-- {
+			if val, ok := i.scopes[j][name]; ok {
+				return val, nil
+			}
+		}
+	}
+	if val, ok := i.hostConstant(name); ok {
+		return val, nil
 	}
 	return nil, fmt.Errorf("undefined variable: %s", name)
 }
 
+// checkAssignable fails if name is a constant. Constants can't be assigned
+// or shadowed by a local variable. The analyzer rejects this in the program
+// that declares the constant; this catches later runs that reuse the globals
+// and constants registered by the host.
+func (i *Interpreter) checkAssignable(node Node, name string) error {
+	key := i.ident(name)
+	if _, host := i.hostConstant(key); host || i.constants[key] {
+		return i.runtimeError(node, "cannot assign to constant %s", name)
+	}
+	return nil
+}
+
 func (i *Interpreter) setVariable(name string, value interface{}) {
-	// Find existing variable in any scope, or create in current scope
-	for j := len(i.scopes) - 1; j >= 0; j-- {
+	i.variableScope(name)[name] = value
+}
+
+// variableScope returns the scope an assignment to name writes to
+func (i *Interpreter) variableScope(name string) map[string]interface{} {
+	if i.isGlobalName(name) {
+		return i.scopes[0]
+	}
+
+	// Find existing variable in any scope, or create in current scope. In
+	// ScopeLocal mode a function only looks through its own scopes.
+	outermost := 0
+	if frame := i.currentFrame(); frame != nil && i.scopeMode == ScopeLocal {
+		outermost = frame.base
+	}
+	for j := len(i.scopes) - 1; j >= outermost; j-- {
 		if _, ok := i.scopes[j][name]; ok {
-			i.scopes[j][name] = value
-			return
+			return i.scopes[j]
 		}
 	}
 	// Create in current scope if not found
-	i.currentScope()[name] = value
+	return i.currentScope()
 }
 
 // -----------------------------------------------------------------------------
@@ -864,6 +2277,36 @@ func (i *Interpreter) setVariable(name string, value interface{}) {
 
 func (i *Interpreter) runtimeError(node Node, format string, args ...interface{}) error {
 	line, col := node.Position()
-	msg := fmt.Sprintf(format, args...)
-	return fmt.Errorf("runtime error at line %d, column %d: %s", line, col, msg)
+	err := fmt.Errorf(format, args...)
+	return &RuntimeError{Kind: runtimeErrorKind(err), File: node.SourceFile(), Line: line, Column: col, Err: err}
+}
+
+// callError tags an error in the arguments of a call with the position of
+// the call expression, if the call comes from the script
+func (i *Interpreter) callError(call Node, err error) error {
+	if call == nil {
+		return err
+	}
+	return i.positionError(call, err)
+}
+
+// positionError tags an error from an external function with the position of
+// the call, unless it already carries a position from deeper in the script
+func (i *Interpreter) positionError(node Node, err error) error {
+	var positioned *RuntimeError
+	if errors.As(err, &positioned) {
+		return err
+	}
+	line, col := node.Position()
+	return &RuntimeError{Kind: runtimeErrorKind(err), File: node.SourceFile(), Line: line, Column: col, Err: err}
+}
+
+// hostError tags an error returned by an external function with the position
+// of the call
+func (i *Interpreter) hostError(call Node, err error) error {
+	err = i.positionError(call, err)
+	if positioned, ok := err.(*RuntimeError); ok && positioned.Kind == ErrorRuntime {
+		positioned.Kind = ErrorHost
+	}
+	return err
 }
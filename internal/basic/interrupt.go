@@ -0,0 +1,83 @@
+package basic
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInterrupted is returned (wrapped with the position reached) by a Run,
+// Call or Evaluate that was cancelled with Stop
+var ErrInterrupted = errors.New("execution interrupted")
+
+// Stop asks the execution in progress to end. It is safe to call from any
+// goroutine; the running script notices before its next statement or loop
+// iteration and returns an error matching ErrInterrupted. A host function
+// that is blocked is not interrupted, but the script stops once it returns.
+// Calling Stop while nothing is running has no effect on later runs.
+func (i *Interpreter) Stop() {
+	i.interrupted.Store(true)
+}
+
+// resetInterrupt clears a stale Stop request when a new execution starts
+func (i *Interpreter) resetInterrupt() {
+	i.interrupted.Store(false)
+}
+
+// checkInterrupt fails with ErrInterrupted if Stop has been called, or with
+// the context's error once the context of the run is done
+func (i *Interpreter) checkInterrupt(node Node) error {
+	if i.ctxDone.Load() {
+		return i.runtimeError(node, "%w", i.ctx.Err())
+	}
+	if !i.interrupted.Load() {
+		return nil
+	}
+	return i.runtimeError(node, "%w", ErrInterrupted)
+}
+
+// InterpretContext is Interpret with cancellation: once ctx is done the
+// script stops before its next statement or loop iteration, returning an
+// error that wraps ctx.Err(). As with Stop, a blocked host function isn't
+// interrupted.
+func (i *Interpreter) InterpretContext(ctx context.Context, code string) error {
+	return i.InterpretWithVarsContext(ctx, code, nil)
+}
+
+// InterpretWithVarsContext is InterpretWithVars with cancellation, as for
+// InterpretContext
+func (i *Interpreter) InterpretWithVarsContext(ctx context.Context, code string, vars map[string]interface{}) error {
+	return i.runContext(ctx, func() error {
+		return i.InterpretWithVars(code, vars)
+	})
+}
+
+// CallContext is Call with cancellation, as for InterpretContext
+func (i *Interpreter) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	var result interface{}
+	err := i.runContext(ctx, func() error {
+		var err error
+		result, err = i.Call(funcName, args...)
+		return err
+	})
+	return result, err
+}
+
+// runContext runs an execution that stops once ctx is done. A context that
+// is already done stops it before it starts.
+func (i *Interpreter) runContext(ctx context.Context, run func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	outerCtx, outerDone := i.ctx, i.ctxDone.Load()
+	i.ctx = ctx
+	i.ctxDone.Store(false)
+	stop := context.AfterFunc(ctx, func() { i.ctxDone.Store(true) })
+	defer func() {
+		stop()
+		i.ctx = outerCtx
+		i.ctxDone.Store(outerDone)
+	}()
+
+	return run()
+}
@@ -0,0 +1,264 @@
+package basic
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrMemoryLimit is returned, wrapped with the position of the assignment,
+// when a script would store more than its MemoryLimits allow
+var ErrMemoryLimit = errors.New("memory limit exceeded")
+
+// MemoryLimits caps the state a script may build up. The limits are checked
+// when the script assigns a value, so values passed in by the host aren't
+// rejected until the script stores them. A zero field means no limit.
+// MaxStringSize and MaxCollectionSize cap each value on its own; the totals
+// count everything held in global variables, the locals of the functions
+// being run and the arrays, maps and records nested in them, with a
+// collection stored in several places counted once.
+type MemoryLimits struct {
+	MaxVariables       int // Variables visible at once, across the enclosing scopes
+	MaxStringSize      int // Bytes in any one string stored in a variable, element or field
+	MaxCollectionSize  int // Elements in any one array or map stored by the script
+	MaxTotalStringSize int // Bytes in all the strings held together, map keys included
+	MaxTotalElements   int // Elements of all the arrays, maps and records held together
+}
+
+// SetMemoryLimits sets the limits on the state scripts may hold
+func (i *Interpreter) SetMemoryLimits(limits MemoryLimits) {
+	i.limits = limits
+	i.usage = memoryUsage{}
+}
+
+// memoryUsage is the size of the state a script holds, as counted by the
+// total memory limits
+type memoryUsage struct {
+	bytes    int  // Bytes of strings
+	elements int  // Elements of arrays, maps and records
+	measured bool // Set once the state has been measured this run
+}
+
+// location is where a value is about to be stored: the key of a scope or map,
+// or the index of an array or record. The zero location is a new variable
+// outside the scopes measured so far.
+type location struct {
+	in  uintptr     // Identity of the scope, array, map or record
+	key interface{} // A string for scopes and maps, an int for arrays and records
+}
+
+// at returns the location of key in the scope, array, map or record in
+func at(in interface{}, key interface{}) location {
+	return location{in: identity(in), key: key}
+}
+
+// identity returns what tells collections apart: the address of their
+// contents, which assigning the collection elsewhere doesn't change
+func identity(v interface{}) uintptr {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}, *Record:
+		return reflect.ValueOf(v).Pointer()
+	}
+	return 0
+}
+
+// checkStore enforces the memory limits on a value about to be stored under
+// name at the given location. created is set when the store adds a new
+// variable.
+func (i *Interpreter) checkStore(node Node, name string, value interface{}, to location, created bool) error {
+	if i.limits == (MemoryLimits{}) {
+		return nil
+	}
+
+	if max := i.limits.MaxVariables; created && max > 0 && i.variableCount() >= max {
+		return i.runtimeError(node, "%w: cannot create %s, the limit is %d variables", ErrMemoryLimit, name, max)
+	}
+
+	var err error
+	switch v := value.(type) {
+	case string:
+		if max := i.limits.MaxStringSize; max > 0 && len(v) > max {
+			err = i.runtimeError(node, "%w: %s would hold a string of %d bytes, the limit is %d", ErrMemoryLimit, name, len(v), max)
+		}
+	case []interface{}:
+		err = i.checkCollectionSize(node, name, len(v))
+	case map[string]interface{}:
+		err = i.checkCollectionSize(node, name, len(v))
+	}
+	if err != nil {
+		return err
+	}
+	return i.checkTotals(node, name, value, to)
+}
+
+// checkLocalStore is checkStore for a value about to be stored under name in
+// the current scope
+func (i *Interpreter) checkLocalStore(node Node, name string, value interface{}) error {
+	if i.limits == (MemoryLimits{}) {
+		return nil
+	}
+	scope := i.currentScope()
+	_, exists := scope[i.ident(name)]
+	return i.checkStore(node, name, value, at(scope, i.ident(name)), !exists)
+}
+
+// checkCollectionSize fails if an array or map stored under name would hold
+// more elements than the limit
+func (i *Interpreter) checkCollectionSize(node Node, name string, size int) error {
+	if max := i.limits.MaxCollectionSize; max > 0 && size > max {
+		return i.runtimeError(node, "%w: %s would hold %d elements, the limit is %d", ErrMemoryLimit, name, size, max)
+	}
+	return nil
+}
+
+// checkTotals enforces the total memory limits on a value about to be stored
+// at the given location. Measuring means walking the whole state, so between
+// measurements usage is kept as a bound: the size of each value stored is
+// added to it, with its key, as though nothing were overwritten or shared.
+// Only when the bound passes a limit is the state measured again, with value
+// in place.
+func (i *Interpreter) checkTotals(node Node, name string, value interface{}, to location) error {
+	if i.limits.MaxTotalStringSize <= 0 && i.limits.MaxTotalElements <= 0 {
+		return nil
+	}
+
+	if i.usage.measured {
+		added := measureValue(value)
+		if key, ok := to.key.(string); ok {
+			added.bytes += len(key)
+		}
+		bound := memoryUsage{bytes: i.usage.bytes + added.bytes, elements: i.usage.elements + added.elements, measured: true}
+		if i.withinTotals(bound) {
+			i.usage = bound
+			return nil
+		}
+	}
+
+	usage := i.measureState(value, to)
+	if max := i.limits.MaxTotalStringSize; max > 0 && usage.bytes > max {
+		return i.runtimeError(node, "%w: storing %s would bring the strings held to %d bytes, the limit is %d", ErrMemoryLimit, name, usage.bytes, max)
+	}
+	if max := i.limits.MaxTotalElements; max > 0 && usage.elements > max {
+		return i.runtimeError(node, "%w: storing %s would bring the elements held to %d, the limit is %d", ErrMemoryLimit, name, usage.elements, max)
+	}
+	i.usage = usage
+	return nil
+}
+
+// withinTotals reports whether usage is within the total memory limits
+func (i *Interpreter) withinTotals(usage memoryUsage) bool {
+	if max := i.limits.MaxTotalStringSize; max > 0 && usage.bytes > max {
+		return false
+	}
+	if max := i.limits.MaxTotalElements; max > 0 && usage.elements > max {
+		return false
+	}
+	return true
+}
+
+// measureState measures the state the script would hold with value stored at
+// the given location: the global scope, the scopes being run and those of
+// the callers they replaced
+func (i *Interpreter) measureState(value interface{}, to location) memoryUsage {
+	m := &measurer{seen: make(map[uintptr]bool), to: to, replacement: value}
+	m.scope(i.globalScope)
+	for _, scopes := range i.callerScopes {
+		for _, scope := range scopes {
+			m.scope(scope)
+		}
+	}
+	for _, scope := range i.scopes {
+		m.scope(scope)
+	}
+	if !m.placed {
+		m.value(value)
+	}
+	m.usage.measured = true
+	return m.usage
+}
+
+// measureValue measures value and the collections nested in it
+func measureValue(value interface{}) memoryUsage {
+	m := &measurer{seen: make(map[uintptr]bool)}
+	m.value(value)
+	return m.usage
+}
+
+// measurer adds up the strings and elements of a state, counting each
+// collection once and the value about to be stored in place of what its
+// location holds now
+type measurer struct {
+	usage       memoryUsage
+	seen        map[uintptr]bool
+	to          location
+	replacement interface{}
+	placed      bool // Set once the location has been found
+}
+
+// scope measures the variables of a scope. The scopes of closures are shared
+// with the scopes they were defined in, so each is measured once.
+func (m *measurer) scope(scope map[string]interface{}) {
+	id := identity(scope)
+	if m.seen[id] {
+		return
+	}
+	m.seen[id] = true
+	for name, value := range scope {
+		m.value(m.at(id, name, value))
+	}
+}
+
+// value measures a value and, the first time a collection is seen, its
+// elements
+func (m *measurer) value(value interface{}) {
+	id := identity(value)
+	if id != 0 {
+		if m.seen[id] {
+			return
+		}
+		m.seen[id] = true
+	}
+
+	switch v := value.(type) {
+	case string:
+		m.usage.bytes += len(v)
+	case []interface{}:
+		m.usage.elements += len(v)
+		for idx, elem := range v {
+			m.value(m.at(id, idx, elem))
+		}
+	case map[string]interface{}:
+		m.usage.elements += len(v)
+		for key, elem := range v {
+			m.usage.bytes += len(key)
+			m.value(m.at(id, key, elem))
+		}
+		if key, ok := m.to.key.(string); ok && id == m.to.in && !m.placed {
+			// The value is stored under a new key
+			m.usage.bytes += len(key)
+		}
+	case *Record:
+		m.usage.elements += len(v.values)
+		for idx, elem := range v.values {
+			m.value(m.at(id, idx, elem))
+		}
+	}
+}
+
+// at returns what key of the collection or scope in holds, or the value
+// being stored if that is its location
+func (m *measurer) at(in uintptr, key interface{}, value interface{}) interface{} {
+	if in == m.to.in && key == m.to.key {
+		m.placed = true
+		return m.replacement
+	}
+	return value
+}
+
+// variableCount returns the number of variables in the enclosing scopes
+func (i *Interpreter) variableCount() int {
+	count := 0
+	for _, scope := range i.scopes {
+		count += len(scope)
+	}
+	return count
+}
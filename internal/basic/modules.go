@@ -0,0 +1,119 @@
+package basic
+
+import "strings"
+
+// ModuleResolver returns the source code of the module a script imports
+// with IMPORT "name"
+type ModuleResolver func(name string) (string, error)
+
+// module is an imported script. Its top-level scope holds its variables and
+// its functions, which are closures over that scope.
+type module struct {
+	scope   map[string]interface{}
+	exports []string // Lowercased names of its public functions, in source order
+	loading bool     // Set while its top-level code runs, to detect circular imports
+}
+
+// SetModuleResolver sets how IMPORT finds the source of a module. Without a
+// resolver every IMPORT fails.
+func (i *Interpreter) SetModuleResolver(resolver ModuleResolver) {
+	i.moduleResolver = resolver
+}
+
+// executeImportStatement loads a module and binds its public functions in
+// the importing scope, where they can be called like the script's own
+func (i *Interpreter) executeImportStatement(stmt *ImportStatement) error {
+	mod, err := i.loadModule(stmt)
+	if err != nil {
+		return err
+	}
+
+	target := i.currentScope()
+	for _, name := range mod.exports {
+		fn := mod.scope[name].(*closure)
+		if prev, ok := target[name].(*closure); ok && prev.module == stmt.Module {
+			// Importing the module again, as when a script is rerun
+			target[name] = fn
+			continue
+		}
+		_, defined := target[name]
+		if _, ok := i.externalFuncs[name]; ok {
+			defined = true
+		}
+		if _, ok := i.userFuncs[name]; ok && !i.inModule() {
+			defined = true
+		}
+		if defined {
+			return i.runtimeError(stmt, "import %q: %s is already defined", stmt.Module, fn.fn.Name)
+		}
+		target[name] = fn
+	}
+	return nil
+}
+
+// loadModule runs a module's top-level code in a scope of its own. Each
+// module runs once per run of the importing script, however often imported.
+func (i *Interpreter) loadModule(stmt *ImportStatement) (*module, error) {
+	if mod, ok := i.modules[stmt.Module]; ok {
+		if mod.loading {
+			return nil, i.runtimeError(stmt, "import %q: circular import", stmt.Module)
+		}
+		return mod, nil
+	}
+
+	if i.moduleResolver == nil {
+		return nil, i.runtimeError(stmt, "import %q: no module resolver is set", stmt.Module)
+	}
+	code, err := i.moduleResolver(stmt.Module)
+	if err != nil {
+		return nil, i.runtimeError(stmt, "import %q: %v", stmt.Module, err)
+	}
+
+	// The importing script's warnings are the ones reported
+	warnings := i.warnings
+	prog, err := i.getOrParseProgram(code)
+	i.warnings = warnings
+	if err != nil {
+		return nil, i.runtimeError(stmt, "import %q: %v", stmt.Module, err)
+	}
+
+	mod := &module{scope: make(map[string]interface{}), loading: true}
+	if i.modules == nil {
+		i.modules = make(map[string]*module)
+	}
+	i.modules[stmt.Module] = mod
+	defer func() { mod.loading = false }()
+
+	scopes, frames, explicit := i.scopes, i.frames, i.explicit
+	i.scopes, i.frames = []map[string]interface{}{mod.scope}, nil
+	i.explicit = i.requiresDeclarations(prog.Statements)
+	defer func() { i.scopes, i.frames, i.explicit = scopes, frames, explicit }()
+
+	i.defineTypes(prog.Statements)
+	i.defineNested(prog.Statements)
+	for _, def := range prog.Statements {
+		fn, ok := def.(*FunctionStatement)
+		if !ok || strings.HasPrefix(fn.Name, "_") {
+			continue
+		}
+		name := i.ident(fn.Name)
+		mod.scope[name].(*closure).module = stmt.Module
+		mod.exports = append(mod.exports, name)
+	}
+
+	if err := i.executeBlock(prog.Statements); err != nil {
+		return nil, err
+	}
+	return mod, nil
+}
+
+// inModule reports whether a module's top-level code is running, rather than
+// the importing script's
+func (i *Interpreter) inModule() bool {
+	for _, mod := range i.modules {
+		if mod.loading {
+			return true
+		}
+	}
+	return false
+}
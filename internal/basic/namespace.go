@@ -0,0 +1,55 @@
+package basic
+
+import "fmt"
+
+// RegisterNamespace registers external functions that scripts call by their
+// name qualified with the namespace, so the function spawn in namespace game
+// is called as game.spawn(x). Libraries that use the same function names
+// don't collide when each has its own namespace. With strict registration
+// on, none are registered if any qualified name is taken.
+func (i *Interpreter) RegisterNamespace(namespace string, funcs map[string]ExternalFunc) error {
+	qualified, err := qualify(namespace, funcs)
+	if err != nil {
+		return err
+	}
+	return i.RegisterFunctions(qualified)
+}
+
+// RegisterLibrary registers a bundle of external functions both under their
+// own names, as the built-in libraries are, and in the namespace name, so
+// each stays reachable as name.function if another library takes its plain
+// name. With strict registration on, nothing is registered if any name is
+// taken.
+func (i *Interpreter) RegisterLibrary(name string, funcs map[string]ExternalFunc) error {
+	qualified, err := qualify(name, funcs)
+	if err != nil {
+		return err
+	}
+	for fn, function := range funcs {
+		qualified[fn] = function
+	}
+	return i.RegisterFunctions(qualified)
+}
+
+// qualify returns the functions keyed by their names in the namespace
+func qualify(namespace string, funcs map[string]ExternalFunc) (map[string]ExternalFunc, error) {
+	if !isIdentifier(namespace) {
+		return nil, fmt.Errorf("invalid namespace %q", namespace)
+	}
+
+	qualified := make(map[string]ExternalFunc, len(funcs))
+	for name, function := range funcs {
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("namespace %s: invalid function name %q", namespace, name)
+		}
+		qualified[namespace+"."+name] = function
+	}
+	return qualified, nil
+}
+
+// isIdentifier reports whether name is a single identifier in a script, not
+// a keyword or anything else
+func isIdentifier(name string) bool {
+	tokens, err := Tokenize(name)
+	return err == nil && len(tokens) == 2 && tokens[0].Type == TOKEN_IDENTIFIER && tokens[0].Value == name
+}
@@ -1,8 +1,11 @@
 package basic
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 // Parser converts tokens into an AST
@@ -10,17 +13,46 @@ type Parser struct {
 	tokens  []Token
 	pos     int
 	current Token
+	file    string // Included file being parsed, "" for the script itself
+
+	// allowExpressions permits bare expressions as statements (eval mode)
+	allowExpressions bool
+
+	// inSub is set while parsing a SUB body, where RETURN takes no value
+	inSub bool
+
+	// Expression nesting guard; maxDepth <= 0 disables it
+	depth    int
+	maxDepth int
+
+	// Lowercased names declared with DIM anywhere in the source, so that
+	// name(i) parses as an element access rather than a call
+	arrays map[string]bool
+
+	// recovering makes a statement that fails to parse be recorded in errs
+	// and skipped, so one pass reports every syntax error
+	recovering bool
+	errs       []error
 }
 
 // NewParser creates a new parser for the given tokens
 func NewParser(tokens []Token) *Parser {
 	p := &Parser{
-		tokens: tokens,
-		pos:    0,
+		tokens:   tokens,
+		pos:      0,
+		maxDepth: MaxExpressionDepth,
+		arrays:   make(map[string]bool),
 	}
 	if len(tokens) > 0 {
 		p.current = tokens[0]
 	}
+
+	// Arrays may be used above their DIM, e.g. in a function defined first
+	for idx := 0; idx+1 < len(tokens); idx++ {
+		if tokens[idx].Type == TOKEN_DIM && tokens[idx+1].Type == TOKEN_IDENTIFIER {
+			p.arrays[strings.ToLower(tokens[idx+1].Value)] = true
+		}
+	}
 	return p
 }
 
@@ -30,6 +62,14 @@ func Parse(tokens []Token) (*Program, error) {
 	return p.ParseProgram()
 }
 
+// ParseEval parses the tokens in eval mode, where a bare expression such as
+// `2 + damage * 3` is accepted as a statement
+func ParseEval(tokens []Token) (*Program, error) {
+	p := NewParser(tokens)
+	p.allowExpressions = true
+	return p.ParseProgram()
+}
+
 // ParseProgram parses the entire program
 func (p *Parser) ParseProgram() (*Program, error) {
 	program := &Program{
@@ -45,7 +85,10 @@ func (p *Parser) ParseProgram() (*Program, error) {
 
 		stmt, err := p.parseStatement()
 		if err != nil {
-			return nil, err
+			if !p.recover(err) {
+				return nil, err
+			}
+			continue
 		}
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
@@ -60,29 +103,138 @@ func (p *Parser) parseStatement() (Statement, error) {
 	switch p.current.Type {
 	case TOKEN_LET:
 		return p.parseLetStatement()
+	case TOKEN_DIM:
+		return p.parseDimStatement()
+	case TOKEN_CONST:
+		return p.parseConstStatement()
+	case TOKEN_GLOBAL:
+		return p.parseGlobalStatement()
+	case TOKEN_LOCAL:
+		return p.parseLocalStatement()
 	case TOKEN_IF:
 		return p.parseIfStatement()
 	case TOKEN_FOR:
 		return p.parseForStatement()
+	case TOKEN_DO:
+		return p.parseDoLoopStatement()
 	case TOKEN_BREAK:
 		return p.parseBreakStatement()
-	case TOKEN_FUNCTION:
+	case TOKEN_EXIT:
+		return p.parseExitStatement()
+	case TOKEN_GOTO:
+		return p.parseGotoStatement()
+	case TOKEN_TRY:
+		return p.parseTryStatement()
+	case TOKEN_ON:
+		return p.parseOnErrorStatement()
+	case TOKEN_THROW:
+		return p.parseThrowStatement()
+	case TOKEN_IMPORT:
+		return p.parseImportStatement()
+	case TOKEN_INCLUDE:
+		return p.parseIncludeStatement()
+	case TOKEN_OPTION:
+		return p.parseOptionStatement()
+	case TOKEN_FUNCTION, TOKEN_SUB:
 		return p.parseFunctionStatement()
+	case TOKEN_TYPE:
+		return p.parseTypeStatement()
 	case TOKEN_RETURN:
 		return p.parseReturnStatement()
 	case TOKEN_PRINT:
 		return p.parsePrintStatement()
+	case TOKEN_YIELD:
+		return p.parseExpressionStatement()
 	case TOKEN_IDENTIFIER:
+		if p.peekNext().Type == TOKEN_COLON {
+			return p.parseLabelStatement()
+		}
+		if p.allowExpressions && p.atStatementStart() && !p.isAssignmentAhead() {
+			return p.parseExpressionStatement()
+		}
 		return p.parseIdentifierStatement()
 	default:
+		if p.allowExpressions && p.atStatementStart() {
+			return p.parseExpressionStatement()
+		}
 		return nil, p.error("unexpected token %s", p.current.Type)
 	}
 }
 
+// parseExpressionStatement parses a bare expression used as a statement
+func (p *Parser) parseExpressionStatement() (*ExpressionStatement, error) {
+	pos := p.position()
+
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	p.consumeNewlineOrEOF()
+	return &ExpressionStatement{Pos: pos, Expr: expr}, nil
+}
+
+// atStatementStart reports whether the current token starts its own
+// statement: it is the first token, or follows a newline, a colon, or the
+// THEN or ELSE of a single-line IF. Bare expressions must, so that in eval
+// mode "print 1 2" is an error rather than two statements.
+func (p *Parser) atStatementStart() bool {
+	if p.pos == 0 {
+		return true
+	}
+	switch p.tokens[p.pos-1].Type {
+	case TOKEN_NEWLINE, TOKEN_COLON, TOKEN_THEN, TOKEN_ELSE:
+		return true
+	}
+	return false
+}
+
+// isAssignmentAhead reports whether the identifier at the current position is
+// followed by an assignment operator, either directly or after an element
+// index and record fields: a(i) = expr, a(i).x = expr
+func (p *Parser) isAssignmentAhead() bool {
+	next := p.pos + 1
+	if next < len(p.tokens) && p.tokens[next].Type == TOKEN_LPAREN {
+		// Skip to the matching ')'
+		depth := 0
+		for ; next < len(p.tokens); next++ {
+			switch p.tokens[next].Type {
+			case TOKEN_LPAREN:
+				depth++
+			case TOKEN_RPAREN:
+				depth--
+			case TOKEN_NEWLINE, TOKEN_EOF:
+				return false
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		next++
+	}
+	// Skip the fields of a record: v.x = expr
+	for next+1 < len(p.tokens) && p.tokens[next].Type == TOKEN_DOT && p.tokens[next+1].Type == TOKEN_IDENTIFIER {
+		next += 2
+	}
+	if next >= len(p.tokens) {
+		return false
+	}
+
+	switch p.tokens[next].Type {
+	case TOKEN_EQ, TOKEN_PLUS_EQ, TOKEN_MINUS_EQ, TOKEN_PLUS_PLUS, TOKEN_MINUS_MINUS:
+		return true
+	case TOKEN_COMMA:
+		// The first target of a multiple assignment: a, b = 1, 2
+		return true
+	default:
+		return false
+	}
+}
+
 // parseLetStatement parses: LET name = expr
-func (p *Parser) parseLetStatement() (*LetStatement, error) {
+func (p *Parser) parseLetStatement() (Statement, error) {
 	stmt := &LetStatement{
-		Pos: Pos{Line: p.current.Line, Column: p.current.Column},
+		Pos: p.position(),
 	}
 
 	p.advance() // consume LET
@@ -93,6 +245,94 @@ func (p *Parser) parseLetStatement() (*LetStatement, error) {
 	stmt.Name = p.current.Value
 	p.advance()
 
+	if p.current.Type == TOKEN_COMMA {
+		first := &AssignStatement{Pos: stmt.Pos, Name: stmt.Name, Operator: TOKEN_EQ}
+		return p.parseMultiAssign(stmt.Pos, true, first)
+	}
+
+	if p.current.Type != TOKEN_EQ {
+		return nil, p.error("expected '=' after variable name")
+	}
+	p.advance()
+
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Value = expr
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseConstStatement parses: CONST name = expr
+func (p *Parser) parseConstStatement() (*ConstStatement, error) {
+	stmt := &ConstStatement{
+		Pos: p.position(),
+	}
+
+	p.advance() // consume CONST
+
+	if p.current.Type != TOKEN_IDENTIFIER {
+		return nil, p.error("expected identifier after CONST")
+	}
+	stmt.Name = p.current.Value
+	p.advance()
+
+	if p.current.Type != TOKEN_EQ {
+		return nil, p.error("expected '=' after constant name")
+	}
+	p.advance()
+
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Value = expr
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseGlobalStatement parses: GLOBAL name[, name...]
+func (p *Parser) parseGlobalStatement() (*GlobalStatement, error) {
+	stmt := &GlobalStatement{
+		Pos: p.position(),
+	}
+
+	p.advance() // consume GLOBAL
+
+	for {
+		if p.current.Type != TOKEN_IDENTIFIER {
+			return nil, p.error("expected variable name after GLOBAL")
+		}
+		stmt.Names = append(stmt.Names, p.current.Value)
+		p.advance()
+
+		if p.current.Type != TOKEN_COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseLocalStatement parses: LOCAL name = expr
+func (p *Parser) parseLocalStatement() (*LocalStatement, error) {
+	stmt := &LocalStatement{
+		Pos: p.position(),
+	}
+
+	p.advance() // consume LOCAL
+
+	if p.current.Type != TOKEN_IDENTIFIER {
+		return nil, p.error("expected identifier after LOCAL")
+	}
+	stmt.Name = p.current.Value
+	p.advance()
+
 	if p.current.Type != TOKEN_EQ {
 		return nil, p.error("expected '=' after variable name")
 	}
@@ -108,12 +348,58 @@ func (p *Parser) parseLetStatement() (*LetStatement, error) {
 	return stmt, nil
 }
 
+// parseDimStatement parses: DIM name(size[, size...])
+func (p *Parser) parseDimStatement() (*DimStatement, error) {
+	stmt := &DimStatement{
+		Pos: p.position(),
+	}
+
+	p.advance() // consume DIM
+
+	if p.current.Type != TOKEN_IDENTIFIER {
+		return nil, p.error("expected array name after DIM")
+	}
+	stmt.Name = p.current.Value
+	p.advance()
+
+	if p.current.Type != TOKEN_LPAREN {
+		return nil, p.error("expected '(' after array name")
+	}
+	p.advance()
+
+	sizes, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+	if len(sizes) == 0 {
+		return nil, p.error("DIM %s needs at least one size", stmt.Name)
+	}
+	stmt.Sizes = sizes
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
 // parseIdentifierStatement parses assignment or expression statement starting with identifier
 func (p *Parser) parseIdentifierStatement() (Statement, error) {
-	pos := Pos{Line: p.current.Line, Column: p.current.Column}
+	pos := p.position()
 	name := p.current.Value
 	p.advance()
 
+	if p.atQualifier() {
+		p.advance() // consume .
+		name += "." + p.current.Value
+		p.advance()
+	}
+
+	if p.current.Type == TOKEN_DOT {
+		fields, err := p.parseFields()
+		if err != nil {
+			return nil, err
+		}
+		return p.parseFieldAssign(&AssignStatement{Pos: pos, Name: name, Fields: fields})
+	}
+
 	// Check for assignment operators
 	switch p.current.Type {
 	case TOKEN_EQ, TOKEN_PLUS_EQ, TOKEN_MINUS_EQ:
@@ -136,17 +422,69 @@ func (p *Parser) parseIdentifierStatement() (Statement, error) {
 		p.consumeNewlineOrEOF()
 		return &AssignStatement{Pos: pos, Name: name, Operator: TOKEN_MINUS_MINUS, Value: nil}, nil
 
+	case TOKEN_COMMA:
+		return p.parseMultiAssign(pos, false, &AssignStatement{Pos: pos, Name: name, Operator: TOKEN_EQ})
+
 	case TOKEN_LPAREN:
-		// Function call as statement
+		// Function call as statement, or array element assignment
 		p.advance() // consume (
-		args, err := p.parseArguments()
+		args, named, err := p.parseCallArguments()
 		if err != nil {
 			return nil, err
 		}
+
+		switch p.current.Type {
+		case TOKEN_EQ, TOKEN_PLUS_EQ, TOKEN_MINUS_EQ, TOKEN_PLUS_PLUS, TOKEN_MINUS_MINUS, TOKEN_COMMA, TOKEN_DOT:
+			if len(named) > 0 {
+				return nil, p.error("named arguments are only allowed in function calls")
+			}
+		}
+
+		if p.current.Type == TOKEN_DOT {
+			if len(args) == 0 {
+				return nil, p.error("expected index in element assignment")
+			}
+			fields, err := p.parseFields()
+			if err != nil {
+				return nil, err
+			}
+			return p.parseFieldAssign(&AssignStatement{Pos: pos, Name: name, Indices: args, Fields: fields})
+		}
+
+		switch p.current.Type {
+		case TOKEN_EQ, TOKEN_PLUS_EQ, TOKEN_MINUS_EQ:
+			if len(args) == 0 {
+				return nil, p.error("expected index in element assignment")
+			}
+			op := p.current.Type
+			p.advance()
+			expr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			p.consumeNewlineOrEOF()
+			return &AssignStatement{Pos: pos, Name: name, Indices: args, Operator: op, Value: expr}, nil
+
+		case TOKEN_PLUS_PLUS, TOKEN_MINUS_MINUS:
+			if len(args) == 0 {
+				return nil, p.error("expected index in element assignment")
+			}
+			op := p.current.Type
+			p.advance()
+			p.consumeNewlineOrEOF()
+			return &AssignStatement{Pos: pos, Name: name, Indices: args, Operator: op}, nil
+
+		case TOKEN_COMMA:
+			if len(args) == 0 {
+				return nil, p.error("expected index in element assignment")
+			}
+			return p.parseMultiAssign(pos, false, &AssignStatement{Pos: pos, Name: name, Indices: args, Operator: TOKEN_EQ})
+		}
+
 		p.consumeNewlineOrEOF()
 		return &ExpressionStatement{
 			Pos:  pos,
-			Expr: &CallExpr{Pos: pos, Name: name, Args: args},
+			Expr: &CallExpr{Pos: pos, Name: name, Args: args, Named: named},
 		}, nil
 
 	default:
@@ -154,26 +492,190 @@ func (p *Parser) parseIdentifierStatement() (Statement, error) {
 	}
 }
 
+// atQualifier reports whether the current '.' joins a namespace to the name
+// of a function being called, as in game.spawn(x), rather than starting a
+// record field
+func (p *Parser) atQualifier() bool {
+	return p.current.Type == TOKEN_DOT && p.pos+2 < len(p.tokens) &&
+		p.tokens[p.pos+1].Type == TOKEN_IDENTIFIER && p.tokens[p.pos+2].Type == TOKEN_LPAREN
+}
+
+// parseFields parses the .field suffixes of an assignment target
+func (p *Parser) parseFields() ([]string, error) {
+	var fields []string
+	for p.current.Type == TOKEN_DOT {
+		p.advance()
+		if p.current.Type != TOKEN_IDENTIFIER {
+			return nil, p.error("expected field name after '.'")
+		}
+		fields = append(fields, p.current.Value)
+		p.advance()
+	}
+	return fields, nil
+}
+
+// parseFieldAssign parses the rest of an assignment to a record field, whose
+// target has been parsed into stmt
+func (p *Parser) parseFieldAssign(stmt *AssignStatement) (Statement, error) {
+	switch p.current.Type {
+	case TOKEN_EQ, TOKEN_PLUS_EQ, TOKEN_MINUS_EQ:
+		stmt.Operator = p.current.Type
+		p.advance()
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Value = expr
+	case TOKEN_PLUS_PLUS, TOKEN_MINUS_MINUS:
+		stmt.Operator = p.current.Type
+		p.advance()
+	case TOKEN_COMMA:
+		stmt.Operator = TOKEN_EQ
+		return p.parseMultiAssign(stmt.Pos, false, stmt)
+	default:
+		return nil, p.error("expected assignment operator after field name")
+	}
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseSingleLineIf parses the rest of IF cond THEN statement [ELSE statement].
+// The ELSE must follow the THEN statement on the same line.
+func (p *Parser) parseSingleLineIf(stmt *IfStatement) (*IfStatement, error) {
+	then, err := p.parseInlineStatements()
+	if err != nil {
+		return nil, err
+	}
+	stmt.ThenBlock = then
+
+	if p.current.Type != TOKEN_ELSE || p.tokens[p.pos-1].Type == TOKEN_NEWLINE {
+		return stmt, nil
+	}
+	p.advance() // consume ELSE
+
+	if p.current.Type == TOKEN_NEWLINE || p.current.Type == TOKEN_EOF {
+		return nil, p.error("expected statement after ELSE")
+	}
+	otherwise, err := p.parseInlineStatements()
+	if err != nil {
+		return nil, err
+	}
+	stmt.ElseBlock = otherwise
+	return stmt, nil
+}
+
+// parseInlineStatements parses the colon-separated statements of a branch of
+// a single-line IF, which all belong to the branch: IF x THEN a = 1: b = 2
+func (p *Parser) parseInlineStatements() ([]Statement, error) {
+	var statements []Statement
+	for {
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmt)
+
+		if p.current.Type != TOKEN_COLON {
+			return statements, nil
+		}
+		p.advance() // consume :
+		if p.current.Type == TOKEN_NEWLINE || p.current.Type == TOKEN_EOF {
+			p.consumeNewlineOrEOF()
+			return statements, nil
+		}
+	}
+}
+
+// parseMultiAssign parses the rest of a multiple assignment after its first
+// target: , target... = expr, expr... Targets of LET must be plain names.
+func (p *Parser) parseMultiAssign(pos Pos, let bool, first *AssignStatement) (*MultiAssignStatement, error) {
+	stmt := &MultiAssignStatement{Pos: pos, Let: let, Targets: []*AssignStatement{first}}
+
+	for p.current.Type == TOKEN_COMMA {
+		p.advance()
+		if p.current.Type != TOKEN_IDENTIFIER {
+			return nil, p.error("expected variable name in assignment")
+		}
+		target := &AssignStatement{
+			Pos:      p.position(),
+			Name:     p.current.Value,
+			Operator: TOKEN_EQ,
+		}
+		p.advance()
+
+		if p.current.Type == TOKEN_LPAREN && !let {
+			p.advance() // consume (
+			args, err := p.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			if len(args) == 0 {
+				return nil, p.error("expected index in element assignment")
+			}
+			target.Indices = args
+		}
+		if p.current.Type == TOKEN_DOT && !let {
+			fields, err := p.parseFields()
+			if err != nil {
+				return nil, err
+			}
+			target.Fields = fields
+		}
+		stmt.Targets = append(stmt.Targets, target)
+	}
+
+	if p.current.Type != TOKEN_EQ {
+		return nil, p.error("expected '=' after variable names")
+	}
+	p.advance()
+
+	for {
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Values = append(stmt.Values, expr)
+
+		if p.current.Type != TOKEN_COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	if len(stmt.Values) != 1 && len(stmt.Values) != len(stmt.Targets) {
+		return nil, p.error("assignment to %d variables needs %d values or one array, got %d values", len(stmt.Targets), len(stmt.Targets), len(stmt.Values))
+	}
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
 // parseIfStatement parses: IF cond THEN ... [ELSEIF cond THEN ...] [ELSE ...] ENDIF
+// and the single-line form: IF cond THEN statement [ELSE statement]
 func (p *Parser) parseIfStatement() (*IfStatement, error) {
 	stmt := &IfStatement{
-		Pos: Pos{Line: p.current.Line, Column: p.current.Column},
+		Pos: p.position(),
 	}
 
 	p.advance() // consume IF
 
 	// Parse condition
 	cond, err := p.parseExpression()
+	if err == nil && p.current.Type != TOKEN_THEN {
+		err = p.error("expected THEN after IF condition")
+	}
 	if err != nil {
-		return nil, err
+		if p.singleLineIf() {
+			return nil, err
+		}
+		return nil, p.skipBlock(err, TOKEN_ELSEIF, TOKEN_ELSE, TOKEN_ENDIF)
 	}
 	stmt.Condition = cond
+	p.advance() // consume THEN
 
-	// Expect THEN
-	if p.current.Type != TOKEN_THEN {
-		return nil, p.error("expected THEN after IF condition")
+	if p.current.Type != TOKEN_NEWLINE && p.current.Type != TOKEN_EOF {
+		return p.parseSingleLineIf(stmt)
 	}
-	p.advance()
 	p.consumeNewline()
 
 	// Parse THEN block
@@ -184,16 +686,15 @@ func (p *Parser) parseIfStatement() (*IfStatement, error) {
 
 	// Parse ELSEIF clauses
 	for p.current.Type == TOKEN_ELSEIF {
-		elseIfPos := Pos{Line: p.current.Line, Column: p.current.Column}
+		elseIfPos := p.position()
 		p.advance() // consume ELSEIF
 
 		elseIfCond, err := p.parseExpression()
-		if err != nil {
-			return nil, err
+		if err == nil && p.current.Type != TOKEN_THEN {
+			err = p.error("expected THEN after ELSEIF condition")
 		}
-
-		if p.current.Type != TOKEN_THEN {
-			return nil, p.error("expected THEN after ELSEIF condition")
+		if err != nil {
+			return nil, p.skipBlock(err, TOKEN_ELSEIF, TOKEN_ELSE, TOKEN_ENDIF)
 		}
 		p.advance()
 		p.consumeNewline()
@@ -221,155 +722,502 @@ func (p *Parser) parseIfStatement() (*IfStatement, error) {
 		}
 	}
 
-	// Expect ENDIF
-	if p.current.Type != TOKEN_ENDIF {
-		return nil, p.error("expected ENDIF")
-	}
-	p.advance()
+	// Expect ENDIF
+	if p.current.Type != TOKEN_ENDIF {
+		return nil, p.error("expected ENDIF")
+	}
+	p.advance()
+	p.consumeNewlineOrEOF()
+
+	return stmt, nil
+}
+
+// parseForStatement parses: FOR var = start TO end ... NEXT var
+func (p *Parser) parseForStatement() (*ForStatement, error) {
+	stmt := &ForStatement{
+		Pos: p.position(),
+	}
+
+	p.advance() // consume FOR
+
+	if err := p.parseForHeader(stmt); err != nil {
+		return nil, p.skipBlock(err, TOKEN_NEXT)
+	}
+	p.consumeNewline()
+
+	// Parse body
+	var err error
+	stmt.Body, err = p.parseBlock(TOKEN_NEXT)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expect NEXT
+	if p.current.Type != TOKEN_NEXT {
+		return nil, p.error("expected NEXT")
+	}
+	p.advance()
+
+	// Optional variable name after NEXT
+	if p.current.Type == TOKEN_IDENTIFIER {
+		if p.current.Value != stmt.Variable {
+			return nil, p.error("NEXT variable '%s' doesn't match FOR variable '%s'", p.current.Value, stmt.Variable)
+		}
+		p.advance()
+	}
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseForHeader parses the rest of the FOR line: var = start TO end
+func (p *Parser) parseForHeader(stmt *ForStatement) error {
+	if p.current.Type != TOKEN_IDENTIFIER {
+		return p.error("expected identifier after FOR")
+	}
+	stmt.Variable = p.current.Value
+	p.advance()
+
+	if p.current.Type != TOKEN_EQ {
+		return p.error("expected '=' after loop variable")
+	}
+	p.advance()
+
+	start, err := p.parseExpression()
+	if err != nil {
+		return err
+	}
+	stmt.Start = start
+
+	if p.current.Type != TOKEN_TO {
+		return p.error("expected TO in FOR loop")
+	}
+	p.advance()
+
+	end, err := p.parseExpression()
+	if err != nil {
+		return err
+	}
+	stmt.End = end
+	return nil
+}
+
+// parseDoLoopStatement parses: DO [WHILE|UNTIL expr] ... LOOP [WHILE|UNTIL expr]
+func (p *Parser) parseDoLoopStatement() (*DoLoopStatement, error) {
+	stmt := &DoLoopStatement{
+		Pos: p.position(),
+	}
+
+	p.advance() // consume DO
+
+	var err error
+	if p.current.Type == TOKEN_WHILE || p.current.Type == TOKEN_UNTIL {
+		stmt.PreTest = true
+		if err := p.parseLoopCondition(stmt); err != nil {
+			return nil, p.skipBlock(err, TOKEN_LOOP)
+		}
+	}
+
+	p.consumeNewline()
+
+	// Parse body
+	stmt.Body, err = p.parseBlock(TOKEN_LOOP)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expect LOOP
+	if p.current.Type != TOKEN_LOOP {
+		return nil, p.error("expected LOOP")
+	}
+	p.advance()
+
+	if p.current.Type == TOKEN_WHILE || p.current.Type == TOKEN_UNTIL {
+		if stmt.PreTest {
+			return nil, p.error("DO loop cannot have a condition after both DO and LOOP")
+		}
+		if err := p.parseLoopCondition(stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseLoopCondition parses the WHILE or UNTIL clause of a DO loop
+func (p *Parser) parseLoopCondition(stmt *DoLoopStatement) error {
+	stmt.Until = p.current.Type == TOKEN_UNTIL
+	p.advance() // consume WHILE or UNTIL
+
+	cond, err := p.parseExpression()
+	if err != nil {
+		return err
+	}
+	stmt.Condition = cond
+	return nil
+}
+
+// parseBreakStatement parses: BREAK
+func (p *Parser) parseBreakStatement() (*BreakStatement, error) {
+	stmt := &BreakStatement{
+		Pos: p.position(),
+	}
+	p.advance()
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseExitStatement parses: EXIT FOR [var], EXIT DO, EXIT WHILE,
+// EXIT FUNCTION or EXIT SUB
+func (p *Parser) parseExitStatement() (*ExitStatement, error) {
+	stmt := &ExitStatement{
+		Pos: p.position(),
+	}
+	p.advance() // consume EXIT
+
+	switch p.current.Type {
+	case TOKEN_FOR, TOKEN_DO, TOKEN_WHILE, TOKEN_FUNCTION, TOKEN_SUB:
+		stmt.Kind = p.current.Type
+	default:
+		return nil, p.error("expected FOR, DO, WHILE, FUNCTION or SUB after EXIT")
+	}
+	p.advance()
+
+	if stmt.Kind == TOKEN_FOR && p.current.Type == TOKEN_IDENTIFIER {
+		stmt.Variable = p.current.Value
+		p.advance()
+	}
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseTryStatement parses: TRY ... CATCH [var] ... ENDTRY
+func (p *Parser) parseTryStatement() (*TryStatement, error) {
+	stmt := &TryStatement{
+		Pos: p.position(),
+	}
+	p.advance() // consume TRY
+	p.consumeNewline()
+
+	var err error
+	stmt.Body, err = p.parseBlock(TOKEN_CATCH)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.current.Type != TOKEN_CATCH {
+		return nil, p.error("expected CATCH")
+	}
+	p.advance()
+
+	if p.current.Type == TOKEN_IDENTIFIER {
+		stmt.ErrorVar = p.current.Value
+		p.advance()
+	}
+	p.consumeNewline()
+
+	stmt.Handler, err = p.parseBlock(TOKEN_ENDTRY)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.current.Type != TOKEN_ENDTRY {
+		return nil, p.error("expected ENDTRY")
+	}
+	p.advance()
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseOnErrorStatement parses: ON ERROR CALL handler. ERROR and CALL are
+// matched as words rather than keywords so they remain usable as names.
+func (p *Parser) parseOnErrorStatement() (*OnErrorStatement, error) {
+	stmt := &OnErrorStatement{
+		Pos: p.position(),
+	}
+	p.advance() // consume ON
+
+	for _, word := range []string{"error", "call"} {
+		if p.current.Type != TOKEN_IDENTIFIER || !strings.EqualFold(p.current.Value, word) {
+			return nil, p.error("expected ON ERROR CALL handler")
+		}
+		p.advance()
+	}
+
+	if p.current.Type != TOKEN_IDENTIFIER {
+		return nil, p.error("expected function name after ON ERROR CALL")
+	}
+	stmt.Handler = p.current.Value
+	p.advance()
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseIncludeStatement parses: INCLUDE "file.bas"
+func (p *Parser) parseIncludeStatement() (*IncludeStatement, error) {
+	stmt := &IncludeStatement{Pos: p.position()}
+	p.advance() // consume INCLUDE
+
+	if p.current.Type != TOKEN_STRING {
+		return nil, p.error("expected file name string after INCLUDE")
+	}
+	stmt.Path = p.current.Value
+	p.advance()
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseOptionStatement parses: OPTION EXPLICIT
+func (p *Parser) parseOptionStatement() (*OptionStatement, error) {
+	stmt := &OptionStatement{Pos: p.position()}
+	p.advance() // consume OPTION
+
+	if p.current.Type != TOKEN_IDENTIFIER {
+		return nil, p.error("expected option name after OPTION")
+	}
+	if !strings.EqualFold(p.current.Value, "explicit") {
+		return nil, p.error("unknown option %s", p.current.Value)
+	}
+	stmt.Name = strings.ToUpper(p.current.Value)
+	p.advance()
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseImportStatement parses: IMPORT "module"
+func (p *Parser) parseImportStatement() (*ImportStatement, error) {
+	stmt := &ImportStatement{
+		Pos: p.position(),
+	}
+	p.advance() // consume IMPORT
+
+	if p.current.Type != TOKEN_STRING {
+		return nil, p.error("expected module name string after IMPORT")
+	}
+	stmt.Module = p.current.Value
+	p.advance()
+
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
+
+// parseThrowStatement parses: THROW expression
+func (p *Parser) parseThrowStatement() (*ThrowStatement, error) {
+	stmt := &ThrowStatement{
+		Pos: p.position(),
+	}
+	p.advance() // consume THROW
+
+	if p.current.Type == TOKEN_NEWLINE || p.current.Type == TOKEN_EOF {
+		return nil, p.error("expected message after THROW")
+	}
+
+	var err error
+	stmt.Message, err = p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
 	p.consumeNewlineOrEOF()
-
 	return stmt, nil
 }
 
-// parseForStatement parses: FOR var = start TO end ... NEXT var
-func (p *Parser) parseForStatement() (*ForStatement, error) {
-	stmt := &ForStatement{
-		Pos: Pos{Line: p.current.Line, Column: p.current.Column},
+// parseLabelStatement parses: name:
+func (p *Parser) parseLabelStatement() (*LabelStatement, error) {
+	stmt := &LabelStatement{
+		Pos:  p.position(),
+		Name: p.current.Value,
 	}
+	p.advance() // consume name
+	p.advance() // consume :
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
 
-	p.advance() // consume FOR
+// parseGotoStatement parses: GOTO label
+func (p *Parser) parseGotoStatement() (*GotoStatement, error) {
+	stmt := &GotoStatement{
+		Pos: p.position(),
+	}
+	p.advance() // consume GOTO
 
 	if p.current.Type != TOKEN_IDENTIFIER {
-		return nil, p.error("expected identifier after FOR")
+		return nil, p.error("expected label after GOTO")
 	}
-	stmt.Variable = p.current.Value
+	stmt.Label = p.current.Value
 	p.advance()
 
-	if p.current.Type != TOKEN_EQ {
-		return nil, p.error("expected '=' after loop variable")
-	}
-	p.advance()
+	p.consumeNewlineOrEOF()
+	return stmt, nil
+}
 
-	start, err := p.parseExpression()
-	if err != nil {
-		return nil, err
+// parseFunctionStatement parses: FUNCTION name(params): ... ENDFUNCTION
+// and SUB name(params): ... ENDSUB
+func (p *Parser) parseFunctionStatement() (*FunctionStatement, error) {
+	stmt := &FunctionStatement{
+		Pos:   p.position(),
+		IsSub: p.current.Type == TOKEN_SUB,
+		Doc:   p.current.Doc,
 	}
-	stmt.Start = start
 
-	if p.current.Type != TOKEN_TO {
-		return nil, p.error("expected TO in FOR loop")
+	kind, terminator := "function", TOKEN_ENDFUNCTION
+	if stmt.IsSub {
+		kind, terminator = "sub", TOKEN_ENDSUB
 	}
-	p.advance()
 
-	end, err := p.parseExpression()
-	if err != nil {
-		return nil, err
+	p.advance() // consume FUNCTION or SUB
+
+	if err := p.parseFunctionHeader(stmt, kind); err != nil {
+		return nil, p.skipBlock(err, terminator)
 	}
-	stmt.End = end
 
+	// Optional colon
+	if p.current.Type == TOKEN_COLON {
+		p.advance()
+	}
 	p.consumeNewline()
 
 	// Parse body
-	stmt.Body, err = p.parseBlock(TOKEN_NEXT)
+	outerSub := p.inSub
+	p.inSub = stmt.IsSub
+	defer func() { p.inSub = outerSub }()
+
+	var err error
+	stmt.Body, err = p.parseBlock(terminator)
 	if err != nil {
 		return nil, err
 	}
 
-	// Expect NEXT
-	if p.current.Type != TOKEN_NEXT {
-		return nil, p.error("expected NEXT")
+	if p.current.Type != terminator {
+		return nil, p.error("expected %s", terminator)
 	}
 	p.advance()
-
-	// Optional variable name after NEXT
-	if p.current.Type == TOKEN_IDENTIFIER {
-		if p.current.Value != stmt.Variable {
-			return nil, p.error("NEXT variable '%s' doesn't match FOR variable '%s'", p.current.Value, stmt.Variable)
-		}
-		p.advance()
-	}
-
 	p.consumeNewlineOrEOF()
-	return stmt, nil
-}
 
-// parseBreakStatement parses: BREAK
-func (p *Parser) parseBreakStatement() (*BreakStatement, error) {
-	stmt := &BreakStatement{
-		Pos: Pos{Line: p.current.Line, Column: p.current.Column},
-	}
-	p.advance()
-	p.consumeNewlineOrEOF()
 	return stmt, nil
 }
 
-// parseFunctionStatement parses: FUNCTION name(params): ... ENDFUNCTION
-func (p *Parser) parseFunctionStatement() (*FunctionStatement, error) {
-	stmt := &FunctionStatement{
-		Pos: Pos{Line: p.current.Line, Column: p.current.Column},
-	}
-
-	p.advance() // consume FUNCTION
-
+// parseFunctionHeader parses the name and parameter list of a FUNCTION or SUB
+func (p *Parser) parseFunctionHeader(stmt *FunctionStatement, kind string) error {
 	if p.current.Type != TOKEN_IDENTIFIER {
-		return nil, p.error("expected function name")
+		return p.error("expected %s name", kind)
 	}
 	stmt.Name = p.current.Value
 	p.advance()
 
 	if p.current.Type != TOKEN_LPAREN {
-		return nil, p.error("expected '(' after function name")
+		return p.error("expected '(' after %s name", kind)
 	}
 	p.advance()
 
-	// Parse parameters
+	// Parse parameters. Once one has a default value, the rest must too.
 	stmt.Params = []string{}
+	stmt.Defaults = []Expression{}
+	stmt.Types = []string{}
+	hasDefault := false
 	for p.current.Type != TOKEN_RPAREN {
 		if p.current.Type != TOKEN_IDENTIFIER {
-			return nil, p.error("expected parameter name")
+			return p.error("expected parameter name")
 		}
-		stmt.Params = append(stmt.Params, p.current.Value)
+		param := p.current.Value
 		p.advance()
 
+		typ := ""
+		if p.current.Type == TOKEN_AS {
+			p.advance()
+			if p.current.Type != TOKEN_IDENTIFIER {
+				return p.error("expected type name after AS")
+			}
+			typ = p.current.Value
+			p.advance()
+		}
+
+		var def Expression
+		if p.current.Type == TOKEN_EQ {
+			p.advance()
+			var err error
+			def, err = p.parseExpression()
+			if err != nil {
+				return err
+			}
+			hasDefault = true
+		} else if hasDefault {
+			return p.error("parameter %s needs a default value because an earlier parameter has one", param)
+		}
+		stmt.Params = append(stmt.Params, param)
+		stmt.Defaults = append(stmt.Defaults, def)
+		stmt.Types = append(stmt.Types, typ)
+
 		if p.current.Type == TOKEN_COMMA {
 			p.advance()
 		} else if p.current.Type != TOKEN_RPAREN {
-			return nil, p.error("expected ',' or ')' in parameter list")
+			return p.error("expected ',' or ')' in parameter list")
 		}
 	}
 	p.advance() // consume )
+	return nil
+}
 
-	// Optional colon
-	if p.current.Type == TOKEN_COLON {
-		p.advance()
+// parseTypeStatement parses: TYPE name, then field names one per line (or
+// separated by commas), then ENDTYPE
+func (p *Parser) parseTypeStatement() (*TypeStatement, error) {
+	stmt := &TypeStatement{
+		Pos: p.position(),
+	}
+	p.advance() // consume TYPE
+
+	if p.current.Type != TOKEN_IDENTIFIER {
+		return nil, p.skipPast(p.error("expected type name after TYPE"), TOKEN_ENDTYPE)
 	}
+	stmt.Name = p.current.Value
+	p.advance()
 	p.consumeNewline()
 
-	// Parse body
-	var err error
-	stmt.Body, err = p.parseBlock(TOKEN_ENDFUNCTION)
-	if err != nil {
-		return nil, err
+	for {
+		p.skipNewlines()
+		if p.current.Type == TOKEN_ENDTYPE {
+			break
+		}
+		if p.current.Type != TOKEN_IDENTIFIER {
+			return nil, p.skipPast(p.error("expected field name or ENDTYPE in TYPE %s", stmt.Name), TOKEN_ENDTYPE)
+		}
+		stmt.Fields = append(stmt.Fields, p.current.Value)
+		p.advance()
+
+		if p.current.Type == TOKEN_COMMA {
+			p.advance()
+		} else if p.current.Type != TOKEN_NEWLINE && p.current.Type != TOKEN_COLON {
+			return nil, p.skipPast(p.error("expected newline after field name"), TOKEN_ENDTYPE)
+		}
 	}
+	p.advance() // consume ENDTYPE
 
-	if p.current.Type != TOKEN_ENDFUNCTION {
-		return nil, p.error("expected ENDFUNCTION")
+	if len(stmt.Fields) == 0 {
+		return nil, parseError(ErrorSyntax, stmt.File, stmt.Line, stmt.Column, "TYPE %s has no fields", stmt.Name)
 	}
-	p.advance()
-	p.consumeNewlineOrEOF()
 
+	p.consumeNewlineOrEOF()
 	return stmt, nil
 }
 
 // parseReturnStatement parses: RETURN [expr]
 func (p *Parser) parseReturnStatement() (*ReturnStatement, error) {
 	stmt := &ReturnStatement{
-		Pos: Pos{Line: p.current.Line, Column: p.current.Column},
+		Pos: p.position(),
 	}
 	p.advance() // consume RETURN
 
 	// Check if there's an expression (not newline or EOF)
 	if p.current.Type != TOKEN_NEWLINE && p.current.Type != TOKEN_EOF {
+		if p.inSub {
+			return nil, p.error("RETURN in a SUB cannot have a value")
+		}
 		expr, err := p.parseExpression()
 		if err != nil {
 			return nil, err
@@ -381,18 +1229,43 @@ func (p *Parser) parseReturnStatement() (*ReturnStatement, error) {
 	return stmt, nil
 }
 
-// parsePrintStatement parses: PRINT expr
+// parsePrintStatement parses: PRINT [USING format;] expr[, expr...][;]
 func (p *Parser) parsePrintStatement() (*PrintStatement, error) {
 	stmt := &PrintStatement{
-		Pos: Pos{Line: p.current.Line, Column: p.current.Column},
+		Pos: p.position(),
 	}
 	p.advance() // consume PRINT
 
-	expr, err := p.parseExpression()
-	if err != nil {
-		return nil, err
+	if p.current.Type == TOKEN_USING {
+		p.advance()
+		format, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.current.Type != TOKEN_SEMICOLON {
+			return nil, p.error("expected ';' after PRINT USING format")
+		}
+		p.advance()
+		stmt.Using = format
+	}
+
+	for {
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Values = append(stmt.Values, expr)
+
+		if p.current.Type != TOKEN_COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	if p.current.Type == TOKEN_SEMICOLON {
+		stmt.NoNewline = true
+		p.advance()
 	}
-	stmt.Value = expr
 
 	p.consumeNewlineOrEOF()
 	return stmt, nil
@@ -417,7 +1290,10 @@ func (p *Parser) parseBlock(terminators ...TokenType) ([]Statement, error) {
 
 		stmt, err := p.parseStatement()
 		if err != nil {
-			return nil, err
+			if !p.recover(err) {
+				return nil, err
+			}
+			continue
 		}
 		if stmt != nil {
 			statements = append(statements, stmt)
@@ -439,10 +1315,13 @@ const (
 	precAnd                   // AND
 	precEquality              // = <> !=
 	precComparison            // < > <= >=
+	precBitOr                 // | XOR
+	precBitAnd                // &
+	precShift                 // << >>
 	precTerm                  // + -
 	precFactor                // * /
 	precUnary                 // NOT -
-	precCall                  // ()
+	precCall                  // () []
 )
 
 func (p *Parser) parseExpression() (Expression, error) {
@@ -450,6 +1329,11 @@ func (p *Parser) parseExpression() (Expression, error) {
 }
 
 func (p *Parser) parsePrecedence(minPrec precedence) (Expression, error) {
+	if err := p.enterExpression(); err != nil {
+		return nil, err
+	}
+	defer p.leaveExpression()
+
 	left, err := p.parseUnary()
 	if err != nil {
 		return nil, err
@@ -471,7 +1355,7 @@ func (p *Parser) parsePrecedence(minPrec precedence) (Expression, error) {
 
 		line, col := left.Position()
 		left = &BinaryExpr{
-			Pos:      Pos{Line: line, Column: col},
+			Pos:      Pos{File: p.file, Line: line, Column: col},
 			Left:     left,
 			Operator: op,
 			Right:    right,
@@ -491,6 +1375,12 @@ func (p *Parser) getInfixPrecedence() precedence {
 		return precEquality
 	case TOKEN_LT, TOKEN_GT, TOKEN_LTE, TOKEN_GTE:
 		return precComparison
+	case TOKEN_PIPE, TOKEN_XOR:
+		return precBitOr
+	case TOKEN_AMP:
+		return precBitAnd
+	case TOKEN_SHL, TOKEN_SHR:
+		return precShift
 	case TOKEN_PLUS, TOKEN_MINUS:
 		return precTerm
 	case TOKEN_STAR, TOKEN_SLASH:
@@ -501,8 +1391,13 @@ func (p *Parser) getInfixPrecedence() precedence {
 }
 
 func (p *Parser) parseUnary() (Expression, error) {
+	if err := p.enterExpression(); err != nil {
+		return nil, err
+	}
+	defer p.leaveExpression()
+
 	if p.current.Type == TOKEN_NOT || p.current.Type == TOKEN_MINUS {
-		pos := Pos{Line: p.current.Line, Column: p.current.Column}
+		pos := p.position()
 		op := p.current.Type
 		p.advance()
 
@@ -513,30 +1408,130 @@ func (p *Parser) parseUnary() (Expression, error) {
 
 		return &UnaryExpr{Pos: pos, Operator: op, Operand: operand}, nil
 	}
+	if p.current.Type == TOKEN_YIELD {
+		return p.parseYield()
+	}
 
 	return p.parseCall()
 }
 
+// parseYield parses: YIELD [expr]. Like RETURN, the value runs to the end of
+// the expression, so (yield) + 1 needs its parentheses.
+func (p *Parser) parseYield() (Expression, error) {
+	expr := &YieldExpr{Pos: p.position()}
+	p.advance() // consume YIELD
+
+	switch p.current.Type {
+	case TOKEN_NEWLINE, TOKEN_EOF, TOKEN_COLON, TOKEN_RPAREN, TOKEN_RBRACKET,
+		TOKEN_COMMA, TOKEN_SEMICOLON, TOKEN_THEN, TOKEN_ELSE, TOKEN_TO:
+		return expr, nil // A bare YIELD
+	}
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	expr.Value = value
+	return expr, nil
+}
+
 func (p *Parser) parseCall() (Expression, error) {
 	expr, err := p.parsePrimary()
 	if err != nil {
 		return nil, err
 	}
 
+	// A namespaced host function is called by its qualified name: game.spawn(x)
+	if ident, ok := expr.(*Identifier); ok && p.atQualifier() {
+		p.advance() // consume .
+		ident.Name += "." + p.current.Value
+		p.advance()
+	}
+
 	// Check for function call
 	if ident, ok := expr.(*Identifier); ok && p.current.Type == TOKEN_LPAREN {
 		pos := ident.Pos
 		p.advance() // consume (
 
-		args, err := p.parseArguments()
+		// Element accesses and iif take no named arguments, so name = expr
+		// is a comparison there
+		if p.arrays[strings.ToLower(ident.Name)] || strings.EqualFold(ident.Name, "iif") {
+			args, err := p.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			if p.arrays[strings.ToLower(ident.Name)] {
+				expr = &IndexExpr{Pos: pos, Name: ident.Name, Indices: args}
+			} else {
+				if len(args) != 3 {
+					return nil, parseError(ErrorSyntax, pos.File, pos.Line, pos.Column, "iif requires 3 arguments")
+				}
+				expr = &ConditionalExpr{Pos: pos, Condition: args[0], Then: args[1], Else: args[2]}
+			}
+		} else {
+			args, named, err := p.parseCallArguments()
+			if err != nil {
+				return nil, err
+			}
+			expr = &CallExpr{Pos: pos, Name: ident.Name, Args: args, Named: named}
+		}
+	}
+
+	// Check for string indexing and slicing and for record fields, which may
+	// be chained: s[1:][0], a(i).name[0]
+	for p.current.Type == TOKEN_LBRACKET || p.current.Type == TOKEN_DOT {
+		if p.current.Type == TOKEN_LBRACKET {
+			expr, err = p.parseSlice(expr)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		pos := p.position()
+		p.advance() // consume .
+		if p.current.Type != TOKEN_IDENTIFIER {
+			return nil, p.error("expected field name after '.'")
+		}
+		expr = &MemberExpr{Pos: pos, Target: expr, Field: p.current.Value}
+		p.advance()
+	}
+
+	return expr, nil
+}
+
+// parseSlice parses the [i] or [a:b] suffix applied to target
+func (p *Parser) parseSlice(target Expression) (*SliceExpr, error) {
+	slice := &SliceExpr{
+		Pos:    p.position(),
+		Target: target,
+	}
+	p.advance() // consume [
+
+	var err error
+	if p.current.Type != TOKEN_COLON {
+		slice.Start, err = p.parseExpression()
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	if p.current.Type == TOKEN_COLON {
+		slice.Slice = true
+		p.advance()
+		if p.current.Type != TOKEN_RBRACKET {
+			slice.End, err = p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 
-		return &CallExpr{Pos: pos, Name: ident.Name, Args: args}, nil
+	if p.current.Type != TOKEN_RBRACKET {
+		return nil, p.error("expected ']' after index")
 	}
+	p.advance()
 
-	return expr, nil
+	return slice, nil
 }
 
 func (p *Parser) parseArguments() ([]Expression, error) {
@@ -569,12 +1564,70 @@ func (p *Parser) parseArguments() ([]Expression, error) {
 	return args, nil
 }
 
+// parseCallArguments parses the arguments of a function call after the '('.
+// An argument written name = expr is passed by parameter name; those must
+// come after all the positional arguments.
+func (p *Parser) parseCallArguments() ([]Expression, []*NamedArg, error) {
+	args := []Expression{}
+	var named []*NamedArg
+
+	if p.current.Type == TOKEN_RPAREN {
+		p.advance()
+		return args, nil, nil
+	}
+
+	for {
+		if p.current.Type == TOKEN_IDENTIFIER && p.peekNext().Type == TOKEN_EQ {
+			arg := &NamedArg{
+				Pos:  p.position(),
+				Name: p.current.Value,
+			}
+			for _, prev := range named {
+				if strings.EqualFold(prev.Name, arg.Name) {
+					return nil, nil, p.error("argument %s is given more than once", arg.Name)
+				}
+			}
+			p.advance() // consume name
+			p.advance() // consume =
+
+			var err error
+			arg.Value, err = p.parseExpression()
+			if err != nil {
+				return nil, nil, err
+			}
+			named = append(named, arg)
+		} else {
+			if len(named) > 0 {
+				return nil, nil, p.error("positional argument after named arguments")
+			}
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, arg)
+		}
+
+		if p.current.Type == TOKEN_COMMA {
+			p.advance()
+		} else {
+			break
+		}
+	}
+
+	if p.current.Type != TOKEN_RPAREN {
+		return nil, nil, p.error("expected ')' after arguments")
+	}
+	p.advance()
+
+	return args, named, nil
+}
+
 func (p *Parser) parsePrimary() (Expression, error) {
-	pos := Pos{Line: p.current.Line, Column: p.current.Column}
+	pos := p.position()
 
 	switch p.current.Type {
 	case TOKEN_INT:
-		value, err := strconv.Atoi(p.current.Value)
+		value, err := parseIntLiteral(p.current.Value)
 		if err != nil {
 			return nil, p.error("invalid integer: %s", p.current.Value)
 		}
@@ -602,6 +1655,10 @@ func (p *Parser) parsePrimary() (Expression, error) {
 		p.advance()
 		return &BoolLiteral{Pos: pos, Value: false}, nil
 
+	case TOKEN_NULL:
+		p.advance()
+		return &NullLiteral{Pos: pos}, nil
+
 	case TOKEN_IDENTIFIER:
 		name := p.current.Value
 		p.advance()
@@ -639,8 +1696,17 @@ func (p *Parser) advance() {
 	}
 }
 
+func (p *Parser) peekNext() Token {
+	if p.pos+1 < len(p.tokens) {
+		return p.tokens[p.pos+1]
+	}
+	return Token{Type: TOKEN_EOF}
+}
+
+// skipNewlines skips the ends of statements: newlines, and the colons that
+// separate statements on one line
 func (p *Parser) skipNewlines() {
-	for p.current.Type == TOKEN_NEWLINE {
+	for p.current.Type == TOKEN_NEWLINE || p.current.Type == TOKEN_COLON {
 		p.advance()
 	}
 }
@@ -658,7 +1724,123 @@ func (p *Parser) consumeNewlineOrEOF() {
 	// EOF is also acceptable at end of statement
 }
 
+// enterExpression records one level of expression nesting, failing once the
+// depth limit is exceeded. Every call must be paired with leaveExpression.
+func (p *Parser) enterExpression() error {
+	p.depth++
+	if p.maxDepth > 0 && p.depth > p.maxDepth {
+		return p.error("expression too complex: nesting exceeds %d levels", p.maxDepth)
+	}
+	return nil
+}
+
+func (p *Parser) leaveExpression() {
+	p.depth--
+}
+
+// errSkipped is returned in recovering mode for a statement whose error has
+// been recorded and whose tokens have already been skipped
+var errSkipped = errors.New("statement skipped")
+
+// recover records a statement's error in recovering mode and skips the rest
+// of its line, so parsing can go on with the next statement. It reports
+// whether the error was recorded.
+func (p *Parser) recover(err error) bool {
+	if !p.recovering {
+		return false
+	}
+	if err != errSkipped {
+		p.errs = append(p.errs, err)
+		p.skipLine()
+	}
+	return true
+}
+
+// skipBlock recovers from an error in the header line of a block, such as a
+// FOR with no TO, in recovering mode. The body is still parsed, so its errors
+// are recorded too, and skipped along with the lines starting with ends, the
+// last of which closes the block. Without this the body would be parsed as
+// if it were outside the block, and its closing keyword reported as
+// unexpected. Returns errSkipped, or err if not recovering.
+func (p *Parser) skipBlock(err error, ends ...TokenType) error {
+	if !p.recovering {
+		return err
+	}
+	p.errs = append(p.errs, err)
+	p.skipLine()
+	for {
+		p.parseBlock(ends...) // Records errors rather than returning them
+		if p.isAtEnd() {
+			return errSkipped
+		}
+		last := p.current.Type == ends[len(ends)-1]
+		p.skipLine() // ELSEIF, ELSE or the closing line
+		if last {
+			return errSkipped
+		}
+	}
+}
+
+// skipPast recovers from an error in a block whose body isn't made of
+// statements, such as a TYPE, by skipping to the end keyword. Returns
+// errSkipped, or err if not recovering.
+func (p *Parser) skipPast(err error, end TokenType) error {
+	if !p.recovering {
+		return err
+	}
+	p.errs = append(p.errs, err)
+	for !p.isAtEnd() && p.current.Type != end {
+		p.advance()
+	}
+	p.skipLine()
+	return errSkipped
+}
+
+// skipLine skips to the end of the current line
+func (p *Parser) skipLine() {
+	for !p.isAtEnd() && p.current.Type != TOKEN_NEWLINE {
+		p.advance()
+	}
+}
+
+// singleLineIf reports whether the rest of the line holds a THEN followed by
+// a statement, as in the single-line IF form
+func (p *Parser) singleLineIf() bool {
+	for idx := p.pos; idx+1 < len(p.tokens) && p.tokens[idx].Type != TOKEN_NEWLINE; idx++ {
+		if p.tokens[idx].Type == TOKEN_THEN {
+			next := p.tokens[idx+1].Type
+			return next != TOKEN_NEWLINE && next != TOKEN_EOF
+		}
+	}
+	return false
+}
+
 func (p *Parser) error(format string, args ...interface{}) error {
-	msg := fmt.Sprintf(format, args...)
-	return fmt.Errorf("line %d, column %d: %s", p.current.Line, p.current.Column, msg)
+	return parseError(ErrorSyntax, p.file, p.current.Line, p.current.Column, format, args...)
+}
+
+// position returns the position of the current token
+func (p *Parser) position() Pos {
+	return Pos{File: p.file, Line: p.current.Line, Column: p.current.Column}
+}
+
+// parseIntLiteral converts the text of an integer token, which may have a
+// 0x, 0b or 0o prefix. A leading 0 alone doesn't make a literal octal.
+func parseIntLiteral(text string) (int, error) {
+	if len(text) > 2 && text[0] == '0' {
+		if prefix, ok := literalBases[unicode.ToLower(rune(text[1]))]; ok {
+			value, err := strconv.ParseInt(text[2:], prefix.base, 64)
+			return int(value), err
+		}
+	}
+	return strconv.Atoi(text)
+}
+
+// positionText describes a position for error messages, naming the file for
+// code from an included file
+func positionText(file string, line, column int) string {
+	if file == "" {
+		return fmt.Sprintf("line %d, column %d", line, column)
+	}
+	return fmt.Sprintf("%s: line %d, column %d", file, line, column)
 }
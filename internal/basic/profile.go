@@ -0,0 +1,110 @@
+package basic
+
+import (
+	"sort"
+	"time"
+)
+
+// LineProfile is what the profiler recorded for one source line
+type LineProfile struct {
+	File string        // Included file the line is in, "" for the script itself
+	Line int           // Line number (1-indexed)
+	Hits int           // Statements executed on the line
+	Time time.Duration // Spent running the line, including the blocks and functions it ran
+	Self time.Duration // Spent on the line itself, excluding statements nested in it
+}
+
+// Profile holds the lines executed while profiling, sorted by file and line
+type Profile struct {
+	Lines []LineProfile
+}
+
+// Top returns up to n lines with the most time spent on the line itself,
+// which is where hot loops show up
+func (p Profile) Top(n int) []LineProfile {
+	lines := make([]LineProfile, len(p.Lines))
+	copy(lines, p.Lines)
+	sort.SliceStable(lines, func(a, b int) bool {
+		return lines[a].Self > lines[b].Self
+	})
+	if n < len(lines) {
+		lines = lines[:n]
+	}
+	return lines
+}
+
+type profileKey struct {
+	file string
+	line int
+}
+
+// profiler accumulates line timings while profiling is on
+type profiler struct {
+	lines  map[profileKey]*LineProfile
+	nested []time.Duration // Time spent in nested statements, one entry per statement running
+}
+
+// SetProfiling turns the profiler on or off. Turning it on starts a fresh
+// profile, which then accumulates over every run and call until it is
+// turned off. Profiling slows statements down, so leave it off in release
+// builds.
+func (i *Interpreter) SetProfiling(enabled bool) {
+	if !enabled {
+		if i.profiler != nil {
+			i.lastProfile = i.profiler.profile()
+		}
+		i.profiler = nil
+		return
+	}
+	i.profiler = &profiler{lines: make(map[profileKey]*LineProfile)}
+	i.lastProfile = Profile{}
+}
+
+// Profile returns the lines recorded so far, or by the last profiling
+// session if profiling has been turned off
+func (i *Interpreter) Profile() Profile {
+	if i.profiler != nil {
+		return i.profiler.profile()
+	}
+	return i.lastProfile
+}
+
+// start times a statement. Call the returned function when it finishes.
+func (p *profiler) start(stmt Statement) func() {
+	began := time.Now()
+	p.nested = append(p.nested, 0)
+	return func() {
+		elapsed := time.Since(began)
+		depth := len(p.nested) - 1
+		nested := p.nested[depth]
+		p.nested = p.nested[:depth]
+		if depth > 0 {
+			p.nested[depth-1] += elapsed
+		}
+
+		line, _ := stmt.Position()
+		key := profileKey{stmt.SourceFile(), line}
+		entry, ok := p.lines[key]
+		if !ok {
+			entry = &LineProfile{File: key.file, Line: line}
+			p.lines[key] = entry
+		}
+		entry.Hits++
+		entry.Time += elapsed
+		entry.Self += elapsed - nested
+	}
+}
+
+func (p *profiler) profile() Profile {
+	lines := make([]LineProfile, 0, len(p.lines))
+	for _, entry := range p.lines {
+		lines = append(lines, *entry)
+	}
+	sort.Slice(lines, func(a, b int) bool {
+		if lines[a].File != lines[b].File {
+			return lines[a].File < lines[b].File
+		}
+		return lines[a].Line < lines[b].Line
+	})
+	return Profile{Lines: lines}
+}
@@ -0,0 +1,175 @@
+package basic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Record is a value of a type declared with TYPE ... ENDTYPE. Records are
+// references, like arrays: assigning one or passing it to a function shares
+// it rather than copying it.
+type Record struct {
+	typ           *TypeStatement
+	values        []interface{} // In the order the fields are declared
+	caseSensitive bool          // Field names must match exactly
+}
+
+// TypeName returns the name of the record's type as declared
+func (r *Record) TypeName() string {
+	return r.typ.Name
+}
+
+// Field returns the value of a field, looked up case-insensitively unless
+// the interpreter that made the record is case-sensitive
+func (r *Record) Field(name string) (interface{}, bool) {
+	idx := r.fieldIndex(name)
+	if idx < 0 {
+		return nil, false
+	}
+	return r.values[idx], true
+}
+
+// Fields returns the field names in declaration order
+func (r *Record) Fields() []string {
+	return append([]string(nil), r.typ.Fields...)
+}
+
+// String formats the record like its construction: Vec2(x = 1, y = 2)
+func (r *Record) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.typ.Name)
+	sb.WriteByte('(')
+	for idx, field := range r.typ.Fields {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%s = %s", field, functions.ToString(r.values[idx]))
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
+
+func (r *Record) fieldIndex(name string) int {
+	for idx, field := range r.typ.Fields {
+		if r.sameName(field, name) {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (r *Record) sameName(a, b string) bool {
+	if r.caseSensitive {
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
+
+// defineTypes registers the TYPE declarations among statements, so records
+// can be constructed before the declaration is reached, like functions are
+// called
+func (i *Interpreter) defineTypes(statements []Statement) {
+	for _, stmt := range statements {
+		if typ, ok := stmt.(*TypeStatement); ok {
+			i.types[i.ident(typ.Name)] = typ
+		}
+	}
+}
+
+// newRecord constructs a record from the arguments of a call to its type's
+// name. Fields can be passed by position or by name; the rest are 0.
+func (i *Interpreter) newRecord(expr *CallExpr, typ *TypeStatement, args, named []interface{}) (*Record, error) {
+	if len(args) > len(typ.Fields) {
+		return nil, i.runtimeError(expr, "type %s has %d fields, got %d arguments", typ.Name, len(typ.Fields), len(args))
+	}
+
+	rec := &Record{typ: typ, values: make([]interface{}, len(typ.Fields)), caseSensitive: i.caseSensitive}
+	for idx := range rec.values {
+		rec.values[idx] = 0
+	}
+	copy(rec.values, args)
+
+	for idx, arg := range expr.Named {
+		field := rec.fieldIndex(arg.Name)
+		if field < 0 {
+			return nil, i.runtimeError(arg, "type %s has no field %s", typ.Name, arg.Name)
+		}
+		if field < len(args) {
+			return nil, i.runtimeError(arg, "argument %s is already passed by position", arg.Name)
+		}
+		rec.values[field] = named[idx]
+	}
+	return rec, nil
+}
+
+func (i *Interpreter) evaluateMemberExpr(expr *MemberExpr) (interface{}, error) {
+	target, err := i.evaluateExpression(expr.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, idx, err := i.recordField(expr, target, expr.Field)
+	if err != nil {
+		return nil, err
+	}
+	return rec.values[idx], nil
+}
+
+// recordField checks that value is a record with the field and returns the
+// field's index
+func (i *Interpreter) recordField(node Node, value interface{}, field string) (*Record, int, error) {
+	rec, ok := value.(*Record)
+	if !ok {
+		return nil, 0, i.runtimeError(node, "cannot access field %s of %s", field, functions.TypeName(value))
+	}
+	idx := rec.fieldIndex(field)
+	if idx < 0 {
+		return nil, 0, i.runtimeError(node, "type %s has no field %s", rec.typ.Name, field)
+	}
+	return rec, idx, nil
+}
+
+// fieldTarget returns accessors for the record field an assignment writes
+// to, after any element indices: a(i).pos.x
+func (i *Interpreter) fieldTarget(stmt *AssignStatement) (get func() (interface{}, error), set func(interface{}) error, err error) {
+	value, err := i.getVariable(i.ident(stmt.Name))
+	if err != nil {
+		return nil, nil, i.runtimeError(stmt, "%v", err)
+	}
+
+	if len(stmt.Indices) > 0 {
+		indices, err := i.evaluateAll(stmt.Indices)
+		if err != nil {
+			return nil, nil, err
+		}
+		value, err = i.indexValue(stmt, stmt.Name, value, indices)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	last := len(stmt.Fields) - 1
+	for _, field := range stmt.Fields[:last] {
+		rec, idx, err := i.recordField(stmt, value, field)
+		if err != nil {
+			return nil, nil, err
+		}
+		value = rec.values[idx]
+	}
+
+	rec, idx, err := i.recordField(stmt, value, stmt.Fields[last])
+	if err != nil {
+		return nil, nil, err
+	}
+	get = func() (interface{}, error) { return rec.values[idx], nil }
+	set = func(value interface{}) error {
+		if err := i.checkStore(stmt, stmt.Name, value, at(rec, idx), false); err != nil {
+			return err
+		}
+		rec.values[idx] = value
+		return nil
+	}
+	return get, set, nil
+}
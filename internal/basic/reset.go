@@ -0,0 +1,51 @@
+package basic
+
+// ResetOptions selects what Reset clears besides the script state
+type ResetOptions struct {
+	ClearCache     bool // Start with an empty AST cache
+	ClearFunctions bool // Drop the registered host functions, keeping only the core functions
+}
+
+// Reset returns the interpreter to the state of a new one with the same
+// settings: variables, constants, script functions and record types, the ON
+// ERROR handler, imported modules and warnings are dropped. The AST cache
+// and registered host functions are kept unless opts asks to clear them. An
+// interpreter made with Spawn gets a cache or function table of its own
+// rather than clearing the shared one.
+func (i *Interpreter) Reset(opts ResetOptions) {
+	i.globalScope = make(map[string]interface{})
+	i.constants = make(map[string]bool)
+	i.userFuncs = make(map[string]*FunctionStatement)
+	i.types = make(map[string]*TypeStatement)
+	i.scopes = []map[string]interface{}{i.globalScope}
+	i.callerScopes = nil
+	i.callStack = nil
+	i.frames = nil
+	i.warnings = nil
+
+	i.resetInterrupt()
+	i.iterationCount = 0
+	i.statementCount = 0
+	i.usage = memoryUsage{}
+	i.exprDepth = 0
+	i.breakFlag = false
+	i.exiting = nil
+	i.gotoLabel = ""
+	i.hookErr = nil
+	i.errorHandler = ""
+	i.tryDepth = 0
+	i.inErrorHandler = false
+	i.returnFlag = false
+	i.returnValue = nil
+	i.modules = nil
+	i.explicit = false
+
+	if opts.ClearCache {
+		i.astCache = newProgramCache(i.astCache.maxSize)
+	}
+	if opts.ClearFunctions {
+		i.externalFuncs = coreFunctions()
+		i.boundFuncs = make(map[string]BoundFunc)
+		i.externalDocs = make(map[string]FunctionInfo)
+	}
+}
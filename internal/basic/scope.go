@@ -0,0 +1,210 @@
+package basic
+
+import "strings"
+
+// ScopeMode selects where a plain assignment inside a function stores a
+// variable that the function hasn't declared itself
+type ScopeMode int
+
+const (
+	ScopeDynamic ScopeMode = iota // Update the innermost visible scope holding the name: the function's own, an enclosing function's or the global one (default)
+	ScopeLocal                    // Keep the assignment local unless the name is declared GLOBAL
+)
+
+// callFrame is the variable state of one running script function
+type callFrame struct {
+	base    int             // Index in scopes of the function's parameter scope
+	globals map[string]bool // Names declared GLOBAL so far
+}
+
+// closure is a function defined inside another function, bound to the
+// scopes that were visible where it was defined
+type closure struct {
+	fn     *FunctionStatement
+	env    []map[string]interface{}
+	module string // Name of the module exporting it, if any
+}
+
+func (c *closure) String() string {
+	return "function " + c.fn.Name
+}
+
+func (c *closure) TypeName() string {
+	return "function"
+}
+
+// SetScopeMode sets how assignments inside functions are resolved. Reads
+// are unaffected: a function can always see global variables.
+func (i *Interpreter) SetScopeMode(mode ScopeMode) {
+	i.scopeMode = mode
+}
+
+// SetCaseSensitive makes identifiers case-sensitive, so Player and player
+// are different variables, functions, types, fields and labels. Keywords and
+// the built-in parameter types stay case-insensitive. It applies to names
+// registered or set afterwards, so set it before registering functions; the
+// AST cache is cleared, since programs are checked according to the mode.
+func (i *Interpreter) SetCaseSensitive(sensitive bool) {
+	if i.caseSensitive != sensitive {
+		i.caseSensitive = sensitive
+		i.ClearCache()
+	}
+}
+
+// ident returns the key an identifier is stored under: lowercased, unless
+// identifiers are case-sensitive
+func (i *Interpreter) ident(name string) string {
+	if i.caseSensitive {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// sameIdent reports whether two identifiers name the same thing
+func (i *Interpreter) sameIdent(a, b string) bool {
+	if i.caseSensitive {
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
+
+// SetStrictVariables makes every script behave as if it began with OPTION
+// EXPLICIT: assigning to a variable that was never declared is an error.
+func (i *Interpreter) SetStrictVariables(strict bool) {
+	i.strictVars = strict
+}
+
+// requiresDeclarations reports whether a program runs with OPTION EXPLICIT
+func (i *Interpreter) requiresDeclarations(statements []Statement) bool {
+	if i.strictVars {
+		return true
+	}
+	for _, stmt := range statements {
+		if _, ok := stmt.(*OptionStatement); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeclared reports whether a plain assignment to name updates an existing
+// variable. LET, LOCAL, DIM, CONST, FOR, GLOBAL and parameters declare names.
+func (i *Interpreter) isDeclared(name string) bool {
+	if i.isGlobalName(name) {
+		return true
+	}
+	_, err := i.getVariable(name)
+	return err == nil
+}
+
+// currentFrame returns the innermost running function, or nil at the top level
+func (i *Interpreter) currentFrame() *callFrame {
+	if len(i.frames) == 0 {
+		return nil
+	}
+	return &i.frames[len(i.frames)-1]
+}
+
+// pushFrame starts a function call whose parameter scope is the current scope
+func (i *Interpreter) pushFrame() {
+	i.frames = append(i.frames, callFrame{base: len(i.scopes) - 1})
+}
+
+func (i *Interpreter) popFrame() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
+
+// enterFunction replaces the scopes with the function's definition
+// environment plus a fresh scope for its parameters and locals, so the body
+// sees the variables visible where it was defined rather than those of its
+// caller. The caller's scopes are kept in callerScopes, for the memory limits,
+// and the returned function restores them.
+func (i *Interpreter) enterFunction(env []map[string]interface{}) func() {
+	saved := i.scopes
+	i.callerScopes = append(i.callerScopes, saved)
+	i.scopes = append(env[:len(env):len(env)], make(map[string]interface{}))
+	return func() {
+		i.scopes = saved
+		i.callerScopes = i.callerScopes[:len(i.callerScopes)-1]
+	}
+}
+
+// defineNested binds the functions defined directly in a function body as
+// closures in the body's scope. They are bound before the body runs, so they
+// can call each other and themselves regardless of order.
+func (i *Interpreter) defineNested(body []Statement) {
+	var env []map[string]interface{}
+	for _, stmt := range body {
+		fn, ok := stmt.(*FunctionStatement)
+		if !ok {
+			continue
+		}
+		if env == nil {
+			env = append([]map[string]interface{}(nil), i.scopes...)
+		}
+		i.currentScope()[i.ident(fn.Name)] = &closure{fn: fn, env: env}
+	}
+}
+
+// lookupClosure finds the nested function visible under name, if the
+// innermost variable with that name holds one
+func (i *Interpreter) lookupClosure(name string) *closure {
+	for idx := len(i.scopes) - 1; idx >= 0; idx-- {
+		if value, ok := i.scopes[idx][name]; ok {
+			c, _ := value.(*closure)
+			return c
+		}
+	}
+	return nil
+}
+
+// isGlobalName reports whether the running function declared name GLOBAL
+func (i *Interpreter) isGlobalName(name string) bool {
+	frame := i.currentFrame()
+	return frame != nil && frame.globals[name]
+}
+
+// executeGlobalStatement binds the names to the global scope for the rest of
+// the function call
+func (i *Interpreter) executeGlobalStatement(stmt *GlobalStatement) error {
+	frame := i.currentFrame()
+	if frame == nil {
+		// The analyzer only allows GLOBAL inside functions
+		return nil
+	}
+	if frame.globals == nil {
+		frame.globals = make(map[string]bool)
+	}
+	for _, name := range stmt.Names {
+		frame.globals[i.ident(name)] = true
+	}
+	return nil
+}
+
+// executeLocalStatement declares a variable in the function's own scope, so
+// it lasts for the rest of the call even when declared inside a loop, and
+// hides any global or enclosing variable with the same name
+func (i *Interpreter) executeLocalStatement(stmt *LocalStatement) error {
+	if err := i.checkAssignable(stmt, stmt.Name); err != nil {
+		return err
+	}
+
+	value, err := i.evaluateExpression(stmt.Value)
+	if err != nil {
+		return err
+	}
+
+	scope := i.currentScope()
+	if frame := i.currentFrame(); frame != nil {
+		scope = i.scopes[frame.base]
+	}
+	name := i.ident(stmt.Name)
+	if i.limits != (MemoryLimits{}) {
+		_, exists := scope[name]
+		if err := i.checkStore(stmt, stmt.Name, value, at(scope, name), !exists); err != nil {
+			return err
+		}
+	}
+	scope[name] = value
+	return nil
+}
@@ -0,0 +1,68 @@
+package basic
+
+import "reflect"
+
+// snapshot copies values with their arrays, maps and records, so the copy
+// doesn't change along with the original. A collection shared by several
+// values is copied once and stays shared in the copy, which also stops
+// collections that contain themselves from looping.
+type snapshot struct {
+	copies map[snapshotKey]interface{}
+}
+
+// snapshotKey identifies a collection by its address, and for arrays its
+// length, since slices of one backing array share an address
+type snapshotKey struct {
+	ptr    uintptr
+	length int
+}
+
+func newSnapshot() *snapshot {
+	return &snapshot{copies: make(map[snapshotKey]interface{})}
+}
+
+func (s *snapshot) copy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		if len(v) == 0 {
+			return []interface{}{}
+		}
+		key := snapshotKey{reflect.ValueOf(v).Pointer(), len(v)}
+		if copied, ok := s.copies[key]; ok {
+			return copied
+		}
+		copied := make([]interface{}, len(v))
+		s.copies[key] = copied
+		for idx, elem := range v {
+			copied[idx] = s.copy(elem)
+		}
+		return copied
+
+	case map[string]interface{}:
+		key := snapshotKey{reflect.ValueOf(v).Pointer(), -1}
+		if copied, ok := s.copies[key]; ok {
+			return copied
+		}
+		copied := make(map[string]interface{}, len(v))
+		s.copies[key] = copied
+		for name, elem := range v {
+			copied[name] = s.copy(elem)
+		}
+		return copied
+
+	case *Record:
+		key := snapshotKey{reflect.ValueOf(v).Pointer(), -1}
+		if copied, ok := s.copies[key]; ok {
+			return copied
+		}
+		copied := &Record{typ: v.typ, values: make([]interface{}, len(v.values)), caseSensitive: v.caseSensitive}
+		s.copies[key] = copied
+		for idx, elem := range v.values {
+			copied.values[idx] = s.copy(elem)
+		}
+		return copied
+
+	default:
+		return value
+	}
+}
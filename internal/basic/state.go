@@ -0,0 +1,214 @@
+package basic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// StateError reports a global variable MarshalState can't encode, because it
+// holds a value other than a finite number, string, bool, NULL, array or map,
+// or an array or map that contains itself
+type StateError struct {
+	Path  string // The variable, with the indices leading to the value, e.g. stats("hp")
+	Type  string // The type name of the value, see functions.TypeName
+	Cycle bool   // The value is an array or map that contains itself
+}
+
+func (e *StateError) Error() string {
+	if e.Cycle {
+		return fmt.Sprintf("cannot serialize %s: the %s contains itself", e.Path, e.Type)
+	}
+	return fmt.Sprintf("cannot serialize %s: %s values aren't supported", e.Path, e.Type)
+}
+
+// MarshalState encodes the global variables as JSON, with keys in sorted
+// order so the same state always gives the same bytes. Floats are written
+// with a decimal point or exponent, so UnmarshalState tells them from ints.
+// Imported modules are left out; any other unsupported value fails with a
+// *StateError.
+func (i *Interpreter) MarshalState() ([]byte, error) {
+	names := make([]string, 0, len(i.globalScope))
+	for name, value := range i.globalScope {
+		if _, ok := value.(*closure); ok {
+			continue // An imported function
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	enc := &stateEncoder{buf: &buf, visiting: make(map[snapshotKey]bool)}
+	buf.WriteByte('{')
+	for idx, name := range names {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+		enc.writeString(name)
+		buf.WriteByte(':')
+		if err := enc.encode(i.globalScope[name], name, nil); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalState replaces the global variables with the JSON produced by
+// MarshalState, as SetGlobals does
+func (i *Interpreter) UnmarshalState(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return fmt.Errorf("invalid state: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid state: unexpected data after the object")
+	}
+
+	vars := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		vars[name] = decodeState(value)
+	}
+	return i.SetGlobals(vars)
+}
+
+// stateEncoder writes script values as JSON
+type stateEncoder struct {
+	buf      *bytes.Buffer
+	visiting map[snapshotKey]bool // Arrays and maps being written, to catch cycles
+}
+
+// encode writes value, which is found by following keys from the variable name
+func (e *stateEncoder) encode(value interface{}, name string, keys []string) error {
+	switch v := value.(type) {
+	case nil:
+		e.buf.WriteString("null")
+	case bool:
+		e.buf.WriteString(strconv.FormatBool(v))
+	case int:
+		e.buf.WriteString(strconv.Itoa(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			err := stateError(name, keys, v)
+			err.Type = strconv.FormatFloat(v, 'g', -1, 64) // NaN, +Inf or -Inf
+			return err
+		}
+		text := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+		e.buf.WriteString(text)
+	case string:
+		e.writeString(v)
+
+	case []interface{}:
+		key := snapshotKey{reflect.ValueOf(v).Pointer(), len(v)}
+		if e.visiting[key] && len(v) > 0 {
+			return cycleError(name, keys, v)
+		}
+		e.visiting[key] = true
+		defer delete(e.visiting, key)
+
+		e.buf.WriteByte('[')
+		for idx, elem := range v {
+			if idx > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.encode(elem, name, append(keys, strconv.Itoa(idx))); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+
+	case map[string]interface{}:
+		key := snapshotKey{reflect.ValueOf(v).Pointer(), -1}
+		if e.visiting[key] {
+			return cycleError(name, keys, v)
+		}
+		e.visiting[key] = true
+		defer delete(e.visiting, key)
+
+		mapKeys := make([]string, 0, len(v))
+		for k := range v {
+			mapKeys = append(mapKeys, k)
+		}
+		sort.Strings(mapKeys)
+
+		e.buf.WriteByte('{')
+		for idx, k := range mapKeys {
+			if idx > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.writeString(k)
+			e.buf.WriteByte(':')
+			if err := e.encode(v[k], name, append(keys, strconv.Quote(k))); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+
+	default:
+		return stateError(name, keys, v)
+	}
+	return nil
+}
+
+func (e *stateEncoder) writeString(s string) {
+	encoded, _ := json.Marshal(s) // Can't fail for a string
+	e.buf.Write(encoded)
+}
+
+// stateError builds the StateError for the value at keys within the variable
+func stateError(name string, keys []string, value interface{}) *StateError {
+	path := name
+	if len(keys) > 0 {
+		path += "(" + strings.Join(keys, ", ") + ")"
+	}
+	return &StateError{Path: path, Type: functions.TypeName(value)}
+}
+
+// cycleError builds the StateError for an array or map that contains itself
+func cycleError(name string, keys []string, value interface{}) error {
+	err := stateError(name, keys, value)
+	err.Cycle = true
+	return err
+}
+
+// decodeState converts a value decoded with UseNumber to a script value.
+// Numbers without a decimal point or exponent become ints if they fit.
+func decodeState(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		text := v.String()
+		if !strings.ContainsAny(text, ".eE") {
+			if n, err := strconv.Atoi(text); err == nil {
+				return n
+			}
+		}
+		f, _ := strconv.ParseFloat(text, 64)
+		return f
+	case []interface{}:
+		for idx, elem := range v {
+			v[idx] = decodeState(elem)
+		}
+		return v
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = decodeState(elem)
+		}
+		return v
+	default:
+		return value
+	}
+}
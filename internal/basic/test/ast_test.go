@@ -110,8 +110,8 @@ func TestIfStatement(t *testing.T) {
 		},
 		ThenBlock: []basic.Statement{
 			&basic.PrintStatement{
-				Pos:   basic.Pos{Line: 2, Column: 5},
-				Value: &basic.StringLiteral{Pos: basic.Pos{Line: 2, Column: 11}, Value: "big"},
+				Pos:    basic.Pos{Line: 2, Column: 5},
+				Values: []basic.Expression{&basic.StringLiteral{Pos: basic.Pos{Line: 2, Column: 11}, Value: "big"}},
 			},
 		},
 		ElseIfClauses: []basic.ElseIfClause{
@@ -125,16 +125,16 @@ func TestIfStatement(t *testing.T) {
 				},
 				Block: []basic.Statement{
 					&basic.PrintStatement{
-						Pos:   basic.Pos{Line: 4, Column: 5},
-						Value: &basic.StringLiteral{Pos: basic.Pos{Line: 4, Column: 11}, Value: "negative"},
+						Pos:    basic.Pos{Line: 4, Column: 5},
+						Values: []basic.Expression{&basic.StringLiteral{Pos: basic.Pos{Line: 4, Column: 11}, Value: "negative"}},
 					},
 				},
 			},
 		},
 		ElseBlock: []basic.Statement{
 			&basic.PrintStatement{
-				Pos:   basic.Pos{Line: 6, Column: 5},
-				Value: &basic.StringLiteral{Pos: basic.Pos{Line: 6, Column: 11}, Value: "small"},
+				Pos:    basic.Pos{Line: 6, Column: 5},
+				Values: []basic.Expression{&basic.StringLiteral{Pos: basic.Pos{Line: 6, Column: 11}, Value: "small"}},
 			},
 		},
 	}
@@ -158,8 +158,8 @@ func TestForStatement(t *testing.T) {
 		End:      &basic.IntLiteral{Pos: basic.Pos{Line: 1, Column: 14}, Value: 10},
 		Body: []basic.Statement{
 			&basic.PrintStatement{
-				Pos:   basic.Pos{Line: 2, Column: 5},
-				Value: &basic.Identifier{Pos: basic.Pos{Line: 2, Column: 11}, Name: "i"},
+				Pos:    basic.Pos{Line: 2, Column: 5},
+				Values: []basic.Expression{&basic.Identifier{Pos: basic.Pos{Line: 2, Column: 11}, Name: "i"}},
 			},
 		},
 	}
@@ -325,8 +325,8 @@ func TestProgram(t *testing.T) {
 				Value: &basic.IntLiteral{Pos: basic.Pos{Line: 1, Column: 9}, Value: 5},
 			},
 			&basic.PrintStatement{
-				Pos:   basic.Pos{Line: 2, Column: 1},
-				Value: &basic.Identifier{Pos: basic.Pos{Line: 2, Column: 7}, Name: "x"},
+				Pos:    basic.Pos{Line: 2, Column: 1},
+				Values: []basic.Expression{&basic.Identifier{Pos: basic.Pos{Line: 2, Column: 7}, Name: "x"}},
 			},
 		},
 	}
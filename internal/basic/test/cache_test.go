@@ -0,0 +1,299 @@
+package basic
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCachedProgramsListsEntries(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	if err := interp.Interpret(`print 1`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interp.Interpret(`print 2`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries := interp.CachedPrograms()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 cache entries, got %d", len(entries))
+	}
+	if entries[0].Hash != interp.CacheKey(`print 2`) {
+		t.Errorf("expected most recently used entry first, got %v", entries)
+	}
+	if entries[0].Size != len(`print 2`) {
+		t.Errorf("expected size %d, got %d", len(`print 2`), entries[0].Size)
+	}
+}
+
+func TestPinnedProgramsAreNotEvicted(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	pinned := `print "pinned"`
+	other := `print "other"`
+	for _, code := range []string{pinned, other} {
+		if err := interp.Interpret(code); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if !interp.PinProgram(interp.CacheKey(pinned)) {
+		t.Fatal("expected pin to succeed")
+	}
+	if interp.EvictProgram(interp.CacheKey(pinned)) {
+		t.Error("expected pinned program to resist eviction")
+	}
+
+	if removed := interp.ClearCache(); removed != 1 {
+		t.Errorf("expected 1 program cleared, got %d", removed)
+	}
+
+	entries := interp.CachedPrograms()
+	if len(entries) != 1 || !entries[0].Pinned {
+		t.Errorf("expected only the pinned program to remain, got %v", entries)
+	}
+
+	interp.UnpinProgram(interp.CacheKey(pinned))
+	if !interp.EvictProgram(interp.CacheKey(pinned)) {
+		t.Error("expected unpinned program to be evicted")
+	}
+	if len(interp.CachedPrograms()) != 0 {
+		t.Error("expected empty cache")
+	}
+}
+
+func TestEvalCacheKey(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	if _, err := interp.Evaluate("1 + 2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if interp.PinProgram(interp.CacheKey("1 + 2")) {
+		t.Error("expected evaluated code not to be cached under CacheKey")
+	}
+	if !interp.PinProgram(interp.EvalCacheKey("1 + 2")) {
+		t.Error("expected evaluated code to be cached under EvalCacheKey")
+	}
+}
+
+func TestPinUnknownProgram(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	if interp.PinProgram("missing") {
+		t.Error("expected pin of unknown hash to fail")
+	}
+	if interp.EvictProgram("missing") {
+		t.Error("expected evict of unknown hash to fail")
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	interp, output := newTestInterpreter()
+
+	result, err := interp.RunBenchmark("print 1", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Iterations != 3 || result.Uncached <= 0 || result.Cached <= 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(*output) != 6 {
+		t.Errorf("expected 6 runs, got %d", len(*output))
+	}
+	if len(interp.CachedPrograms()) != 1 {
+		t.Errorf("expected the code to be cached once, got %v", interp.CachedPrograms())
+	}
+
+	if _, err := interp.RunBenchmark("print 1", 0); err == nil {
+		t.Error("expected error for zero iterations")
+	}
+	if _, err := interp.RunBenchmark("let x = ", 1); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestSpawnSharesCacheAndFunctions(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetMaxIterations(5)
+	interp.RegisterFunction("twice", func(args ...interface{}) (interface{}, error) {
+		return args[0].(int) * 2, nil
+	})
+
+	entity := interp.Spawn()
+	code := "let hp = twice(21)\nprint hp"
+	if err := entity.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*output, []interface{}{42}) {
+		t.Errorf("expected [42], got %v", *output)
+	}
+
+	// The program parsed by the spawned interpreter is cached for both
+	entries := interp.CachedPrograms()
+	if len(entries) != 1 || entries[0].Hash != interp.CacheKey(code) {
+		t.Errorf("expected the program in the shared cache, got %v", entries)
+	}
+
+	// Variables and script functions are separate
+	if interp.HasVariable("hp") {
+		t.Error("expected hp to belong to the spawned interpreter only")
+	}
+	if err := interp.Load("function hit():\n    return 1\nendfunction"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entity.HasFunction("hit") {
+		t.Error("expected hit to belong to the original interpreter only")
+	}
+
+	// Functions registered later are shared, and settings were copied
+	interp.RegisterFunction("later", func(args ...interface{}) (interface{}, error) {
+		return "later", nil
+	})
+	if err := entity.Interpret("print later()"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := entity.Interpret("do\nloop"); err == nil {
+		t.Error("expected the copied iteration limit to stop the loop")
+	}
+}
+
+func TestExportAndImportCache(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	code := `type point
+    x
+    y
+endtype
+function greet(name, greeting = "hello"):
+    return greeting + " " + name
+endfunction
+let p = point(3, 4)
+let total = 0
+for n = 1 to 3
+    if n = 2 then
+        print "skip"
+    else
+        total += n
+    endif
+next
+try
+    throw "oops"
+catch err
+    print err
+endtry
+print greet("bob"), greet(name = "amy", greeting = "hi")
+print total, p.x + p.y, "abcdef"[1:3], iif(total > 3, "big", "small")`
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interp.Interpret(""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	interp.PinProgram(interp.CacheKey(code))
+
+	var buf bytes.Buffer
+	if err := interp.ExportCache(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fresh, output := newTestInterpreter()
+	if err := fresh.ImportCache(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries := fresh.CachedPrograms()
+	if len(entries) != 2 || entries[0].Hash != interp.CacheKey("") || entries[1].Hash != interp.CacheKey(code) {
+		t.Fatalf("expected both programs, most recent first, got %v", entries)
+	}
+	if !entries[1].Pinned || entries[1].Size != len(code) {
+		t.Errorf("expected the pinned flag and size to be kept, got %v", entries[1])
+	}
+
+	if err := fresh.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := fresh.Interpret(""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{"skip", "oops", "hello bob hi amy", "4 7 bc big"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+	if len(fresh.CachedPrograms()) != 2 {
+		t.Errorf("expected the imported programs to be used, got %v", fresh.CachedPrograms())
+	}
+}
+
+func TestImportCacheRejectsBadInput(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	if err := interp.ImportCache(strings.NewReader("not a cache file")); err == nil {
+		t.Error("expected an error for invalid input")
+	}
+	if len(interp.CachedPrograms()) != 0 {
+		t.Errorf("expected nothing to be imported, got %v", interp.CachedPrograms())
+	}
+}
+
+func TestCacheSizeEvictsLeastRecentlyUsed(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetCacheSize(2)
+
+	for _, code := range []string{`print 1`, `print 2`, `print 1`, `print 3`} {
+		if err := interp.Interpret(code); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	entries := interp.CachedPrograms()
+	if len(entries) != 2 || entries[0].Hash != interp.CacheKey(`print 3`) || entries[1].Hash != interp.CacheKey(`print 1`) {
+		t.Fatalf("expected print 2 to be evicted, got %v", entries)
+	}
+
+	// Pinned programs survive, and lowering the size evicts right away
+	interp.PinProgram(interp.CacheKey(`print 1`))
+	interp.SetCacheSize(1)
+	entries = interp.CachedPrograms()
+	if len(entries) != 1 || entries[0].Hash != interp.CacheKey(`print 1`) {
+		t.Fatalf("expected only the pinned program to remain, got %v", entries)
+	}
+	if err := interp.Interpret(`print 4`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries = interp.CachedPrograms(); len(entries) != 1 || !entries[0].Pinned {
+		t.Errorf("expected the new program to be evicted rather than the pinned one, got %v", entries)
+	}
+
+	interp.SetCacheSize(0)
+	if err := interp.Interpret(`print 5`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries = interp.CachedPrograms(); len(entries) != 2 {
+		t.Errorf("expected no limit, got %v", entries)
+	}
+}
+
+func TestCachingDisabled(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetCaching(false)
+
+	if err := interp.Interpret(`print 1`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, err := interp.Evaluate(`2 + 3`); err != nil || value != 5 {
+		t.Fatalf("expected 5, got %v (%v)", value, err)
+	}
+	if len(interp.CachedPrograms()) != 0 {
+		t.Errorf("expected nothing to be cached, got %v", interp.CachedPrograms())
+	}
+	if !reflect.DeepEqual(*output, []interface{}{1}) {
+		t.Errorf("expected [1], got %v", *output)
+	}
+
+	interp.SetCaching(true)
+	if err := interp.Interpret(`print 1`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(interp.CachedPrograms()) != 1 {
+		t.Errorf("expected the program to be cached again, got %v", interp.CachedPrograms())
+	}
+}
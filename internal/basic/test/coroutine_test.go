@@ -0,0 +1,133 @@
+package basic
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mechanical-lich/mechanical-basic/internal/basic"
+)
+
+func TestCoroutineYieldAndResume(t *testing.T) {
+	interp, output := newTestInterpreter()
+	if err := interp.Load(`
+let frames = 0
+function tick():
+    frames += 1
+endfunction
+function wait(n):
+    for i = 1 to n
+        yield "waiting"
+    next i
+endfunction
+function patrol(name):
+    print name + " starts"
+    wait(2)
+    let answer = yield "where next?"
+    print name + " goes " + answer
+    return frames
+endfunction
+`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	co, err := interp.StartCall("patrol", "guard")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var yielded []interface{}
+	for !co.Done() {
+		yielded = append(yielded, co.Value())
+		// The host can run other code while the coroutine is paused
+		if _, err := interp.Call("tick"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := co.Resume("north"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if expected := []interface{}{"waiting", "waiting", "where next?"}; !reflect.DeepEqual(yielded, expected) {
+		t.Errorf("expected yields %v, got %v", expected, yielded)
+	}
+	if expected := []interface{}{"guard starts", "guard goes north"}; !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected output %v, got %v", expected, *output)
+	}
+	if co.Value() != 3 {
+		t.Errorf("expected the function to see the globals the host changed, got %v", co.Value())
+	}
+	if _, err := co.Resume(nil); !errors.Is(err, basic.ErrCoroutineDone) {
+		t.Errorf("expected ErrCoroutineDone, got %v", err)
+	}
+}
+
+func TestCoroutineErrors(t *testing.T) {
+	interp, output := newTestInterpreter()
+	if err := interp.Interpret("yield 1"); err == nil || !strings.Contains(err.Error(), "YIELD outside a coroutine") {
+		t.Errorf("expected an error for YIELD outside a coroutine, got %v", err)
+	}
+
+	if err := interp.Load(`
+function guarded():
+    try
+        yield
+    catch e
+        print "caught " + e
+    endtry
+    print "after"
+endfunction
+function failing():
+    yield 1
+    throw "boom"
+endfunction
+`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	co, err := interp.StartCall("guarded")
+	if err != nil || co.Done() {
+		t.Fatalf("expected the coroutine to pause, got %v", err)
+	}
+	co.Close()
+	if !co.Done() || len(*output) != 0 {
+		t.Errorf("expected Close to end the coroutine without TRY catching it, got %v", *output)
+	}
+
+	co, _ = interp.StartCall("failing")
+	if _, err := co.Resume(nil); err == nil || !strings.Contains(err.Error(), "boom") || !co.Done() {
+		t.Errorf("expected the error thrown after resuming, got %v", err)
+	}
+
+	if co, err := interp.StartCall("missing"); err == nil || !co.Done() {
+		t.Errorf("expected an error for an undefined function, got %v", err)
+	}
+}
+
+func TestCoroutineBudgetPerResume(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetMaxStatements(20)
+	if err := interp.Load(`
+function work():
+    do
+        for i = 1 to 5
+            let x = i
+        next i
+        yield
+    loop
+endfunction
+`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	co, err := interp.StartCall("work")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer co.Close()
+	for frame := 0; frame < 10; frame++ {
+		if _, err := co.Resume(nil); err != nil {
+			t.Fatalf("frame %d: expected each resume to get a fresh budget, got %v", frame, err)
+		}
+	}
+}
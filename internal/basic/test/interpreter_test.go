@@ -1,11 +1,21 @@
 package basic
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	arraylib "github.com/mechanical-lich/mechanical-basic/internal/array_lib"
 	"github.com/mechanical-lich/mechanical-basic/internal/basic"
+	maplib "github.com/mechanical-lich/mechanical-basic/internal/map_lib"
+	mathlib "github.com/mechanical-lich/mechanical-basic/internal/math_lib"
+	stringlib "github.com/mechanical-lich/mechanical-basic/internal/string_lib"
 )
 
 func newTestInterpreter() (*basic.Interpreter, *[]interface{}) {
@@ -322,6 +332,81 @@ next i
 	}
 }
 
+func TestInterpretExit(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+function find(target):
+    let found = ""
+    for row = 0 to 9
+        for col = 0 to 9
+            if row * 10 + col = target then
+                found = row + ":" + col
+                exit for row
+            endif
+        next col
+    next row
+    return found
+endfunction
+
+sub report(n):
+    if n < 0 then exit sub
+    print "report " + n
+endsub
+
+function count_to(stop, limit):
+    let n = 1
+    do while n < limit
+        for step = 1 to 3
+            if n = stop then exit while
+            n++
+        next
+    loop
+    if n >= limit then exit function
+    return n
+endfunction
+
+print find(42)
+report(-1)
+report(2)
+print count_to(3, 10), isnull(count_to(20, 5))
+for i = 1 to 3
+    for j = 1 to 3
+        if j = 2 then exit for
+        print i * 10 + j
+    next
+next`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"4:2", "report 2", "3 true", 11, 21, 31}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestInterpretExitErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"exit for", "line 1, column 1: EXIT FOR is not inside a FOR loop"},
+		{"for i = 1 to 2\n    exit for j\nnext", "EXIT FOR j is not inside a FOR j loop"},
+		{"do\n    exit for\nloop", "EXIT FOR is not inside a FOR loop"},
+		{"for i = 1 to 2\n    exit do\nnext", "EXIT DO is not inside a DO loop"},
+		{"exit function", "EXIT FUNCTION is only allowed inside a FUNCTION"},
+		{"function f():\n    exit sub\nendfunction", "EXIT SUB cannot leave f; use EXIT FUNCTION"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
 func TestInterpretInfiniteLoopProtection(t *testing.T) {
 	interp, _ := newTestInterpreter()
 	interp.SetMaxIterations(100)
@@ -339,6 +424,83 @@ next i
 	}
 }
 
+func TestInterpretDoLoopUntilRunsOnce(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+let n = 10
+do
+    print n
+    n += 1
+loop until n > 5
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != 10 {
+		t.Errorf("expected the body to run once, got %v", *output)
+	}
+}
+
+func TestInterpretDoLoopVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected []interface{}
+	}{
+		{"loop while", "let n = 0\ndo\n    n += 1\n    print n\nloop while n < 3", []interface{}{1, 2, 3}},
+		{"do while", "let n = 5\ndo while n < 3\n    print n\nloop", nil},
+		{"do until", "let n = 0\ndo until n = 2\n    print n\n    n += 1\nloop", []interface{}{0, 1}},
+		{"break", "let n = 0\ndo\n    n += 1\n    if n = 3 then\n        break\n    endif\n    print n\nloop\nprint \"done\"", []interface{}{1, 2, "done"}},
+		{"body variable in condition", "do\n    let last = 1\n    print last\nloop until last = 1", []interface{}{1}},
+	}
+
+	for _, tt := range tests {
+		interp, output := newTestInterpreter()
+		if err := interp.Interpret(tt.code); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if fmt.Sprint(*output) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, *output)
+		}
+	}
+}
+
+func TestInterpretDoLoopReturn(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	result, err := interp.Evaluate(`
+function firstOver(limit):
+    let n = 1
+    do
+        n = n * 2
+        if n > limit then
+            return n
+        endif
+    loop
+endfunction
+firstOver(20)
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 32 {
+		t.Errorf("expected 32, got %v", result)
+	}
+}
+
+func TestInterpretDoLoopIterationLimit(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetMaxIterations(50)
+
+	err := interp.Interpret("let n = 0\ndo\n    n += 1\nloop until n < 0")
+	if err == nil || !strings.Contains(err.Error(), "maximum iterations exceeded (50)") {
+		t.Errorf("expected iteration limit error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "line 2, column 1") {
+		t.Errorf("expected error at the DO statement, got %v", err)
+	}
+}
+
 func TestInterpretFunction(t *testing.T) {
 	interp, output := newTestInterpreter()
 	err := interp.Interpret(`
@@ -526,6 +688,76 @@ func TestInterpretValidate(t *testing.T) {
 	}
 }
 
+func TestValidateAll(t *testing.T) {
+	interp := basic.NewInterpreter()
+	if diags := interp.ValidateAll("let x = 5"); len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+
+	diags := interp.ValidateAll(`let = 5
+const MAX = 1
+if MAX > 0 then
+    let y = (2
+    max = 2
+endif
+let z = * 3`)
+	expected := []struct {
+		line int
+		kind basic.ErrorKind
+	}{{1, basic.ErrorSyntax}, {4, basic.ErrorSyntax}, {5, basic.ErrorCheck}, {7, basic.ErrorSyntax}}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %v", len(expected), diags)
+	}
+	for idx, want := range expected {
+		d := diags[idx]
+		if d.Line != want.line || d.Kind != want.kind || d.Severity != basic.SeverityError {
+			t.Errorf("diagnostic %d: expected a %v error at line %d, got %v", idx, want.kind, want.line, d)
+		}
+	}
+
+	diags = interp.ValidateAll("let s = \"open\nlet = 1")
+	if len(diags) != 1 || diags[0].Line != 1 {
+		t.Errorf("expected the bad token to end the check, got %v", diags)
+	}
+}
+
+func TestValidateAllRecoversFromBadBlockHeaders(t *testing.T) {
+	interp := basic.NewInterpreter()
+	diags := interp.ValidateAll(`for i = 1 10
+    let a = (1
+next i
+function broken(x y)
+    return x
+endfunction
+if x > then
+    print 1
+endif
+if x then
+    print 1
+elseif then
+    print 2
+else
+    let b = * 2
+endif
+if x then print )
+type point
+    x y
+endtype
+do while
+loop
+let ok = 1
+let c = )`)
+
+	var lines []int
+	for _, d := range diags {
+		lines = append(lines, d.Line)
+	}
+	expected := []int{1, 2, 4, 7, 12, 15, 17, 19, 21, 24}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected errors at lines %v, got %v", expected, diags)
+	}
+}
+
 func TestInterpretBooleanLiterals(t *testing.T) {
 	interp, output := newTestInterpreter()
 	err := interp.Interpret(`
@@ -545,6 +777,80 @@ print y
 	}
 }
 
+func TestNull(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("lookup", func(args ...interface{}) (interface{}, error) {
+		if args[0] == "sword" {
+			return 10, nil
+		}
+		return nil, nil
+	})
+
+	err := interp.Interpret(`
+let missing = lookup("axe")
+print missing = null, missing <> nil, isnull(missing), isnull(lookup("sword"))
+print null = null, null = 0, null = "", 0 = null, null <> false
+if not missing then
+    print "falsy"
+endif
+let price = iif(isnull(missing), 0, missing)
+print price
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{"true false true false", "true false false false true", "falsy", 0}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+
+	for _, code := range []string{"print null < 1", "print 1 >= nil"} {
+		err := interp.Interpret(code)
+		if err == nil || !strings.Contains(err.Error(), "line 1, column 7: cannot order NULL") {
+			t.Errorf("%q: expected an ordering error, got %v", code, err)
+		}
+	}
+	if err := interp.Interpret("print isnull()"); err == nil || !strings.Contains(err.Error(), "isnull requires 1 argument") {
+		t.Errorf("expected an argument count error, got %v", err)
+	}
+}
+
+func TestTypeOf(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("loadout", func(args ...interface{}) (interface{}, error) {
+		return map[string]interface{}{"arrows": 12}, nil
+	})
+
+	err := interp.Interpret(`
+type Vec2
+    x
+    y
+endtype
+
+function outer():
+    function inner():
+        return 1
+    endfunction
+    return typeof(inner)
+endfunction
+
+dim arr(2)
+print typeof(1), typeof(1.5), typeof("a"), typeof(true), typeof(arr), typeof(loadout()), typeof(null)
+print typeof(Vec2()), outer()
+let inv = loadout()
+if typeof(inv) = "map" then
+    print inv("arrows")
+endif
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{"int float string bool array map null", "Vec2 function", 12}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
 func TestInterpretCaseInsensitiveVariables(t *testing.T) {
 	interp, output := newTestInterpreter()
 	err := interp.Interpret(`
@@ -853,6 +1159,51 @@ endfunction
 	}
 }
 
+func TestClone(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.RegisterFunction("roar", func(args ...interface{}) (interface{}, error) {
+		return "roar", nil
+	})
+	err := interp.Load(`
+let hp = 10
+type Claw
+    damage
+endtype
+function attack(target):
+    return roar() + " " + target + " " + Claw(3).damage
+endfunction
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clone := interp.Clone()
+	if clone.HasVariable("hp") {
+		t.Error("expected the clone to start without variables")
+	}
+	result, err := clone.Call("attack", "hero")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "roar hero 3" {
+		t.Errorf("expected \"roar hero 3\", got %v", result)
+	}
+
+	// Variables and functions registered afterwards are the clone's own
+	clone.RegisterFunction("whisper", func(args ...interface{}) (interface{}, error) {
+		return "psst", nil
+	})
+	if err := clone.Interpret("let hp = whisper()"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hp, _ := interp.Evaluate("hp"); hp != 10 {
+		t.Errorf("expected the original's hp to stay 10, got %v", hp)
+	}
+	if _, err := interp.Evaluate("whisper()"); err == nil {
+		t.Error("expected whisper to be undefined in the original")
+	}
+}
+
 func toFloat(v interface{}) float64 {
 	switch val := v.(type) {
 	case float64:
@@ -863,3 +1214,3819 @@ func toFloat(v interface{}) float64 {
 		return 0
 	}
 }
+
+// -----------------------------------------------------------------------------
+// Host Binding Tests
+// -----------------------------------------------------------------------------
+
+func TestInterpretWithVars(t *testing.T) {
+	interp, output := newTestInterpreter()
+
+	err := interp.InterpretWithVars(`
+let total = Damage * multiplier
+print total
+`, map[string]interface{}{"damage": 12, "Multiplier": 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != 36 {
+		t.Errorf("expected [36], got %v", *output)
+	}
+
+	globals := interp.Globals()
+	if globals["total"] != 36 {
+		t.Errorf("expected total=36 in globals, got %v", globals)
+	}
+	if globals["multiplier"] != 3 {
+		t.Errorf("expected seeded multiplier in globals, got %v", globals)
+	}
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	result, err := interp.EvaluateWithVars(`2 + damage * 3`, map[string]interface{}{"damage": 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 14 {
+		t.Errorf("expected 14, got %v", result)
+	}
+}
+
+func TestEvaluateLastExpressionWins(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	result, err := interp.Evaluate(`
+function double(n):
+    return n * 2
+endfunction
+
+let x = 5
+x + 1
+double(x)
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 10 {
+		t.Errorf("expected 10, got %v", result)
+	}
+}
+
+func TestEvaluateExpressionMustStartStatement(t *testing.T) {
+	for _, code := range []string{"print 1 2", "print 10 mod 0", "let x = 1 x"} {
+		interp, output := newTestInterpreter()
+		var parseErr *basic.ParseError
+		if _, err := interp.Evaluate(code); !errors.As(err, &parseErr) {
+			t.Errorf("%q: expected a parse error, got %v", code, err)
+		}
+		if len(*output) != 0 {
+			t.Errorf("%q: expected nothing printed, got %v", code, *output)
+		}
+	}
+
+	interp, output := newTestInterpreter()
+	result, err := interp.Evaluate("print 1: 2\nif 1 < 2 then 3 else 4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 2 || len(*output) != 1 {
+		t.Errorf("expected 2 after printing 1, got %v and %v", result, *output)
+	}
+}
+
+func TestEvaluateTopLevelReturn(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	result, err := interp.Evaluate(`
+let hp = 30
+if hp < 50 then
+    return "low"
+endif
+"ok"
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "low" {
+		t.Errorf("expected 'low', got %v", result)
+	}
+}
+
+func TestEvaluateSessionKeepsDefinitions(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	if _, err := interp.EvaluateSession("type Point\n    x\nendtype\nfunction double(n):\n    return n * 2\nendfunction"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := interp.EvaluateSession("double(Point(4).x)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 8 {
+		t.Errorf("expected 8, got %v", result)
+	}
+
+	if _, err := interp.Evaluate("double(1)"); err == nil {
+		t.Error("expected Evaluate to drop the session's functions")
+	}
+}
+
+func TestInterpretRejectsBareExpressions(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	if err := interp.Interpret(`2 + 3`); err == nil {
+		t.Error("expected error for bare expression outside eval mode")
+	}
+}
+
+func TestCallNamed(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	err := interp.Load(`
+function hit(x, forceX):
+    return x + forceX * 10
+endfunction
+`)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	result, err := interp.CallNamed("hit", map[string]interface{}{"forcex": 5, "X": 1, "extra": true})
+	if err != nil {
+		t.Fatalf("CallNamed error: %v", err)
+	}
+	if result != 51 {
+		t.Errorf("expected 51, got %v", result)
+	}
+
+	if _, err := interp.CallNamed("hit", map[string]interface{}{"x": 1}); err == nil {
+		t.Error("expected error for missing argument")
+	}
+
+	interp.SetNamedArgPolicy(basic.NamedArgPolicy{RejectExtra: true})
+	if _, err := interp.CallNamed("hit", map[string]interface{}{"x": 1, "forcex": 2, "extra": 3}); err == nil {
+		t.Error("expected error for extra argument")
+	}
+}
+
+func TestCallNamedAllowMissing(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetNamedArgPolicy(basic.NamedArgPolicy{AllowMissing: true})
+
+	err := interp.Load(`
+function describe(name, title):
+    if not title then
+        return name
+    endif
+    return title + " " + name
+endfunction
+`)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	result, err := interp.CallNamed("describe", map[string]interface{}{"name": "Ada"})
+	if err != nil {
+		t.Fatalf("CallNamed error: %v", err)
+	}
+	if result != "Ada" {
+		t.Errorf("expected 'Ada', got %v", result)
+	}
+}
+
+func TestDefaultParams(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Load(`
+let bonus = 5
+
+function attack(target, damage = 1, crit = damage * 2 + bonus):
+    return target + ":" + damage + ":" + crit
+endfunction
+
+sub setBonus(value):
+    bonus = value
+endsub
+
+print attack("orc")
+print attack("orc", 3)
+print attack("orc", 3, 0)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"orc:1:7", "orc:3:11", "orc:3:0"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+
+	// Defaults are evaluated on each call, after the globals change
+	if _, err := interp.Call("setBonus", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := interp.Call("attack", "elf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "elf:1:102" {
+		t.Errorf("expected elf:1:102, got %v", result)
+	}
+
+	result, err = interp.CallNamed("attack", map[string]interface{}{"target": "imp", "crit": 9})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "imp:1:9" {
+		t.Errorf("expected imp:1:9, got %v", result)
+	}
+
+	for _, args := range [][]interface{}{{}, {"a", 1, 2, 3}} {
+		_, err := interp.Call("attack", args...)
+		if err == nil || !strings.Contains(err.Error(), "function attack expects 1 to 3 arguments") {
+			t.Errorf("expected arity error for %d arguments, got %v", len(args), err)
+		}
+	}
+	if err := interp.Interpret("function f(a = 1):\n    return a\nendfunction\nprint f(1, 2)"); err == nil ||
+		!strings.Contains(err.Error(), "function f expects 0 to 1 arguments, got 2") {
+		t.Errorf("expected arity error, got %v", err)
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("host", func(args ...interface{}) (interface{}, error) {
+		return len(args), nil
+	})
+	err := interp.Load(`
+function spawn(x, y = 0, kind = "slime", hp = 10):
+    return kind + "@" + x + "," + y + ":" + hp
+endfunction
+
+function squad(size):
+    function member(idx, kind = "orc"):
+        return kind + idx
+    endfunction
+    return member(kind = "elf", idx = size)
+endfunction
+
+print spawn(x = 10, y = 20, kind = "orc")
+print spawn(1, hp = 3)
+print spawn(kind = "bat", x = 2)
+print squad(4)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"orc@10,20:10", "slime@1,0:3", "bat@2,0:10", "elf4"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"print spawn(x = 1, speed = 2)", "line 4, column 20: function spawn has no parameter speed"},
+		{"print spawn(1, x = 2)", "argument x is already passed by position"},
+		{"print spawn(y = 2)", "function spawn: missing argument x"},
+		{"print spawn(1, 2, 3, 4, 5, kind = 1)", "function spawn expects 1 to 4 arguments, got 6"},
+		{"print host(x = 1)", "line 4, column 12: host does not take named arguments"},
+	}
+	for _, tt := range tests {
+		err := interp.Interpret("function spawn(x, y = 0, kind = \"slime\", hp = 10):\n    return x\nendfunction\n" + tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+func TestTypedParams(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("prices", func(args ...interface{}) (interface{}, error) {
+		return map[string]interface{}{"x": 1}, nil
+	})
+	err := interp.Load(`
+type Vec2
+    x
+    y
+endtype
+
+function describe(count AS INTEGER, ratio AS FLOAT, size AS NUMBER, name AS STRING, alive AS BOOLEAN):
+    return typeof(count) + " " + typeof(ratio) + " " + typeof(size) + " " + name + " " + alive
+endfunction
+
+function length(v AS Vec2, scale as float = 1):
+    return (v.x + v.y) * scale
+endfunction
+
+function total(items AS ARRAY, costs AS MAP):
+    return items(1) + costs("x")
+endfunction
+
+print describe(3.0, 2, 1.5, "orc", true)
+print length(Vec2(1, 2), scale = 2)
+dim counts(2)
+counts(1) = 2
+print total(counts, prices())
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{"int float float orc true", 6.0, 3}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+
+	// The host's arguments are converted and checked too
+	if result, err := interp.Call("describe", int64(4), float32(0.5), 7, "imp", false); err != nil || result != "int float int imp false" {
+		t.Errorf("expected converted arguments, got %v, %v", result, err)
+	}
+	_, err = interp.Call("describe", 1.5, 1, 1, "imp", false)
+	if err == nil || err.Error() != "function describe: parameter count must be INTEGER, got float" {
+		t.Errorf("expected a type error, got %v", err)
+	}
+	_, err = interp.CallNamed("length", map[string]interface{}{"v": map[string]interface{}{}})
+	if err == nil || !strings.Contains(err.Error(), "parameter v must be Vec2, got map") {
+		t.Errorf("expected a record type error, got %v", err)
+	}
+}
+
+func TestTypedParamErrors(t *testing.T) {
+	funcs := "function f(n AS INTEGER, s AS STRING = \"\"):\n    return n\nendfunction\n"
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		// Literal arguments are checked when the script is loaded
+		{`print f("3")`, "line 4, column 9: function f: parameter n must be INTEGER, got string"},
+		{"f(1, s = 2)", "line 4, column 10: function f: parameter s must be STRING, got int"},
+		{"print f(null)", "parameter n must be INTEGER, got null"},
+		{"function g(x AS Widget):\nendfunction", "line 4, column 1: parameter x of g has unknown type Widget"},
+		{"function g(x AS INTEGER = 0.5):\nendfunction", "line 4, column 27: function g: parameter x must be INTEGER, got float"},
+		// Other arguments when the call runs
+		{"let x = 2.5\nprint f(x)", "runtime error at line 5, column 7: function f: parameter n must be INTEGER, got float"},
+		{"let x = 1\nprint f(x, x)", "runtime error at line 5, column 7: function f: parameter s must be STRING, got int"},
+	}
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		err := interp.Interpret(funcs + tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+func TestHasVariableAndVarType(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	err := interp.Load(`
+let SpawnRate = 1.5
+let name = "goblin"
+
+function update():
+    let temp = 1
+endfunction
+`)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if !interp.HasVariable("spawnrate") {
+		t.Error("expected spawnrate to exist")
+	}
+	if interp.HasVariable("temp") {
+		t.Error("function locals should not be visible")
+	}
+
+	tests := map[string]string{"SpawnRate": "float", "name": "string"}
+	for name, expected := range tests {
+		typ, ok := interp.VarType(name)
+		if !ok || typ != expected {
+			t.Errorf("VarType(%s): expected %s, got %s (%v)", name, expected, typ, ok)
+		}
+	}
+
+	if _, ok := interp.VarType("missing"); ok {
+		t.Error("expected missing variable to report false")
+	}
+}
+
+func TestGetAndSetVariable(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetResultPolicy(basic.ResultInt64)
+
+	err := interp.Load(`
+const MAX_HP = 20
+let hp = 10
+
+function heal(amount):
+    hp = hp + amount + bonus
+    print hp
+endfunction
+`)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	if value, ok := interp.GetVariable("HP"); !ok || value != int64(10) {
+		t.Errorf("expected hp to be 10, got %v (%v)", value, ok)
+	}
+	if _, ok := interp.GetVariable("missing"); ok {
+		t.Error("expected missing variable to report false")
+	}
+
+	if err := interp.SetVariable("bonus", int32(5)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interp.SetVariable("hp", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := interp.Call("heal", 2); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if !reflect.DeepEqual(*output, []interface{}{8}) {
+		t.Errorf("expected [8], got %v", *output)
+	}
+	if value, _ := interp.GetVariable("hp"); value != int64(8) {
+		t.Errorf("expected hp to be 8, got %v", value)
+	}
+
+	if err := interp.SetVariable("max_hp", 99); err == nil || err.Error() != "cannot assign to constant max_hp" {
+		t.Errorf("expected constant error, got %v", err)
+	}
+}
+
+func TestGlobalsSnapshotAndRestore(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Load(`
+const SPEED = 3
+type Vec2
+    x
+    y
+endtype
+dim inventory(2)
+inventory(0) = "sword"
+let pos = Vec2(1, 2)
+let hp = 10
+
+function step():
+    hp -= 1
+    pos.x += SPEED
+    inventory(1) = "shield"
+    print hp + " " + pos.x + " " + inventory(1)
+endfunction
+`)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	saved := interp.Globals()
+	if _, err := interp.Call("step"); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+
+	// The snapshot didn't change with the script's state
+	if saved["hp"] != 10 || !reflect.DeepEqual(saved["inventory"], []interface{}{"sword", 0}) {
+		t.Errorf("expected the snapshot to keep the saved state, got %v", saved)
+	}
+	if x, _ := saved["pos"].(*basic.Record).Field("x"); x != 1 {
+		t.Errorf("expected the saved pos.x to be 1, got %v", x)
+	}
+
+	interp.SetVariable("temp", 1)
+	saved["speed"] = 100
+	interp.SetGlobals(saved)
+	if interp.HasVariable("temp") {
+		t.Error("expected SetGlobals to remove variables missing from the snapshot")
+	}
+	for run := 0; run < 2; run++ {
+		if _, err := interp.Call("step"); err != nil {
+			t.Fatalf("Call error: %v", err)
+		}
+	}
+
+	expected := []interface{}{"9 4 shield", "9 4 shield", "8 7 shield"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+	if saved["hp"] != 10 {
+		t.Errorf("expected restoring not to tie the snapshot to the script, got hp %v", saved["hp"])
+	}
+}
+
+func TestGlobalsSelfReference(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	if err := interp.Interpret("dim ring(2)\nring(0) = ring\nlet alias = ring"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	globals := interp.Globals()
+	arr := globals["ring"].([]interface{})
+	if inner, ok := arr[0].([]interface{}); !ok || &inner[0] != &arr[0] {
+		t.Error("expected the copy to refer to itself like the original")
+	}
+	if alias := globals["alias"].([]interface{}); &alias[0] != &arr[0] {
+		t.Error("expected variables sharing an array to share its copy")
+	}
+}
+
+func TestMarshalState(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("map", maplib.Map)
+	err := interp.Load(`
+let name = "gob\"lin"
+let hp = 12
+let speed = 2.0
+let alive = true
+let target = null
+dim bag(2)
+bag(0) = 1.5
+let stats = map("str", 3, "dex", map("base", 4))
+function show():
+    print name + " " + hp + " " + speed + " " + bag(0) + " " + stats("dex", "base") + " " + typeof(speed)
+endfunction
+`)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	data, err := interp.MarshalState()
+	if err != nil {
+		t.Fatalf("MarshalState error: %v", err)
+	}
+	expected := `{"alive":true,"bag":[1.5,0],"hp":12,"name":"gob\"lin","speed":2.0,"stats":{"dex":{"base":4},"str":3},"target":null}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	interp.SetVariable("hp", 1)
+	if err := interp.UnmarshalState(data); err != nil {
+		t.Fatalf("UnmarshalState error: %v", err)
+	}
+	if _, err := interp.Call("show"); err != nil {
+		t.Fatalf("Call error: %v", err)
+	}
+	if !reflect.DeepEqual(*output, []interface{}{"gob\"lin 12 2 1.5 4 float"}) {
+		t.Errorf("unexpected output %v", *output)
+	}
+
+	again, err := interp.MarshalState()
+	if err != nil || string(again) != expected {
+		t.Errorf("expected a round trip to give the same JSON, got %s (%v)", again, err)
+	}
+
+	for _, bad := range []string{`[1]`, `{"hp": }`, `{"hp": 1} {}`} {
+		if err := interp.UnmarshalState([]byte(bad)); err == nil || !strings.HasPrefix(err.Error(), "invalid state") {
+			t.Errorf("UnmarshalState(%s): expected invalid state error, got %v", bad, err)
+		}
+	}
+}
+
+func TestMarshalStateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		errMsg string
+	}{
+		{"record", "type Vec2\n    x\nendtype\ndim list(2)\nlist(1) = map(\"at\", Vec2(1))", `cannot serialize list(1, "at"): Vec2 values aren't supported`},
+		{"cycle", "dim ring(1)\nring(0) = ring", "cannot serialize ring(0): the array contains itself"},
+		{"infinity", "let big = huge()", "cannot serialize big: +Inf values aren't supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp, _ := newTestInterpreter()
+			interp.RegisterFunction("map", maplib.Map)
+			interp.RegisterFunction("huge", func(args ...interface{}) (interface{}, error) {
+				return math.Inf(1), nil
+			})
+			if err := interp.Interpret(tt.code); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err := interp.MarshalState()
+			var stateErr *basic.StateError
+			if !errors.As(err, &stateErr) {
+				t.Fatalf("expected StateError, got %v", err)
+			}
+			if err.Error() != tt.errMsg {
+				t.Errorf("expected %q, got %q", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("bonus", func(args ...interface{}) (interface{}, error) {
+		return 5, nil
+	})
+	level := `
+const GRAVITY = 10
+type Door
+    open
+endtype
+on error call recover
+let score = bonus()
+function recover(message, line):
+    print "recovered"
+endfunction
+`
+	if err := interp.Load(level); err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+
+	interp.Reset(basic.ResetOptions{})
+	if interp.HasVariable("score") || interp.HasVariable("gravity") || interp.HasFunction("recover") {
+		t.Error("expected the script state to be dropped")
+	}
+	if len(interp.CachedPrograms()) != 1 {
+		t.Errorf("expected the cache to be kept, got %v", interp.CachedPrograms())
+	}
+
+	// Constants, types and the error handler are gone, host functions stay
+	err := interp.Interpret("let gravity = 1\nlet door = Door(true)")
+	if err == nil || !strings.Contains(err.Error(), "undefined function: Door") {
+		t.Errorf("expected Door to be undefined, got %v", err)
+	}
+	if len(*output) != 0 {
+		t.Errorf("expected no error handler, got %v", *output)
+	}
+	if err := interp.Interpret("print bonus()"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	interp.Reset(basic.ResetOptions{ClearCache: true, ClearFunctions: true})
+	if len(interp.CachedPrograms()) != 0 {
+		t.Errorf("expected the cache to be cleared, got %v", interp.CachedPrograms())
+	}
+	if err := interp.Interpret("print bonus()"); err == nil {
+		t.Error("expected bonus to be dropped")
+	}
+	if err := interp.Interpret("print typeof(1)"); err != nil {
+		t.Errorf("expected the core functions to stay, got %v", err)
+	}
+}
+
+func TestInvokeScriptFunctionFromBuiltin(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("count_if", arraylib.CountIf(interp.Invoke))
+	interp.RegisterFunction("values", func(args ...interface{}) (interface{}, error) {
+		return []interface{}{1, 2, 3, 4, 5, 6}, nil
+	})
+
+	err := interp.Interpret(`
+function isEven(n):
+    return n - (n / 2) * 2 = 0
+endfunction
+
+print count_if(values(), "isEven")
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != 3 {
+		t.Errorf("expected [3], got %v", *output)
+	}
+}
+
+func TestSeedMakesRandomReproducible(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("rnd", mathlib.RndFrom(interp.Rand()))
+
+	code := `print rnd(1000)`
+	interp.Seed(99)
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	interp.Seed(99)
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*output) != 2 || (*output)[0] != (*output)[1] {
+		t.Errorf("expected two identical values, got %v", *output)
+	}
+}
+
+func TestUnregisterAndStrictRegistration(t *testing.T) {
+	interp, output := newTestInterpreter()
+	hit := func(args ...interface{}) (interface{}, error) { return "hit", nil }
+	miss := func(args ...interface{}) (interface{}, error) { return "miss", nil }
+
+	if err := interp.RegisterFunction("Attack", hit); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !interp.HasExternalFunction("attack") || interp.HasExternalFunction("defend") {
+		t.Error("expected only attack to be registered")
+	}
+
+	interp.SetStrictRegistration(true)
+	err := interp.RegisterFunction("attack", miss)
+	if !errors.Is(err, basic.ErrFunctionRegistered) {
+		t.Errorf("expected ErrFunctionRegistered, got %v", err)
+	}
+	err = interp.RegisterFunctions(map[string]basic.ExternalFunc{"defend": miss, "attack": miss})
+	if !errors.Is(err, basic.ErrFunctionRegistered) || interp.HasExternalFunction("defend") {
+		t.Errorf("expected no functions to be registered, got %v", err)
+	}
+	if err := interp.Interpret(`print attack()`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !interp.UnregisterFunction("attack") || interp.UnregisterFunction("attack") {
+		t.Error("expected attack to be removed once")
+	}
+	if err := interp.Interpret(`print attack()`); err == nil {
+		t.Error("expected an error calling an unregistered function")
+	}
+	if err := interp.RegisterFunction("attack", miss); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interp.Interpret(`print attack()`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*output, []interface{}{"hit", "miss"}) {
+		t.Errorf("expected [hit miss], got %v", *output)
+	}
+}
+
+func TestRegisterNamespace(t *testing.T) {
+	interp, output := newTestInterpreter()
+	spawn := func(args ...interface{}) (interface{}, error) { return "spawned " + args[0].(string), nil }
+	area := func(args ...interface{}) (interface{}, error) { return args[0].(int) * args[0].(int), nil }
+
+	if err := interp.RegisterNamespace("game", map[string]basic.ExternalFunc{"spawn": spawn}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interp.RegisterNamespace("geo", map[string]basic.ExternalFunc{"spawn": area}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := interp.Interpret(`type point
+    x
+    y
+endtype
+let p = point(2, 3)
+print Game.Spawn("orc")
+game.spawn("imp")
+print geo.spawn(p.x) + p.y`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*output, []interface{}{"spawned orc", 7}) {
+		t.Errorf("expected [spawned orc 7], got %v", *output)
+	}
+	if value, err := interp.Evaluate(`geo.spawn(4)`); err != nil || value != 16 {
+		t.Errorf("expected 16, got %v (%v)", value, err)
+	}
+	if interp.HasExternalFunction("spawn") || !interp.HasExternalFunction("game.spawn") {
+		t.Error("expected spawn to be registered only under its namespace")
+	}
+
+	if err := interp.Interpret(`print game.missing(1)`); err == nil || !strings.Contains(err.Error(), "game.missing") {
+		t.Errorf("expected an undefined function error naming game.missing, got %v", err)
+	}
+	for _, namespace := range []string{"", "a.b", "if", "9lives"} {
+		if err := interp.RegisterNamespace(namespace, nil); err == nil {
+			t.Errorf("%q: expected an invalid namespace error", namespace)
+		}
+	}
+	if err := interp.RegisterNamespace("game", map[string]basic.ExternalFunc{"two words": spawn}); err == nil {
+		t.Error("expected an invalid function name error")
+	}
+}
+
+func TestHelpAndListFunctions(t *testing.T) {
+	interp, output := newTestInterpreter()
+	pow := func(args ...interface{}) (interface{}, error) { return 0, nil }
+	interp.RegisterFunction("pow", pow)
+	interp.RegisterFunction("spawn", pow)
+	interp.RegisterFunction("help", interp.Help)
+	if !interp.DocumentFunction("POW", "pow(base, exponent)", "Raises base to exponent.") {
+		t.Fatal("expected pow to be documented")
+	}
+	if interp.DocumentFunction("missing", "missing()", "") {
+		t.Error("expected documenting an unregistered function to fail")
+	}
+
+	err := interp.Interpret(`## Deals damage after armor
+function hit(base, armor AS integer)
+  return base - armor
+endfunction
+help("pow")
+help("spawn")
+help("hit")
+help()`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{
+		"pow(base, exponent)", "Raises base to exponent.",
+		"spawn(...)", "No description.",
+		"hit(base, armor AS integer)", "Deals damage after armor",
+		"help, hit, isnull, pow, spawn, typeof",
+	}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+	if err := interp.Interpret(`help("nothing")`); err == nil || !strings.Contains(err.Error(), "unknown function nothing") {
+		t.Errorf("expected an unknown function error, got %v", err)
+	}
+
+	var names []string
+	for _, info := range interp.ListFunctions() {
+		names = append(names, info.Name)
+		if info.Name == "pow" && info.Signature != "pow(base, exponent)" {
+			t.Errorf("expected pow's signature, got %q", info.Signature)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"help", "isnull", "pow", "spawn", "typeof"}) {
+		t.Errorf("expected the sorted function names, got %v", names)
+	}
+
+	interp.RegisterFunction("pow", pow)
+	for _, info := range interp.ListFunctions() {
+		if info.Name == "pow" && info.Signature != "" {
+			t.Errorf("expected registering pow again to clear its documentation, got %q", info.Signature)
+		}
+	}
+}
+
+func TestRegisterConstant(t *testing.T) {
+	interp, output := newTestInterpreter()
+	if err := interp.RegisterConstant("PI", math.Pi); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interp.RegisterConstant("SCREEN_W", 640); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := interp.Interpret(`function half()
+  return screen_w / 2
+endfunction
+print half()
+print pi > 3`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*output, []interface{}{320, true}) {
+		t.Errorf("expected [320 true], got %v", *output)
+	}
+
+	for _, code := range []string{
+		"let pi = 3",
+		"screen_w = 800",
+		"const PI = 3",
+		"function f()\n  local pi = 3\nendfunction\nf()",
+	} {
+		if err := interp.Interpret(code); err == nil || !strings.Contains(err.Error(), "constant") {
+			t.Errorf("%q: expected a constant error, got %v", code, err)
+		}
+	}
+	if err := interp.SetVariable("pi", 3); err == nil {
+		t.Error("expected SetVariable to refuse a constant")
+	}
+
+	// Registered constants outlive the script and are shared with spawned interpreters
+	interp.Reset(basic.ResetOptions{})
+	if value, err := interp.Spawn().Evaluate("screen_w"); err != nil || value != 640 {
+		t.Errorf("expected 640, got %v (%v)", value, err)
+	}
+	if _, ok := interp.Globals()["pi"]; ok {
+		t.Error("expected registered constants to be left out of the globals")
+	}
+	if err := interp.RegisterConstant("two words", 1); err == nil {
+		t.Error("expected an invalid constant name error")
+	}
+}
+
+// =============================================================================
+// Depth Guard Tests
+// =============================================================================
+
+func TestMaxExpressionDepthParse(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetMaxExpressionDepth(20)
+
+	err := interp.Interpret("let x = " + strings.Repeat("(", 20) + "1" + strings.Repeat(")", 20))
+	if err == nil || !strings.Contains(err.Error(), "too complex") {
+		t.Errorf("expected 'too complex' error, got %v", err)
+	}
+
+	if err := interp.Interpret("let y = ((1 + 2) * 3)"); err != nil {
+		t.Errorf("unexpected error for shallow expression: %v", err)
+	}
+}
+
+func TestMaxExpressionDepthRecursion(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetMaxExpressionDepth(100)
+
+	code := `
+function down(n):
+    if n = 0 then
+        return 0
+    endif
+    return down(n - 1)
+endfunction
+`
+	if err := interp.Load(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := interp.Call("down", 10); err != nil {
+		t.Errorf("unexpected error for shallow recursion: %v", err)
+	}
+
+	_, err := interp.Call("down", 1000)
+	if err == nil || !strings.Contains(err.Error(), "too complex") {
+		t.Errorf("expected 'too complex' error, got %v", err)
+	}
+
+	// The depth counter must unwind after an error
+	result, err := interp.Call("down", 40)
+	if err != nil {
+		t.Errorf("unexpected error after depth failure: %v", err)
+	}
+	if result != 0 {
+		t.Errorf("expected 0, got %v", result)
+	}
+}
+
+// =============================================================================
+// Number Format Tests
+// =============================================================================
+
+func TestNumberFormatDefault(t *testing.T) {
+	interp, output := newTestInterpreter()
+
+	err := interp.Interpret(`
+print 0.5 + 0.25
+print "x=" + (0.1 + 0.2)
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if (*output)[0] != 0.75 {
+		t.Errorf("expected raw float 0.75, got %v (%T)", (*output)[0], (*output)[0])
+	}
+	if (*output)[1] != "x=0.30000000000000004" {
+		t.Errorf("expected shortest representation, got %v", (*output)[1])
+	}
+}
+
+func TestNumberFormatPrecision(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetNumberFormat(basic.NumberFormat{Precision: 10})
+
+	err := interp.Interpret(`
+print 0.1 + 0.2
+print "Price: " + (0.1 + 0.2)
+print 7
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"0.3", "Price: 0.3", 7}
+	if fmt.Sprint(*output) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestNumberFormatFixed(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetNumberFormat(basic.NumberFormat{Precision: 2, Fixed: true})
+
+	err := interp.Interpret(`
+print 2.0 / 3.0
+print "big: " + 1000000.0 * 1000000.0 * 1000000000.0
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if (*output)[0] != "0.67" {
+		t.Errorf("expected 0.67, got %v", (*output)[0])
+	}
+	if (*output)[1] != "big: 1000000000000000000000.00" {
+		t.Errorf("expected fixed notation, got %v", (*output)[1])
+	}
+}
+
+func TestNumberFormatAppliesToStr(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetNumberFormat(basic.NumberFormat{Precision: 2, Fixed: true})
+	interp.RegisterFunction("str", stringlib.Str(interp.ToString))
+	interp.RegisterFunction("val", stringlib.Val)
+
+	err := interp.Interpret(`
+let x = 2.0 / 3.0
+print str(x)
+print str(x) = "" + x
+print val(str(x)) + 1`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"0.67", true, "1.67"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+// =============================================================================
+// Overflow Mode Tests
+// =============================================================================
+
+func TestOverflowWrapByDefault(t *testing.T) {
+	interp, output := newTestInterpreter()
+
+	if err := interp.Interpret("print 9223372036854775807 + 1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if (*output)[0] != math.MinInt {
+		t.Errorf("expected wraparound to %d, got %v", math.MinInt, (*output)[0])
+	}
+}
+
+func TestOverflowError(t *testing.T) {
+	tests := []string{
+		"let x = 9223372036854775807 + 1",
+		"let x = -9223372036854775807 - 2",
+		"let x = 4611686018427387904 * 2",
+		"let big = 9223372036854775807\nlet x = (-big - 1) / -1",
+		"let big = 9223372036854775807\nlet x = -(-big - 1)",
+		"let x = 9223372036854775807\nx++",
+		"let x = -9223372036854775807 - 1\nx--",
+		"let x = 9223372036854775807\nx += 1",
+		"let x = -9223372036854775807 - 1\nx -= 1",
+	}
+
+	for _, code := range tests {
+		interp, _ := newTestInterpreter()
+		interp.SetOverflowMode(basic.OverflowError)
+
+		err := interp.Interpret(code)
+		if err == nil || !strings.Contains(err.Error(), "integer overflow") {
+			t.Errorf("%q: expected integer overflow error, got %v", code, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), "line ") {
+			t.Errorf("%q: expected position in error, got %v", code, err)
+		}
+	}
+
+	interp, output := newTestInterpreter()
+	interp.SetOverflowMode(basic.OverflowError)
+	if err := interp.Interpret("print 3000000000 * 3"); err != nil {
+		t.Fatalf("unexpected error for in-range product: %v", err)
+	}
+	if (*output)[0] != 9000000000 {
+		t.Errorf("expected 9000000000, got %v", (*output)[0])
+	}
+}
+
+func TestSumFollowsOverflowMode(t *testing.T) {
+	code := "sum(values)"
+	vars := map[string]interface{}{"values": []interface{}{math.MaxInt64, 1}}
+
+	interp, _ := newTestInterpreter()
+	interp.RegisterFunction("sum", arraylib.Sum(interp.Add))
+	interp.SetOverflowMode(basic.OverflowError)
+	if _, err := interp.EvaluateWithVars(code, vars); err == nil || !strings.Contains(err.Error(), "integer overflow") {
+		t.Errorf("expected an integer overflow error, got %v", err)
+	}
+
+	interp.SetOverflowMode(basic.OverflowPromote)
+	if result, err := interp.EvaluateWithVars(code, vars); err != nil || result != float64(math.MaxInt64)+1 {
+		t.Errorf("expected the total as a float, got %v (%v)", result, err)
+	}
+}
+
+func TestOverflowPromote(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetOverflowMode(basic.OverflowPromote)
+
+	err := interp.Interpret(`
+print 9223372036854775807 + 1
+print 4611686018427387904 * 4
+print 2 + 3
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if (*output)[0] != 9223372036854775808.0 {
+		t.Errorf("expected float 9223372036854775808, got %v (%T)", (*output)[0], (*output)[0])
+	}
+	if (*output)[1] != 18446744073709551616.0 {
+		t.Errorf("expected float 18446744073709551616, got %v (%T)", (*output)[1], (*output)[1])
+	}
+	if (*output)[2] != 5 {
+		t.Errorf("expected int 5, got %v (%T)", (*output)[2], (*output)[2])
+	}
+}
+
+// =============================================================================
+// Numeric Boundary Tests
+// =============================================================================
+
+func TestHostNumbersAreNormalized(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.RegisterFunction("getHP", func(args ...interface{}) (interface{}, error) {
+		return int64(40), nil
+	})
+	interp.RegisterFunction("getScale", func(args ...interface{}) (interface{}, error) {
+		return float32(0.5), nil
+	})
+
+	err := interp.Load(`
+function hit(damage, armor):
+    let result = damage - armor
+    if result = 7 then
+        return result * getScale()
+    endif
+    return getHP() + result
+endfunction
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{[]interface{}{10, 3}, 3.5},
+		{[]interface{}{int64(10), int32(3)}, 3.5},
+		{[]interface{}{uint8(10), uint16(3)}, 3.5},
+		{[]interface{}{int64(20), int64(10)}, 50},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Call("hit", tt.args...)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.args, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%v: expected %v (%T), got %v (%T)", tt.args, tt.expected, tt.expected, result, result)
+		}
+	}
+}
+
+func TestHostVarsAreNormalized(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("sum", arraylib.Sum(interp.Add))
+
+	err := interp.InterpretWithVars(`
+if level = 3 then
+    print sum(items)
+endif
+`, map[string]interface{}{
+		"level": int64(3),
+		"items": []interface{}{int32(1), int16(41)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != 42 {
+		t.Errorf("expected [42], got %v", *output)
+	}
+}
+
+func TestHostUnsignedOutOfRange(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.RegisterFunction("huge", func(args ...interface{}) (interface{}, error) {
+		return []interface{}{uint64(math.MaxUint64)}, nil
+	})
+	if _, err := interp.Evaluate("huge()"); err == nil || !strings.Contains(err.Error(), "18446744073709551615 is out of range") {
+		t.Errorf("expected an out of range error from a host function, got %v", err)
+	}
+
+	if err := interp.Load("function id(n):\n    return n\nendfunction"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := interp.Call("id", uint(math.MaxUint64)); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("expected an out of range error for an argument, got %v", err)
+	}
+	if err := interp.SetVariable("big", uint64(math.MaxInt64)+1); err == nil {
+		t.Error("expected an out of range error for a variable")
+	}
+	if result, err := interp.Call("id", uint64(math.MaxInt64)); err != nil || result != math.MaxInt64 {
+		t.Errorf("expected MaxInt64, got %v (%v)", result, err)
+	}
+}
+
+func TestResultPolicy(t *testing.T) {
+	code := `
+let total = 5
+function half(n):
+    return n / 2
+endfunction
+`
+	interp, _ := newTestInterpreter()
+	if err := interp.Load(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	interp.SetResultPolicy(basic.ResultInt64)
+	result, err := interp.Call("half", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != int64(5) {
+		t.Errorf("expected int64(5), got %v (%T)", result, result)
+	}
+	if total := interp.Globals()["total"]; total != int64(5) {
+		t.Errorf("expected global int64(5), got %v (%T)", total, total)
+	}
+
+	interp.SetResultPolicy(basic.ResultFloat64)
+	result, err = interp.Evaluate("total * 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 10.0 {
+		t.Errorf("expected float64(10), got %v (%T)", result, result)
+	}
+}
+
+// =============================================================================
+// SUB and Warning Tests
+// =============================================================================
+
+func TestSubCalledAsStatement(t *testing.T) {
+	interp, output := newTestInterpreter()
+
+	err := interp.Interpret(`
+let total = 0
+sub add(n)
+    if n < 0 then
+        return
+    endif
+    total += n
+endsub
+
+add(5)
+add(-1)
+add(3)
+print total
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != 8 {
+		t.Errorf("expected [8], got %v", *output)
+	}
+	if warnings := interp.Warnings(); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
+
+func TestSubInExpressionIsError(t *testing.T) {
+	tests := []string{
+		"sub log(x)\n    print x\nendsub\nlet y = log(1)",
+		"sub log(x)\n    print x\nendsub\nprint 1 + log(1)",
+		"sub log(x)\n    print x\nendsub\nfunction f()\n    return log(1)\nendfunction",
+		"sub log(x)\n    print x\nendsub\nlog(log(1))",
+	}
+
+	for _, code := range tests {
+		interp, output := newTestInterpreter()
+		err := interp.Interpret(code)
+		if err == nil || !strings.Contains(err.Error(), "cannot be used in an expression") {
+			t.Errorf("%q: expected SUB-in-expression error, got %v", code, err)
+		}
+		if len(*output) != 0 {
+			t.Errorf("%q: expected nothing to run, got %v", code, *output)
+		}
+	}
+}
+
+func TestDiscardedFunctionResultWarning(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.RegisterFunction("notify", func(args ...interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	code := `
+function double(n):
+    return n * 2
+endfunction
+
+double(4)
+notify("external calls are not flagged")
+let x = double(2)
+`
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	warnings := interp.Warnings()
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	if warnings[0].Line != 6 || !strings.Contains(warnings[0].Message, "double") {
+		t.Errorf("unexpected warning: %v", warnings[0])
+	}
+
+	// Warnings survive the AST cache
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(interp.Warnings()) != 1 {
+		t.Errorf("expected cached warning, got %v", interp.Warnings())
+	}
+
+	// And are replaced by the next program's diagnostics
+	if err := interp.Validate("let y = 1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(interp.Warnings()) != 0 {
+		t.Errorf("expected no warnings, got %v", interp.Warnings())
+	}
+}
+
+func TestUnusedAndShadowingWarnings(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	code := `let hp = 10
+let total = 0
+function heal(hp, amount):
+    let bonus = amount * 2
+    let unused = 1
+    local spare = 2
+    dim scratch(3)
+    let shared = 0
+    shared += 1
+    dim grid(2)
+    grid(1) = bonus
+    for i = 1 to 3
+        total = total + i
+    next i
+    return hp + bonus
+endfunction
+let result = heal(5, 1)`
+	if err := interp.Validate(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var messages []string
+	for _, w := range interp.Warnings() {
+		messages = append(messages, fmt.Sprintf("%d: %s", w.Line, w.Message))
+	}
+	expected := []string{
+		"5: variable unused is assigned but never read",
+		"6: variable spare is assigned but never read",
+		"7: variable scratch is assigned but never read",
+		"8: variable shared is assigned but never read",
+		"3: parameter hp of heal shadows the global variable hp set at line 1",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected %v, got %v", expected, messages)
+	}
+}
+
+// =============================================================================
+// Debug Hook Tests
+// =============================================================================
+
+func TestDebugHookSeesEveryStatement(t *testing.T) {
+	interp, _ := newTestInterpreter()
+
+	var frames []basic.DebugFrame
+	var seen []interface{}
+	interp.SetDebugHook(func(frame basic.DebugFrame) error {
+		frames = append(frames, frame)
+		if frame.Function == "twice" {
+			value, err := interp.EvalInFrame("n * 10")
+			if err != nil {
+				return err
+			}
+			seen = append(seen, value)
+		}
+		return nil
+	})
+
+	err := interp.Interpret(`function twice(n):
+    return n * 2
+endfunction
+let x = twice(4)
+print x`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []basic.DebugFrame{
+		{Line: 4, Column: 1},
+		{Line: 2, Column: 5, Function: "twice", Depth: 1},
+		{Line: 5, Column: 1},
+	}
+	if fmt.Sprint(frames) != fmt.Sprint(expected) {
+		t.Errorf("expected frames %v, got %v", expected, frames)
+	}
+	if len(seen) != 1 || seen[0] != 40 {
+		t.Errorf("expected EvalInFrame to see n = 4, got %v", seen)
+	}
+}
+
+func TestDebugHookErrorAborts(t *testing.T) {
+	interp, output := newTestInterpreter()
+	stop := fmt.Errorf("stopped")
+	interp.SetDebugHook(func(frame basic.DebugFrame) error {
+		if frame.Line == 2 {
+			return stop
+		}
+		return nil
+	})
+
+	err := interp.Interpret("print 1\nprint 2\nprint 3")
+	if err != stop {
+		t.Errorf("expected hook error, got %v", err)
+	}
+	if len(*output) != 1 {
+		t.Errorf("expected only the first print to run, got %v", *output)
+	}
+}
+
+// =============================================================================
+// Error Position Tests
+// =============================================================================
+
+func TestExternalErrorHasPosition(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.RegisterFunction("fail", func(args ...interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("boom")
+	})
+
+	err := interp.Interpret("let x = 1\nlet y = x + fail()")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "line 2, column 13: boom") {
+		t.Errorf("expected error with position, got %v", err)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.RegisterFunction("fail", func(args ...interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("boom")
+	})
+
+	parseTests := []struct {
+		code string
+		kind basic.ErrorKind
+		line int
+	}{
+		{"let x = 1\nlet y = (2", basic.ErrorSyntax, 2},
+		{"let s = \"open", basic.ErrorSyntax, 1},
+		{"const MAX = 1\nlet max = 2", basic.ErrorCheck, 2},
+		{"include \"lib.bas\"", basic.ErrorInclude, 1},
+	}
+	for _, tt := range parseTests {
+		var parseErr *basic.ParseError
+		if err := interp.Interpret(tt.code); !errors.As(err, &parseErr) {
+			t.Errorf("%q: expected a ParseError, got %v", tt.code, err)
+		} else if parseErr.Kind != tt.kind || parseErr.Line != tt.line {
+			t.Errorf("%q: expected a %v error at line %d, got %v at line %d", tt.code, tt.kind, tt.line, parseErr.Kind, parseErr.Line)
+		}
+	}
+
+	runTests := []struct {
+		code string
+		kind basic.ErrorKind
+	}{
+		{"let x = 1\nlet y = undefined_var", basic.ErrorRuntime},
+		{"let x = 1\nlet y = fail()", basic.ErrorHost},
+		{"let x = 1\nthrow \"no\"", basic.ErrorThrow},
+	}
+	for _, tt := range runTests {
+		var runErr *basic.RuntimeError
+		if err := interp.Interpret(tt.code); !errors.As(err, &runErr) {
+			t.Errorf("%q: expected a RuntimeError, got %v", tt.code, err)
+		} else if runErr.Kind != tt.kind || runErr.Line != 2 {
+			t.Errorf("%q: expected a %v error at line 2, got %v at line %d", tt.code, tt.kind, runErr.Kind, runErr.Line)
+		}
+	}
+
+	interp.SetMaxStatements(5)
+	var runErr *basic.RuntimeError
+	err := interp.Interpret("do\nlet x = 1\nloop")
+	if !errors.As(err, &runErr) || runErr.Kind != basic.ErrorBudget {
+		t.Errorf("expected a budget error, got %v", err)
+	}
+	var budget *basic.BudgetExceededError
+	if !errors.As(err, &budget) {
+		t.Errorf("expected the RuntimeError to wrap a BudgetExceededError, got %v", err)
+	}
+}
+
+// =============================================================================
+// Function Documentation Tests
+// =============================================================================
+
+func TestDescribeFunctions(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	code := `## Heals the player.
+sub heal(amount):
+endsub
+function _secret():
+    return 1
+endfunction
+## Scales x.
+function scale(x AS FLOAT, factor = 2):
+    return x * factor
+endfunction`
+
+	docs, err := interp.DescribeScript(code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if interp.HasFunction("heal") {
+		t.Error("expected DescribeScript not to load the script")
+	}
+
+	expected := []basic.FunctionDoc{
+		{Name: "heal", Params: []string{"amount"}, Types: []string{""}, Required: 1, Doc: "Heals the player.", IsSub: true, Line: 2},
+		{Name: "scale", Params: []string{"x", "factor"}, Types: []string{"FLOAT", ""}, Required: 1, Doc: "Scales x.", Line: 8},
+	}
+	if !reflect.DeepEqual(docs, expected) {
+		t.Errorf("expected %+v, got %+v", expected, docs)
+	}
+
+	if err := interp.Load(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded := interp.DescribeFunctions(); !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("expected %+v, got %+v", expected, loaded)
+	}
+}
+
+// =============================================================================
+// Interrupt Tests
+// =============================================================================
+
+func TestStopInterruptsRunningScript(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetMaxIterations(math.MaxInt)
+	started := make(chan struct{})
+	interp.RegisterFunction("started", func(args ...interface{}) (interface{}, error) {
+		close(started)
+		return nil, nil
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- interp.Interpret("started()\nlet n = 0\nfor i = 1 to 2000000000\n    n = n + 1\nnext i")
+	}()
+
+	<-started
+	interp.Stop()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, basic.ErrInterrupted) {
+			t.Fatalf("expected ErrInterrupted, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "line 4, column 5") && !strings.Contains(err.Error(), "line 3, column 1") {
+			t.Errorf("expected error with loop position, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("script did not stop")
+	}
+
+	// A stale Stop doesn't affect the next run
+	if err := interp.Interpret("let x = 1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestStopInterruptsCall(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.RegisterFunction("halt", func(args ...interface{}) (interface{}, error) {
+		interp.Stop()
+		return nil, nil
+	})
+
+	if err := interp.Load("function work():\n    halt()\n    return 1\nendfunction"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := interp.Call("work")
+	if !errors.Is(err, basic.ErrInterrupted) {
+		t.Errorf("expected ErrInterrupted, got %v", err)
+	}
+}
+
+func TestInterpretContextCancel(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetMaxIterations(math.MaxInt)
+	ctx, cancel := context.WithCancel(context.Background())
+	interp.RegisterFunction("started", func(args ...interface{}) (interface{}, error) {
+		cancel()
+		return nil, nil
+	})
+
+	err := interp.InterpretContext(ctx, "started()\ntry\n    do\n    loop\ncatch e\n    print e\nendtry")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(*output) != 0 {
+		t.Errorf("expected the CATCH block not to run, got %v", *output)
+	}
+
+	// The next run isn't affected by the cancelled context
+	if err := interp.InterpretContext(context.Background(), "print 1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := interp.Interpret("print 2"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestInterpretContextDone(t *testing.T) {
+	interp, output := newTestInterpreter()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := interp.InterpretContext(ctx, "print 1")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if len(*output) != 0 {
+		t.Errorf("expected nothing to run, got %v", *output)
+	}
+}
+
+func TestInterpretContextDeadline(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetMaxIterations(math.MaxInt)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- interp.InterpretContext(ctx, "let n = 0\nfor i = 1 to 2000000000\n    n = n + 1\nnext i")
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "line 3, column 5") && !strings.Contains(err.Error(), "line 2, column 1") {
+			t.Errorf("expected error with loop position, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("script did not stop")
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetMaxIterations(math.MaxInt)
+	if err := interp.Load("function add(a, b):\n    return a + b\nendfunction\nfunction spin():\n    do\n    loop\nendfunction"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := interp.CallContext(context.Background(), "add", 2, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 5 {
+		t.Errorf("expected 5, got %v", result)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := interp.CallContext(ctx, "spin"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestMaxStatements(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetMaxStatements(3)
+
+	if err := interp.Interpret("let a = 1\nlet b = 2\nprint a + b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := interp.Interpret("let a = 1\nlet b = 2\nprint a + b\nprint a")
+	var budget *basic.BudgetExceededError
+	if !errors.As(err, &budget) {
+		t.Fatalf("expected BudgetExceededError, got %v", err)
+	}
+	if budget.Limit != 3 {
+		t.Errorf("expected limit 3, got %d", budget.Limit)
+	}
+	if !strings.Contains(err.Error(), "line 4, column 1") {
+		t.Errorf("expected error at line 4, got %v", err)
+	}
+	if !reflect.DeepEqual(*output, []interface{}{3, 3}) {
+		t.Errorf("expected [3 3], got %v", *output)
+	}
+
+	// Bare expressions count as statements too
+	if _, err := interp.Evaluate("1\n2\n3\n4"); !errors.As(err, &budget) {
+		t.Errorf("expected BudgetExceededError for bare expressions, got %v", err)
+	}
+}
+
+func TestMaxStatementsRecursion(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetMaxStatements(50)
+	err := interp.Interpret(`
+function down(n):
+    if n > 0 then
+        down(n - 1)
+    endif
+endfunction
+try
+    down(1000)
+catch e
+    print e
+endtry
+`)
+	var budget *basic.BudgetExceededError
+	if !errors.As(err, &budget) {
+		t.Fatalf("expected BudgetExceededError, got %v", err)
+	}
+	if len(*output) != 0 {
+		t.Errorf("expected the CATCH block not to run, got %v", *output)
+	}
+
+	// Each call gets a fresh budget
+	if err := interp.Load("function two():\n    let x = 1\n    return x + 1\nendfunction"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for n := 0; n < 30; n++ {
+		if _, err := interp.Call("two"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	interp.SetMaxStatements(0)
+	if err := interp.Interpret("function down(n):\n    if n > 0 then\n        down(n - 1)\n    endif\nendfunction\ndown(1000)"); err != nil {
+		t.Errorf("unexpected error without a budget: %v", err)
+	}
+}
+
+func TestMemoryLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits basic.MemoryLimits
+		code   string
+		errMsg string
+	}{
+		{"variables", basic.MemoryLimits{MaxVariables: 2}, "let a = 1\nlet b = 2\nlet c = 3", "line 3, column 1: memory limit exceeded: cannot create c, the limit is 2 variables"},
+		{"assigned variable", basic.MemoryLimits{MaxVariables: 1}, "let a = 1\nb = 2", "cannot create b"},
+		{"loop variable", basic.MemoryLimits{MaxVariables: 1}, "let a = 1\nfor i = 1 to 3\nnext i", "cannot create i"},
+		{"string", basic.MemoryLimits{MaxStringSize: 8}, "let s = \"abcd\"\ns = s + s\ns = s + s", "line 3, column 1: memory limit exceeded: s would hold a string of 16 bytes, the limit is 8"},
+		{"string element", basic.MemoryLimits{MaxStringSize: 3}, "dim a(2)\na(1) = \"long\"", "a would hold a string of 4 bytes"},
+		{"string field", basic.MemoryLimits{MaxStringSize: 3}, "type item\n    name\nendtype\nlet it = item(\"axe\")\nit.name = \"sword\"", "it would hold a string of 5 bytes"},
+		{"dim", basic.MemoryLimits{MaxCollectionSize: 10}, "dim grid(5, 11)", "line 1, column 13: memory limit exceeded: grid would hold 11 elements, the limit is 10"},
+		{"map entry", basic.MemoryLimits{MaxCollectionSize: 2}, "let m = map()\nm(\"a\") = 1\nm(\"b\") = 2\nm(\"a\") = 3\nm(\"c\") = 4", "line 5, column 1: memory limit exceeded: m would hold 3 elements, the limit is 2"},
+		{"total strings", basic.MemoryLimits{MaxTotalStringSize: 10}, "let a = \"abcd\"\nlet b = \"efgh\"\nlet c = \"ijkl\"", "line 3, column 1: memory limit exceeded: storing c would bring the strings held to 12 bytes, the limit is 10"},
+		{"total map keys", basic.MemoryLimits{MaxTotalStringSize: 10}, "let m = map()\nm(\"abcdef\") = \"ghijk\"", "strings held to 11 bytes"},
+		{"total locals", basic.MemoryLimits{MaxTotalStringSize: 10}, "let s = \"abcdef\"\nfunction f()\n    let t = \"ghijk\"\nendfunction\nf()", "line 3, column 5: memory limit exceeded: storing t would bring the strings held to 11 bytes"},
+		{"total caller locals", basic.MemoryLimits{MaxTotalStringSize: 10}, "function f()\n    let s = \"abcdef\"\n    g()\nendfunction\nfunction g()\n    let t = \"ghijk\"\nendfunction\nf()", "storing t would bring the strings held to 11 bytes"},
+		{"total nested arrays", basic.MemoryLimits{MaxTotalElements: 50}, "dim a(10)\nfor i = 0 to 9\n    dim b(10)\n    a(i) = b\nnext i", "line 3, column 5: memory limit exceeded: storing b would bring the elements held to 60, the limit is 50"},
+		{"total record fields", basic.MemoryLimits{MaxTotalElements: 5}, "type pair\n    a\n    b\nendtype\ndim ps(2)\nps(0) = pair(1, 2)\nps(1) = pair(3, 4)", "line 7, column 1: memory limit exceeded: storing ps would bring the elements held to 6, the limit is 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp, _ := newTestInterpreter()
+			interp.RegisterFunction("map", maplib.Map)
+			interp.SetMemoryLimits(tt.limits)
+			err := interp.Interpret(tt.code)
+			if !errors.Is(err, basic.ErrMemoryLimit) {
+				t.Fatalf("expected ErrMemoryLimit, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestMemoryLimitsWithinBounds(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetMemoryLimits(basic.MemoryLimits{MaxVariables: 3, MaxStringSize: 5, MaxCollectionSize: 3})
+	err := interp.Interpret(`
+let s = "ab"
+s = s + "cde"
+dim a(3)
+a(0) = s
+try
+    s = s + "f"
+catch e
+    print e
+endtry
+print s
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{"memory limit exceeded: s would hold a string of 6 bytes, the limit is 5", "abcde"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestMemoryTotalsWithinBounds(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetMemoryLimits(basic.MemoryLimits{MaxTotalStringSize: 12, MaxTotalElements: 9})
+	err := interp.Interpret(`
+let s = ""
+for i = 1 to 100
+    s = s + "x"
+    if s = "xxxxxxxxxx" then
+        s = ""
+    endif
+next i
+dim a(3)
+for i = 1 to 100
+    dim b(3)
+    a(0) = b
+next i
+let c = a
+print s
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{""}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+// =============================================================================
+// Array Tests
+// =============================================================================
+
+func TestArrayDimAndAssign(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+dim scores(4)
+for i = 0 to 3
+    scores(i) = i * 10
+next i
+scores(1) += 5
+scores(3)++
+print scores
+print scores(1) + scores(3)
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(*output) != "[[0 15 20 31] 46]" {
+		t.Errorf("unexpected output: %v", *output)
+	}
+}
+
+func TestArrayMultiDimensional(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	result, err := interp.Evaluate(`
+dim grid(2, 3)
+grid(1, 2) = "x"
+grid(1, 2) + grid(0, 0)
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "x0" {
+		t.Errorf("expected x0, got %v", result)
+	}
+}
+
+func TestArrayVisibleInFunctions(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	err := interp.Load(`
+function total():
+    let sum = 0
+    for i = 0 to 2
+        sum += counts(i)
+    next i
+    return sum
+endfunction
+dim counts(3)
+counts(0) = 1
+counts(2) = 5
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := interp.Call("total")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 6 {
+		t.Errorf("expected 6, got %v", result)
+	}
+}
+
+func TestArrayFromHost(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	vars := map[string]interface{}{
+		"items": []interface{}{"sword", "shield"},
+		"data":  []byte{7, 9},
+	}
+
+	result, err := interp.EvaluateWithVars(`
+items(1) = "bow"
+items(1) + data(1)
+`, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "bow9" {
+		t.Errorf("expected bow9, got %v", result)
+	}
+	if vars["items"].([]interface{})[1] != "bow" {
+		t.Errorf("expected the host array to be updated, got %v", vars["items"])
+	}
+}
+
+func TestArrayErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"dim a(3)\nprint a(3)", "line 2, column 7: a: index 3 out of range (length 3)"},
+		{"dim a(3)\na(-1) = 1", "line 2, column 1: a: index -1 out of range (length 3)"},
+		{"dim a(3)\nprint a(1.5)", "a: index must be an integer, got float"},
+		{"dim a(2, 2)\nprint a(0, 0, 0)", "a is not an array or map (got int)"},
+		{"let n = 5\nn(0) = 1", "cannot assign an element of n (got int)"},
+		{"dim a(-1)", "size must not be negative"},
+		{"dim a(2000, 2000)", "more than 1048576 elements"},
+		{"dim a(4, 4611686018427387904)", "more than 1048576 elements"},
+		{"dim a(4611686018427387904, 4611686018427387904)", "more than 1048576 elements"},
+		{"dim a(\"x\")", "size must be an integer, got string"},
+		{"print b(0)", "undefined function: b"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+// =============================================================================
+// Map Tests
+// =============================================================================
+
+func newMapInterpreter() (*basic.Interpreter, *[]interface{}) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("map", maplib.Map)
+	interp.RegisterFunction("has_key", maplib.HasKey)
+	interp.RegisterFunction("delete_key", maplib.DeleteKey)
+	interp.RegisterFunction("keys", maplib.Keys)
+	return interp, output
+}
+
+func TestMapReadWrite(t *testing.T) {
+	interp, output := newMapInterpreter()
+	err := interp.Interpret(`
+let prices = map("sword", 10)
+prices("shield") = 8
+prices("sword") += 2
+print prices("sword") + prices("shield")
+print has_key(prices, "bow")
+delete_key(prices, "shield")
+print keys(prices)
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(*output) != "[20 false [sword]]" {
+		t.Errorf("unexpected output: %v", *output)
+	}
+}
+
+func TestMapNested(t *testing.T) {
+	interp, _ := newMapInterpreter()
+	result, err := interp.Evaluate(`
+let stats = map("hp", map("max", 100))
+stats("hp", "now") = 40
+dim slots(2)
+slots(1) = stats
+slots(1, "hp", "max") - slots(1, "hp", "now")
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 60 {
+		t.Errorf("expected 60, got %v", result)
+	}
+}
+
+func TestMapHostInterop(t *testing.T) {
+	interp, _ := newMapInterpreter()
+	var received map[string]interface{}
+	interp.RegisterFunction("inventory", func(args ...interface{}) (interface{}, error) {
+		return map[string]int{"arrows": 12}, nil
+	})
+	interp.RegisterFunction("save", func(args ...interface{}) (interface{}, error) {
+		received = args[0].(map[string]interface{})
+		return nil, nil
+	})
+
+	err := interp.Interpret(`
+let inv = inventory()
+inv("arrows") -= 2
+inv("torch") = true
+save(inv)
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received["arrows"] != 10 || received["torch"] != true {
+		t.Errorf("unexpected map passed to host: %v", received)
+	}
+}
+
+func TestMapErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"let m = map()\nprint m(\"gold\")", "line 2, column 7: m: no key \"gold\""},
+		{"let m = map()\nm(\"gold\") += 1", "m: no key \"gold\""},
+		{"let m = map()\nm(1) = 2", "m: key must be a string, got int"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newMapInterpreter()
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+// =============================================================================
+// Bitwise Operator Tests
+// =============================================================================
+
+func TestBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"12 & 10", 8},
+		{"12 | 3", 15},
+		{"12 xor 10", 6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 << 2 + 1", 8},
+		{"flags & 4 <> 0", true},
+		{"flags | 8 xor 1", 12},
+		{"flags & 4 <> 0 and flags & 2 = 0", true},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		result, err := interp.EvaluateWithVars(tt.expr, map[string]interface{}{"flags": 5})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.expr, tt.expected, result)
+		}
+	}
+}
+
+func TestBitwiseOperatorErrors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"1.5 & 1", "line 1, column 1: operator & requires integers, got float and int"},
+		{"true | 1", "operator | requires integers, got bool and int"},
+		{"1 << -1", "negative shift count -1"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		_, err := interp.Evaluate(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.expr, tt.expected, err)
+		}
+	}
+}
+
+// =============================================================================
+// String Slicing Tests
+// =============================================================================
+
+func TestStringIndexAndSlice(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{`"hello"[0]`, "h"},
+		{`"hello"[4]`, "o"},
+		{`"hello"[1:3]`, "el"},
+		{`"hello"[:2]`, "he"},
+		{`"hello"[3:]`, "lo"},
+		{`"hello"[:]`, "hello"},
+		{`"hello"[2:2]`, ""},
+		{`"héllo wörld"[1]`, "é"},
+		{`"héllo wörld"[6:11]`, "wörld"},
+		{`"héllo"[1:][0]`, "é"},
+		{`("ab" + "cd")[1:3]`, "bc"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		result, err := interp.Evaluate(tt.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %q, got %v", tt.expr, tt.expected, result)
+		}
+	}
+}
+
+func TestStringSliceInScript(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`let s = "abc"
+let out = ""
+for i = 0 to 2
+    out = s[i] + out
+next
+print out`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != "cba" {
+		t.Errorf("expected [cba], got %v", *output)
+	}
+}
+
+func TestStringSliceErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"let s = \"héllo\"\nprint s[5]", "line 2, column 8: string index 5 out of range (length 5)"},
+		{"let s = \"abc\"\nprint s[-1]", "string index -1 out of range (length 3)"},
+		{"let s = \"abc\"\nprint s[2:5]", "slice [2:5] out of range (length 3)"},
+		{"let s = \"abc\"\nprint s[2:1]", "slice [2:1] out of range (length 3)"},
+		{"let s = \"abc\"\nprint s[1.5]", "line 2, column 9: string index must be an integer, got float"},
+		{"let n = 42\nprint n[0]", "[] requires a string, got int"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+func TestSplitAndJoinInScript(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("split", stringlib.Split)
+	interp.RegisterFunction("join", stringlib.Join)
+	interp.RegisterFunction("len", stringlib.Len)
+
+	err := interp.Interpret(`
+let fields = split("3,1,2", ",")
+for i = 0 to len(fields) - 1
+    fields(i) = fields(i) + "!"
+next
+print len(fields)
+print join(fields, " ")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{3, "3! 1! 2!"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+// =============================================================================
+// Print Tests
+// =============================================================================
+
+func TestPrintMultipleValues(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+let hp = 7
+print "hp:", hp, 2.5, hp > 5
+print hp`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A single value still reaches the handler unconverted
+	expected := []interface{}{"hp: 7 2.5 true", 7}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestPrintSeparatorAndNumberFormat(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetPrintSeparator(", ")
+	interp.SetNumberFormat(basic.NumberFormat{Precision: 2, Fixed: true})
+
+	if err := interp.Interpret(`print "a", 1, 2.0 / 3.0`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != "a, 1, 0.67" {
+		t.Errorf("expected [a, 1, 0.67], got %v", *output)
+	}
+}
+
+func TestPrintTrailingSemicolon(t *testing.T) {
+	interp := basic.NewInterpreter()
+	var out strings.Builder
+	interp.SetRawPrintFunc(func(value interface{}, newline bool) {
+		out.WriteString(fmt.Sprint(value))
+		if newline {
+			out.WriteString("\n")
+		}
+	})
+
+	err := interp.Interpret(`
+for i = 1 to 3
+    print i;
+    print ",";
+next
+print ""
+print "a", "b";
+print "!"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "1,2,3,\na b!\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	// A plain print handler still sees every value
+	interp, output := newTestInterpreter()
+	if err := interp.Interpret(`print "x";`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != "x" {
+		t.Errorf("expected [x], got %v", *output)
+	}
+}
+
+func TestPrintUsing(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`print using "###.##"; 3.14159`, "  3.14"},
+		{`print using "###.##"; -2`, " -2.00"},
+		{`print using "#.##"; 1234.5`, "%1234.50"},
+		{`print using ".##"; 0.5`, ".50"},
+		{`print using "+###"; 5`, "  +5"},
+		{`print using "$##,###.##"; 12345.678`, "$12,345.68"},
+		{`print using "##,###"; -1234`, "-1,234"},
+		{`print using "Score: #####"; 42`, "Score:    42"},
+		{`print using "& has ### HP"; "Orc", 17`, "Orc has  17 HP"},
+		{`print using "!."; "Mechanical"`, "M."},
+		{`print using "\\  \\|"; "Knight", "Al"`, "Knig|Al  |"},
+		{`print using "##_#"; 7`, " 7#"},
+		{`print using "[##]"; 1, 2, 3`, "[ 1][ 2][ 3]"},
+		{`print using "# and #"; 1`, "1 and "},
+	}
+
+	for _, tt := range tests {
+		interp, output := newTestInterpreter()
+		if err := interp.Interpret(tt.code); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.code, err)
+			continue
+		}
+		if len(*output) != 1 || (*output)[0] != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.code, tt.expected, *output)
+		}
+	}
+}
+
+func TestPrintUsingErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`print using "###"; "abc"`, "line 1, column 1: PRINT USING field ### needs a number, got string"},
+		{`print using "no fields"; 1`, `PRINT USING format "no fields" has no fields`},
+		{`print using 5; 1`, "PRINT USING format must be a string, got int"},
+		{`print using "###" 1`, "expected ';' after PRINT USING format"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+// =============================================================================
+// GOTO Tests
+// =============================================================================
+
+func TestGotoLoop(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+let n = 0
+top:
+n++
+if n < 3 then
+    goto top
+endif
+goto finish
+print "skipped"
+finish:
+print n`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*output, []interface{}{3}) {
+		t.Errorf("expected [3], got %v", *output)
+	}
+}
+
+func TestGotoOutOfNestedLoops(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+function find(target):
+    let hit = ""
+    for i = 1 to 5
+        for j = 1 to 5
+            if i * j = target then
+                hit = i + "x" + j
+                goto found
+            endif
+        next j
+    next i
+    return "none"
+found:
+    return hit
+endfunction
+print find(12)
+print find(7)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{"3x4", "none"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestGotoInLoadedCode(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	err := interp.Load(`
+let total = 0
+let i = 0
+again:
+i++
+total += i
+if i < 4 then
+    goto again
+endif
+function getTotal():
+    return total
+endfunction`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := interp.Call("getTotal")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 10 {
+		t.Errorf("expected 10, got %v", result)
+	}
+}
+
+func TestGotoIterationLimit(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetMaxIterations(50)
+
+	err := interp.Interpret("spin:\ngoto spin")
+	if err == nil || !strings.Contains(err.Error(), "line 2, column 1: maximum iterations exceeded (50)") {
+		t.Errorf("expected iteration limit error, got %v", err)
+	}
+}
+
+func TestGotoValidation(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"goto nowhere", "line 1, column 1: GOTO nowhere: label not defined"},
+		{"goto inside\nif true then\ninside:\nprint 1\nendif", "GOTO inside jumps into a block"},
+		{"for i = 1 to 2\n    goto later\nnext\nif true then\n    later:\nendif", "GOTO later jumps into a block"},
+		{"outer:\nfunction f():\n    goto outer\nendfunction", "line 3, column 5: GOTO outer: label not defined"},
+		{"a:\nprint 1\nA:", "line 3, column 1: label A is already defined at line 1"},
+	}
+
+	for _, tt := range tests {
+		interp, output := newTestInterpreter()
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+		if len(*output) != 0 {
+			t.Errorf("%q: expected the program to be rejected before running, got output %v", tt.code, *output)
+		}
+	}
+}
+
+// =============================================================================
+// TRY/CATCH Tests
+// =============================================================================
+
+func TestTryCatch(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.RegisterFunction("fail", func(args ...interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("host refused")
+	})
+
+	err := interp.Interpret(`
+let hp = 10
+try
+    hp = hp / 0
+    print "not reached"
+catch e
+    print "caught: " + e
+endtry
+try
+    fail()
+catch e
+    print "caught: " + e
+endtry
+try
+    hp = hp - 1
+catch e
+    print "not reached"
+endtry
+print hp`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"caught: division by zero", "caught: host refused", 9}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestTryCatchAcrossFunctions(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+function inner(n):
+    for i = 1 to 3
+        if i = n then
+            return missing(i)
+        endif
+    next
+    return 0
+endfunction
+
+function outer(n):
+    try
+        return inner(n)
+    catch problem
+        return "recovered from " + problem
+    endtry
+endfunction
+
+print outer(2)
+print outer(5)
+print inner(5)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"recovered from undefined function: missing", 0, 0}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+	if stack := interp.CallStack(); len(stack) != 0 {
+		t.Errorf("expected an empty call stack after recovering, got %v", stack)
+	}
+}
+
+func TestTryCatchScopeAndRethrow(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+try
+    try
+        let x = 1 / 0
+    catch e
+        print "inner"
+        let y = e + 1 / 0
+    endtry
+catch e
+    print "outer: " + e
+endtry
+print e`)
+	if err == nil || !strings.Contains(err.Error(), "undefined variable: e") {
+		t.Errorf("expected the CATCH variable to be gone after ENDTRY, got %v", err)
+	}
+
+	expected := []interface{}{"inner", "outer: division by zero"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestTryCannotCatchLimits(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetMaxIterations(10)
+	err := interp.Interpret(`
+try
+    do
+    loop
+catch e
+    print "caught"
+endtry`)
+	if err == nil || !strings.Contains(err.Error(), "maximum iterations exceeded (10)") {
+		t.Errorf("expected the iteration limit to end the run, got %v", err)
+	}
+
+	interp, _ = newTestInterpreter()
+	stop := fmt.Errorf("stopped")
+	interp.SetDebugHook(func(frame basic.DebugFrame) error {
+		if frame.Line == 3 {
+			return stop
+		}
+		return nil
+	})
+	err = interp.Interpret("try\n    print 1\n    print 2\ncatch e\n    print e\nendtry")
+	if err != stop {
+		t.Errorf("expected the hook error to end the run, got %v", err)
+	}
+
+	interp, output := newTestInterpreter()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		interp.Stop()
+	}()
+	interp.SetMaxIterations(math.MaxInt)
+	err = interp.Interpret("try\n    do\n    loop\ncatch e\n    print e\nendtry")
+	if !errors.Is(err, basic.ErrInterrupted) {
+		t.Errorf("expected Stop to end the run, got %v", err)
+	}
+	if len(*output) != 0 {
+		t.Errorf("expected the CATCH block not to run, got %v", *output)
+	}
+}
+
+func TestThrow(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+let hp = -3
+if hp < 0 then
+    throw "hp must not be negative, got " + hp
+endif
+print "not reached"`)
+
+	var scriptErr *basic.ScriptError
+	if !errors.As(err, &scriptErr) {
+		t.Fatalf("expected a ScriptError, got %v", err)
+	}
+	if scriptErr.Message != "hp must not be negative, got -3" || scriptErr.Line != 4 || scriptErr.Column != 5 {
+		t.Errorf("unexpected ScriptError: %+v", scriptErr)
+	}
+	if err.Error() != "runtime error at line 4, column 5: hp must not be negative, got -3" {
+		t.Errorf("unexpected message: %v", err)
+	}
+	if len(*output) != 0 {
+		t.Errorf("expected no output, got %v", *output)
+	}
+
+	// Other runtime errors aren't ScriptErrors
+	err = interp.Interpret("print 1 / 0")
+	if err == nil || errors.As(err, &scriptErr) {
+		t.Errorf("expected a plain runtime error, got %v", err)
+	}
+}
+
+func TestThrowCaughtAndRethrown(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Load(`
+function spend(gold, cost):
+    if cost > gold then
+        throw "not enough gold"
+    endif
+    return gold - cost
+endfunction
+
+function buy(gold, cost):
+    try
+        return spend(gold, cost)
+    catch e
+        print "refused: " + e
+        throw "purchase failed: " + e
+    endtry
+endfunction`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := interp.Call("buy", 10, 4)
+	if err != nil || result != 6 {
+		t.Fatalf("expected 6, got %v (%v)", result, err)
+	}
+
+	_, err = interp.Call("buy", 3, 4)
+	var scriptErr *basic.ScriptError
+	if !errors.As(err, &scriptErr) {
+		t.Fatalf("expected a ScriptError, got %v", err)
+	}
+	if scriptErr.Message != "purchase failed: not enough gold" || scriptErr.Line != 14 {
+		t.Errorf("unexpected ScriptError: %+v", scriptErr)
+	}
+
+	expected := []interface{}{"refused: not enough gold"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+// =============================================================================
+// ON ERROR Tests
+// =============================================================================
+
+func TestOnErrorCall(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+function report(message, line):
+    print "line " + line + ": " + message
+endfunction
+
+on error call report
+let hp = 10
+hp = hp / 0
+print missing
+if hp > 5 then
+    hp = hp + "x" * 2
+    print "still in IF"
+endif
+print hp`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{
+		"line 8: division by zero",
+		"line 9: undefined variable: missing",
+		"line 11: cannot multiply string and int",
+		"still in IF",
+		10,
+	}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestOnErrorPersistsAcrossCall(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	var reports []string
+	interp.RegisterFunction("report", func(args ...interface{}) (interface{}, error) {
+		reports = append(reports, fmt.Sprintf("%v@%v", args[0], args[1]))
+		return nil, nil
+	})
+
+	err := interp.Load(`
+on error call report
+
+function update(n):
+    let result = 100 / n
+    return n
+endfunction`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The failing statement is skipped and the function carries on
+	result, err := interp.Call("update", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 0 {
+		t.Errorf("expected 0, got %v", result)
+	}
+	if len(reports) != 1 || reports[0] != "division by zero@5" {
+		t.Errorf("unexpected reports: %v", reports)
+	}
+
+	// Loading another script clears the handler
+	if err := interp.Load("function update(n):\n    return 100 / n\nendfunction"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := interp.Call("update", 0); err == nil {
+		t.Error("expected the error to abort the call once the handler is gone")
+	}
+}
+
+func TestOnErrorPrecedenceAndFailures(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+sub report(message, line):
+    print "handler: " + message
+endsub
+
+on error call report
+try
+    let x = 1 / 0
+catch e
+    print "catch: " + e
+    let y = 1 / 0
+endtry`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"catch: division by zero", "handler: division by zero"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+
+	// Errors raised by the handler itself end the run
+	interp, _ = newTestInterpreter()
+	err = interp.Interpret(`
+sub report(message, line):
+    print missing
+endsub
+
+on error call report
+print 1 / 0`)
+	if err == nil || !strings.Contains(err.Error(), "undefined variable: missing") {
+		t.Errorf("expected the handler's error, got %v", err)
+	}
+
+	interp, _ = newTestInterpreter()
+	err = interp.Interpret("on error call nothing\nprint 1")
+	if err == nil || !strings.Contains(err.Error(), "ON ERROR: undefined function: nothing") {
+		t.Errorf("expected undefined handler error, got %v", err)
+	}
+
+	err = interp.Interpret("sub report(message):\nendsub\non error call report")
+	if err == nil || !strings.Contains(err.Error(), "must take 2 parameters") {
+		t.Errorf("expected handler arity error, got %v", err)
+	}
+
+	interp.SetMaxIterations(10)
+	err = interp.Interpret("sub report(message, line):\nendsub\non error call report\ndo\nloop")
+	if err == nil || !strings.Contains(err.Error(), "maximum iterations exceeded") {
+		t.Errorf("expected the iteration limit to end the run, got %v", err)
+	}
+}
+
+// =============================================================================
+// CONST Tests
+// =============================================================================
+
+func TestConst(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Load(`
+const MAX_HP = 100
+const GREETING = "hp: "
+
+function heal(hp):
+    return min2(hp + 30, max_hp)
+endfunction
+
+function min2(a, b):
+    if a < b then
+        return a
+    endif
+    return b
+endfunction
+
+print GREETING + MAX_HP`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := interp.Call("heal", 90)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 100 {
+		t.Errorf("expected 100, got %v", result)
+	}
+
+	expected := []interface{}{"hp: 100"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestConstCannotBeAssigned(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{"assign", "const max = 1\nmax = 2", "line 2, column 1: cannot assign to constant max"},
+		{"compound", "const max = 1\nmax += 2", "cannot assign to constant max"},
+		{"element", "const names = map()\nnames(\"a\") = 1", "cannot assign to constant names"},
+		{"let in function", "const max = 1\nfunction f():\n    let MAX = 2\n    return MAX\nendfunction", "line 3, column 5: cannot assign to constant MAX"},
+		{"dim", "const grid = 1\ndim grid(3)", "cannot assign to constant grid"},
+		{"loop variable", "const i = 1\nfor i = 1 to 3\nnext", "cannot assign to constant i"},
+		{"parameter", "function f(max):\n    return max\nendfunction\nconst max = 1", "cannot assign to constant max"},
+		{"catch variable", "const e = 1\ntry\ncatch e\nendtry", "cannot assign to constant e"},
+		{"duplicate", "const max = 1\nconst MAX = 2", "line 2, column 1: constant MAX is already defined at line 1"},
+		{"inside block", "if true then\n    const max = 1\nendif", "CONST max must be declared at the top level"},
+	}
+
+	for _, tt := range tests {
+		interp, output := newTestInterpreter()
+		err := interp.Interpret(tt.code + "\nprint \"ran\"")
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.expected, err)
+		}
+		if len(*output) != 0 {
+			t.Errorf("%s: expected the program to be rejected before running, got %v", tt.name, *output)
+		}
+	}
+}
+
+func TestConstAcrossRuns(t *testing.T) {
+	interp, output := newTestInterpreter()
+
+	// Rerunning the declaration is allowed
+	for run := 0; run < 2; run++ {
+		if err := interp.Interpret("const max_hp = 100"); err != nil {
+			t.Fatalf("run %d: unexpected error: %v", run, err)
+		}
+	}
+
+	err := interp.Interpret("max_hp = 5")
+	if err == nil || !strings.Contains(err.Error(), "runtime error at line 1, column 1: cannot assign to constant max_hp") {
+		t.Errorf("expected a runtime error, got %v", err)
+	}
+	if err := interp.Interpret("print max_hp"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{100}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+// =============================================================================
+// GLOBAL and Scope Mode Tests
+// =============================================================================
+
+const scopeScript = `
+let score = 0
+
+function award(points):
+    score = score + points
+    return score
+endfunction
+
+function awardGlobal(points):
+    global score
+    score = score + points
+    return score
+endfunction
+
+function withLocalScore():
+    let score = 1000
+    return awardGlobal(5)
+endfunction
+`
+
+func TestGlobalDynamicScope(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	if err := interp.Load(scopeScript); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// By default assignment updates the global that already exists
+	if result, err := interp.Call("award", 10); err != nil || result != 10 {
+		t.Fatalf("expected 10, got %v (%v)", result, err)
+	}
+
+	// GLOBAL skips over a caller's local of the same name
+	if result, err := interp.Call("withLocalScore"); err != nil || result != 15 {
+		t.Fatalf("expected 15, got %v (%v)", result, err)
+	}
+	if score := interp.Globals()["score"]; score != 15 {
+		t.Errorf("expected global score 15, got %v", score)
+	}
+}
+
+func TestGlobalLocalScopeMode(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetScopeMode(basic.ScopeLocal)
+	if err := interp.Load(scopeScript); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The assignment creates a local, leaving the global alone
+	if result, err := interp.Call("award", 10); err != nil || result != 10 {
+		t.Fatalf("expected 10, got %v (%v)", result, err)
+	}
+	if score := interp.Globals()["score"]; score != 0 {
+		t.Errorf("expected global score 0, got %v", score)
+	}
+
+	if result, err := interp.Call("awardGlobal", 7); err != nil || result != 7 {
+		t.Fatalf("expected 7, got %v (%v)", result, err)
+	}
+	if score := interp.Globals()["score"]; score != 7 {
+		t.Errorf("expected global score 7, got %v", score)
+	}
+
+	// Top-level code and GLOBAL can still create globals
+	err := interp.Interpret(`
+function setup():
+    global lives
+    lives = 3
+endfunction
+setup()
+print lives`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// =============================================================================
+// Line Continuation Tests
+// =============================================================================
+
+func TestLineContinuation(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`function clamp(value,
+               lo, hi):
+    return iif(value < lo, lo, _
+               iif(value > hi, hi, value))
+endfunction
+let total = 1 + _
+    2 *
+    3
+print clamp(total, 0, 5), total
+print -"x"`)
+	if err == nil || !strings.Contains(err.Error(), "line 10") {
+		t.Errorf("expected an error on line 10, got %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != "5 7" {
+		t.Errorf("expected [5 7], got %v", *output)
+	}
+}
+
+// =============================================================================
+// OPTION EXPLICIT Tests
+// =============================================================================
+
+func TestOptionExplicit(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`option explicit
+let hp = 10
+dim items(2)
+const MAX = 3
+hp = hp - 1
+items(0) = MAX
+let a, b = 1, 2
+a, b = b, a
+function heal(amount):
+    local total = hp + amount
+    total = total * 1
+    return total
+endfunction
+function reset():
+    global score
+    score = 0
+endfunction
+reset()
+for i = 1 to 2
+    i = i
+next
+try
+    throw "oops"
+catch e
+    e = "caught"
+endtry
+print hp, items(0), a, heal(5), score`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != "9 3 2 14 0" {
+		t.Errorf("expected [9 3 2 14 0], got %v", *output)
+	}
+}
+
+func TestOptionExplicitErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"option explicit\nlet hp = 10\nhel = hp - 1", "line 3, column 1: variable hel is not declared"},
+		{"option explicit\nlet a = 1\na, typo = 2, 3", "variable typo is not declared"},
+		{"option explicit\nfunction f():\n    total = 1\nendfunction\nf()", "line 3, column 5: variable total is not declared"},
+		{"function f():\n    option explicit\nendfunction", "OPTION EXPLICIT must be at the top level of the script"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+func TestSetStrictVariables(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	if err := interp.Interpret("hp = 10"); err != nil {
+		t.Fatalf("unexpected error without strict variables: %v", err)
+	}
+
+	interp.SetStrictVariables(true)
+	if err := interp.Load("let hp = 10\nhp = 9"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interp.Load("hp = 10"); err == nil || !strings.Contains(err.Error(), "variable hp is not declared") {
+		t.Errorf("expected undeclared variable error, got %v", err)
+	}
+}
+
+// =============================================================================
+// Case Sensitivity Tests
+// =============================================================================
+
+func TestCaseSensitiveIdentifiers(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetCaseSensitive(true)
+	interp.RegisterFunction("GetHealth", func(args ...interface{}) (interface{}, error) {
+		return 50, nil
+	})
+
+	err := interp.Load(`TYPE Vec
+    X, x
+ENDTYPE
+let Player = "hero"
+let player = "npc"
+function Greet(Name):
+    return "hi " + Name
+endfunction
+function greet(name):
+    return "yo " + name
+endfunction
+let v = Vec(1, 2)
+PRINT Player, player, Greet("a"), greet("b"), v.X, v.x, GetHealth()`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != "hero npc hi a yo b 1 2 50" {
+		t.Errorf("expected [hero npc hi a yo b 1 2 50], got %v", *output)
+	}
+
+	if result, err := interp.Call("Greet", "c"); err != nil || result != "hi c" {
+		t.Errorf("expected hi c, got %v (%v)", result, err)
+	}
+	if _, err := interp.Call("GREET"); err == nil {
+		t.Error("expected error calling GREET")
+	}
+	if globals := interp.Globals(); globals["Player"] != "hero" || globals["player"] != "npc" {
+		t.Errorf("expected both Player and player globals, got %v", globals)
+	}
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"print gethealth()", "gethealth"},
+		{"let hp = 1\nprint HP", "undefined variable: HP"},
+		{"TYPE Vec\n    x\nENDTYPE\nlet v = Vec(1)\nprint v.X", "type Vec has no field X"},
+	}
+	for _, tt := range tests {
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+func TestCaseInsensitiveByDefault(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`let Player = "hero"
+function Greet(name):
+    return "hi " + NAME
+endfunction
+print player, GREET("a")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*output) != 1 || (*output)[0] != "hero hi a" {
+		t.Errorf("expected [hero hi a], got %v", *output)
+	}
+
+	// Names that differ only in case collide unless case-sensitive
+	code := "const max = 1\nconst MAX = 2"
+	if err := interp.Validate(code); err == nil {
+		t.Error("expected duplicate constant error")
+	}
+	interp.SetCaseSensitive(true)
+	if err := interp.Validate(code); err != nil {
+		t.Errorf("unexpected error in case-sensitive mode: %v", err)
+	}
+}
+
+func TestGlobalErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"global score", "line 1, column 1: GLOBAL is only allowed inside a function"},
+		{"function f(score):\n    global score\nendfunction", "score is a parameter of f and cannot be declared GLOBAL"},
+		{"function f():\n    global score\n    let score = 1\nendfunction", "line 3, column 5: score is declared GLOBAL and cannot also be a local variable"},
+		{"function f():\n    global i\n    for i = 1 to 2\n    next\nendfunction", "i is declared GLOBAL and cannot also be a local variable"},
+		{"function f():\n    global missing\n    return missing\nendfunction\nf()", "undefined variable: missing"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Load(`
+let tmp = "shared"
+let total = 0
+
+function sumTo(n):
+    for i = 1 to n
+        local total = 0
+        break
+    next
+    for i = 1 to n
+        total = total + i
+    next
+    return total
+endfunction
+
+function update(n):
+    local tmp = n
+    tmp = tmp * 2
+    return tmp + sumTo(n)
+endfunction`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := interp.Call("update", 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 18 {
+		t.Errorf("expected 18, got %v", result)
+	}
+
+	// sumTo's LOCAL outlived its loop, and update's LOCAL hid the global
+	// from its own assignment
+	globals := interp.Globals()
+	if globals["total"] != 0 || globals["tmp"] != "shared" {
+		t.Errorf("expected globals to be untouched, got total=%v tmp=%v", globals["total"], globals["tmp"])
+	}
+	if len(*output) != 0 {
+		t.Errorf("unexpected output: %v", *output)
+	}
+
+	for _, code := range []string{
+		"local tmp = 1",
+		"function f():\n    global tmp\n    local tmp = 1\nendfunction",
+		"const tmp = 1\nfunction f():\n    local tmp = 1\nendfunction",
+	} {
+		if err := interp.Interpret(code); err == nil {
+			t.Errorf("%q: expected error", code)
+		}
+	}
+}
+
+// =============================================================================
+// Multiple Assignment Tests
+// =============================================================================
+
+func TestMultipleAssignment(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+function minmax(a, b):
+    if a > b then
+        a, b = b, a
+    endif
+    dim pair(2)
+    pair(0), pair(1) = a, b
+    return pair
+endfunction
+
+let x, y = 1, "two"
+x, y = y, x
+print x, y
+
+let lo, hi = minmax(9, 4)
+print lo, hi
+
+dim order(3)
+order(0), order(1), order(2) = 3, 2, 1
+order(0), order(2) = order(2), order(0)
+print order(0), order(1), order(2)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"two 1", "4 9", "1 2 3"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+
+	result, err := interp.Evaluate("a, b = 6, 7\na * b")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 42 {
+		t.Errorf("expected 42, got %v", result)
+	}
+}
+
+func TestMultipleAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"let a, b = 5", "line 1, column 1: cannot unpack int into 2 variables"},
+		{"dim arr(3)\nlet a, b = arr", "line 2, column 1: cannot unpack 3 values into 2 variables"},
+		{"const a = 1\na, b = 2, 3", "cannot assign to constant a"},
+		{"dim arr(2)\narr(0), arr(5) = 1, 2", "index 5 out of range"},
+	}
+
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
+
+func TestSingleLineIf(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+function describe(x):
+    if x > 5 then return "big" else return "small"
+endfunction
+
+let tries = 0
+retry:
+tries++
+if tries < 3 then goto retry
+if tries = 3 then print "three"
+print describe(9), describe(2)
+if false then print "no"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"three", "big small"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestColonSeparators(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`x = 1: y = 2: print x + y
+let total = 0: for i = 1 to 3: total += i: next: print total
+if x > 5 then print "big": x = 0 else print "small": x = 10
+print x
+if false then print "no": print "still no"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{3, 6, "small", 10}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+// =============================================================================
+// IIF Tests
+// =============================================================================
+
+func TestIif(t *testing.T) {
+	interp, output := newTestInterpreter()
+	calls := 0
+	interp.RegisterFunction("expensive", func(args ...interface{}) (interface{}, error) {
+		calls++
+		return "expensive", nil
+	})
+
+	err := interp.Interpret(`
+function fact(n):
+    return iif(n <= 1, 1, n * fact(n - 1))
+endfunction
+
+let count = 0
+print iif(count = 0, "none", 100 / count)
+print iif(count > 0, expensive(), "cheap")
+print iif(fact(5) = 120, "yes", "no")
+print "hp: " + iif(count, count, iif(true, "full", "empty"))`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"none", "cheap", "yes", "hp: full"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+	if calls != 0 {
+		t.Errorf("expected the untaken branch not to run, got %d calls", calls)
+	}
+
+	err = interp.Interpret("function iif(a, b, c):\n    return a\nendfunction")
+	if err == nil || !strings.Contains(err.Error(), "iif is built into the language") {
+		t.Errorf("expected redefinition error, got %v", err)
+	}
+}
+
+// =============================================================================
+// Lexical Scope and Closure Tests
+// =============================================================================
+
+func TestFunctionsSeeDefinitionScope(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Load(`
+let counter = 0
+
+function bump():
+    counter = counter + 1
+    return counter
+endfunction
+
+function caller():
+    let counter = 100
+    return bump() + counter
+endfunction`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// bump updates the global, not the local of the function calling it
+	for _, expected := range []int{101, 102} {
+		result, err := interp.Call("caller")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != expected {
+			t.Errorf("expected %d, got %v", expected, result)
+		}
+	}
+	if counter := interp.Globals()["counter"]; counter != 2 {
+		t.Errorf("expected global counter 2, got %v", counter)
+	}
+	if len(*output) != 0 {
+		t.Errorf("unexpected output: %v", *output)
+	}
+}
+
+func TestNestedFunctions(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Load(`
+function scaled(n, k):
+    function fact(x):
+        if x <= 1 then
+            return k
+        endif
+        return x * fact(x - 1)
+    endfunction
+    return twice(fact(n))
+
+    function twice(x):
+        return x * 2
+    endfunction
+endfunction`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// fact recurses, reads scaled's parameter and calls a later sibling
+	result, err := interp.Call("scaled", 4, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 144 {
+		t.Errorf("expected 144, got %v", result)
+	}
+
+	// Nested functions aren't visible outside their enclosing function
+	if _, err := interp.Call("fact", 3); err == nil {
+		t.Error("expected error calling a nested function from the host")
+	}
+	if err := interp.Interpret("print twice(2)"); err == nil || !strings.Contains(err.Error(), "undefined function: twice") {
+		t.Errorf("expected undefined function error, got %v", err)
+	}
+	if len(*output) != 0 {
+		t.Errorf("unexpected output: %v", *output)
+	}
+}
+
+func TestClosureCounters(t *testing.T) {
+	interp, output := newTestInterpreter()
+	err := interp.Interpret(`
+function makeCounter(start):
+    let count = start
+    function increment():
+        count = count + 1
+        return count
+    endfunction
+    return increment
+endfunction
+
+let a = makeCounter(0)
+let b = makeCounter(10)
+print a()
+print a()
+print b()
+print "" + a`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Each call of makeCounter creates a separate count
+	expected := []interface{}{1, 2, 11, "function increment"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestFunctionDefinedInBlock(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	err := interp.Interpret("if 1 then\n    function f():\n        return 1\n    endfunction\nendif")
+	expected := "line 2, column 5: function f must be defined at the top level of the script or of a function"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+// =============================================================================
+// IMPORT Tests
+// =============================================================================
+
+// moduleResolver serves modules from a map of name to source
+func moduleResolver(modules map[string]string) basic.ModuleResolver {
+	return func(name string) (string, error) {
+		code, ok := modules[name]
+		if !ok {
+			return "", fmt.Errorf("module not found")
+		}
+		return code, nil
+	}
+}
+
+func TestImport(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetModuleResolver(moduleResolver(map[string]string{
+		"utils": `
+print "loading utils"
+let calls = 0
+
+function clamp(x, lo, hi):
+    calls = calls + 1
+    return _limit(_limit(x, lo, 1), hi, -1)
+endfunction
+
+function _limit(x, bound, sign):
+    if (x - bound) * sign < 0 then
+        return bound
+    endif
+    return x
+endfunction
+
+function callCount():
+    global calls
+    return calls
+endfunction`,
+		"combat": `
+import "utils"
+
+function hit(hp, damage):
+    return clamp(hp - damage, 0, 100)
+endfunction`,
+	}))
+
+	err := interp.Load(`
+import "utils"
+import "combat"
+let calls = 100
+
+function heal(hp):
+    return clamp(hp + 50, 0, 100)
+endfunction
+
+print hit(30, 50)
+print heal(80)
+print callCount()`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// utils runs once and keeps its own calls, shared by both importers
+	expected := []interface{}{"loading utils", 0, 100, 2}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+
+	result, err := interp.Call("heal", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 60 {
+		t.Errorf("expected 60, got %v", result)
+	}
+
+	globals := interp.Globals()
+	if globals["calls"] != 100 {
+		t.Errorf("expected script's calls to be 100, got %v", globals["calls"])
+	}
+	if _, ok := globals["clamp"]; ok {
+		t.Error("expected imported functions to be left out of Globals")
+	}
+
+	// Rerunning a script imports the module afresh
+	*output = nil
+	if err := interp.Interpret("import \"utils\"\nprint callCount()"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = []interface{}{"loading utils", 0}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	modules := map[string]string{
+		"utils":  "function clamp(x):\n    return _helper(x)\nendfunction\nfunction _helper(x):\n    return x\nendfunction",
+		"other":  "function clamp(x):\n    return 0\nendfunction",
+		"broken": "let x = ",
+		"a":      `import "b"`,
+		"b":      `import "a"`,
+	}
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`import "missing"`, `line 1, column 1: import "missing": module not found`},
+		{`import "broken"`, `import "broken": line 1, column 9`},
+		{`import "a"`, `import "a": circular import`},
+		{"import \"utils\"\nimport \"other\"", `line 2, column 1: import "other": clamp is already defined`},
+		{"import \"utils\"\nfunction clamp(x):\n    return x\nendfunction", `import "utils": clamp is already defined`},
+		{"function f():\n    import \"utils\"\nendfunction", `line 2, column 5: IMPORT "utils" must be at the top level`},
+		{"import \"utils\"\nprint _helper(1)", "line 2, column 7: undefined function: _helper"},
+	}
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		interp.SetModuleResolver(moduleResolver(modules))
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+
+	interp, _ := newTestInterpreter()
+	if err := interp.Interpret(`import "utils"`); err == nil || !strings.Contains(err.Error(), "no module resolver is set") {
+		t.Errorf("expected error without a resolver, got %v", err)
+	}
+}
+
+// =============================================================================
+// Includes
+// =============================================================================
+
+func sourceFS(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, source := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(source)}
+	}
+	return fsys
+}
+
+func TestInclude(t *testing.T) {
+	interp, output := newTestInterpreter()
+	interp.SetSourceFS(sourceFS(map[string]string{
+		"lib/stats.bas": "include \"math.bas\"\n\nfunction mean(a, b):\n    return half(a + b)\nendfunction",
+		"lib/math.bas":  "const TWO = 2\n\nfunction half(x):\n    return x / TWO\nendfunction",
+	}))
+
+	code := `include "lib/stats.bas"
+include "lib/math.bas"
+print mean(3, 5)
+print TWO`
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []interface{}{4, 2}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+
+	// Running again uses the cached ASTs
+	*output = nil
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error on rerun: %v", err)
+	}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v on rerun, got %v", expected, *output)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	files := sourceFS(map[string]string{
+		"a.bas":       `include "b.bas"`,
+		"b.bas":       `include "a.bas"`,
+		"broken.bas":  "print 1\nlet x = ",
+		"fail.bas":    "function fail():\n    return 1 / nothing()\nendfunction",
+		"lib/one.bas": "const N = 1",
+	})
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`include "missing.bas"`, `line 1, column 1: INCLUDE "missing.bas": open missing.bas: file does not exist`},
+		{`include "a.bas"`, `b.bas: line 1, column 1: INCLUDE "a.bas": include cycle: a.bas -> b.bas -> a.bas`},
+		{`include "broken.bas"`, "broken.bas: line 2, column 9"},
+		{"include \"fail.bas\"\nprint fail()", "undefined function: nothing"},
+		{"include \"lib/one.bas\"\nconst N = 2", "line 2, column 1: constant N is already defined"},
+		{"if true then\n    include \"a.bas\"\nendif", `line 2, column 5: INCLUDE "a.bas" must be at the top level of the script`},
+	}
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		interp.SetSourceFS(files)
+		err := interp.Interpret(tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+
+	interp, _ := newTestInterpreter()
+	if err := interp.Interpret(`include "a.bas"`); err == nil || !strings.Contains(err.Error(), "no source file system is set") {
+		t.Errorf("expected error without a source FS, got %v", err)
+	}
+}
+
+func TestIncludeRuntimeErrorNamesFile(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetSourceFS(sourceFS(map[string]string{
+		"lib/check.bas": "\nfunction check(x):\n    throw \"bad value\"\nendfunction",
+		"lib/neg.bas":   "function neg(x):\n    return -x\nendfunction",
+	}))
+
+	err := interp.Interpret("include \"lib/check.bas\"\ncheck(1)")
+	var scriptErr *basic.ScriptError
+	if !errors.As(err, &scriptErr) {
+		t.Fatalf("expected a ScriptError, got %v", err)
+	}
+	if scriptErr.File != "lib/check.bas" || scriptErr.Line != 3 {
+		t.Errorf("expected the THROW in lib/check.bas at line 3, got %+v", scriptErr)
+	}
+
+	err = interp.Interpret("include \"lib/neg.bas\"\nprint neg(\"a\")")
+	if err == nil || !strings.Contains(err.Error(), "runtime error in lib/neg.bas at line 2, column") {
+		t.Errorf("expected a runtime error in lib/neg.bas, got %v", err)
+	}
+}
+
+// =============================================================================
+// Records
+// =============================================================================
+
+func TestRecords(t *testing.T) {
+	interp, output := newTestInterpreter()
+
+	code := `let v = Vec2(1, 2)
+print v.x + v.y
+v.x = 10
+v.Y += 5
+v.x++
+print "" + v
+
+dim es(2)
+es(0) = Entity("orc", Vec2(3, 4), hp = 20)
+es(0).pos.x -= 1
+es(0).hp--
+print es(0).pos.x, es(0).hp, es(0).name[0], es(1)
+
+a, v.y = 1, 99
+print v.y, Vec2().x
+
+type Vec2
+    x
+    y
+endtype
+
+type Entity
+    name, pos
+    hp
+endtype`
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []interface{}{3, "Vec2(x = 11, y = 7)", "2 19 o 0", "99 0"}
+	if !reflect.DeepEqual(*output, expected) {
+		t.Errorf("expected %v, got %v", expected, *output)
+	}
+
+	if typ, _ := interp.VarType("v"); typ != "Vec2" {
+		t.Errorf("expected VarType Vec2, got %q", typ)
+	}
+	rec := interp.Globals()["v"].(*basic.Record)
+	if x, ok := rec.Field("X"); !ok || x != 11 {
+		t.Errorf("expected field x = 11, got %v", x)
+	}
+}
+
+func TestRecordsAreShared(t *testing.T) {
+	interp, output := newTestInterpreter()
+
+	code := `type Counter
+    n
+endtype
+
+sub bump(c):
+    c.n += 1
+endsub
+
+let a = Counter()
+let b = a
+bump(a)
+bump(b)
+print a.n`
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*output, []interface{}{2}) {
+		t.Errorf("expected [2], got %v", *output)
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	types := "type Vec2\n    x\n    y\nendtype\n"
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"let v = Vec2(1, 2, 3)", "line 5, column 9: type Vec2 has 2 fields, got 3 arguments"},
+		{"let v = Vec2(z = 1)", "line 5, column 14: type Vec2 has no field z"},
+		{"let v = Vec2(1, x = 2)", "argument x is already passed by position"},
+		{"let v = Vec2()\nprint v.z", "line 6, column 8: type Vec2 has no field z"},
+		{"let v = Vec2()\nv.z = 1", "line 6, column 1: type Vec2 has no field z"},
+		{"let n = 1\nprint n.x", "cannot access field x of int"},
+		{"let n = 1\nn.x = 2", "cannot access field x of int"},
+		{"const ORIGIN = Vec2()\nORIGIN.x = 1", "cannot assign to constant ORIGIN"},
+		{"type Vec2\n    z\nendtype", "line 5, column 1: type Vec2 is already defined at line 1"},
+		{"type Pair\n    a\n    A\nendtype", "field A is repeated in TYPE Pair"},
+		{"function Vec2():\n    return 0\nendfunction", "line 1, column 1: type Vec2 has the same name as the function defined at line 5"},
+		{"sub f():\n    type Inner\n        a\n    endtype\nendsub", "line 6, column 5: TYPE Inner must be defined at the top level of the script"},
+	}
+	for _, tt := range tests {
+		interp, _ := newTestInterpreter()
+		err := interp.Interpret(types + tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.code, tt.expected, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package basic
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/mechanical-lich/mechanical-basic/internal/basic"
@@ -130,13 +131,49 @@ func TestParsePrint(t *testing.T) {
 		t.Fatalf("expected PrintStatement, got %T", prog.Statements[0])
 	}
 
-	str, ok := print.Value.(*basic.StringLiteral)
+	if len(print.Values) != 1 {
+		t.Fatalf("expected 1 value, got %d", len(print.Values))
+	}
+	str, ok := print.Values[0].(*basic.StringLiteral)
 	if !ok {
-		t.Fatalf("expected StringLiteral, got %T", print.Value)
+		t.Fatalf("expected StringLiteral, got %T", print.Values[0])
 	}
 	if str.Value != "Hello" {
 		t.Errorf("expected 'Hello', got %q", str.Value)
 	}
+	if print.NoNewline {
+		t.Error("expected a plain PRINT to end the line")
+	}
+
+	prog = parseCode(t, `print "hp:", hp, max(hp, 1)`)
+	print = prog.Statements[0].(*basic.PrintStatement)
+	if len(print.Values) != 3 {
+		t.Fatalf("expected 3 values, got %d", len(print.Values))
+	}
+	if call, ok := print.Values[2].(*basic.CallExpr); !ok || len(call.Args) != 2 {
+		t.Errorf("expected the call to keep both arguments, got %#v", print.Values[2])
+	}
+
+	prog = parseCode(t, "print \"a\", b;\nprint c")
+	if len(prog.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(prog.Statements))
+	}
+	if print = prog.Statements[0].(*basic.PrintStatement); !print.NoNewline || len(print.Values) != 2 {
+		t.Errorf("expected trailing ; to set NoNewline, got %+v", print)
+	}
+}
+
+func TestParsePrintUsing(t *testing.T) {
+	prog := parseCode(t, `print using "## ##"; a, b;`)
+
+	print := prog.Statements[0].(*basic.PrintStatement)
+	format, ok := print.Using.(*basic.StringLiteral)
+	if !ok || format.Value != "## ##" {
+		t.Fatalf("expected the format string, got %#v", print.Using)
+	}
+	if len(print.Values) != 2 || !print.NoNewline {
+		t.Errorf("expected 2 values and no newline, got %d values, NoNewline %v", len(print.Values), print.NoNewline)
+	}
 }
 
 func TestParseIfThenEndif(t *testing.T) {
@@ -262,6 +299,631 @@ next i`
 	_ = breakStmt
 }
 
+func TestParseExit(t *testing.T) {
+	tests := []struct {
+		code     string
+		kind     basic.TokenType
+		variable string
+	}{
+		{"exit for", basic.TOKEN_FOR, ""},
+		{"EXIT FOR row", basic.TOKEN_FOR, "row"},
+		{"exit do", basic.TOKEN_DO, ""},
+		{"exit while", basic.TOKEN_WHILE, ""},
+		{"exit function", basic.TOKEN_FUNCTION, ""},
+		{"exit sub", basic.TOKEN_SUB, ""},
+	}
+
+	for _, tt := range tests {
+		tokens, err := basic.Tokenize(tt.code)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.code, err)
+		}
+		prog, err := basic.Parse(tokens)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.code, err)
+		}
+		stmt, ok := prog.Statements[0].(*basic.ExitStatement)
+		if !ok {
+			t.Fatalf("%q: expected ExitStatement, got %T", tt.code, prog.Statements[0])
+		}
+		if stmt.Kind != tt.kind || stmt.Variable != tt.variable {
+			t.Errorf("%q: expected %s %q, got %s %q", tt.code, tt.kind, tt.variable, stmt.Kind, stmt.Variable)
+		}
+	}
+
+	tokens, _ := basic.Tokenize("exit loop")
+	if _, err := basic.Parse(tokens); err == nil || !strings.Contains(err.Error(), "expected FOR, DO, WHILE, FUNCTION or SUB after EXIT") {
+		t.Errorf("expected EXIT error, got %v", err)
+	}
+}
+
+func TestParseDoLoop(t *testing.T) {
+	tests := []struct {
+		code    string
+		hasCond bool
+		until   bool
+		preTest bool
+	}{
+		{"do\n    print 1\nloop until x > 3", true, true, false},
+		{"do\n    print 1\nloop while x < 3", true, false, false},
+		{"do while x < 3\n    print 1\nloop", true, false, true},
+		{"do until x > 3\n    print 1\nloop", true, true, true},
+		{"do\n    break\nloop", false, false, false},
+	}
+
+	for _, tt := range tests {
+		prog := parseCode(t, tt.code)
+		loop, ok := prog.Statements[0].(*basic.DoLoopStatement)
+		if !ok {
+			t.Fatalf("%q: expected DoLoopStatement, got %T", tt.code, prog.Statements[0])
+		}
+		if (loop.Condition != nil) != tt.hasCond || loop.Until != tt.until || loop.PreTest != tt.preTest {
+			t.Errorf("%q: unexpected loop %+v", tt.code, loop)
+		}
+		if len(loop.Body) != 1 {
+			t.Errorf("%q: expected 1 statement in body, got %d", tt.code, len(loop.Body))
+		}
+	}
+}
+
+func TestParseDoLoopErrors(t *testing.T) {
+	for _, code := range []string{
+		"do\n    print 1\n",
+		"do while x < 3\n    print 1\nloop until x > 5",
+		"do\n    print 1\nloop until",
+	} {
+		tokens, err := basic.Tokenize(code)
+		if err != nil {
+			t.Fatalf("tokenize error: %v", err)
+		}
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseSingleLineIf(t *testing.T) {
+	prog := parseCode(t, `if x > 5 then print "big" else print "small"
+if done then goto finish
+if a then
+    print a
+else
+    print b
+endif`)
+
+	if len(prog.Statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(prog.Statements))
+	}
+
+	both := prog.Statements[0].(*basic.IfStatement)
+	if len(both.ThenBlock) != 1 || len(both.ElseBlock) != 1 {
+		t.Errorf("expected one statement in each branch, got %+v", both)
+	}
+	if _, ok := both.ElseBlock[0].(*basic.PrintStatement); !ok {
+		t.Errorf("expected PRINT in ELSE, got %T", both.ElseBlock[0])
+	}
+
+	jump := prog.Statements[1].(*basic.IfStatement)
+	if _, ok := jump.ThenBlock[0].(*basic.GotoStatement); !ok || jump.ElseBlock != nil {
+		t.Errorf("unexpected single-line IF: %+v", jump)
+	}
+
+	for _, code := range []string{
+		"if x then print 1 else",
+		"if x then else print 1",
+		"if x then print 1\nelse print 2", // ELSE must be on the same line
+	} {
+		tokens, _ := basic.Tokenize(code)
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseColonSeparators(t *testing.T) {
+	prog := parseCode(t, `x = 1: y = 2: print x + y
+start: x++
+for i = 1 to 3: print i: next
+if x > 1 then a = 1: b = 2 else a = 0: b = 0:
+function f(): return 1: endfunction
+type V: x: y: endtype`)
+
+	if len(prog.Statements) != 9 {
+		t.Fatalf("expected 9 statements, got %d", len(prog.Statements))
+	}
+	if _, ok := prog.Statements[3].(*basic.LabelStatement); !ok {
+		t.Errorf("expected a label, got %T", prog.Statements[3])
+	}
+	if loop := prog.Statements[5].(*basic.ForStatement); len(loop.Body) != 1 {
+		t.Errorf("expected one statement in the loop, got %d", len(loop.Body))
+	}
+
+	// Every statement after THEN or ELSE on the line belongs to the branch
+	cond := prog.Statements[6].(*basic.IfStatement)
+	if len(cond.ThenBlock) != 2 || len(cond.ElseBlock) != 2 {
+		t.Errorf("expected two statements in each branch, got %d and %d", len(cond.ThenBlock), len(cond.ElseBlock))
+	}
+
+	if fn := prog.Statements[7].(*basic.FunctionStatement); len(fn.Body) != 1 {
+		t.Errorf("expected one statement in the function, got %d", len(fn.Body))
+	}
+	if typ := prog.Statements[8].(*basic.TypeStatement); len(typ.Fields) != 2 {
+		t.Errorf("expected two fields, got %v", typ.Fields)
+	}
+}
+
+func TestParseIif(t *testing.T) {
+	prog := parseCode(t, `let x = IIF(a > b, a, b) + 1`)
+
+	let := prog.Statements[0].(*basic.LetStatement)
+	sum, ok := let.Value.(*basic.BinaryExpr)
+	if !ok {
+		t.Fatalf("expected BinaryExpr, got %T", let.Value)
+	}
+	cond, ok := sum.Left.(*basic.ConditionalExpr)
+	if !ok {
+		t.Fatalf("expected ConditionalExpr, got %T", sum.Left)
+	}
+	if _, ok := cond.Condition.(*basic.BinaryExpr); !ok {
+		t.Errorf("expected comparison condition, got %T", cond.Condition)
+	}
+
+	tokens, _ := basic.Tokenize("let x = iif(a, b)")
+	if _, err := basic.Parse(tokens); err == nil || !strings.Contains(err.Error(), "line 1, column 9: iif requires 3 arguments") {
+		t.Errorf("expected argument count error, got %v", err)
+	}
+}
+
+func TestParseMultiAssign(t *testing.T) {
+	prog := parseCode(t, `let a, b = 1, 2
+a, grid(1, 2) = b, a
+x, y = pair()`)
+
+	let, ok := prog.Statements[0].(*basic.MultiAssignStatement)
+	if !ok {
+		t.Fatalf("expected MultiAssignStatement, got %T", prog.Statements[0])
+	}
+	if !let.Let || len(let.Targets) != 2 || let.Targets[1].Name != "b" || len(let.Values) != 2 {
+		t.Errorf("unexpected LET: %+v", let)
+	}
+
+	swap := prog.Statements[1].(*basic.MultiAssignStatement)
+	if swap.Let || swap.Targets[0].Name != "a" || swap.Targets[1].Name != "grid" || len(swap.Targets[1].Indices) != 2 {
+		t.Errorf("unexpected assignment: %+v", swap)
+	}
+
+	unpack := prog.Statements[2].(*basic.MultiAssignStatement)
+	if len(unpack.Targets) != 2 || len(unpack.Values) != 1 {
+		t.Errorf("unexpected unpacking: %+v", unpack)
+	}
+
+	for _, code := range []string{
+		"let a, = 1, 2",
+		"let a, b(1) = 1, 2",
+		"a, b 1, 2",
+		"a, b = 1, 2, 3",
+		"a, b() = 1, 2",
+	} {
+		tokens, _ := basic.Tokenize(code)
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseConst(t *testing.T) {
+	prog := parseCode(t, `const MAX_HP = 100 * 2`)
+
+	stmt, ok := prog.Statements[0].(*basic.ConstStatement)
+	if !ok {
+		t.Fatalf("expected ConstStatement, got %T", prog.Statements[0])
+	}
+	if stmt.Name != "MAX_HP" {
+		t.Errorf("expected name MAX_HP, got %q", stmt.Name)
+	}
+	if _, ok := stmt.Value.(*basic.BinaryExpr); !ok {
+		t.Errorf("expected BinaryExpr value, got %T", stmt.Value)
+	}
+
+	for _, code := range []string{"const = 1", "const x 1", "const x ="} {
+		tokens, _ := basic.Tokenize(code)
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseGlobal(t *testing.T) {
+	prog := parseCode(t, `function f():
+    global score, Lives
+endfunction`)
+
+	fn := prog.Statements[0].(*basic.FunctionStatement)
+	stmt, ok := fn.Body[0].(*basic.GlobalStatement)
+	if !ok {
+		t.Fatalf("expected GlobalStatement, got %T", fn.Body[0])
+	}
+	if len(stmt.Names) != 2 || stmt.Names[0] != "score" || stmt.Names[1] != "Lives" {
+		t.Errorf("unexpected names: %v", stmt.Names)
+	}
+
+	for _, code := range []string{"global", "global x,", "global 5"} {
+		tokens, _ := basic.Tokenize(code)
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseLocal(t *testing.T) {
+	prog := parseCode(t, `function f():
+    local tmp = 1 + 2
+endfunction`)
+
+	fn := prog.Statements[0].(*basic.FunctionStatement)
+	stmt, ok := fn.Body[0].(*basic.LocalStatement)
+	if !ok {
+		t.Fatalf("expected LocalStatement, got %T", fn.Body[0])
+	}
+	if stmt.Name != "tmp" {
+		t.Errorf("expected name tmp, got %q", stmt.Name)
+	}
+
+	for _, code := range []string{"local", "local tmp", "local tmp ="} {
+		tokens, _ := basic.Tokenize(code)
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseTry(t *testing.T) {
+	prog := parseCode(t, `try
+    let x = 1 / 0
+    print x
+catch err
+    print err
+endtry
+try
+    risky()
+catch
+endtry`)
+
+	stmt, ok := prog.Statements[0].(*basic.TryStatement)
+	if !ok {
+		t.Fatalf("expected TryStatement, got %T", prog.Statements[0])
+	}
+	if len(stmt.Body) != 2 || len(stmt.Handler) != 1 || stmt.ErrorVar != "err" {
+		t.Errorf("unexpected TRY: %+v", stmt)
+	}
+
+	bare := prog.Statements[1].(*basic.TryStatement)
+	if bare.ErrorVar != "" || len(bare.Handler) != 0 {
+		t.Errorf("expected CATCH without a variable or handler, got %+v", bare)
+	}
+
+	for _, code := range []string{
+		"try\n    print 1\nendtry",
+		"try\n    print 1\ncatch e\n    print e\n",
+	} {
+		tokens, _ := basic.Tokenize(code)
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseOnError(t *testing.T) {
+	prog := parseCode(t, `On Error Call Report
+let error = 1`)
+
+	stmt, ok := prog.Statements[0].(*basic.OnErrorStatement)
+	if !ok {
+		t.Fatalf("expected OnErrorStatement, got %T", prog.Statements[0])
+	}
+	if stmt.Handler != "Report" {
+		t.Errorf("expected handler Report, got %q", stmt.Handler)
+	}
+
+	// ERROR isn't reserved
+	if _, ok := prog.Statements[1].(*basic.LetStatement); !ok {
+		t.Errorf("expected LetStatement, got %T", prog.Statements[1])
+	}
+
+	for _, code := range []string{
+		"on error report",
+		"on call report",
+		"on error call",
+		"on error call 5",
+	} {
+		tokens, _ := basic.Tokenize(code)
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseThrow(t *testing.T) {
+	prog := parseCode(t, `throw "bad value: " + x`)
+
+	stmt, ok := prog.Statements[0].(*basic.ThrowStatement)
+	if !ok {
+		t.Fatalf("expected ThrowStatement, got %T", prog.Statements[0])
+	}
+	if _, ok := stmt.Message.(*basic.BinaryExpr); !ok {
+		t.Errorf("expected BinaryExpr message, got %T", stmt.Message)
+	}
+
+	tokens, _ := basic.Tokenize("throw\nprint 1")
+	if _, err := basic.Parse(tokens); err == nil {
+		t.Error("expected parse error for THROW without a message")
+	}
+}
+
+func TestParseImport(t *testing.T) {
+	prog := parseCode(t, `import "utils/geometry"`)
+
+	stmt, ok := prog.Statements[0].(*basic.ImportStatement)
+	if !ok {
+		t.Fatalf("expected ImportStatement, got %T", prog.Statements[0])
+	}
+	if stmt.Module != "utils/geometry" {
+		t.Errorf("expected module utils/geometry, got %q", stmt.Module)
+	}
+
+	tokens, _ := basic.Tokenize("import utils")
+	if _, err := basic.Parse(tokens); err == nil {
+		t.Error("expected parse error for IMPORT without a string")
+	}
+}
+
+func TestParseInclude(t *testing.T) {
+	prog := parseCode(t, `include "lib/stats.bas"`)
+
+	stmt, ok := prog.Statements[0].(*basic.IncludeStatement)
+	if !ok {
+		t.Fatalf("expected IncludeStatement, got %T", prog.Statements[0])
+	}
+	if stmt.Path != "lib/stats.bas" {
+		t.Errorf("expected path lib/stats.bas, got %q", stmt.Path)
+	}
+
+	tokens, _ := basic.Tokenize("include stats")
+	if _, err := basic.Parse(tokens); err == nil {
+		t.Error("expected parse error for INCLUDE without a string")
+	}
+}
+
+func TestParseOptionExplicit(t *testing.T) {
+	prog := parseCode(t, "Option Explicit\nlet hp = 10")
+
+	stmt, ok := prog.Statements[0].(*basic.OptionStatement)
+	if !ok {
+		t.Fatalf("expected OptionStatement, got %T", prog.Statements[0])
+	}
+	if stmt.Name != "EXPLICIT" {
+		t.Errorf("expected option EXPLICIT, got %q", stmt.Name)
+	}
+
+	for _, code := range []string{"option", "option base 1"} {
+		tokens, _ := basic.Tokenize(code)
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseTypeStatement(t *testing.T) {
+	prog := parseCode(t, `type Entity
+    name
+    x, y    # position
+
+endtype`)
+
+	stmt, ok := prog.Statements[0].(*basic.TypeStatement)
+	if !ok {
+		t.Fatalf("expected TypeStatement, got %T", prog.Statements[0])
+	}
+	if stmt.Name != "Entity" || strings.Join(stmt.Fields, ",") != "name,x,y" {
+		t.Errorf("expected Entity with fields name, x, y, got %s %v", stmt.Name, stmt.Fields)
+	}
+
+	for _, code := range []string{"type\nx\nendtype", "type Empty\nendtype", "type Vec2\n    x y\nendtype", "type Vec2\n    x"} {
+		tokens, _ := basic.Tokenize(code)
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("%q: expected parse error", code)
+		}
+	}
+}
+
+func TestParseRecordFields(t *testing.T) {
+	prog := parseCode(t, `print es(0).pos.x
+es(i).pos.x += 1
+v.x, v.y = 1, 2`)
+
+	print := prog.Statements[0].(*basic.PrintStatement)
+	outer, ok := print.Values[0].(*basic.MemberExpr)
+	if !ok || outer.Field != "x" {
+		t.Fatalf("expected field x, got %#v", print.Values[0])
+	}
+	inner, ok := outer.Target.(*basic.MemberExpr)
+	if !ok || inner.Field != "pos" {
+		t.Fatalf("expected field pos, got %#v", outer.Target)
+	}
+	if _, ok := inner.Target.(*basic.CallExpr); !ok {
+		t.Errorf("expected es(0) as the record, got %T", inner.Target)
+	}
+
+	assign, ok := prog.Statements[1].(*basic.AssignStatement)
+	if !ok {
+		t.Fatalf("expected AssignStatement, got %T", prog.Statements[1])
+	}
+	if assign.Name != "es" || len(assign.Indices) != 1 || strings.Join(assign.Fields, ".") != "pos.x" || assign.Operator != basic.TOKEN_PLUS_EQ {
+		t.Errorf("unexpected field assignment: %#v", assign)
+	}
+
+	multi, ok := prog.Statements[2].(*basic.MultiAssignStatement)
+	if !ok {
+		t.Fatalf("expected MultiAssignStatement, got %T", prog.Statements[2])
+	}
+	for idx, field := range []string{"x", "y"} {
+		if target := multi.Targets[idx]; target.Name != "v" || len(target.Fields) != 1 || target.Fields[0] != field {
+			t.Errorf("target %d: expected v.%s, got %#v", idx, field, target)
+		}
+	}
+
+	tokens, _ := basic.Tokenize("v. = 1")
+	if _, err := basic.Parse(tokens); err == nil {
+		t.Error("expected parse error for a missing field name")
+	}
+}
+
+func TestParseLabelAndGoto(t *testing.T) {
+	prog := parseCode(t, `start:
+let x = 1
+if x < 3 then
+    GoTo start
+endif
+done: print x`)
+
+	label, ok := prog.Statements[0].(*basic.LabelStatement)
+	if !ok || label.Name != "start" {
+		t.Fatalf("expected label start, got %#v", prog.Statements[0])
+	}
+
+	ifStmt := prog.Statements[2].(*basic.IfStatement)
+	jump, ok := ifStmt.ThenBlock[0].(*basic.GotoStatement)
+	if !ok || jump.Label != "start" {
+		t.Errorf("expected GOTO start, got %#v", ifStmt.ThenBlock[0])
+	}
+
+	if label, ok := prog.Statements[3].(*basic.LabelStatement); !ok || label.Name != "done" {
+		t.Errorf("expected label done, got %#v", prog.Statements[3])
+	}
+	if _, ok := prog.Statements[4].(*basic.PrintStatement); !ok {
+		t.Errorf("expected a statement after a label on the same line, got %T", prog.Statements[4])
+	}
+
+	tokens, _ := basic.Tokenize("goto 10")
+	if _, err := basic.Parse(tokens); err == nil {
+		t.Error("expected error for GOTO without a label")
+	}
+}
+
+func TestParseDimAndIndex(t *testing.T) {
+	prog := parseCode(t, `function first():
+    return items(0)
+endfunction
+dim items(10)
+dim grid(3, 4)
+items(2) = grid(1, 2) + abs(-1)
+grid(0, 1) += 1
+items(3)++`)
+
+	fn := prog.Statements[0].(*basic.FunctionStatement)
+	ret := fn.Body[0].(*basic.ReturnStatement)
+	if _, ok := ret.Value.(*basic.IndexExpr); !ok {
+		t.Errorf("expected an array used above its DIM to parse as IndexExpr, got %T", ret.Value)
+	}
+
+	dim, ok := prog.Statements[2].(*basic.DimStatement)
+	if !ok {
+		t.Fatalf("expected DimStatement, got %T", prog.Statements[2])
+	}
+	if dim.Name != "grid" || len(dim.Sizes) != 2 {
+		t.Errorf("unexpected DIM: %+v", dim)
+	}
+
+	assign, ok := prog.Statements[3].(*basic.AssignStatement)
+	if !ok {
+		t.Fatalf("expected AssignStatement, got %T", prog.Statements[3])
+	}
+	if assign.Name != "items" || len(assign.Indices) != 1 || assign.Operator != basic.TOKEN_EQ {
+		t.Errorf("unexpected assignment: %+v", assign)
+	}
+	sum := assign.Value.(*basic.BinaryExpr)
+	if _, ok := sum.Left.(*basic.IndexExpr); !ok {
+		t.Errorf("expected IndexExpr, got %T", sum.Left)
+	}
+	if _, ok := sum.Right.(*basic.CallExpr); !ok {
+		t.Errorf("expected CallExpr, got %T", sum.Right)
+	}
+
+	if op := prog.Statements[4].(*basic.AssignStatement).Operator; op != basic.TOKEN_PLUS_EQ {
+		t.Errorf("expected +=, got %s", op)
+	}
+	if op := prog.Statements[5].(*basic.AssignStatement).Operator; op != basic.TOKEN_PLUS_PLUS {
+		t.Errorf("expected ++, got %s", op)
+	}
+}
+
+func TestParseSlice(t *testing.T) {
+	prog := parseCode(t, `let a = s[0]
+let b = s[1:n + 1]
+let c = name()[:2]
+let d = s[2:][0]`)
+
+	index := prog.Statements[0].(*basic.LetStatement).Value.(*basic.SliceExpr)
+	if index.Slice || index.Start == nil || index.End != nil {
+		t.Errorf("unexpected index: %+v", index)
+	}
+	if index.Line != 1 || index.Column != 10 {
+		t.Errorf("expected position of '[' (1:10), got %d:%d", index.Line, index.Column)
+	}
+
+	slice := prog.Statements[1].(*basic.LetStatement).Value.(*basic.SliceExpr)
+	if !slice.Slice || slice.Start == nil {
+		t.Errorf("unexpected slice: %+v", slice)
+	}
+	if _, ok := slice.End.(*basic.BinaryExpr); !ok {
+		t.Errorf("expected BinaryExpr end, got %T", slice.End)
+	}
+
+	call := prog.Statements[2].(*basic.LetStatement).Value.(*basic.SliceExpr)
+	if _, ok := call.Target.(*basic.CallExpr); !ok {
+		t.Errorf("expected CallExpr target, got %T", call.Target)
+	}
+	if call.Start != nil || call.End == nil {
+		t.Errorf("expected [:2], got %+v", call)
+	}
+
+	chained := prog.Statements[3].(*basic.LetStatement).Value.(*basic.SliceExpr)
+	inner, ok := chained.Target.(*basic.SliceExpr)
+	if !ok {
+		t.Fatalf("expected chained SliceExpr, got %T", chained.Target)
+	}
+	if !inner.Slice || inner.End != nil {
+		t.Errorf("expected [2:], got %+v", inner)
+	}
+}
+
+func TestParseBitwisePrecedence(t *testing.T) {
+	// | binds looser than &, which binds looser than shifts, which bind
+	// looser than +; all bind tighter than comparisons
+	prog := parseCode(t, "let x = a | b & c << 1 + d = e")
+	let := prog.Statements[0].(*basic.LetStatement)
+
+	eq := let.Value.(*basic.BinaryExpr)
+	if eq.Operator != basic.TOKEN_EQ {
+		t.Fatalf("expected = at the root, got %s", eq.Operator)
+	}
+	or := eq.Left.(*basic.BinaryExpr)
+	if or.Operator != basic.TOKEN_PIPE {
+		t.Fatalf("expected |, got %s", or.Operator)
+	}
+	and := or.Right.(*basic.BinaryExpr)
+	if and.Operator != basic.TOKEN_AMP {
+		t.Fatalf("expected &, got %s", and.Operator)
+	}
+	shift := and.Right.(*basic.BinaryExpr)
+	if shift.Operator != basic.TOKEN_SHL {
+		t.Fatalf("expected <<, got %s", shift.Operator)
+	}
+	if sum := shift.Right.(*basic.BinaryExpr); sum.Operator != basic.TOKEN_PLUS {
+		t.Errorf("expected +, got %s", sum.Operator)
+	}
+}
+
 func TestParseFunction(t *testing.T) {
 	code := `function add(x, y):
     let z = x + y
@@ -298,6 +960,43 @@ endfunction`
 	}
 }
 
+func TestParseSub(t *testing.T) {
+	code := `sub greet(name):
+    print "Hello " + name
+    return
+endsub`
+	prog := parseCode(t, code)
+
+	fn, ok := prog.Statements[0].(*basic.FunctionStatement)
+	if !ok {
+		t.Fatalf("expected FunctionStatement, got %T", prog.Statements[0])
+	}
+	if !fn.IsSub {
+		t.Error("expected IsSub to be set")
+	}
+	if fn.Name != "greet" || len(fn.Params) != 1 || len(fn.Body) != 2 {
+		t.Errorf("unexpected sub %q params %v body %d", fn.Name, fn.Params, len(fn.Body))
+	}
+}
+
+func TestParseSubErrors(t *testing.T) {
+	tests := []string{
+		"sub greet()\n    return 5\nendsub",     // SUBs return no value
+		"sub greet()\n    print 1\nendfunction", // mismatched terminator
+		"function greet()\n    print 1\nendsub", // mismatched terminator
+	}
+
+	for _, code := range tests {
+		tokens, err := basic.Tokenize(code)
+		if err != nil {
+			t.Fatalf("tokenize error: %v", err)
+		}
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("expected parse error for: %q", code)
+		}
+	}
+}
+
 func TestParseFunctionNoParams(t *testing.T) {
 	code := `function greet():
     print "Hello"
@@ -310,6 +1009,110 @@ endfunction`
 	}
 }
 
+func TestParseDefaultParams(t *testing.T) {
+	code := `function attack(target, damage = 1, crit = damage * 2):
+    return damage
+endfunction`
+	prog := parseCode(t, code)
+
+	fn, ok := prog.Statements[0].(*basic.FunctionStatement)
+	if !ok {
+		t.Fatalf("expected FunctionStatement, got %T", prog.Statements[0])
+	}
+	if len(fn.Params) != 3 || len(fn.Defaults) != 3 {
+		t.Fatalf("expected 3 params and defaults, got %v and %v", fn.Params, fn.Defaults)
+	}
+	if fn.Defaults[0] != nil {
+		t.Errorf("expected no default for target, got %T", fn.Defaults[0])
+	}
+	if lit, ok := fn.Defaults[1].(*basic.IntLiteral); !ok || lit.Value != 1 {
+		t.Errorf("expected default 1 for damage, got %#v", fn.Defaults[1])
+	}
+	if _, ok := fn.Defaults[2].(*basic.BinaryExpr); !ok {
+		t.Errorf("expected BinaryExpr default for crit, got %T", fn.Defaults[2])
+	}
+
+	for _, code := range []string{
+		"function f(a = 1, b):\nendfunction",
+		"function f(a = ):\nendfunction",
+	} {
+		tokens, err := basic.Tokenize(code)
+		if err != nil {
+			t.Fatalf("tokenize error: %v", err)
+		}
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("expected parse error for: %q", code)
+		}
+	}
+}
+
+func TestParseTypedParams(t *testing.T) {
+	prog := parseCode(t, `function hit(target AS Entity, damage as integer = 1, note = ""):
+    return damage
+endfunction`)
+
+	fn := prog.Statements[0].(*basic.FunctionStatement)
+	if strings.Join(fn.Params, ",") != "target,damage,note" {
+		t.Errorf("unexpected params %v", fn.Params)
+	}
+	if len(fn.Types) != 3 || fn.Types[0] != "Entity" || fn.Types[1] != "integer" || fn.Types[2] != "" {
+		t.Errorf("unexpected types %q", fn.Types)
+	}
+	if fn.Defaults[1] == nil || fn.Defaults[2] == nil {
+		t.Error("expected defaults after the types")
+	}
+
+	tokens, _ := basic.Tokenize("function f(x AS):\nendfunction")
+	if _, err := basic.Parse(tokens); err == nil {
+		t.Error("expected parse error for AS without a type")
+	}
+}
+
+func TestParseNamedArgs(t *testing.T) {
+	prog := parseCode(t, `spawn(1, y = 20, kind = "orc")
+print iif(x = 1, "one", "other")`)
+
+	stmt := prog.Statements[0].(*basic.ExpressionStatement)
+	call, ok := stmt.Expr.(*basic.CallExpr)
+	if !ok {
+		t.Fatalf("expected CallExpr, got %T", stmt.Expr)
+	}
+	if len(call.Args) != 1 || len(call.Named) != 2 {
+		t.Fatalf("expected 1 positional and 2 named arguments, got %d and %d", len(call.Args), len(call.Named))
+	}
+	if call.Named[0].Name != "y" || call.Named[1].Name != "kind" {
+		t.Errorf("expected named arguments y and kind, got %s and %s", call.Named[0].Name, call.Named[1].Name)
+	}
+	if str, ok := call.Named[1].Value.(*basic.StringLiteral); !ok || str.Value != "orc" {
+		t.Errorf("expected \"orc\" for kind, got %#v", call.Named[1].Value)
+	}
+
+	// iif takes no named arguments, so x = 1 stays a comparison
+	print := prog.Statements[1].(*basic.PrintStatement)
+	cond, ok := print.Values[0].(*basic.ConditionalExpr)
+	if !ok {
+		t.Fatalf("expected ConditionalExpr, got %T", print.Values[0])
+	}
+	if _, ok := cond.Condition.(*basic.BinaryExpr); !ok {
+		t.Errorf("expected comparison condition, got %T", cond.Condition)
+	}
+
+	for _, code := range []string{
+		"spawn(x = 1, 2)",
+		"spawn(x = 1, X = 2)",
+		"grid(x = 1) = 5",
+		"let a = spawn(x = )",
+	} {
+		tokens, err := basic.Tokenize(code)
+		if err != nil {
+			t.Fatalf("tokenize error: %v", err)
+		}
+		if _, err := basic.Parse(tokens); err == nil {
+			t.Errorf("expected parse error for: %q", code)
+		}
+	}
+}
+
 func TestParseFunctionCall(t *testing.T) {
 	code := `let x = add(1, 2)`
 	prog := parseCode(t, code)
@@ -463,9 +1266,16 @@ func TestParseLiterals(t *testing.T) {
 	}{
 		{"let x = 42", 42},
 		{"let x = 3.14", 3.14},
+		{"let x = 0xff", 255},
+		{"let x = 0XFF", 255},
+		{"let x = 0b1010", 10},
+		{"let x = 0o755", 493},
+		{"let x = 010", 10},
 		{`let x = "hello"`, "hello"},
 		{"let x = true", true},
 		{"let x = false", false},
+		{"let x = null", nil},
+		{"let x = NIL", nil},
 	}
 
 	for _, tt := range tests {
@@ -493,10 +1303,24 @@ func TestParseLiterals(t *testing.T) {
 			if lit.Value != expected {
 				t.Errorf("%s: expected %v, got %v", tt.code, expected, lit.Value)
 			}
+		case nil:
+			if _, ok := let.Value.(*basic.NullLiteral); !ok {
+				t.Errorf("%s: expected NullLiteral, got %T", tt.code, let.Value)
+			}
 		}
 	}
 }
 
+func TestParseIntLiteralOutOfRange(t *testing.T) {
+	tokens, err := basic.Tokenize("let x = 0x10000000000000000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := basic.Parse(tokens); err == nil || !strings.Contains(err.Error(), "invalid integer: 0x10000000000000000") {
+		t.Errorf("expected invalid integer error, got %v", err)
+	}
+}
+
 func TestParseMultipleStatements(t *testing.T) {
 	code := `let x = 1
 let y = 2
@@ -517,6 +1341,8 @@ func TestParseError(t *testing.T) {
 		"for i = 1",     // missing TO
 		"function",      // missing name
 		"function foo",  // missing parens
+		"let x = s[1",   // missing ]
+		"let x = s[]",   // missing index
 	}
 
 	for _, code := range tests {
@@ -530,3 +1356,56 @@ func TestParseError(t *testing.T) {
 		}
 	}
 }
+
+func TestParseExpressionTooDeep(t *testing.T) {
+	depth := basic.MaxExpressionDepth
+	code := "let x = " + strings.Repeat("(", depth) + "1" + strings.Repeat(")", depth)
+
+	tokens, err := basic.Tokenize(code)
+	if err != nil {
+		t.Fatalf("tokenize error: %v", err)
+	}
+	_, err = basic.Parse(tokens)
+	if err == nil || !strings.Contains(err.Error(), "too complex") {
+		t.Errorf("expected 'too complex' error, got %v", err)
+	}
+
+	tokens, err = basic.Tokenize("let x = " + strings.Repeat("-", depth+1) + "1")
+	if err != nil {
+		t.Fatalf("tokenize error: %v", err)
+	}
+	if _, err = basic.Parse(tokens); err == nil {
+		t.Error("expected error for deeply nested unary operators")
+	}
+}
+
+func TestParseDocComments(t *testing.T) {
+	prog := parseCode(t, `## Deals damage.
+##   Never below zero.
+function hit(hp, n):
+    return hp - n
+endfunction
+## detached
+
+sub quiet():
+endsub
+let x = 1 ## trailing
+function plain():
+endfunction`)
+
+	expected := []string{"Deals damage.\nNever below zero.", "", "", ""}
+	idx := 0
+	for _, stmt := range prog.Statements {
+		fn, ok := stmt.(*basic.FunctionStatement)
+		if !ok {
+			continue
+		}
+		if fn.Doc != expected[idx] {
+			t.Errorf("%s: expected doc %q, got %q", fn.Name, expected[idx], fn.Doc)
+		}
+		idx++
+	}
+	if idx != 3 {
+		t.Errorf("expected 3 functions, got %d", idx)
+	}
+}
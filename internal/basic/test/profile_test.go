@@ -0,0 +1,66 @@
+package basic
+
+import (
+	"testing"
+)
+
+func TestProfileCountsLines(t *testing.T) {
+	interp, _ := newTestInterpreter()
+	interp.SetProfiling(true)
+
+	code := `function add(a, b):
+    return a + b
+endfunction
+let total = 0
+for i = 1 to 5
+    total = add(total, i)
+next i
+add(1, 2)
+`
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hits := map[int]int{}
+	for _, line := range interp.Profile().Lines {
+		hits[line.Line] = line.Hits
+		if line.Self > line.Time || line.Self < 0 {
+			t.Errorf("line %d: self time %v outside total %v", line.Line, line.Self, line.Time)
+		}
+	}
+	expected := map[int]int{2: 6, 4: 1, 5: 1, 6: 5, 8: 1}
+	for line, count := range expected {
+		if hits[line] != count {
+			t.Errorf("line %d: expected %d hits, got %d (%v)", line, count, hits[line], hits)
+		}
+	}
+	if len(hits) != len(expected) {
+		t.Errorf("expected lines %v, got %v", expected, hits)
+	}
+
+	profile := interp.Profile()
+	loop := profile.Lines[2]
+	if loop.Line != 5 || loop.Time < profile.Lines[3].Time {
+		t.Errorf("expected the FOR line to include its body, got %+v", profile.Lines)
+	}
+	if top := profile.Top(2); len(top) != 2 || top[0].Self < top[1].Self {
+		t.Errorf("expected the two hottest lines first, got %+v", top)
+	}
+
+	// Runs accumulate until profiling is turned off, which keeps the profile
+	if _, err := interp.Call("add", 1, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	interp.SetProfiling(false)
+	if err := interp.Interpret(code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := interp.Profile().Lines; lines[0].Line != 2 || lines[0].Hits != 7 {
+		t.Errorf("expected the call to be added to the profile, got %+v", lines)
+	}
+
+	interp.SetProfiling(true)
+	if lines := interp.Profile().Lines; len(lines) != 0 {
+		t.Errorf("expected a fresh profile, got %+v", lines)
+	}
+}
@@ -1,6 +1,7 @@
 package basic
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/mechanical-lich/mechanical-basic/internal/basic"
@@ -39,7 +40,7 @@ func TestTokenizeBasicAssignment(t *testing.T) {
 }
 
 func TestTokenizeNumbers(t *testing.T) {
-	input := "42 3.14 0.5"
+	input := "42 3.14 0.5 0xFF 0B1010 0o755 007"
 	tokens, err := basic.Tokenize(input)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -52,6 +53,10 @@ func TestTokenizeNumbers(t *testing.T) {
 		{basic.TOKEN_INT, "42"},
 		{basic.TOKEN_FLOAT, "3.14"},
 		{basic.TOKEN_FLOAT, "0.5"},
+		{basic.TOKEN_INT, "0xFF"},
+		{basic.TOKEN_INT, "0B1010"},
+		{basic.TOKEN_INT, "0o755"},
+		{basic.TOKEN_INT, "007"},
 		{basic.TOKEN_EOF, ""},
 	}
 
@@ -69,6 +74,25 @@ func TestTokenizeNumbers(t *testing.T) {
 	}
 }
 
+func TestTokenizeBasedIntErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let m = 0x", "line 1, column 9: hex literal 0x has no digits"},
+		{"0xFG", "invalid hex literal 0xFG"},
+		{"0b102", "invalid binary literal 0b102"},
+		{"0o8", "invalid octal literal 0o8"},
+	}
+
+	for _, tt := range tests {
+		_, err := basic.Tokenize(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
 func TestTokenizeString(t *testing.T) {
 	input := `"Hello World"`
 	tokens, err := basic.Tokenize(input)
@@ -104,8 +128,19 @@ func TestTokenizeStringWithEscapes(t *testing.T) {
 	}
 }
 
+func TestTokenizeStringUTF8(t *testing.T) {
+	tokens, err := basic.Tokenize(`"héllo wörld"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tokens[0].Value != "héllo wörld" {
+		t.Errorf("expected 'héllo wörld', got %q", tokens[0].Value)
+	}
+}
+
 func TestTokenizeOperators(t *testing.T) {
-	input := "+ - * / = < > <= >= <> != += -= ++ --"
+	input := "+ - * / = < > <= >= <> != += -= ++ -- & | << >> [ ] ; ."
 	tokens, err := basic.Tokenize(input)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -115,6 +150,8 @@ func TestTokenizeOperators(t *testing.T) {
 		basic.TOKEN_PLUS, basic.TOKEN_MINUS, basic.TOKEN_STAR, basic.TOKEN_SLASH, basic.TOKEN_EQ,
 		basic.TOKEN_LT, basic.TOKEN_GT, basic.TOKEN_LTE, basic.TOKEN_GTE, basic.TOKEN_NEQ, basic.TOKEN_NEQ,
 		basic.TOKEN_PLUS_EQ, basic.TOKEN_MINUS_EQ, basic.TOKEN_PLUS_PLUS, basic.TOKEN_MINUS_MINUS,
+		basic.TOKEN_AMP, basic.TOKEN_PIPE, basic.TOKEN_SHL, basic.TOKEN_SHR,
+		basic.TOKEN_LBRACKET, basic.TOKEN_RBRACKET, basic.TOKEN_SEMICOLON, basic.TOKEN_DOT,
 		basic.TOKEN_EOF,
 	}
 
@@ -130,7 +167,7 @@ func TestTokenizeOperators(t *testing.T) {
 }
 
 func TestTokenizeKeywords(t *testing.T) {
-	input := "if then else elseif endif for to next break function endfunction return print and or not let true false"
+	input := "if then else elseif endif for to next do loop while until break exit goto try catch endtry on throw import include option function endfunction type endtype as return print using and or not xor let dim const global local true false null nil"
 	tokens, err := basic.Tokenize(input)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -138,9 +175,11 @@ func TestTokenizeKeywords(t *testing.T) {
 
 	expected := []basic.TokenType{
 		basic.TOKEN_IF, basic.TOKEN_THEN, basic.TOKEN_ELSE, basic.TOKEN_ELSEIF, basic.TOKEN_ENDIF,
-		basic.TOKEN_FOR, basic.TOKEN_TO, basic.TOKEN_NEXT, basic.TOKEN_BREAK,
-		basic.TOKEN_FUNCTION, basic.TOKEN_ENDFUNCTION, basic.TOKEN_RETURN, basic.TOKEN_PRINT,
-		basic.TOKEN_AND, basic.TOKEN_OR, basic.TOKEN_NOT, basic.TOKEN_LET, basic.TOKEN_TRUE, basic.TOKEN_FALSE,
+		basic.TOKEN_FOR, basic.TOKEN_TO, basic.TOKEN_NEXT,
+		basic.TOKEN_DO, basic.TOKEN_LOOP, basic.TOKEN_WHILE, basic.TOKEN_UNTIL, basic.TOKEN_BREAK, basic.TOKEN_EXIT, basic.TOKEN_GOTO,
+		basic.TOKEN_TRY, basic.TOKEN_CATCH, basic.TOKEN_ENDTRY, basic.TOKEN_ON, basic.TOKEN_THROW, basic.TOKEN_IMPORT, basic.TOKEN_INCLUDE, basic.TOKEN_OPTION,
+		basic.TOKEN_FUNCTION, basic.TOKEN_ENDFUNCTION, basic.TOKEN_TYPE, basic.TOKEN_ENDTYPE, basic.TOKEN_AS, basic.TOKEN_RETURN, basic.TOKEN_PRINT, basic.TOKEN_USING,
+		basic.TOKEN_AND, basic.TOKEN_OR, basic.TOKEN_NOT, basic.TOKEN_XOR, basic.TOKEN_LET, basic.TOKEN_DIM, basic.TOKEN_CONST, basic.TOKEN_GLOBAL, basic.TOKEN_LOCAL, basic.TOKEN_TRUE, basic.TOKEN_FALSE, basic.TOKEN_NULL, basic.TOKEN_NULL,
 		basic.TOKEN_EOF,
 	}
 
@@ -319,6 +358,39 @@ y = 10`
 	}
 }
 
+func TestTokenizeRem(t *testing.T) {
+	input := `REM Classic comment: x = 1
+rem
+remainder = 5: Rem trailing
+z = rem_count`
+	tokens, err := basic.Tokenize(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		typ   basic.TokenType
+		value string
+		line  int
+	}{
+		{basic.TOKEN_NEWLINE, "\\n", 1},
+		{basic.TOKEN_NEWLINE, "\\n", 2},
+		{basic.TOKEN_IDENTIFIER, "remainder", 3}, {basic.TOKEN_EQ, "=", 3}, {basic.TOKEN_INT, "5", 3}, {basic.TOKEN_COLON, ":", 3},
+		{basic.TOKEN_NEWLINE, "\\n", 3},
+		{basic.TOKEN_IDENTIFIER, "z", 4}, {basic.TOKEN_EQ, "=", 4}, {basic.TOKEN_IDENTIFIER, "rem_count", 4},
+		{basic.TOKEN_EOF, "", 4},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, exp := range expected {
+		if tokens[i].Type != exp.typ || tokens[i].Value != exp.value || tokens[i].Line != exp.line {
+			t.Errorf("token %d: expected %s %q on line %d, got %s %q on line %d", i, exp.typ, exp.value, exp.line, tokens[i].Type, tokens[i].Value, tokens[i].Line)
+		}
+	}
+}
+
 func TestTokenizeLineNumbers(t *testing.T) {
 	input := `x = 1
 y = 2
@@ -338,6 +410,35 @@ z = 3`
 	}
 }
 
+func TestTokenizeLineContinuation(t *testing.T) {
+	input := "let total = a + _\n    b +   # base\n    c\nlet _x = 1"
+	tokens, err := basic.Tokenize(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		typ  basic.TokenType
+		line int
+	}{
+		{basic.TOKEN_LET, 1}, {basic.TOKEN_IDENTIFIER, 1}, {basic.TOKEN_EQ, 1},
+		{basic.TOKEN_IDENTIFIER, 1}, {basic.TOKEN_PLUS, 1},
+		{basic.TOKEN_IDENTIFIER, 2}, {basic.TOKEN_PLUS, 2},
+		{basic.TOKEN_IDENTIFIER, 3}, {basic.TOKEN_NEWLINE, 3},
+		{basic.TOKEN_LET, 4}, {basic.TOKEN_IDENTIFIER, 4}, {basic.TOKEN_EQ, 4}, {basic.TOKEN_INT, 4},
+		{basic.TOKEN_EOF, 4},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+	for i, exp := range expected {
+		if tokens[i].Type != exp.typ || tokens[i].Line != exp.line {
+			t.Errorf("token %d: expected %s on line %d, got %s on line %d", i, exp.typ, exp.line, tokens[i].Type, tokens[i].Line)
+		}
+	}
+}
+
 func TestTokenizeUnterminatedString(t *testing.T) {
 	input := `"Hello`
 	_, err := basic.Tokenize(input)
@@ -16,9 +16,14 @@ const (
 	TOKEN_STRING
 	TOKEN_TRUE
 	TOKEN_FALSE
+	TOKEN_NULL
 
 	// Keywords
 	TOKEN_LET
+	TOKEN_DIM
+	TOKEN_CONST
+	TOKEN_GLOBAL
+	TOKEN_LOCAL
 	TOKEN_IF
 	TOKEN_THEN
 	TOKEN_ELSE
@@ -27,14 +32,36 @@ const (
 	TOKEN_FOR
 	TOKEN_TO
 	TOKEN_NEXT
+	TOKEN_DO
+	TOKEN_LOOP
+	TOKEN_WHILE
+	TOKEN_UNTIL
 	TOKEN_BREAK
+	TOKEN_EXIT
+	TOKEN_GOTO
+	TOKEN_TRY
+	TOKEN_CATCH
+	TOKEN_ENDTRY
+	TOKEN_ON
+	TOKEN_THROW
+	TOKEN_IMPORT
+	TOKEN_INCLUDE
+	TOKEN_OPTION
 	TOKEN_FUNCTION
 	TOKEN_ENDFUNCTION
+	TOKEN_SUB
+	TOKEN_ENDSUB
+	TOKEN_TYPE
+	TOKEN_ENDTYPE
+	TOKEN_AS
 	TOKEN_RETURN
+	TOKEN_YIELD
 	TOKEN_PRINT
+	TOKEN_USING
 	TOKEN_AND
 	TOKEN_OR
 	TOKEN_NOT
+	TOKEN_XOR
 
 	// Operators
 	TOKEN_PLUS        // +
@@ -51,12 +78,20 @@ const (
 	TOKEN_MINUS_EQ    // -=
 	TOKEN_PLUS_PLUS   // ++
 	TOKEN_MINUS_MINUS // --
+	TOKEN_AMP         // &
+	TOKEN_PIPE        // |
+	TOKEN_SHL         // <<
+	TOKEN_SHR         // >>
 
 	// Delimiters
-	TOKEN_LPAREN // (
-	TOKEN_RPAREN // )
-	TOKEN_COMMA  // ,
-	TOKEN_COLON  // :
+	TOKEN_LPAREN    // (
+	TOKEN_RPAREN    // )
+	TOKEN_LBRACKET  // [
+	TOKEN_RBRACKET  // ]
+	TOKEN_COMMA     // ,
+	TOKEN_COLON     // :
+	TOKEN_SEMICOLON // ;
+	TOKEN_DOT       // .
 )
 
 // Token represents a lexical token with its type, value, and position
@@ -65,6 +100,7 @@ type Token struct {
 	Value  string // The literal value of the token
 	Line   int    // Line number (1-indexed)
 	Column int    // Column number (1-indexed)
+	Doc    string // Text of a ## doc comment block on the lines directly above
 }
 
 // String returns a human-readable name for the token type
@@ -79,7 +115,12 @@ func (t TokenType) String() string {
 		TOKEN_STRING:      "STRING",
 		TOKEN_TRUE:        "TRUE",
 		TOKEN_FALSE:       "FALSE",
+		TOKEN_NULL:        "NULL",
 		TOKEN_LET:         "LET",
+		TOKEN_DIM:         "DIM",
+		TOKEN_CONST:       "CONST",
+		TOKEN_GLOBAL:      "GLOBAL",
+		TOKEN_LOCAL:       "LOCAL",
 		TOKEN_IF:          "IF",
 		TOKEN_THEN:        "THEN",
 		TOKEN_ELSE:        "ELSE",
@@ -88,14 +129,36 @@ func (t TokenType) String() string {
 		TOKEN_FOR:         "FOR",
 		TOKEN_TO:          "TO",
 		TOKEN_NEXT:        "NEXT",
+		TOKEN_DO:          "DO",
+		TOKEN_LOOP:        "LOOP",
+		TOKEN_WHILE:       "WHILE",
+		TOKEN_UNTIL:       "UNTIL",
 		TOKEN_BREAK:       "BREAK",
+		TOKEN_EXIT:        "EXIT",
+		TOKEN_GOTO:        "GOTO",
+		TOKEN_TRY:         "TRY",
+		TOKEN_CATCH:       "CATCH",
+		TOKEN_ENDTRY:      "ENDTRY",
+		TOKEN_ON:          "ON",
+		TOKEN_THROW:       "THROW",
+		TOKEN_IMPORT:      "IMPORT",
+		TOKEN_INCLUDE:     "INCLUDE",
+		TOKEN_OPTION:      "OPTION",
 		TOKEN_FUNCTION:    "FUNCTION",
 		TOKEN_ENDFUNCTION: "ENDFUNCTION",
+		TOKEN_SUB:         "SUB",
+		TOKEN_ENDSUB:      "ENDSUB",
+		TOKEN_TYPE:        "TYPE",
+		TOKEN_ENDTYPE:     "ENDTYPE",
+		TOKEN_AS:          "AS",
 		TOKEN_RETURN:      "RETURN",
+		TOKEN_YIELD:       "YIELD",
 		TOKEN_PRINT:       "PRINT",
+		TOKEN_USING:       "USING",
 		TOKEN_AND:         "AND",
 		TOKEN_OR:          "OR",
 		TOKEN_NOT:         "NOT",
+		TOKEN_XOR:         "XOR",
 		TOKEN_PLUS:        "PLUS",
 		TOKEN_MINUS:       "MINUS",
 		TOKEN_STAR:        "STAR",
@@ -110,10 +173,18 @@ func (t TokenType) String() string {
 		TOKEN_MINUS_EQ:    "MINUS_EQ",
 		TOKEN_PLUS_PLUS:   "PLUS_PLUS",
 		TOKEN_MINUS_MINUS: "MINUS_MINUS",
+		TOKEN_AMP:         "AMP",
+		TOKEN_PIPE:        "PIPE",
+		TOKEN_SHL:         "SHL",
+		TOKEN_SHR:         "SHR",
 		TOKEN_LPAREN:      "LPAREN",
 		TOKEN_RPAREN:      "RPAREN",
+		TOKEN_LBRACKET:    "LBRACKET",
+		TOKEN_RBRACKET:    "RBRACKET",
 		TOKEN_COMMA:       "COMMA",
 		TOKEN_COLON:       "COLON",
+		TOKEN_SEMICOLON:   "SEMICOLON",
+		TOKEN_DOT:         "DOT",
 	}
 	if name, ok := names[t]; ok {
 		return name
@@ -124,6 +195,10 @@ func (t TokenType) String() string {
 // keywords maps keyword strings to their token types (case-insensitive)
 var keywords = map[string]TokenType{
 	"let":         TOKEN_LET,
+	"dim":         TOKEN_DIM,
+	"const":       TOKEN_CONST,
+	"global":      TOKEN_GLOBAL,
+	"local":       TOKEN_LOCAL,
 	"if":          TOKEN_IF,
 	"then":        TOKEN_THEN,
 	"else":        TOKEN_ELSE,
@@ -132,16 +207,40 @@ var keywords = map[string]TokenType{
 	"for":         TOKEN_FOR,
 	"to":          TOKEN_TO,
 	"next":        TOKEN_NEXT,
+	"do":          TOKEN_DO,
+	"loop":        TOKEN_LOOP,
+	"while":       TOKEN_WHILE,
+	"until":       TOKEN_UNTIL,
 	"break":       TOKEN_BREAK,
+	"exit":        TOKEN_EXIT,
+	"goto":        TOKEN_GOTO,
+	"try":         TOKEN_TRY,
+	"catch":       TOKEN_CATCH,
+	"endtry":      TOKEN_ENDTRY,
+	"on":          TOKEN_ON,
+	"throw":       TOKEN_THROW,
+	"import":      TOKEN_IMPORT,
+	"include":     TOKEN_INCLUDE,
+	"option":      TOKEN_OPTION,
 	"function":    TOKEN_FUNCTION,
 	"endfunction": TOKEN_ENDFUNCTION,
+	"sub":         TOKEN_SUB,
+	"endsub":      TOKEN_ENDSUB,
+	"type":        TOKEN_TYPE,
+	"endtype":     TOKEN_ENDTYPE,
+	"as":          TOKEN_AS,
 	"return":      TOKEN_RETURN,
+	"yield":       TOKEN_YIELD,
 	"print":       TOKEN_PRINT,
+	"using":       TOKEN_USING,
 	"and":         TOKEN_AND,
 	"or":          TOKEN_OR,
 	"not":         TOKEN_NOT,
+	"xor":         TOKEN_XOR,
 	"true":        TOKEN_TRUE,
 	"false":       TOKEN_FALSE,
+	"null":        TOKEN_NULL,
+	"nil":         TOKEN_NULL,
 }
 
 // LookupKeyword checks if an identifier is a keyword and returns the appropriate token type
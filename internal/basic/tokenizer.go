@@ -35,6 +35,9 @@ func Tokenize(input string) ([]Token, error) {
 // ScanAll scans all tokens from the input
 func (t *Tokenizer) ScanAll() ([]Token, error) {
 	var tokens []Token
+	var doc []string
+	lineStart := true // No token seen yet on the current line
+	inDoc := false    // The current line holds a ## doc comment
 
 	for {
 		tok, err := t.NextToken()
@@ -42,11 +45,39 @@ func (t *Tokenizer) ScanAll() ([]Token, error) {
 			return nil, err
 		}
 
-		// Skip comments - they're not needed for parsing
+		// Skip comments - they're not needed for parsing, except ## doc
+		// comments on lines of their own, which attach to the next token
 		if tok.Type == TOKEN_COMMENT {
+			if lineStart && strings.HasPrefix(tok.Value, "##") {
+				doc = append(doc, strings.TrimSpace(strings.TrimPrefix(tok.Value, "##")))
+				inDoc = true
+			}
+			lineStart = false
+			continue
+		}
+
+		// A line ending in an operator, comma or opening bracket continues
+		// on the next one
+		if tok.Type == TOKEN_NEWLINE && len(tokens) > 0 && continuesLine[tokens[len(tokens)-1].Type] {
 			continue
 		}
 
+		switch {
+		case tok.Type == TOKEN_NEWLINE:
+			// A blank line or a line of code ends the doc block
+			if !inDoc {
+				doc = nil
+			}
+			inDoc = false
+			lineStart = true
+		case len(doc) > 0:
+			tok.Doc = strings.Join(doc, "\n")
+			doc = nil
+			lineStart = false
+		default:
+			lineStart = false
+		}
+
 		tokens = append(tokens, tok)
 
 		if tok.Type == TOKEN_EOF {
@@ -57,6 +88,17 @@ func (t *Tokenizer) ScanAll() ([]Token, error) {
 	return tokens, nil
 }
 
+// continuesLine are the tokens that can't end a line, so a newline after one
+// is treated as whitespace
+var continuesLine = map[TokenType]bool{
+	TOKEN_PLUS: true, TOKEN_MINUS: true, TOKEN_STAR: true, TOKEN_SLASH: true,
+	TOKEN_EQ: true, TOKEN_NEQ: true, TOKEN_LT: true, TOKEN_GT: true, TOKEN_LTE: true, TOKEN_GTE: true,
+	TOKEN_PLUS_EQ: true, TOKEN_MINUS_EQ: true,
+	TOKEN_AMP: true, TOKEN_PIPE: true, TOKEN_SHL: true, TOKEN_SHR: true,
+	TOKEN_AND: true, TOKEN_OR: true, TOKEN_XOR: true, TOKEN_NOT: true,
+	TOKEN_COMMA: true, TOKEN_LPAREN: true, TOKEN_LBRACKET: true,
+}
+
 // NextToken scans and returns the next token
 func (t *Tokenizer) NextToken() (Token, error) {
 	t.skipWhitespace()
@@ -90,7 +132,18 @@ func (t *Tokenizer) NextToken() (Token, error) {
 
 	// Numbers
 	if unicode.IsDigit(ch) {
-		return t.scanNumber(), nil
+		return t.scanNumber(ch)
+	}
+
+	// A _ ending a line continues it on the next one
+	if ch == '_' && t.restOfLineBlank() {
+		t.skipWhitespace()
+		if !t.isAtEnd() {
+			t.advance() // consume the newline
+			t.line++
+			t.column = 1
+		}
+		return t.NextToken()
 	}
 
 	// Identifiers and keywords
@@ -104,10 +157,18 @@ func (t *Tokenizer) NextToken() (Token, error) {
 		return t.makeToken(TOKEN_LPAREN, "("), nil
 	case ')':
 		return t.makeToken(TOKEN_RPAREN, ")"), nil
+	case '[':
+		return t.makeToken(TOKEN_LBRACKET, "["), nil
+	case ']':
+		return t.makeToken(TOKEN_RBRACKET, "]"), nil
 	case ',':
 		return t.makeToken(TOKEN_COMMA, ","), nil
 	case ':':
 		return t.makeToken(TOKEN_COLON, ":"), nil
+	case ';':
+		return t.makeToken(TOKEN_SEMICOLON, ";"), nil
+	case '.':
+		return t.makeToken(TOKEN_DOT, "."), nil
 	case '*':
 		return t.makeToken(TOKEN_STAR, "*"), nil
 	case '/':
@@ -130,7 +191,14 @@ func (t *Tokenizer) NextToken() (Token, error) {
 		return t.makeToken(TOKEN_MINUS, "-"), nil
 	case '=':
 		return t.makeToken(TOKEN_EQ, "="), nil
+	case '&':
+		return t.makeToken(TOKEN_AMP, "&"), nil
+	case '|':
+		return t.makeToken(TOKEN_PIPE, "|"), nil
 	case '<':
+		if t.match('<') {
+			return t.makeToken(TOKEN_SHL, "<<"), nil
+		}
 		if t.match('=') {
 			return t.makeToken(TOKEN_LTE, "<="), nil
 		}
@@ -139,6 +207,9 @@ func (t *Tokenizer) NextToken() (Token, error) {
 		}
 		return t.makeToken(TOKEN_LT, "<"), nil
 	case '>':
+		if t.match('>') {
+			return t.makeToken(TOKEN_SHR, ">>"), nil
+		}
 		if t.match('=') {
 			return t.makeToken(TOKEN_GTE, ">="), nil
 		}
@@ -204,18 +275,37 @@ func (t *Tokenizer) scanString() (Token, error) {
 				builder.WriteRune('\r')
 			default:
 				// For unknown escapes, just include the character literally
-				builder.WriteRune(escaped)
+				builder.WriteByte(byte(escaped))
 			}
 		} else {
-			builder.WriteRune(t.advance())
+			// Copy bytes as-is so multi-byte UTF-8 characters survive
+			builder.WriteByte(byte(t.advance()))
 		}
 	}
 
 	return Token{}, t.error("unterminated string")
 }
 
-// scanNumber scans an integer or float literal
-func (t *Tokenizer) scanNumber() Token {
+// literalBases are the prefixes of integer literals in other bases: 0xFF,
+// 0b1010 and 0o755
+var literalBases = map[rune]struct {
+	name string
+	base int
+}{
+	'x': {"hex", 16},
+	'b': {"binary", 2},
+	'o': {"octal", 8},
+}
+
+// scanNumber scans an integer or float literal. first is its first digit,
+// already consumed.
+func (t *Tokenizer) scanNumber(first rune) (Token, error) {
+	if first == '0' {
+		if prefix, ok := literalBases[unicode.ToLower(t.peek())]; ok {
+			return t.scanBasedInt(prefix.name, prefix.base)
+		}
+	}
+
 	for !t.isAtEnd() && unicode.IsDigit(t.peek()) {
 		t.advance()
 	}
@@ -235,9 +325,45 @@ func (t *Tokenizer) scanNumber() Token {
 
 	value := t.input[t.start:t.pos]
 	if isFloat {
-		return t.makeToken(TOKEN_FLOAT, value)
+		return t.makeToken(TOKEN_FLOAT, value), nil
+	}
+	return t.makeToken(TOKEN_INT, value), nil
+}
+
+// scanBasedInt scans the rest of a hex, binary or octal literal after its
+// leading 0. The token keeps the prefix; the parser converts it.
+func (t *Tokenizer) scanBasedInt(name string, base int) (Token, error) {
+	t.advance() // consume the base letter
+
+	// Take the whole word, so 0b102 is reported rather than read as 0b10 2
+	for !t.isAtEnd() && (unicode.IsLetter(t.peek()) || unicode.IsDigit(t.peek()) || t.peek() == '_') {
+		t.advance()
+	}
+
+	value := t.input[t.start:t.pos]
+	digits := value[2:]
+	if digits == "" {
+		return Token{}, t.error(fmt.Sprintf("%s literal %s has no digits", name, value))
+	}
+	for _, ch := range digits {
+		if d := digitValue(ch); d < 0 || d >= base {
+			return Token{}, t.error(fmt.Sprintf("invalid %s literal %s", name, value))
+		}
+	}
+	return t.makeToken(TOKEN_INT, value), nil
+}
+
+// digitValue returns the value of a digit in bases up to 16, or -1
+func digitValue(ch rune) int {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return int(ch - '0')
+	case ch >= 'a' && ch <= 'f':
+		return int(ch-'a') + 10
+	case ch >= 'A' && ch <= 'F':
+		return int(ch-'A') + 10
 	}
-	return t.makeToken(TOKEN_INT, value)
+	return -1
 }
 
 // scanIdentifier scans an identifier or keyword
@@ -249,6 +375,11 @@ func (t *Tokenizer) scanIdentifier() Token {
 	value := t.input[t.start:t.pos]
 	lower := strings.ToLower(value)
 
+	// REM starts a comment, as in classic BASIC
+	if lower == "rem" {
+		return t.scanComment()
+	}
+
 	// Check if it's a keyword
 	tokenType := LookupKeyword(lower)
 
@@ -304,6 +435,19 @@ func (t *Tokenizer) skipWhitespace() {
 	}
 }
 
+// restOfLineBlank reports whether only whitespace is left before the next
+// newline or the end of the input
+func (t *Tokenizer) restOfLineBlank() bool {
+	for idx := t.pos; idx < len(t.input) && t.input[idx] != '\n'; idx++ {
+		switch t.input[idx] {
+		case ' ', '\t', '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func (t *Tokenizer) makeToken(tokenType TokenType, value string) Token {
 	return Token{
 		Type:   tokenType,
@@ -314,5 +458,5 @@ func (t *Tokenizer) makeToken(tokenType TokenType, value string) Token {
 }
 
 func (t *Tokenizer) error(message string) error {
-	return fmt.Errorf("line %d, column %d: %s", t.line, t.startCol, message)
+	return parseError(ErrorSyntax, "", t.line, t.startCol, "%s", message)
 }
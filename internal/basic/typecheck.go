@@ -0,0 +1,88 @@
+package basic
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// paramTypes are the built-in types a parameter can be declared AS. A
+// parameter can also be declared as one of the script's record types.
+var paramTypes = map[string]bool{
+	"integer": true,
+	"float":   true,
+	"number":  true,
+	"string":  true,
+	"boolean": true,
+	"array":   true,
+	"map":     true,
+}
+
+// coerce converts a value to a declared parameter type, reporting false if it
+// doesn't fit. Only conversions that lose nothing are made: an int becomes a
+// FLOAT, and a float with no fractional part an INTEGER.
+func coerce(typ string, value interface{}) (interface{}, bool) {
+	switch strings.ToLower(typ) {
+	case "integer":
+		switch v := value.(type) {
+		case int:
+			return v, true
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int(v), true
+			}
+		}
+	case "float":
+		switch v := value.(type) {
+		case int:
+			return float64(v), true
+		case float64:
+			return v, true
+		}
+	case "number":
+		switch value.(type) {
+		case int, float64:
+			return value, true
+		}
+	case "string":
+		_, ok := value.(string)
+		return value, ok
+	case "boolean":
+		_, ok := value.(bool)
+		return value, ok
+	case "array":
+		_, ok := value.([]interface{})
+		return value, ok
+	case "map":
+		_, ok := value.(map[string]interface{})
+		return value, ok
+	default:
+		rec, ok := value.(*Record)
+		return value, ok && rec.sameName(rec.typ.Name, typ)
+	}
+	return value, false
+}
+
+// typeLabel spells a declared type for error messages: built-in types in
+// capitals, record types as declared
+func typeLabel(typ string) string {
+	if paramTypes[strings.ToLower(typ)] {
+		return strings.ToUpper(typ)
+	}
+	return typ
+}
+
+// coerceParam applies the declared type of a function's parameter to the
+// value bound to it
+func coerceParam(fn *FunctionStatement, idx int, value interface{}) (interface{}, error) {
+	if idx >= len(fn.Types) || fn.Types[idx] == "" {
+		return value, nil
+	}
+	coerced, ok := coerce(fn.Types[idx], value)
+	if !ok {
+		return nil, fmt.Errorf("function %s: parameter %s must be %s, got %s", fn.Name, fn.Params[idx], typeLabel(fn.Types[idx]), functions.TypeName(value))
+	}
+	return coerced, nil
+}
@@ -0,0 +1,222 @@
+package basic
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// usingField is a field of a PRINT USING format and the text before it
+type usingField struct {
+	literal string // Text copied before the field
+	spec    string // The field as written, e.g. "###.##", "&" or "\  \"; "" for the text after the last field
+}
+
+// executePrintUsing formats the values with the format, in order, and prints
+// the result as one string. The format is reused while values remain.
+func (i *Interpreter) executePrintUsing(stmt *PrintStatement) error {
+	formatValue, err := i.evaluateExpression(stmt.Using)
+	if err != nil {
+		return err
+	}
+	format, ok := formatValue.(string)
+	if !ok {
+		return i.runtimeError(stmt, "PRINT USING format must be a string, got %s", functions.TypeName(formatValue))
+	}
+	fields := parseUsing(format)
+	if len(fields) == 1 {
+		return i.runtimeError(stmt, "PRINT USING format %q has no fields", format)
+	}
+
+	values, err := i.evaluateAll(stmt.Values)
+	if err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	for next := 0; next < len(values); {
+		for _, field := range fields {
+			out.WriteString(field.literal)
+			if field.spec == "" {
+				continue
+			}
+			if next == len(values) {
+				// Output stops at the first field without a value
+				break
+			}
+			text, err := i.usingValue(stmt, field.spec, values[next])
+			if err != nil {
+				return err
+			}
+			out.WriteString(text)
+			next++
+		}
+	}
+
+	i.printFunc(out.String(), !stmt.NoNewline)
+	return nil
+}
+
+// usingValue formats one value for a field
+func (i *Interpreter) usingValue(stmt *PrintStatement, spec string, value interface{}) (string, error) {
+	switch spec[0] {
+	case '&':
+		return i.toString(value), nil
+	case '!':
+		r, _ := utf8.DecodeRuneInString(i.toString(value) + " ")
+		return string(r), nil
+	case '\\':
+		text := []rune(i.toString(value))
+		if len(text) >= len(spec) {
+			return string(text[:len(spec)]), nil
+		}
+		return string(text) + strings.Repeat(" ", len(spec)-len(text)), nil
+	}
+
+	switch v := value.(type) {
+	case int:
+		return formatUsingNumber(spec, strconv.FormatUint(absInt(v), 10), v < 0), nil
+	case float64:
+		return formatUsingNumber(spec, strconv.FormatFloat(math.Abs(v), 'f', usingDecimals(spec), 64), v < 0), nil
+	}
+	return "", i.runtimeError(stmt, "PRINT USING field %s needs a number, got %s", spec, functions.TypeName(value))
+}
+
+// parseUsing splits a PRINT USING format into its fields. The last one only
+// holds the text after the final field.
+func parseUsing(format string) []usingField {
+	var fields []usingField
+	var text strings.Builder
+	for pos := 0; pos < len(format); {
+		// _ makes the next character literal
+		if format[pos] == '_' && pos+1 < len(format) {
+			text.WriteByte(format[pos+1])
+			pos += 2
+			continue
+		}
+		if n := usingFieldLen(format[pos:]); n > 0 {
+			fields = append(fields, usingField{literal: text.String(), spec: format[pos : pos+n]})
+			text.Reset()
+			pos += n
+			continue
+		}
+		text.WriteByte(format[pos])
+		pos++
+	}
+	return append(fields, usingField{literal: text.String()})
+}
+
+// usingFieldLen returns the length of the field at the start of s, or 0 if
+// s doesn't start with one. String fields are &, ! and \  \; number fields
+// are [+][$]#[,#...][.#...].
+func usingFieldLen(s string) int {
+	switch s[0] {
+	case '&', '!':
+		return 1
+	case '\\':
+		end := strings.IndexByte(s[1:], '\\')
+		if end >= 0 && strings.Trim(s[1:end+1], " ") == "" {
+			return end + 2
+		}
+		return 0
+	}
+
+	n, digits := 0, 0
+	if s[n] == '+' {
+		n++
+	}
+	if n < len(s) && s[n] == '$' {
+		n++
+	}
+	for n < len(s) && (s[n] == '#' || s[n] == ',' && digits > 0 && n+1 < len(s) && s[n+1] == '#') {
+		if s[n] == '#' {
+			digits++
+		}
+		n++
+	}
+	if n+1 < len(s) && s[n] == '.' && s[n+1] == '#' {
+		n++
+		for n < len(s) && s[n] == '#' {
+			digits++
+			n++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	return n
+}
+
+// usingDecimals returns the number of digits a number field shows after the
+// decimal point
+func usingDecimals(spec string) int {
+	dot := strings.IndexByte(spec, '.')
+	if dot < 0 {
+		return 0
+	}
+	return len(spec) - dot - 1
+}
+
+// formatUsingNumber lays out the digits of a number's magnitude in a number
+// field, right-aligned. A number too wide for the field is shown in full
+// after a %, as in classic BASIC.
+func formatUsingNumber(spec, digits string, negative bool) string {
+	whole, frac, _ := strings.Cut(digits, ".")
+	if decimals := usingDecimals(spec); decimals > len(frac) {
+		frac += strings.Repeat("0", decimals-len(frac))
+	}
+
+	intSpec, _, hasDot := strings.Cut(spec, ".")
+	if whole == "0" && !strings.Contains(intSpec, "#") {
+		whole = ""
+	}
+	if strings.Contains(intSpec, ",") {
+		whole = groupThousands(whole)
+	}
+
+	var body strings.Builder
+	switch {
+	case negative && strings.Trim(digits, "0.") != "":
+		body.WriteByte('-')
+	case strings.HasPrefix(spec, "+"):
+		body.WriteByte('+')
+	}
+	if strings.Contains(intSpec, "$") {
+		body.WriteByte('$')
+	}
+	body.WriteString(whole)
+	if hasDot {
+		body.WriteByte('.')
+		body.WriteString(frac)
+	}
+
+	text := body.String()
+	if len(text) > len(spec) {
+		return "%" + text
+	}
+	return strings.Repeat(" ", len(spec)-len(text)) + text
+}
+
+// groupThousands separates the digits in groups of three with commas
+func groupThousands(digits string) string {
+	var sb strings.Builder
+	for idx, ch := range digits {
+		if idx > 0 && (len(digits)-idx)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
+
+// absInt returns the magnitude of n, which doesn't fit in an int for the
+// smallest int
+func absInt(n int) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
@@ -0,0 +1,204 @@
+package bitlib
+
+import (
+	"fmt"
+	"math/bits"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// defaultRotateWidth is the word size used by rotl/rotr when none is given
+const defaultRotateWidth = 32
+
+// SetBit returns n with the given bit set: setbit(n, bit)
+func SetBit(args ...interface{}) (interface{}, error) {
+	n, bit, err := intAndBit("setbit", args)
+	if err != nil {
+		return nil, err
+	}
+	return n | (1 << bit), nil
+}
+
+// ClearBit returns n with the given bit cleared: clearbit(n, bit)
+func ClearBit(args ...interface{}) (interface{}, error) {
+	n, bit, err := intAndBit("clearbit", args)
+	if err != nil {
+		return nil, err
+	}
+	return n &^ (1 << bit), nil
+}
+
+// ToggleBit returns n with the given bit flipped: togglebit(n, bit)
+func ToggleBit(args ...interface{}) (interface{}, error) {
+	n, bit, err := intAndBit("togglebit", args)
+	if err != nil {
+		return nil, err
+	}
+	return n ^ (1 << bit), nil
+}
+
+// TestBit reports whether the given bit of n is set: testbit(n, bit)
+func TestBit(args ...interface{}) (interface{}, error) {
+	n, bit, err := intAndBit("testbit", args)
+	if err != nil {
+		return nil, err
+	}
+	return n&(1<<bit) != 0, nil
+}
+
+// PopCount returns the number of set bits in n
+func PopCount(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("popcount requires 1 argument")
+	}
+
+	n, err := basic.EnsureInt(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("popcount: argument must be numeric: %v", err)
+	}
+
+	return bits.OnesCount64(uint64(n)), nil
+}
+
+// Rotl rotates n left by k bits within a word: rotl(n, k[, width]).
+// Width may be 8, 16, 32 or 64 and defaults to 32.
+func Rotl(args ...interface{}) (interface{}, error) {
+	return rotate("rotl", args, 1)
+}
+
+// Rotr rotates n right by k bits within a word: rotr(n, k[, width]).
+// Width may be 8, 16, 32 or 64 and defaults to 32.
+func Rotr(args ...interface{}) (interface{}, error) {
+	return rotate("rotr", args, -1)
+}
+
+// BitAnd returns the bitwise AND of its arguments
+func BitAnd(args ...interface{}) (interface{}, error) {
+	return fold("bitand", args, func(a, b int) int { return a & b })
+}
+
+// BitOr returns the bitwise OR of its arguments
+func BitOr(args ...interface{}) (interface{}, error) {
+	return fold("bitor", args, func(a, b int) int { return a | b })
+}
+
+// BitXor returns the bitwise XOR of its arguments
+func BitXor(args ...interface{}) (interface{}, error) {
+	return fold("bitxor", args, func(a, b int) int { return a ^ b })
+}
+
+// BitNot returns the bitwise complement of n
+func BitNot(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("bitnot requires 1 argument")
+	}
+
+	n, err := basic.EnsureInt(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("bitnot: argument must be numeric: %v", err)
+	}
+
+	return ^n, nil
+}
+
+// Shl shifts n left by k bits: shl(n, k)
+func Shl(args ...interface{}) (interface{}, error) {
+	n, k, err := intAndBit("shl", args)
+	if err != nil {
+		return nil, err
+	}
+	return n << k, nil
+}
+
+// Shr shifts n right by k bits, preserving the sign: shr(n, k)
+func Shr(args ...interface{}) (interface{}, error) {
+	n, k, err := intAndBit("shr", args)
+	if err != nil {
+		return nil, err
+	}
+	return n >> k, nil
+}
+
+// intAndBit validates an (int, bit index) argument pair
+func intAndBit(name string, args []interface{}) (int, uint, error) {
+	if len(args) != 2 {
+		return 0, 0, fmt.Errorf("%s requires 2 arguments", name)
+	}
+
+	n, err := basic.EnsureInt(args[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: first argument must be numeric: %v", name, err)
+	}
+
+	bit, err := basic.EnsureInt(args[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: second argument must be numeric: %v", name, err)
+	}
+
+	if bit < 0 || bit > 63 {
+		return 0, 0, fmt.Errorf("%s: bit index %d out of range 0-63", name, bit)
+	}
+
+	return n, uint(bit), nil
+}
+
+// rotate implements Rotl and Rotr; direction is 1 for left and -1 for right
+func rotate(name string, args []interface{}, direction int) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("%s requires 2 or 3 arguments", name)
+	}
+
+	n, err := basic.EnsureInt(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: first argument must be numeric: %v", name, err)
+	}
+
+	k, err := basic.EnsureInt(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: second argument must be numeric: %v", name, err)
+	}
+
+	width := defaultRotateWidth
+	if len(args) == 3 {
+		width, err = basic.EnsureInt(args[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s: width must be numeric: %v", name, err)
+		}
+	}
+
+	k *= direction
+	switch width {
+	case 8:
+		return int(bits.RotateLeft8(uint8(n), k)), nil
+	case 16:
+		return int(bits.RotateLeft16(uint16(n), k)), nil
+	case 32:
+		return int(bits.RotateLeft32(uint32(n), k)), nil
+	case 64:
+		return int(bits.RotateLeft64(uint64(n), k)), nil
+	default:
+		return nil, fmt.Errorf("%s: width must be 8, 16, 32 or 64", name)
+	}
+}
+
+// fold applies a bitwise operator across two or more integer arguments
+func fold(name string, args []interface{}, op func(a, b int) int) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("%s requires at least 2 arguments", name)
+	}
+
+	result := 0
+	for idx, arg := range args {
+		n, err := basic.EnsureInt(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %d must be numeric: %v", name, idx+1, err)
+		}
+		if idx == 0 {
+			result = n
+		} else {
+			result = op(result, n)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package bitlib
+
+import (
+	"testing"
+)
+
+func TestBitFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func(args ...interface{}) (interface{}, error)
+		args     []interface{}
+		expected interface{}
+	}{
+		{"setbit", SetBit, []interface{}{0, 3}, 8},
+		{"clearbit", ClearBit, []interface{}{15, 0}, 14},
+		{"togglebit", ToggleBit, []interface{}{5, 1}, 7},
+		{"testbit set", TestBit, []interface{}{4, 2}, true},
+		{"testbit clear", TestBit, []interface{}{4, 1}, false},
+		{"popcount", PopCount, []interface{}{0xff}, 8},
+		{"rotl", Rotl, []interface{}{0x80000001, 1}, 3},
+		{"rotr", Rotr, []interface{}{1, 1}, 0x80000000},
+		{"rotl 8", Rotl, []interface{}{0x81, 1, 8}, 3},
+		{"bitand", BitAnd, []interface{}{12, 10}, 8},
+		{"bitor", BitOr, []interface{}{12, 10, 1}, 15},
+		{"bitxor", BitXor, []interface{}{12, 10}, 6},
+		{"bitnot", BitNot, []interface{}{0}, -1},
+		{"shl", Shl, []interface{}{1, 4}, 16},
+		{"shr", Shr, []interface{}{-16, 2}, -4},
+		{"float input", SetBit, []interface{}{2.0, 0}, 3},
+	}
+
+	for _, tt := range tests {
+		result, err := tt.fn(tt.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, result)
+		}
+	}
+}
+
+func TestBitErrors(t *testing.T) {
+	if _, err := SetBit(1, 64); err == nil {
+		t.Error("expected error for bit index out of range")
+	}
+	if _, err := Rotl(1, 1, 12); err == nil {
+		t.Error("expected error for unsupported width")
+	}
+	if _, err := BitAnd(1); err == nil {
+		t.Error("expected error for too few arguments")
+	}
+	if _, err := PopCount("x"); err == nil {
+		t.Error("expected error for string argument")
+	}
+}
@@ -0,0 +1,350 @@
+package bufferlib
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Buffers are byte slices ([]byte) handed to scripts by the host or created
+// with buffer(). Multi-byte reads and writes are little-endian unless "be" is
+// passed as the optional last argument.
+
+// Buffer creates a zero-filled buffer of the given size
+func Buffer(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("buffer requires 1 argument")
+	}
+
+	size, err := basic.EnsureInt(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("buffer: size must be numeric: %v", err)
+	}
+
+	if size < 0 {
+		return nil, fmt.Errorf("buffer: size must not be negative")
+	}
+
+	return make([]byte, size), nil
+}
+
+// BufLen returns the number of bytes in a buffer
+func BufLen(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("buflen requires 1 argument")
+	}
+
+	buf, err := basic.EnsureBytes(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("buflen: %v", err)
+	}
+
+	return len(buf), nil
+}
+
+// ReadU8 reads an unsigned byte: readu8(buf, offset)
+func ReadU8(args ...interface{}) (interface{}, error) {
+	return read("readu8", args, 1, false)
+}
+
+// ReadI8 reads a signed byte: readi8(buf, offset)
+func ReadI8(args ...interface{}) (interface{}, error) {
+	return read("readi8", args, 1, true)
+}
+
+// ReadU16 reads an unsigned 16-bit integer: readu16(buf, offset[, "be"])
+func ReadU16(args ...interface{}) (interface{}, error) {
+	return read("readu16", args, 2, false)
+}
+
+// ReadI16 reads a signed 16-bit integer: readi16(buf, offset[, "be"])
+func ReadI16(args ...interface{}) (interface{}, error) {
+	return read("readi16", args, 2, true)
+}
+
+// ReadU32 reads an unsigned 32-bit integer: readu32(buf, offset[, "be"])
+func ReadU32(args ...interface{}) (interface{}, error) {
+	return read("readu32", args, 4, false)
+}
+
+// ReadI32 reads a signed 32-bit integer: readi32(buf, offset[, "be"])
+func ReadI32(args ...interface{}) (interface{}, error) {
+	return read("readi32", args, 4, true)
+}
+
+// WriteU8 writes a byte in place: writeu8(buf, offset, value)
+func WriteU8(args ...interface{}) (interface{}, error) {
+	return write("writeu8", args, 1)
+}
+
+// WriteU16 writes a 16-bit integer in place: writeu16(buf, offset, value[, "be"])
+func WriteU16(args ...interface{}) (interface{}, error) {
+	return write("writeu16", args, 2)
+}
+
+// WriteU32 writes a 32-bit integer in place: writeu32(buf, offset, value[, "be"])
+func WriteU32(args ...interface{}) (interface{}, error) {
+	return write("writeu32", args, 4)
+}
+
+// Pack encodes values into a new buffer according to a format string:
+// pack("<HHb", x, y, flags). Format codes are b/B (signed/unsigned 8-bit),
+// h/H (16-bit) and i/I (32-bit); a leading '<' or '>' selects little- or
+// big-endian byte order.
+func Pack(args ...interface{}) (interface{}, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("pack requires a format argument")
+	}
+
+	format, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("pack: format: %v", err)
+	}
+
+	order, codes, err := parseFormat(format)
+	if err != nil {
+		return nil, fmt.Errorf("pack: %v", err)
+	}
+
+	values := args[1:]
+	if len(values) != len(codes) {
+		return nil, fmt.Errorf("pack: format expects %d values, got %d", len(codes), len(values))
+	}
+
+	buf := make([]byte, formatSize(codes))
+	offset := 0
+	for idx, code := range codes {
+		v, err := basic.EnsureInt(values[idx])
+		if err != nil {
+			return nil, fmt.Errorf("pack: value %d must be numeric: %v", idx, err)
+		}
+		size := codeSize(code)
+		putUint(buf[offset:], order, size, uint32(v))
+		offset += size
+	}
+
+	return buf, nil
+}
+
+// Unpack decodes values from a buffer according to a format string, returning
+// them as an array: unpack("<HHb", buf[, offset])
+func Unpack(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("unpack requires 2 or 3 arguments")
+	}
+
+	format, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("unpack: format: %v", err)
+	}
+
+	buf, err := basic.EnsureBytes(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("unpack: %v", err)
+	}
+
+	offset := 0
+	if len(args) == 3 {
+		offset, err = basic.EnsureInt(args[2])
+		if err != nil {
+			return nil, fmt.Errorf("unpack: offset must be numeric: %v", err)
+		}
+	}
+
+	order, codes, err := parseFormat(format)
+	if err != nil {
+		return nil, fmt.Errorf("unpack: %v", err)
+	}
+
+	if offset < 0 || offset+formatSize(codes) > len(buf) {
+		return nil, fmt.Errorf("unpack: format needs %d bytes at offset %d, buffer has %d", formatSize(codes), offset, len(buf))
+	}
+
+	result := make([]interface{}, len(codes))
+	for idx, code := range codes {
+		size := codeSize(code)
+		result[idx] = decode(getUint(buf[offset:], order, size), size, code >= 'a')
+		offset += size
+	}
+
+	return result, nil
+}
+
+// read implements the readXX builtins
+func read(name string, args []interface{}, size int, signed bool) (interface{}, error) {
+	maxArgs := 3
+	if size == 1 {
+		maxArgs = 2
+	}
+	if len(args) < 2 || len(args) > maxArgs {
+		return nil, fmt.Errorf("%s requires %d to %d arguments", name, 2, maxArgs)
+	}
+
+	buf, offset, err := bufferAndOffset(name, args, size)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := byteOrder(name, args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(getUint(buf[offset:], order, size), size, signed), nil
+}
+
+// write implements the writeXX builtins
+func write(name string, args []interface{}, size int) (interface{}, error) {
+	maxArgs := 4
+	if size == 1 {
+		maxArgs = 3
+	}
+	if len(args) < 3 || len(args) > maxArgs {
+		return nil, fmt.Errorf("%s requires %d to %d arguments", name, 3, maxArgs)
+	}
+
+	buf, offset, err := bufferAndOffset(name, args, size)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := basic.EnsureInt(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("%s: value must be numeric: %v", name, err)
+	}
+
+	order, err := byteOrder(name, args, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	putUint(buf[offset:], order, size, uint32(value))
+	return nil, nil
+}
+
+// bufferAndOffset validates the buffer and offset arguments, checking that
+// size bytes are available at the offset
+func bufferAndOffset(name string, args []interface{}, size int) ([]byte, int, error) {
+	buf, err := basic.EnsureBytes(args[0])
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %v", name, err)
+	}
+
+	offset, err := basic.EnsureInt(args[1])
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: offset must be numeric: %v", name, err)
+	}
+
+	if offset < 0 || offset+size > len(buf) {
+		return nil, 0, fmt.Errorf("%s: offset %d out of range for buffer of %d bytes", name, offset, len(buf))
+	}
+
+	return buf, offset, nil
+}
+
+// byteOrder reads the optional "le"/"be" argument at the given index
+func byteOrder(name string, args []interface{}, idx int) (binary.ByteOrder, error) {
+	if idx >= len(args) {
+		return binary.LittleEndian, nil
+	}
+
+	order, err := basic.EnsureString(args[idx])
+	if err != nil {
+		return nil, fmt.Errorf("%s: byte order: %v", name, err)
+	}
+
+	switch strings.ToLower(order) {
+	case "le":
+		return binary.LittleEndian, nil
+	case "be":
+		return binary.BigEndian, nil
+	default:
+		return nil, fmt.Errorf("%s: byte order must be \"le\" or \"be\", got %q", name, order)
+	}
+}
+
+// parseFormat splits a pack format into its byte order and type codes
+func parseFormat(format string) (binary.ByteOrder, []byte, error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if strings.HasPrefix(format, "<") {
+		format = format[1:]
+	} else if strings.HasPrefix(format, ">") {
+		order = binary.BigEndian
+		format = format[1:]
+	}
+
+	codes := []byte{}
+	for idx := 0; idx < len(format); idx++ {
+		code := format[idx]
+		if code == ' ' {
+			continue
+		}
+		if codeSize(code) == 0 {
+			return nil, nil, fmt.Errorf("unknown format code %q", code)
+		}
+		codes = append(codes, code)
+	}
+
+	return order, codes, nil
+}
+
+// codeSize returns the byte width of a format code, or 0 if unknown
+func codeSize(code byte) int {
+	switch code {
+	case 'b', 'B':
+		return 1
+	case 'h', 'H':
+		return 2
+	case 'i', 'I':
+		return 4
+	default:
+		return 0
+	}
+}
+
+func formatSize(codes []byte) int {
+	total := 0
+	for _, code := range codes {
+		total += codeSize(code)
+	}
+	return total
+}
+
+func getUint(buf []byte, order binary.ByteOrder, size int) uint32 {
+	switch size {
+	case 1:
+		return uint32(buf[0])
+	case 2:
+		return uint32(order.Uint16(buf))
+	default:
+		return order.Uint32(buf)
+	}
+}
+
+func putUint(buf []byte, order binary.ByteOrder, size int, value uint32) {
+	switch size {
+	case 1:
+		buf[0] = byte(value)
+	case 2:
+		order.PutUint16(buf, uint16(value))
+	default:
+		order.PutUint32(buf, value)
+	}
+}
+
+// decode converts raw bits into a script int, sign-extending when signed
+func decode(raw uint32, size int, signed bool) int {
+	if !signed {
+		return int(raw)
+	}
+	switch size {
+	case 1:
+		return int(int8(raw))
+	case 2:
+		return int(int16(raw))
+	default:
+		return int(int32(raw))
+	}
+}
@@ -0,0 +1,122 @@
+package bufferlib
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestBufferAndLen(t *testing.T) {
+	buf, err := Buffer(4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.([]byte), []byte{0, 0, 0, 0}) {
+		t.Errorf("expected zeroed buffer, got %v", buf)
+	}
+
+	n, err := BufLen(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("expected 4, got %v", n)
+	}
+}
+
+func TestReadIntegers(t *testing.T) {
+	buf := []byte{0xff, 0x01, 0x02, 0x03, 0x04}
+
+	tests := []struct {
+		name     string
+		fn       func(args ...interface{}) (interface{}, error)
+		args     []interface{}
+		expected int
+	}{
+		{"readu8", ReadU8, []interface{}{buf, 0}, 255},
+		{"readi8", ReadI8, []interface{}{buf, 0}, -1},
+		{"readu16", ReadU16, []interface{}{buf, 1}, 0x0201},
+		{"readu16 be", ReadU16, []interface{}{buf, 1, "be"}, 0x0102},
+		{"readi16", ReadI16, []interface{}{buf, 0}, 0x01ff},
+		{"readu32", ReadU32, []interface{}{buf, 1}, 0x04030201},
+		{"readi32 be", ReadI32, []interface{}{[]byte{0xff, 0xff, 0xff, 0xfe}, 0, "be"}, -2},
+	}
+
+	for _, tt := range tests {
+		result, err := tt.fn(tt.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %d, got %v", tt.name, tt.expected, result)
+		}
+	}
+
+	if _, err := ReadU32(buf, 2); err == nil {
+		t.Error("expected out-of-range error")
+	}
+	if _, err := ReadU16(buf, 0, "middle"); err == nil {
+		t.Error("expected error for unknown byte order")
+	}
+}
+
+func TestWriteIntegers(t *testing.T) {
+	buf := make([]byte, 7)
+
+	if _, err := WriteU8(buf, 0, 0xab); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := WriteU16(buf, 1, 0x1234, "be"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := WriteU32(buf, 3, 0x01020304); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []byte{0xab, 0x12, 0x34, 0x04, 0x03, 0x02, 0x01}
+	if !bytes.Equal(buf, expected) {
+		t.Errorf("expected %v, got %v", expected, buf)
+	}
+
+	if _, err := WriteU8(buf, 7, 1); err == nil {
+		t.Error("expected out-of-range error")
+	}
+}
+
+func TestPackUnpack(t *testing.T) {
+	packed, err := Pack(">Hb I", 513, -2, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []byte{0x02, 0x01, 0xfe, 0, 0, 0, 7}
+	if !bytes.Equal(packed.([]byte), expected) {
+		t.Errorf("expected %v, got %v", expected, packed)
+	}
+
+	values, err := Unpack(">Hb I", packed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(values) != "[513 -2 7]" {
+		t.Errorf("expected [513 -2 7], got %v", values)
+	}
+
+	values, err = Unpack("B", packed, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(values) != "[254]" {
+		t.Errorf("expected [254], got %v", values)
+	}
+
+	if _, err := Pack("Hx", 1, 2); err == nil {
+		t.Error("expected error for unknown format code")
+	}
+	if _, err := Pack("HH", 1); err == nil {
+		t.Error("expected error for missing value")
+	}
+	if _, err := Unpack("I", []byte{1, 2}); err == nil {
+		t.Error("expected error for short buffer")
+	}
+}
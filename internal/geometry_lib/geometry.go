@@ -0,0 +1,152 @@
+package geometrylib
+
+import (
+	"fmt"
+	"math"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Distance returns the Euclidean distance between two points:
+// distance(x1, y1, x2, y2)
+func Distance(args ...interface{}) (interface{}, error) {
+	v, err := floats("distance", args, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	return math.Hypot(v[2]-v[0], v[3]-v[1]), nil
+}
+
+// RectIntersects reports whether two axis-aligned rectangles overlap:
+// rect_intersects(x1, y1, w1, h1, x2, y2, w2, h2). Rectangles that only
+// share an edge do not intersect.
+func RectIntersects(args ...interface{}) (interface{}, error) {
+	v, err := floats("rect_intersects", args, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	x1, y1, w1, h1 := v[0], v[1], v[2], v[3]
+	x2, y2, w2, h2 := v[4], v[5], v[6], v[7]
+	return x1 < x2+w2 && x2 < x1+w1 && y1 < y2+h2 && y2 < y1+h1, nil
+}
+
+// CircleIntersects reports whether two circles overlap or touch:
+// circle_intersects(x1, y1, r1, x2, y2, r2)
+func CircleIntersects(args ...interface{}) (interface{}, error) {
+	v, err := floats("circle_intersects", args, 6)
+	if err != nil {
+		return nil, err
+	}
+
+	dx := v[3] - v[0]
+	dy := v[4] - v[1]
+	radii := v[2] + v[5]
+	return dx*dx+dy*dy <= radii*radii, nil
+}
+
+// PointInPolygon reports whether a point lies inside a polygon given as an
+// array of [x, y] vertices: point_in_polygon(x, y, poly). Uses the even-odd
+// rule, so points exactly on an edge may fall on either side.
+func PointInPolygon(args ...interface{}) (interface{}, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("point_in_polygon requires 3 arguments")
+	}
+
+	x, err := basic.EnsureFloat(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("point_in_polygon: x must be numeric: %v", err)
+	}
+
+	y, err := basic.EnsureFloat(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("point_in_polygon: y must be numeric: %v", err)
+	}
+
+	vertices, err := basic.EnsureArray(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("point_in_polygon: polygon: %v", err)
+	}
+
+	if len(vertices) < 3 {
+		return nil, fmt.Errorf("point_in_polygon: polygon needs at least 3 vertices, got %d", len(vertices))
+	}
+
+	xs := make([]float64, len(vertices))
+	ys := make([]float64, len(vertices))
+	for idx, vertex := range vertices {
+		xs[idx], ys[idx], err = toPoint(vertex)
+		if err != nil {
+			return nil, fmt.Errorf("point_in_polygon: vertex %d: %v", idx, err)
+		}
+	}
+
+	inside := false
+	for i, j := 0, len(xs)-1; i < len(xs); j, i = i, i+1 {
+		if (ys[i] > y) != (ys[j] > y) && x < (xs[j]-xs[i])*(y-ys[i])/(ys[j]-ys[i])+xs[i] {
+			inside = !inside
+		}
+	}
+
+	return inside, nil
+}
+
+// AngleBetween returns the unsigned angle in radians (0 to pi) between two
+// vectors: angle_between(x1, y1, x2, y2)
+func AngleBetween(args ...interface{}) (interface{}, error) {
+	v, err := floats("angle_between", args, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	if (v[0] == 0 && v[1] == 0) || (v[2] == 0 && v[3] == 0) {
+		return nil, fmt.Errorf("angle_between: vectors must not be zero length")
+	}
+
+	cross := v[0]*v[3] - v[1]*v[2]
+	dot := v[0]*v[2] + v[1]*v[3]
+	return math.Abs(math.Atan2(cross, dot)), nil
+}
+
+// floats validates that exactly count numeric arguments were passed
+func floats(name string, args []interface{}, count int) ([]float64, error) {
+	if len(args) != count {
+		return nil, fmt.Errorf("%s requires %d arguments", name, count)
+	}
+
+	result := make([]float64, count)
+	for idx, arg := range args {
+		v, err := basic.EnsureFloat(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %d must be numeric: %v", name, idx+1, err)
+		}
+		result[idx] = v
+	}
+
+	return result, nil
+}
+
+// toPoint validates an [x, y] array
+func toPoint(value interface{}) (float64, float64, error) {
+	arr, err := basic.EnsureArray(value)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(arr) != 2 {
+		return 0, 0, fmt.Errorf("expected [x, y], got %d elements", len(arr))
+	}
+
+	x, err := basic.EnsureFloat(arr[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("x must be numeric: %v", err)
+	}
+
+	y, err := basic.EnsureFloat(arr[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("y must be numeric: %v", err)
+	}
+
+	return x, y, nil
+}
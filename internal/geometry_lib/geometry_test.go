@@ -0,0 +1,118 @@
+package geometrylib
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	result, err := Distance(0, 0, 3, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 5.0 {
+		t.Errorf("expected 5, got %v", result)
+	}
+
+	if _, err := Distance(0, 0, 3); err == nil {
+		t.Error("expected error for missing argument")
+	}
+	if _, err := Distance(0, 0, "x", 4); err == nil {
+		t.Error("expected error for non-numeric argument")
+	}
+}
+
+func TestRectIntersects(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []interface{}
+		expected bool
+	}{
+		{"overlap", []interface{}{0, 0, 10, 10, 5, 5, 10, 10}, true},
+		{"contained", []interface{}{0, 0, 10, 10, 2, 2, 1, 1}, true},
+		{"touching edge", []interface{}{0, 0, 10, 10, 10, 0, 5, 5}, false},
+		{"apart", []interface{}{0, 0, 1, 1, 5, 5, 1, 1}, false},
+	}
+
+	for _, tt := range tests {
+		result, err := RectIntersects(tt.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, result)
+		}
+	}
+}
+
+func TestCircleIntersects(t *testing.T) {
+	result, err := CircleIntersects(0, 0, 1, 2, 0, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != true {
+		t.Errorf("expected touching circles to intersect")
+	}
+
+	result, err = CircleIntersects(0, 0, 1, 3, 0, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != false {
+		t.Errorf("expected separate circles not to intersect")
+	}
+}
+
+func TestPointInPolygon(t *testing.T) {
+	triangle := []interface{}{
+		[]interface{}{0, 0},
+		[]interface{}{10, 0},
+		[]interface{}{0, 10},
+	}
+
+	result, err := PointInPolygon(2, 2, triangle)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != true {
+		t.Error("expected (2, 2) to be inside")
+	}
+
+	result, err = PointInPolygon(8, 8, triangle)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != false {
+		t.Error("expected (8, 8) to be outside")
+	}
+
+	if _, err := PointInPolygon(0, 0, triangle[:2]); err == nil {
+		t.Error("expected error for degenerate polygon")
+	}
+	if _, err := PointInPolygon(0, 0, []interface{}{1, 2, 3}); err == nil {
+		t.Error("expected error for malformed vertex")
+	}
+}
+
+func TestAngleBetween(t *testing.T) {
+	result, err := AngleBetween(1, 0, 0, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(result.(float64)-math.Pi/2) > 1e-9 {
+		t.Errorf("expected pi/2, got %v", result)
+	}
+
+	result, err = AngleBetween(1, 0, -1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(result.(float64)-math.Pi) > 1e-9 {
+		t.Errorf("expected pi, got %v", result)
+	}
+
+	if _, err := AngleBetween(0, 0, 1, 0); err == nil {
+		t.Error("expected error for zero-length vector")
+	}
+}
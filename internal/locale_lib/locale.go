@@ -0,0 +1,113 @@
+package localelib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// StringTable maps translation keys to localized strings. The host swaps the
+// table when the language changes; scripts look strings up with tr().
+type StringTable struct {
+	mu      sync.RWMutex
+	entries map[string]string
+}
+
+// NewStringTable creates an empty string table
+func NewStringTable() *StringTable {
+	return &StringTable{entries: make(map[string]string)}
+}
+
+// Set replaces the contents of the table. The map is copied, so the caller
+// may keep modifying its own copy.
+func (t *StringTable) Set(entries map[string]string) {
+	copied := make(map[string]string, len(entries))
+	for key, value := range entries {
+		copied[key] = value
+	}
+
+	t.mu.Lock()
+	t.entries = copied
+	t.mu.Unlock()
+}
+
+// Lookup returns the string stored under key
+func (t *StringTable) Lookup(key string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	value, ok := t.entries[key]
+	return value, ok
+}
+
+// Tr looks up a key and substitutes its placeholders: tr("greeting", name).
+// Placeholders are written {0}, {1}, ... and refer to the arguments after
+// the key; "{{" and "}}" produce literal braces. Keys missing from the table
+// are returned unchanged so untranslated text is still visible.
+func (t *StringTable) Tr(args ...interface{}) (interface{}, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("tr requires at least 1 argument")
+	}
+
+	key, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("tr: key: %v", err)
+	}
+
+	template, ok := t.Lookup(key)
+	if !ok {
+		template = key
+	}
+
+	result, err := substitute(template, args[1:])
+	if err != nil {
+		return nil, fmt.Errorf("tr: %q: %v", key, err)
+	}
+	return result, nil
+}
+
+// substitute replaces {n} placeholders with the matching argument
+func substitute(template string, args []interface{}) (string, error) {
+	var sb strings.Builder
+	for idx := 0; idx < len(template); idx++ {
+		ch := template[idx]
+
+		if ch == '}' && idx+1 < len(template) && template[idx+1] == '}' {
+			sb.WriteByte('}')
+			idx++
+			continue
+		}
+
+		if ch != '{' {
+			sb.WriteByte(ch)
+			continue
+		}
+
+		if idx+1 < len(template) && template[idx+1] == '{' {
+			sb.WriteByte('{')
+			idx++
+			continue
+		}
+
+		end := strings.IndexByte(template[idx:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed placeholder at offset %d", idx)
+		}
+
+		name := template[idx+1 : idx+end]
+		n, err := strconv.Atoi(name)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid placeholder {%s}", name)
+		}
+		if n >= len(args) {
+			return "", fmt.Errorf("placeholder {%d} has no matching argument", n)
+		}
+
+		sb.WriteString(basic.ToString(args[n]))
+		idx += end
+	}
+
+	return sb.String(), nil
+}
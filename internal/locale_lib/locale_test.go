@@ -0,0 +1,76 @@
+package localelib
+
+import "testing"
+
+func TestTrSubstitutesPlaceholders(t *testing.T) {
+	table := NewStringTable()
+	table.Set(map[string]string{
+		"greeting": "Hello, {0}! You have {1} new {2}.",
+		"reorder":  "{1} before {0}",
+		"braces":   "{{literal}} {0}",
+	})
+
+	tests := []struct {
+		args     []interface{}
+		expected string
+	}{
+		{[]interface{}{"greeting", "Ada", 3, "messages"}, "Hello, Ada! You have 3 new messages."},
+		{[]interface{}{"reorder", "a", "b"}, "b before a"},
+		{[]interface{}{"braces", 1.5}, "{literal} 1.5"},
+		{[]interface{}{"missing.key"}, "missing.key"},
+	}
+
+	for _, tt := range tests {
+		result, err := table.Tr(tt.args...)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.args, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%v: expected %q, got %q", tt.args, tt.expected, result)
+		}
+	}
+}
+
+func TestTrSetReplacesTable(t *testing.T) {
+	table := NewStringTable()
+	entries := map[string]string{"yes": "Yes"}
+	table.Set(entries)
+	entries["yes"] = "changed"
+
+	result, _ := table.Tr("yes")
+	if result != "Yes" {
+		t.Errorf("expected table to copy entries, got %q", result)
+	}
+
+	table.Set(map[string]string{"yes": "Oui"})
+	result, _ = table.Tr("yes")
+	if result != "Oui" {
+		t.Errorf("expected Oui, got %q", result)
+	}
+}
+
+func TestTrErrors(t *testing.T) {
+	table := NewStringTable()
+	table.Set(map[string]string{
+		"needs.arg": "Hi {0}",
+		"bad":       "Hi {name}",
+		"open":      "Hi {0",
+	})
+
+	if _, err := table.Tr(); err == nil {
+		t.Error("expected error for missing key")
+	}
+	if _, err := table.Tr(5); err == nil {
+		t.Error("expected error for non-string key")
+	}
+	if _, err := table.Tr("needs.arg"); err == nil {
+		t.Error("expected error for missing placeholder argument")
+	}
+	if _, err := table.Tr("bad", "x"); err == nil {
+		t.Error("expected error for non-numeric placeholder")
+	}
+	if _, err := table.Tr("open", "x"); err == nil {
+		t.Error("expected error for unclosed placeholder")
+	}
+}
@@ -0,0 +1,122 @@
+package maplib
+
+import (
+	"fmt"
+	"sort"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Maps are map[string]interface{} values. Scripts read and write entries with
+// the element syntax, prices("sword") = 10; these builtins cover the rest.
+
+// Map creates a map from alternating keys and values:
+// map() or map("sword", 10, "shield", 8)
+func Map(args ...interface{}) (interface{}, error) {
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf("map requires an even number of arguments (key, value pairs)")
+	}
+
+	result := make(map[string]interface{}, len(args)/2)
+	for idx := 0; idx < len(args); idx += 2 {
+		key, err := basic.EnsureString(args[idx])
+		if err != nil {
+			return nil, fmt.Errorf("map: key %d: %v", idx/2, err)
+		}
+		result[key] = args[idx+1]
+	}
+
+	return result, nil
+}
+
+// HasKey reports whether a map holds a key: has_key(m, key)
+func HasKey(args ...interface{}) (interface{}, error) {
+	m, key, err := mapAndKey("has_key", args)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ok := m[key]
+	return ok, nil
+}
+
+// DeleteKey removes a key from a map in place, returning whether it was
+// present: delete_key(m, key)
+func DeleteKey(args ...interface{}) (interface{}, error) {
+	m, key, err := mapAndKey("delete_key", args)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ok := m[key]
+	delete(m, key)
+	return ok, nil
+}
+
+// Keys returns the keys of a map as a sorted array
+func Keys(args ...interface{}) (interface{}, error) {
+	m, err := singleMap("keys", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, 0, len(m))
+	for _, key := range sortedKeys(m) {
+		result = append(result, key)
+	}
+	return result, nil
+}
+
+// Values returns the values of a map as an array, ordered by key
+func Values(args ...interface{}) (interface{}, error) {
+	m, err := singleMap("values", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, 0, len(m))
+	for _, key := range sortedKeys(m) {
+		result = append(result, m[key])
+	}
+	return result, nil
+}
+
+func singleMap(name string, args []interface{}) (map[string]interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s requires 1 argument", name)
+	}
+
+	m, err := basic.EnsureMap(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return m, nil
+}
+
+// mapAndKey validates the (map, key) argument pair
+func mapAndKey(name string, args []interface{}) (map[string]interface{}, string, error) {
+	if len(args) != 2 {
+		return nil, "", fmt.Errorf("%s requires 2 arguments", name)
+	}
+
+	m, err := basic.EnsureMap(args[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: first argument: %v", name, err)
+	}
+
+	key, err := basic.EnsureString(args[1])
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: key: %v", name, err)
+	}
+
+	return m, key, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,78 @@
+package maplib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	result, err := Map("sword", 10, "shield", 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := result.(map[string]interface{})
+	if len(m) != 2 || m["sword"] != 10 || m["shield"] != 8 {
+		t.Errorf("unexpected map: %v", m)
+	}
+
+	empty, err := Map()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(empty.(map[string]interface{})) != 0 {
+		t.Errorf("expected empty map, got %v", empty)
+	}
+
+	if _, err := Map("sword"); err == nil {
+		t.Error("expected error for a key without a value")
+	}
+	if _, err := Map(1, 2); err == nil {
+		t.Error("expected error for a non-string key")
+	}
+}
+
+func TestHasKeyAndDeleteKey(t *testing.T) {
+	m := map[string]interface{}{"gold": 5}
+
+	if found, _ := HasKey(m, "gold"); found != true {
+		t.Error("expected gold to be present")
+	}
+	if found, _ := HasKey(m, "silver"); found != false {
+		t.Error("expected silver to be absent")
+	}
+
+	removed, err := DeleteKey(m, "gold")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != true || len(m) != 0 {
+		t.Errorf("expected gold to be removed in place, got %v, %v", removed, m)
+	}
+	if removed, _ := DeleteKey(m, "gold"); removed != false {
+		t.Error("expected false for a missing key")
+	}
+
+	if _, err := HasKey([]interface{}{}, "x"); err == nil {
+		t.Error("expected error for a non-map argument")
+	}
+}
+
+func TestKeysAndValues(t *testing.T) {
+	m := map[string]interface{}{"b": 2, "a": 1, "c": 3}
+
+	keys, err := Keys(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(keys) != "[a b c]" {
+		t.Errorf("expected sorted keys, got %v", keys)
+	}
+
+	values, err := Values(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(values) != "[1 2 3]" {
+		t.Errorf("expected values in key order, got %v", values)
+	}
+}
@@ -118,8 +118,20 @@ func Log(args ...interface{}) (interface{}, error) {
 // Rnd returns a random number between 0 and 1 if no argument,
 // or between 0 and the specified value if an argument is provided
 func Rnd(args ...interface{}) (interface{}, error) {
+	return rnd(rand.Float64, args)
+}
+
+// RndFrom returns an rnd builtin that draws from the given generator, so
+// seeding the generator makes scripts reproducible
+func RndFrom(r *rand.Rand) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		return rnd(r.Float64, args)
+	}
+}
+
+func rnd(next func() float64, args []interface{}) (interface{}, error) {
 	if len(args) == 0 {
-		return rand.Float64(), nil
+		return next(), nil
 	}
 
 	if len(args) == 1 {
@@ -127,7 +139,7 @@ func Rnd(args ...interface{}) (interface{}, error) {
 		if err != nil {
 			return nil, fmt.Errorf("rnd: argument must be numeric: %v", err)
 		}
-		return rand.Float64() * max, nil
+		return next() * max, nil
 	}
 
 	return nil, fmt.Errorf("rnd requires 0 or 1 argument")
@@ -2,6 +2,7 @@ package mathlib
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -141,6 +142,17 @@ func TestRnd(t *testing.T) {
 	}
 }
 
+func TestRndFrom(t *testing.T) {
+	a, err := RndFrom(rand.New(rand.NewSource(7)))(100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := RndFrom(rand.New(rand.NewSource(7)))(100)
+	if a != b {
+		t.Errorf("expected identical values for identical seeds, got %v and %v", a, b)
+	}
+}
+
 func TestSin(t *testing.T) {
 	result, err := Sin(0.0)
 	if err != nil {
@@ -0,0 +1,247 @@
+package matrixlib
+
+import (
+	"fmt"
+	"math"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Matrices are 2D arrays: an array of rows, each row an array of numbers.
+
+// singularEpsilon is the pivot magnitude below which a matrix is treated as singular
+const singularEpsilon = 1e-12
+
+// Matrix creates a rows x cols matrix filled with 0 (or the optional fill value)
+func Matrix(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("matrix requires 2 or 3 arguments")
+	}
+
+	rows, err := basic.EnsureInt(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("matrix: rows must be numeric: %v", err)
+	}
+
+	cols, err := basic.EnsureInt(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("matrix: cols must be numeric: %v", err)
+	}
+
+	if rows <= 0 || cols <= 0 {
+		return nil, fmt.Errorf("matrix: dimensions must be positive")
+	}
+
+	var fill interface{} = 0
+	if len(args) == 3 {
+		if _, err := basic.EnsureFloat(args[2]); err != nil {
+			return nil, fmt.Errorf("matrix: fill value must be numeric: %v", err)
+		}
+		fill = args[2]
+	}
+
+	result := make([]interface{}, rows)
+	for r := range result {
+		row := make([]interface{}, cols)
+		for c := range row {
+			row[c] = fill
+		}
+		result[r] = row
+	}
+
+	return result, nil
+}
+
+// Identity creates an n x n identity matrix
+func Identity(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("identity requires 1 argument")
+	}
+
+	n, err := basic.EnsureInt(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("identity: argument must be numeric: %v", err)
+	}
+
+	if n <= 0 {
+		return nil, fmt.Errorf("identity: size must be positive")
+	}
+
+	m := make([][]float64, n)
+	for r := range m {
+		m[r] = make([]float64, n)
+		m[r][r] = 1
+	}
+
+	return fromFloats(m), nil
+}
+
+// MatMul multiplies two matrices
+func MatMul(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("matmul requires 2 arguments")
+	}
+
+	a, err := toFloats(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("matmul: first argument: %v", err)
+	}
+
+	b, err := toFloats(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("matmul: second argument: %v", err)
+	}
+
+	if len(a[0]) != len(b) {
+		return nil, fmt.Errorf("matmul: cannot multiply %dx%d by %dx%d", len(a), len(a[0]), len(b), len(b[0]))
+	}
+
+	result := make([][]float64, len(a))
+	for r := range a {
+		result[r] = make([]float64, len(b[0]))
+		for c := range b[0] {
+			sum := 0.0
+			for k := range b {
+				sum += a[r][k] * b[k][c]
+			}
+			result[r][c] = sum
+		}
+	}
+
+	return fromFloats(result), nil
+}
+
+// Transpose swaps the rows and columns of a matrix
+func Transpose(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("transpose requires 1 argument")
+	}
+
+	m, err := toFloats(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("transpose: %v", err)
+	}
+
+	result := make([][]float64, len(m[0]))
+	for c := range result {
+		result[c] = make([]float64, len(m))
+		for r := range m {
+			result[c][r] = m[r][c]
+		}
+	}
+
+	return fromFloats(result), nil
+}
+
+// Invert returns the inverse of a square matrix using Gauss-Jordan elimination
+func Invert(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("invert requires 1 argument")
+	}
+
+	m, err := toFloats(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invert: %v", err)
+	}
+
+	n := len(m)
+	if len(m[0]) != n {
+		return nil, fmt.Errorf("invert: matrix must be square, got %dx%d", n, len(m[0]))
+	}
+
+	// Augment with the identity matrix
+	aug := make([][]float64, n)
+	for r := range m {
+		aug[r] = make([]float64, 2*n)
+		copy(aug[r], m[r])
+		aug[r][n+r] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		// Partial pivoting for numerical stability
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(aug[r][col]) > math.Abs(aug[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(aug[pivot][col]) < singularEpsilon {
+			return nil, fmt.Errorf("invert: matrix is singular")
+		}
+		aug[col], aug[pivot] = aug[pivot], aug[col]
+
+		scale := aug[col][col]
+		for c := range aug[col] {
+			aug[col][c] /= scale
+		}
+
+		for r := 0; r < n; r++ {
+			if r == col {
+				continue
+			}
+			factor := aug[r][col]
+			for c := range aug[r] {
+				aug[r][c] -= factor * aug[col][c]
+			}
+		}
+	}
+
+	result := make([][]float64, n)
+	for r := range aug {
+		result[r] = aug[r][n:]
+	}
+
+	return fromFloats(result), nil
+}
+
+// toFloats validates a rectangular numeric 2D array and converts it to floats
+func toFloats(value interface{}) ([][]float64, error) {
+	rows, err := basic.EnsureArray(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("matrix is empty")
+	}
+
+	result := make([][]float64, len(rows))
+	cols := -1
+	for r, rowValue := range rows {
+		row, err := basic.EnsureArray(rowValue)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", r, err)
+		}
+
+		if cols == -1 {
+			cols = len(row)
+		}
+		if len(row) != cols || cols == 0 {
+			return nil, fmt.Errorf("row %d: matrix rows must be non-empty and equal length", r)
+		}
+
+		result[r] = make([]float64, cols)
+		for c, elem := range row {
+			v, err := basic.EnsureFloat(elem)
+			if err != nil {
+				return nil, fmt.Errorf("element (%d, %d) must be numeric: %v", r, c, err)
+			}
+			result[r][c] = v
+		}
+	}
+
+	return result, nil
+}
+
+// fromFloats converts a float matrix back into script arrays
+func fromFloats(m [][]float64) []interface{} {
+	result := make([]interface{}, len(m))
+	for r, row := range m {
+		values := make([]interface{}, len(row))
+		for c, v := range row {
+			values[c] = v
+		}
+		result[r] = values
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package matrixlib
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func mat(rows ...[]interface{}) []interface{} {
+	result := make([]interface{}, len(rows))
+	for i, row := range rows {
+		result[i] = row
+	}
+	return result
+}
+
+func TestMatrix(t *testing.T) {
+	result, err := Matrix(2, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(result) != "[[0 0 0] [0 0 0]]" {
+		t.Errorf("expected 2x3 zero matrix, got %v", result)
+	}
+
+	result, err = Matrix(1, 2, 1.5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(result) != "[[1.5 1.5]]" {
+		t.Errorf("expected filled matrix, got %v", result)
+	}
+
+	if _, err := Matrix(0, 2); err == nil {
+		t.Error("expected error for zero rows")
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	result, err := Identity(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(result) != "[[1 0] [0 1]]" {
+		t.Errorf("expected identity, got %v", result)
+	}
+}
+
+func TestMatMul(t *testing.T) {
+	a := mat([]interface{}{1, 2}, []interface{}{3, 4})
+	b := mat([]interface{}{5, 6}, []interface{}{7, 8})
+
+	result, err := MatMul(a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(result) != "[[19 22] [43 50]]" {
+		t.Errorf("unexpected product %v", result)
+	}
+
+	if _, err := MatMul(a, mat([]interface{}{1, 2, 3})); err == nil {
+		t.Error("expected dimension mismatch error")
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	result, err := Transpose(mat([]interface{}{1, 2, 3}, []interface{}{4, 5, 6}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(result) != "[[1 4] [2 5] [3 6]]" {
+		t.Errorf("unexpected transpose %v", result)
+	}
+
+	if _, err := Transpose(mat([]interface{}{1, 2}, []interface{}{3})); err == nil {
+		t.Error("expected error for ragged matrix")
+	}
+}
+
+func TestInvert(t *testing.T) {
+	result, err := Invert(mat([]interface{}{4, 7}, []interface{}{2, 6}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := [][]float64{{0.6, -0.7}, {-0.2, 0.4}}
+	rows := result.([]interface{})
+	for r, row := range rows {
+		for c, v := range row.([]interface{}) {
+			if math.Abs(v.(float64)-expected[r][c]) > 1e-9 {
+				t.Errorf("element (%d, %d): expected %v, got %v", r, c, expected[r][c], v)
+			}
+		}
+	}
+
+	if _, err := Invert(mat([]interface{}{1, 2}, []interface{}{2, 4})); err == nil {
+		t.Error("expected error for singular matrix")
+	}
+	if _, err := Invert(mat([]interface{}{1, 2, 3}, []interface{}{4, 5, 6})); err == nil {
+		t.Error("expected error for non-square matrix")
+	}
+}
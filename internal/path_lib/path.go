@@ -0,0 +1,224 @@
+package pathlib
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Grids are 2D arrays indexed as grid[y][x]. Each cell holds the cost of
+// entering it; cells with a cost of zero or less are impassable, as are cells
+// missing from rows shorter than the others.
+
+type point struct {
+	x, y int
+}
+
+// Pathfind finds the cheapest path between two cells using A*:
+// pathfind(grid, [sx, sy], [gx, gy][, diagonal]). It returns an array of
+// [x, y] waypoints from start to goal inclusive, or an empty array when the
+// goal is unreachable. Diagonal moves are allowed when the optional fourth
+// argument is true.
+func Pathfind(args ...interface{}) (interface{}, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return nil, fmt.Errorf("pathfind requires 3 or 4 arguments")
+	}
+
+	grid, err := toGrid(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("pathfind: grid: %v", err)
+	}
+
+	start, err := toPoint(args[1], grid)
+	if err != nil {
+		return nil, fmt.Errorf("pathfind: start: %v", err)
+	}
+
+	goal, err := toPoint(args[2], grid)
+	if err != nil {
+		return nil, fmt.Errorf("pathfind: goal: %v", err)
+	}
+
+	diagonal := len(args) == 4 && basic.IsTruthy(args[3])
+
+	path := search(grid, start, goal, diagonal)
+
+	result := make([]interface{}, len(path))
+	for idx, p := range path {
+		result[idx] = []interface{}{p.x, p.y}
+	}
+	return result, nil
+}
+
+// search runs A* and returns the path from start to goal, or nil
+func search(grid [][]float64, start, goal point, diagonal bool) []point {
+	if grid[start.y][start.x] <= 0 || grid[goal.y][goal.x] <= 0 {
+		return nil
+	}
+
+	// Scale the heuristic by the cheapest cell so it never overestimates
+	minCost := math.Inf(1)
+	for _, row := range grid {
+		for _, cost := range row {
+			if cost > 0 && cost < minCost {
+				minCost = cost
+			}
+		}
+	}
+
+	heuristic := func(p point) float64 {
+		dx := math.Abs(float64(p.x - goal.x))
+		dy := math.Abs(float64(p.y - goal.y))
+		if diagonal {
+			return minCost * (math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy))
+		}
+		return minCost * (dx + dy)
+	}
+
+	moves := []point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	if diagonal {
+		moves = append(moves, point{1, 1}, point{1, -1}, point{-1, 1}, point{-1, -1})
+	}
+
+	cameFrom := map[point]point{}
+	costSoFar := map[point]float64{start: 0}
+	open := &frontier{}
+	heap.Push(open, &node{p: start, priority: heuristic(start)})
+
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*node).p
+		if current == goal {
+			return reconstruct(cameFrom, start, goal)
+		}
+
+		for _, move := range moves {
+			next := point{current.x + move.x, current.y + move.y}
+			cellCost := costAt(grid, next.x, next.y)
+			if cellCost <= 0 {
+				continue
+			}
+
+			stepCost := cellCost
+			if move.x != 0 && move.y != 0 {
+				// Don't cut corners past impassable cells
+				if costAt(grid, next.x, current.y) <= 0 || costAt(grid, current.x, next.y) <= 0 {
+					continue
+				}
+				stepCost *= math.Sqrt2
+			}
+
+			newCost := costSoFar[current] + stepCost
+			if known, ok := costSoFar[next]; ok && newCost >= known {
+				continue
+			}
+
+			costSoFar[next] = newCost
+			cameFrom[next] = current
+			heap.Push(open, &node{p: next, priority: newCost + heuristic(next)})
+		}
+	}
+
+	return nil
+}
+
+// costAt returns the cost of entering a cell, 0 for cells off the grid
+func costAt(grid [][]float64, x, y int) float64 {
+	if y < 0 || y >= len(grid) || x < 0 || x >= len(grid[y]) {
+		return 0
+	}
+	return grid[y][x]
+}
+
+func reconstruct(cameFrom map[point]point, start, goal point) []point {
+	path := []point{goal}
+	for current := goal; current != start; {
+		current = cameFrom[current]
+		path = append(path, current)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// toGrid validates a 2D numeric array
+func toGrid(value interface{}) ([][]float64, error) {
+	rows, err := basic.EnsureArray(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("grid is empty")
+	}
+
+	grid := make([][]float64, len(rows))
+	for y, rowValue := range rows {
+		row, err := basic.EnsureArray(rowValue)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", y, err)
+		}
+
+		grid[y] = make([]float64, len(row))
+		for x, cell := range row {
+			cost, err := basic.EnsureFloat(cell)
+			if err != nil {
+				return nil, fmt.Errorf("cell (%d, %d) must be numeric: %v", x, y, err)
+			}
+			grid[y][x] = cost
+		}
+	}
+
+	return grid, nil
+}
+
+// toPoint validates an [x, y] array that lies inside the grid
+func toPoint(value interface{}, grid [][]float64) (point, error) {
+	arr, err := basic.EnsureArray(value)
+	if err != nil {
+		return point{}, err
+	}
+
+	if len(arr) != 2 {
+		return point{}, fmt.Errorf("expected [x, y], got %d elements", len(arr))
+	}
+
+	x, err := basic.EnsureInt(arr[0])
+	if err != nil {
+		return point{}, fmt.Errorf("x must be numeric: %v", err)
+	}
+
+	y, err := basic.EnsureInt(arr[1])
+	if err != nil {
+		return point{}, fmt.Errorf("y must be numeric: %v", err)
+	}
+
+	if y < 0 || y >= len(grid) || x < 0 || x >= len(grid[y]) {
+		return point{}, fmt.Errorf("(%d, %d) is outside the grid", x, y)
+	}
+
+	return point{x, y}, nil
+}
+
+// node is an entry in the A* open set
+type node struct {
+	p        point
+	priority float64
+}
+
+// frontier is a min-heap of nodes ordered by priority
+type frontier []*node
+
+func (f frontier) Len() int            { return len(f) }
+func (f frontier) Less(i, j int) bool  { return f[i].priority < f[j].priority }
+func (f frontier) Swap(i, j int)       { f[i], f[j] = f[j], f[i] }
+func (f *frontier) Push(x interface{}) { *f = append(*f, x.(*node)) }
+func (f *frontier) Pop() interface{} {
+	old := *f
+	n := old[len(old)-1]
+	*f = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,113 @@
+package pathlib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func grid(rows ...[]interface{}) []interface{} {
+	result := make([]interface{}, len(rows))
+	for i, row := range rows {
+		result[i] = row
+	}
+	return result
+}
+
+func TestPathfindAroundWall(t *testing.T) {
+	g := grid(
+		[]interface{}{1, 1, 1},
+		[]interface{}{0, 0, 1},
+		[]interface{}{1, 1, 1},
+	)
+
+	path, err := Pathfind(g, []interface{}{0, 0}, []interface{}{0, 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "[[0 0] [1 0] [2 0] [2 1] [2 2] [1 2] [0 2]]"
+	if fmt.Sprint(path) != expected {
+		t.Errorf("expected %s, got %v", expected, path)
+	}
+}
+
+func TestPathfindPrefersCheapCells(t *testing.T) {
+	g := grid(
+		[]interface{}{1, 9, 1},
+		[]interface{}{1, 1, 1},
+	)
+
+	path, err := Pathfind(g, []interface{}{0, 0}, []interface{}{2, 0})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "[[0 0] [0 1] [1 1] [2 1] [2 0]]"
+	if fmt.Sprint(path) != expected {
+		t.Errorf("expected %s, got %v", expected, path)
+	}
+}
+
+func TestPathfindDiagonal(t *testing.T) {
+	g := grid(
+		[]interface{}{1, 1, 1},
+		[]interface{}{1, 1, 1},
+		[]interface{}{1, 1, 1},
+	)
+
+	path, err := Pathfind(g, []interface{}{0, 0}, []interface{}{2, 2}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fmt.Sprint(path) != "[[0 0] [1 1] [2 2]]" {
+		t.Errorf("expected diagonal path, got %v", path)
+	}
+}
+
+func TestPathfindJaggedGrid(t *testing.T) {
+	g := grid(
+		[]interface{}{1, 1},
+		[]interface{}{1, 1, 1},
+	)
+
+	// The cell missing from the first row can't be cut past
+	path, err := Pathfind(g, []interface{}{1, 0}, []interface{}{2, 1}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "[[1 0] [1 1] [2 1]]"
+	if fmt.Sprint(path) != expected {
+		t.Errorf("expected %s, got %v", expected, path)
+	}
+}
+
+func TestPathfindUnreachable(t *testing.T) {
+	g := grid(
+		[]interface{}{1, 0, 1},
+		[]interface{}{1, 0, 1},
+	)
+
+	path, err := Pathfind(g, []interface{}{0, 0}, []interface{}{2, 0})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(path.([]interface{})) != 0 {
+		t.Errorf("expected empty path, got %v", path)
+	}
+}
+
+func TestPathfindErrors(t *testing.T) {
+	g := grid([]interface{}{1, 1})
+
+	if _, err := Pathfind(g, []interface{}{0, 0}, []interface{}{5, 0}); err == nil {
+		t.Error("expected error for goal outside grid")
+	}
+	if _, err := Pathfind(g, []interface{}{0}, []interface{}{1, 0}); err == nil {
+		t.Error("expected error for malformed start")
+	}
+	if _, err := Pathfind("grid", []interface{}{0, 0}, []interface{}{1, 0}); err == nil {
+		t.Error("expected error for non-array grid")
+	}
+}
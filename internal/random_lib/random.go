@@ -0,0 +1,123 @@
+package randomlib
+
+import (
+	"fmt"
+	"math/rand"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// The builtins in this package are built around a caller-supplied generator
+// so they share the interpreter's seedable random source.
+
+// Gaussian returns a builtin that draws from a normal distribution:
+// gaussian([mean[, stddev]]). Mean defaults to 0 and stddev to 1.
+func Gaussian(r *rand.Rand) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) > 2 {
+			return nil, fmt.Errorf("gaussian requires 0 to 2 arguments")
+		}
+
+		mean := 0.0
+		stddev := 1.0
+		var err error
+		if len(args) >= 1 {
+			mean, err = basic.EnsureFloat(args[0])
+			if err != nil {
+				return nil, fmt.Errorf("gaussian: mean must be numeric: %v", err)
+			}
+		}
+		if len(args) == 2 {
+			stddev, err = basic.EnsureFloat(args[1])
+			if err != nil {
+				return nil, fmt.Errorf("gaussian: stddev must be numeric: %v", err)
+			}
+			if stddev < 0 {
+				return nil, fmt.Errorf("gaussian: stddev must not be negative")
+			}
+		}
+
+		return mean + r.NormFloat64()*stddev, nil
+	}
+}
+
+// WeightedChoice returns a builtin that picks an element of an array with
+// probability proportional to its weight: weighted_choice(values, weights)
+func WeightedChoice(r *rand.Rand) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("weighted_choice requires 2 arguments")
+		}
+
+		values, err := basic.EnsureArray(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("weighted_choice: values: %v", err)
+		}
+
+		weightArgs, err := basic.EnsureArray(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("weighted_choice: weights: %v", err)
+		}
+
+		if len(values) != len(weightArgs) {
+			return nil, fmt.Errorf("weighted_choice: got %d values but %d weights", len(values), len(weightArgs))
+		}
+
+		weights := make([]float64, len(weightArgs))
+		total := 0.0
+		for idx, w := range weightArgs {
+			weight, err := basic.EnsureFloat(w)
+			if err != nil {
+				return nil, fmt.Errorf("weighted_choice: weight %d must be numeric: %v", idx, err)
+			}
+			if weight < 0 {
+				return nil, fmt.Errorf("weighted_choice: weight %d must not be negative", idx)
+			}
+			weights[idx] = weight
+			total += weight
+		}
+
+		if total <= 0 {
+			return nil, fmt.Errorf("weighted_choice: weights must sum to more than 0")
+		}
+
+		roll := r.Float64() * total
+		for idx, weight := range weights {
+			if roll < weight {
+				return values[idx], nil
+			}
+			roll -= weight
+		}
+
+		// Floating point rounding can leave roll just past the last bucket
+		for idx := len(weights) - 1; idx >= 0; idx-- {
+			if weights[idx] > 0 {
+				return values[idx], nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// Shuffle returns a builtin that produces a shuffled copy of an array,
+// leaving the original untouched: shuffle(values)
+func Shuffle(r *rand.Rand) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("shuffle requires 1 argument")
+		}
+
+		arr, err := basic.EnsureArray(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("shuffle: %v", err)
+		}
+
+		result := make([]interface{}, len(arr))
+		copy(result, arr)
+		r.Shuffle(len(result), func(a, b int) {
+			result[a], result[b] = result[b], result[a]
+		})
+
+		return result, nil
+	}
+}
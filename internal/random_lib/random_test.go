@@ -0,0 +1,100 @@
+package randomlib
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestGaussian(t *testing.T) {
+	gaussian := Gaussian(rand.New(rand.NewSource(1)))
+
+	total := 0.0
+	for i := 0; i < 2000; i++ {
+		result, err := gaussian(10, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		total += result.(float64)
+	}
+	if mean := total / 2000; math.Abs(mean-10) > 0.5 {
+		t.Errorf("expected mean near 10, got %v", mean)
+	}
+
+	if _, err := gaussian(0, -1); err == nil {
+		t.Error("expected error for negative stddev")
+	}
+	if _, err := gaussian("x"); err == nil {
+		t.Error("expected error for non-numeric mean")
+	}
+}
+
+func TestGaussianSeeded(t *testing.T) {
+	a, _ := Gaussian(rand.New(rand.NewSource(42)))()
+	b, _ := Gaussian(rand.New(rand.NewSource(42)))()
+	if a != b {
+		t.Errorf("expected identical draws for identical seeds, got %v and %v", a, b)
+	}
+}
+
+func TestWeightedChoice(t *testing.T) {
+	choose := WeightedChoice(rand.New(rand.NewSource(1)))
+	values := []interface{}{"common", "rare", "never"}
+	weights := []interface{}{9, 1, 0}
+
+	counts := map[interface{}]int{}
+	for i := 0; i < 1000; i++ {
+		result, err := choose(values, weights)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		counts[result]++
+	}
+
+	if counts["never"] != 0 {
+		t.Errorf("zero-weight value was chosen %d times", counts["never"])
+	}
+	if counts["common"] < counts["rare"]*4 {
+		t.Errorf("expected common to dominate, got %v", counts)
+	}
+
+	if _, err := choose(values, []interface{}{1, 2}); err == nil {
+		t.Error("expected error for mismatched lengths")
+	}
+	if _, err := choose(values, []interface{}{0, 0, 0}); err == nil {
+		t.Error("expected error for all-zero weights")
+	}
+	if _, err := choose(values, []interface{}{1, -1, 0}); err == nil {
+		t.Error("expected error for negative weight")
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	shuffle := Shuffle(rand.New(rand.NewSource(1)))
+	arr := []interface{}{1, 2, 3, 4, 5}
+
+	result, err := shuffle(arr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fmt.Sprint(arr) != "[1 2 3 4 5]" {
+		t.Errorf("original array was modified: %v", arr)
+	}
+
+	shuffled := result.([]interface{})
+	sorted := make([]int, len(shuffled))
+	for idx, v := range shuffled {
+		sorted[idx] = v.(int)
+	}
+	sort.Ints(sorted)
+	if fmt.Sprint(sorted) != "[1 2 3 4 5]" {
+		t.Errorf("expected a permutation of the input, got %v", shuffled)
+	}
+
+	if _, err := shuffle("abc"); err == nil {
+		t.Error("expected error for non-array argument")
+	}
+}
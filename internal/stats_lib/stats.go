@@ -0,0 +1,149 @@
+package statslib
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Mean returns the arithmetic mean of a numeric array
+func Mean(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("mean requires 1 argument")
+	}
+
+	values, err := numbers("mean", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return mean(values), nil
+}
+
+// Median returns the middle value of a numeric array, averaging the two
+// middle values when the length is even
+func Median(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("median requires 1 argument")
+	}
+
+	values, err := numbers("median", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2, nil
+	}
+	return values[mid], nil
+}
+
+// Variance returns the population variance of a numeric array, or the sample
+// variance when the optional second argument is true
+func Variance(args ...interface{}) (interface{}, error) {
+	return variance("variance", args)
+}
+
+// StdDev returns the population standard deviation of a numeric array, or the
+// sample standard deviation when the optional second argument is true
+func StdDev(args ...interface{}) (interface{}, error) {
+	v, err := variance("stddev", args)
+	if err != nil {
+		return nil, err
+	}
+	return math.Sqrt(v.(float64)), nil
+}
+
+// Percentile returns the p-th percentile (0-100) of a numeric array using
+// linear interpolation between the closest ranks
+func Percentile(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("percentile requires 2 arguments")
+	}
+
+	values, err := numbers("percentile", args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := basic.EnsureFloat(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("percentile: percentile must be numeric: %v", err)
+	}
+
+	if p < 0 || p > 100 {
+		return nil, fmt.Errorf("percentile: percentile must be between 0 and 100")
+	}
+
+	sort.Float64s(values)
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	frac := rank - float64(lower)
+
+	return values[lower] + (values[upper]-values[lower])*frac, nil
+}
+
+// variance implements Variance and StdDev
+func variance(name string, args []interface{}) (interface{}, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("%s requires 1 or 2 arguments", name)
+	}
+
+	values, err := numbers(name, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sample := len(args) == 2 && basic.IsTruthy(args[1])
+	n := float64(len(values))
+	if sample {
+		if len(values) < 2 {
+			return nil, fmt.Errorf("%s: sample statistics need at least 2 values", name)
+		}
+		n--
+	}
+
+	m := mean(values)
+	total := 0.0
+	for _, v := range values {
+		total += (v - m) * (v - m)
+	}
+
+	return total / n, nil
+}
+
+// numbers converts a non-empty numeric array to floats
+func numbers(name string, value interface{}) ([]float64, error) {
+	arr, err := basic.EnsureArray(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if len(arr) == 0 {
+		return nil, fmt.Errorf("%s: array is empty", name)
+	}
+
+	values := make([]float64, len(arr))
+	for idx, elem := range arr {
+		v, err := basic.EnsureFloat(elem)
+		if err != nil {
+			return nil, fmt.Errorf("%s: element %d must be numeric: %v", name, idx, err)
+		}
+		values[idx] = v
+	}
+
+	return values, nil
+}
+
+func mean(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
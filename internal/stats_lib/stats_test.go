@@ -0,0 +1,54 @@
+package statslib
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStatistics(t *testing.T) {
+	data := []interface{}{2, 4, 4, 4, 5, 5, 7, 9}
+
+	tests := []struct {
+		name     string
+		fn       func(args ...interface{}) (interface{}, error)
+		args     []interface{}
+		expected float64
+	}{
+		{"mean", Mean, []interface{}{data}, 5},
+		{"median even", Median, []interface{}{data}, 4.5},
+		{"median odd", Median, []interface{}{[]interface{}{3, 1, 2}}, 2},
+		{"variance", Variance, []interface{}{data}, 4},
+		{"stddev", StdDev, []interface{}{data}, 2},
+		{"sample variance", Variance, []interface{}{data, true}, 32.0 / 7},
+		{"percentile 0", Percentile, []interface{}{data, 0}, 2},
+		{"percentile 50", Percentile, []interface{}{data, 50}, 4.5},
+		{"percentile 100", Percentile, []interface{}{data, 100}, 9},
+		{"percentile 25", Percentile, []interface{}{[]interface{}{1, 2, 3, 4, 5}, 25}, 2},
+	}
+
+	for _, tt := range tests {
+		result, err := tt.fn(tt.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if math.Abs(result.(float64)-tt.expected) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, result)
+		}
+	}
+}
+
+func TestStatisticsErrors(t *testing.T) {
+	if _, err := Mean([]interface{}{}); err == nil {
+		t.Error("expected error for empty array")
+	}
+	if _, err := Median([]interface{}{1, "two"}); err == nil {
+		t.Error("expected error for non-numeric element")
+	}
+	if _, err := Percentile([]interface{}{1, 2}, 101); err == nil {
+		t.Error("expected error for percentile out of range")
+	}
+	if _, err := StdDev([]interface{}{1}, true); err == nil {
+		t.Error("expected error for sample stddev of one value")
+	}
+}
@@ -0,0 +1,130 @@
+package stringlib
+
+import (
+	"fmt"
+	"strings"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// maxFormatWidth bounds the width and precision of a format verb, so a script
+// can't build enormous strings with something like %999999999d
+const maxFormatWidth = 100
+
+// sprintf formats values with a safe subset of Go's fmt verbs. Only script
+// values (ints, floats, strings and booleans) are accepted, so host values
+// can't leak into the output through %v-style formatting.
+//
+// Supported verbs: %d %x %X %o %b (integers), %f %e %g (numbers), %s (any
+// script value) and %% (a literal percent sign). Verbs may carry the flags
+// - + 0 and space, a width and a precision: %-8s, %05d, %0.2f.
+func sprintf(format string, values []interface{}) (string, error) {
+	var out strings.Builder
+	next := 0
+
+	for pos := 0; pos < len(format); pos++ {
+		ch := format[pos]
+		if ch != '%' {
+			out.WriteByte(ch)
+			continue
+		}
+
+		spec, verb, end, err := parseVerb(format, pos)
+		if err != nil {
+			return "", err
+		}
+		pos = end
+
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+
+		if next >= len(values) {
+			return "", fmt.Errorf("%%%c has no matching argument", verb)
+		}
+		value, err := verbValue(verb, values[next], next+1)
+		if err != nil {
+			return "", err
+		}
+		next++
+
+		out.WriteString(fmt.Sprintf(spec, value))
+	}
+
+	if next < len(values) {
+		return "", fmt.Errorf("%d arguments given but the format uses %d", len(values), next)
+	}
+	return out.String(), nil
+}
+
+// parseVerb reads the verb starting at the % at format[start], returning the
+// complete spec (such as "%-08.2f"), the verb letter and the index of its
+// last byte
+func parseVerb(format string, start int) (string, byte, int, error) {
+	pos := start + 1
+	for pos < len(format) && strings.IndexByte("-+0 ", format[pos]) >= 0 {
+		pos++
+	}
+
+	width, pos := readNumber(format, pos)
+	precision := 0
+	if pos < len(format) && format[pos] == '.' {
+		precision, pos = readNumber(format, pos+1)
+	}
+	if width > maxFormatWidth || precision > maxFormatWidth {
+		return "", 0, 0, fmt.Errorf("width and precision must not exceed %d", maxFormatWidth)
+	}
+
+	if pos >= len(format) {
+		return "", 0, 0, fmt.Errorf("incomplete verb at end of format")
+	}
+	verb := format[pos]
+	if strings.IndexByte("dxXobfegs%", verb) < 0 {
+		return "", 0, 0, fmt.Errorf("unsupported verb %%%c", verb)
+	}
+	if verb == '%' && pos != start+1 {
+		return "", 0, 0, fmt.Errorf("%%%% does not take flags, width or precision")
+	}
+
+	return format[start : pos+1], verb, pos, nil
+}
+
+// readNumber reads a run of decimal digits, saturating well above
+// maxFormatWidth so long runs can't overflow
+func readNumber(format string, pos int) (int, int) {
+	n := 0
+	for pos < len(format) && format[pos] >= '0' && format[pos] <= '9' {
+		if n <= maxFormatWidth {
+			n = n*10 + int(format[pos]-'0')
+		}
+		pos++
+	}
+	return n, pos
+}
+
+// verbValue checks that a value suits its verb, converting it where needed
+func verbValue(verb byte, value interface{}, argNum int) (interface{}, error) {
+	switch verb {
+	case 'd', 'x', 'X', 'o', 'b':
+		n, ok := value.(int)
+		if !ok {
+			return nil, fmt.Errorf("%%%c needs an integer for argument %d, got %s", verb, argNum, basic.TypeName(value))
+		}
+		return n, nil
+
+	case 'f', 'e', 'g':
+		switch value.(type) {
+		case int, float64:
+			return basic.EnsureFloat(value)
+		}
+		return nil, fmt.Errorf("%%%c needs a number for argument %d, got %s", verb, argNum, basic.TypeName(value))
+
+	default: // 's'
+		switch value.(type) {
+		case string, int, float64, bool:
+			return basic.ToString(value), nil
+		}
+		return nil, fmt.Errorf("%%s cannot format %s (argument %d)", basic.TypeName(value), argNum)
+	}
+}
@@ -0,0 +1,62 @@
+package stringlib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatTemplate(t *testing.T) {
+	tests := []struct {
+		args     []interface{}
+		expected string
+	}{
+		{[]interface{}{"hp: %d / %0.2f", 7, 2.0 / 3.0}, "hp: 7 / 0.67"},
+		{[]interface{}{"%s has %d gold", "Ada", 12}, "Ada has 12 gold"},
+		{[]interface{}{"[%-6s][%6s]", "ab", "cd"}, "[ab    ][    cd]"},
+		{[]interface{}{"%05d|%+d|% d", 42, 3, 3}, "00042|+3| 3"},
+		{[]interface{}{"%x %X %o %b", 255, 255, 8, 5}, "ff FF 10 101"},
+		{[]interface{}{"%.1f %e %g", 3, 1500.0, 0.25}, "3.0 1.500000e+03 0.25"},
+		{[]interface{}{"%s %s %s", 1.5, true, 7}, "1.5 true 7"},
+		{[]interface{}{"100%% done"}, "100% done"},
+		{[]interface{}{"héllo %s", "wörld"}, "héllo wörld"},
+		{[]interface{}{"no verbs"}, "no verbs"},
+	}
+
+	for _, tt := range tests {
+		result, err := Format(tt.args...)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.args, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%v: expected %q, got %q", tt.args, tt.expected, result)
+		}
+	}
+}
+
+func TestFormatTemplateErrors(t *testing.T) {
+	tests := []struct {
+		args     []interface{}
+		expected string
+	}{
+		{[]interface{}{"%d", 1.5}, "%d needs an integer for argument 1, got float"},
+		{[]interface{}{"%f", "x"}, "%f needs a number for argument 1, got string"},
+		{[]interface{}{"%s", []interface{}{1}}, "%s cannot format array (argument 1)"},
+		{[]interface{}{"%s", map[string]interface{}{}}, "%s cannot format map"},
+		{[]interface{}{"%v", 1}, "unsupported verb %v"},
+		{[]interface{}{"%T", 1}, "unsupported verb %T"},
+		{[]interface{}{"%*d", 5, 1}, "unsupported verb %*"},
+		{[]interface{}{"%d and %d", 1}, "%d has no matching argument"},
+		{[]interface{}{"%d", 1, 2}, "2 arguments given but the format uses 1"},
+		{[]interface{}{"50%"}, "incomplete verb"},
+		{[]interface{}{"%999999999999999999999d", 1}, "must not exceed 100"},
+		{[]interface{}{"%.200f", 1.0}, "must not exceed 100"},
+	}
+
+	for _, tt := range tests {
+		_, err := Format(tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package stringlib
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// Format converts a number to fixed-point text, bypassing the interpreter's
+// number format: format(x[, decimals]). Without decimals the shortest exact
+// representation is used, never in exponent form.
+//
+// When the first argument is a string it is a printf-style template instead:
+// format("hp: %d / %0.2f", hp, ratio). See sprintf for the supported verbs.
+func Format(args ...interface{}) (interface{}, error) {
+	if len(args) > 0 {
+		if template, ok := args[0].(string); ok {
+			result, err := sprintf(template, args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("format: %v", err)
+			}
+			return result, nil
+		}
+	}
+
+	if len(args) != 1 && len(args) != 2 {
+		return nil, fmt.Errorf("format requires 1 or 2 arguments")
+	}
+
+	if v, ok := args[0].(int); ok && len(args) == 1 {
+		return strconv.Itoa(v), nil
+	}
+
+	value, err := basic.EnsureFloat(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("format: value must be numeric: %v", err)
+	}
+
+	decimals := -1
+	if len(args) == 2 {
+		decimals, err = basic.EnsureInt(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("format: decimals must be numeric: %v", err)
+		}
+		if decimals < 0 {
+			return nil, fmt.Errorf("format: decimals must not be negative")
+		}
+	}
+
+	return strconv.FormatFloat(value, 'f', decimals, 64), nil
+}
+
+// Val parses a string holding an integer or a decimal number: val("42"),
+// val(" 2.5 "). Surrounding whitespace is ignored.
+func Val(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("val requires 1 argument")
+	}
+
+	s, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("val: %v", err)
+	}
+	text := strings.TrimSpace(s)
+
+	if n, err := strconv.Atoi(text); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("val: %q is not a number", s)
+	}
+	return f, nil
+}
+
+// Str returns a str builtin that converts a number or boolean to text using
+// toString, normally the interpreter's own conversion, so str(x) matches
+// what "" + x produces under the current number format
+func Str(toString func(value interface{}) string) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("str requires 1 argument")
+		}
+
+		switch args[0].(type) {
+		case int, float64, bool, string:
+			return toString(args[0]), nil
+		default:
+			return nil, fmt.Errorf("str: cannot convert %s to a string", basic.TypeName(args[0]))
+		}
+	}
+}
+
+// String positions count characters (runes), not bytes, and MID's start is
+// 1-based as in classic BASIC.
+
+// Len returns the number of characters in a string, or the number of
+// elements in an array or map: len(s), len(parts)
+func Len(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("len requires 1 argument")
+	}
+
+	switch v := args[0].(type) {
+	case []interface{}:
+		return len(v), nil
+	case map[string]interface{}:
+		return len(v), nil
+	}
+
+	s, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("len: %v", err)
+	}
+
+	return utf8.RuneCountInString(s), nil
+}
+
+// Mid returns up to length characters starting at the 1-based position start,
+// or the rest of the string when length is omitted: mid(s, start[, length])
+func Mid(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("mid requires 2 or 3 arguments")
+	}
+
+	s, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("mid: %v", err)
+	}
+	runes := []rune(s)
+
+	start, err := basic.EnsureInt(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("mid: start must be numeric: %v", err)
+	}
+	if start < 1 {
+		return nil, fmt.Errorf("mid: start must be at least 1, got %d", start)
+	}
+
+	length := len(runes)
+	if len(args) == 3 {
+		length, err = count("mid", "length", args[2])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if start > len(runes) {
+		return "", nil
+	}
+	end := min(start-1+length, len(runes))
+	return string(runes[start-1 : end]), nil
+}
+
+// Left returns the first n characters of a string, or all of it when it is
+// shorter: left(s, n)
+func Left(args ...interface{}) (interface{}, error) {
+	runes, n, err := stringAndCount("left", args)
+	if err != nil {
+		return nil, err
+	}
+	return string(runes[:n]), nil
+}
+
+// Right returns the last n characters of a string, or all of it when it is
+// shorter: right(s, n)
+func Right(args ...interface{}) (interface{}, error) {
+	runes, n, err := stringAndCount("right", args)
+	if err != nil {
+		return nil, err
+	}
+	return string(runes[len(runes)-n:]), nil
+}
+
+// Chr returns the one-character string for a Unicode code point: chr(65)
+func Chr(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("chr requires 1 argument")
+	}
+
+	code, err := basic.EnsureInt(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("chr: code must be numeric: %v", err)
+	}
+	if !utf8.ValidRune(rune(code)) || code != int(rune(code)) {
+		return nil, fmt.Errorf("chr: %d is not a valid character code", code)
+	}
+
+	return string(rune(code)), nil
+}
+
+// Asc returns the Unicode code point of the first character of a string:
+// asc("A")
+func Asc(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("asc requires 1 argument")
+	}
+
+	s, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("asc: %v", err)
+	}
+	if s == "" {
+		return nil, fmt.Errorf("asc: string is empty")
+	}
+
+	r, _ := utf8.DecodeRuneInString(s)
+	return int(r), nil
+}
+
+// InStr returns the 1-based position of the first occurrence of needle in
+// haystack at or after the 1-based position start, or 0 when it is absent:
+// instr(haystack, needle[, start])
+func InStr(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("instr requires 2 or 3 arguments")
+	}
+
+	haystack, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("instr: %v", err)
+	}
+	runes := []rune(haystack)
+
+	needle, err := basic.EnsureString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("instr: needle: %v", err)
+	}
+
+	start := 1
+	if len(args) == 3 {
+		start, err = basic.EnsureInt(args[2])
+		if err != nil {
+			return nil, fmt.Errorf("instr: start must be numeric: %v", err)
+		}
+		if start < 1 {
+			return nil, fmt.Errorf("instr: start must be at least 1, got %d", start)
+		}
+	}
+
+	if start > len(runes)+1 {
+		return 0, nil
+	}
+	rest := string(runes[start-1:])
+	idx := strings.Index(rest, needle)
+	if idx < 0 {
+		return 0, nil
+	}
+	return start + utf8.RuneCountInString(rest[:idx]), nil
+}
+
+// Replace returns a copy of s with occurrences of old replaced by new, all of
+// them or only the first count: replace(s, old, new[, count])
+func Replace(args ...interface{}) (interface{}, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return nil, fmt.Errorf("replace requires 3 or 4 arguments")
+	}
+
+	strs := make([]string, 3)
+	for idx, what := range []string{"string", "old", "new"} {
+		s, err := basic.EnsureString(args[idx])
+		if err != nil {
+			return nil, fmt.Errorf("replace: %s: %v", what, err)
+		}
+		strs[idx] = s
+	}
+	if strs[1] == "" {
+		return nil, fmt.Errorf("replace: old must not be empty")
+	}
+
+	n := -1
+	if len(args) == 4 {
+		var err error
+		n, err = count("replace", "count", args[3])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return strings.Replace(strs[0], strs[1], strs[2], n), nil
+}
+
+// Split breaks a string into an array of the pieces between separators:
+// split("a,b,c", ","). An empty separator splits between characters, and an
+// empty string gives an empty array.
+func Split(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("split requires 2 arguments")
+	}
+
+	s, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("split: %v", err)
+	}
+
+	sep, err := basic.EnsureString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("split: separator: %v", err)
+	}
+
+	result := []interface{}{}
+	if s == "" {
+		return result, nil
+	}
+	for _, part := range strings.Split(s, sep) {
+		result = append(result, part)
+	}
+	return result, nil
+}
+
+// Join concatenates the elements of an array with a separator between them:
+// join(parts, ","). Non-string elements are converted as PRINT shows them.
+func Join(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("join requires 2 arguments")
+	}
+
+	arr, err := basic.EnsureArray(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("join: %v", err)
+	}
+
+	sep, err := basic.EnsureString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("join: separator: %v", err)
+	}
+
+	parts := make([]string, len(arr))
+	for idx, elem := range arr {
+		parts[idx] = basic.ToString(elem)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// UCase converts a string to upper case: ucase(s)
+func UCase(args ...interface{}) (interface{}, error) {
+	return transform("ucase", args, strings.ToUpper)
+}
+
+// LCase converts a string to lower case: lcase(s)
+func LCase(args ...interface{}) (interface{}, error) {
+	return transform("lcase", args, strings.ToLower)
+}
+
+// Trim removes leading and trailing whitespace: trim(s)
+func Trim(args ...interface{}) (interface{}, error) {
+	return transform("trim", args, strings.TrimSpace)
+}
+
+// LTrim removes leading whitespace: ltrim(s)
+func LTrim(args ...interface{}) (interface{}, error) {
+	return transform("ltrim", args, func(s string) string {
+		return strings.TrimLeftFunc(s, unicode.IsSpace)
+	})
+}
+
+// RTrim removes trailing whitespace: rtrim(s)
+func RTrim(args ...interface{}) (interface{}, error) {
+	return transform("rtrim", args, func(s string) string {
+		return strings.TrimRightFunc(s, unicode.IsSpace)
+	})
+}
+
+// transform implements the builtins that map one string to another
+func transform(name string, args []interface{}, fn func(string) string) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s requires 1 argument", name)
+	}
+
+	s, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return fn(s), nil
+}
+
+// stringAndCount validates the (string, count) arguments of left and right,
+// clamping the count to the string's length
+func stringAndCount(name string, args []interface{}) ([]rune, int, error) {
+	if len(args) != 2 {
+		return nil, 0, fmt.Errorf("%s requires 2 arguments", name)
+	}
+
+	s, err := basic.EnsureString(args[0])
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %v", name, err)
+	}
+	runes := []rune(s)
+
+	n, err := count(name, "count", args[1])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return runes, min(n, len(runes)), nil
+}
+
+// count validates a non-negative character count
+func count(name, what string, arg interface{}) (int, error) {
+	n, err := basic.EnsureInt(arg)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s must be numeric: %v", name, what, err)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s: %s must not be negative", name, what)
+	}
+	return n, nil
+}
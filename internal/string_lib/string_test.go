@@ -0,0 +1,342 @@
+package stringlib
+
+import (
+	"fmt"
+	"testing"
+
+	basic "github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		args     []interface{}
+		expected string
+	}{
+		{[]interface{}{0.1 + 0.2, 2}, "0.30"},
+		{[]interface{}{3.14159, 0}, "3"},
+		{[]interface{}{7, 2}, "7.00"},
+		{[]interface{}{42}, "42"},
+		{[]interface{}{1e21}, "1000000000000000000000"},
+		{[]interface{}{0.00001}, "0.00001"},
+	}
+
+	for _, tt := range tests {
+		result, err := Format(tt.args...)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.args, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%v: expected %q, got %q", tt.args, tt.expected, result)
+		}
+	}
+
+	if _, err := Format(true); err == nil {
+		t.Error("expected error for non-numeric value")
+	}
+	if _, err := Format(1.5, -1); err == nil {
+		t.Error("expected error for negative decimals")
+	}
+	if _, err := Format(); err == nil {
+		t.Error("expected error for missing argument")
+	}
+}
+
+func TestLenMidLeftRight(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func(args ...interface{}) (interface{}, error)
+		args     []interface{}
+		expected interface{}
+	}{
+		{"len", Len, []interface{}{"hello"}, 5},
+		{"len unicode", Len, []interface{}{"héllo"}, 5},
+		{"len empty", Len, []interface{}{""}, 0},
+		{"len array", Len, []interface{}{[]interface{}{1, "a", 2.5}}, 3},
+		{"len map", Len, []interface{}{map[string]interface{}{"a": 1}}, 1},
+		{"mid", Mid, []interface{}{"hello world", 7, 3}, "wor"},
+		{"mid rest", Mid, []interface{}{"hello world", 7}, "world"},
+		{"mid unicode", Mid, []interface{}{"héllo", 2, 2}, "él"},
+		{"mid past end", Mid, []interface{}{"hello", 9, 2}, ""},
+		{"mid long", Mid, []interface{}{"hello", 4, 10}, "lo"},
+		{"left", Left, []interface{}{"hello", 2}, "he"},
+		{"left unicode", Left, []interface{}{"éa", 1}, "é"},
+		{"left long", Left, []interface{}{"hi", 5}, "hi"},
+		{"right", Right, []interface{}{"hello", 3}, "llo"},
+		{"right unicode", Right, []interface{}{"wörld", 4}, "örld"},
+		{"right zero", Right, []interface{}{"hello", 0}, ""},
+	}
+
+	for _, tt := range tests {
+		result, err := tt.fn(tt.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, result)
+		}
+	}
+
+	if _, err := Len(42); err == nil {
+		t.Error("expected error for non-string argument")
+	}
+	if _, err := Mid("hello", 0); err == nil {
+		t.Error("expected error for start below 1")
+	}
+	if _, err := Mid("hello", 1, -1); err == nil {
+		t.Error("expected error for negative length")
+	}
+	if _, err := Left("hello", -1); err == nil {
+		t.Error("expected error for negative count")
+	}
+	if _, err := Right("hello"); err == nil {
+		t.Error("expected error for missing count")
+	}
+}
+
+func TestCaseAndTrim(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func(args ...interface{}) (interface{}, error)
+		arg      string
+		expected string
+	}{
+		{"ucase", UCase, "Hello, Wörld", "HELLO, WÖRLD"},
+		{"lcase", LCase, "Hello, WÖRLD", "hello, wörld"},
+		{"trim", Trim, " \t hi there \n", "hi there"},
+		{"ltrim", LTrim, "  hi  ", "hi  "},
+		{"rtrim", RTrim, "  hi  ", "  hi"},
+		{"trim unicode space", Trim, "\u00a0hi\u3000", "hi"},
+	}
+
+	for _, tt := range tests {
+		result, err := tt.fn(tt.arg)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, result)
+		}
+	}
+
+	if _, err := UCase(1); err == nil {
+		t.Error("expected error for non-string argument")
+	}
+	if _, err := Trim("a", "b"); err == nil {
+		t.Error("expected error for extra argument")
+	}
+}
+
+func TestSplitJoin(t *testing.T) {
+	tests := []struct {
+		s, sep   string
+		expected string
+	}{
+		{"a,b,c", ",", "[a b c]"},
+		{"a,,c", ",", "[a  c]"},
+		{"one", ",", "[one]"},
+		{"", ",", "[]"},
+		{"héllo", "", "[h é l l o]"},
+		{"x => y => z", " => ", "[x y z]"},
+	}
+
+	for _, tt := range tests {
+		result, err := Split(tt.s, tt.sep)
+		if err != nil {
+			t.Errorf("split(%q, %q): unexpected error: %v", tt.s, tt.sep, err)
+			continue
+		}
+		if fmt.Sprint(result) != tt.expected {
+			t.Errorf("split(%q, %q): expected %s, got %v", tt.s, tt.sep, tt.expected, result)
+		}
+	}
+
+	joined, err := Join([]interface{}{"a", 1, 2.5, true}, ", ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if joined != "a, 1, 2.5, true" {
+		t.Errorf("expected \"a, 1, 2.5, true\", got %q", joined)
+	}
+
+	parts, _ := Split("x;y;z", ";")
+	if roundTrip, _ := Join(parts, ";"); roundTrip != "x;y;z" {
+		t.Errorf("expected round trip to x;y;z, got %q", roundTrip)
+	}
+
+	if _, err := Split(1, ","); err == nil {
+		t.Error("expected error for non-string argument")
+	}
+	if _, err := Join("abc", ","); err == nil {
+		t.Error("expected error for non-array argument")
+	}
+	if _, err := Join([]interface{}{"a"}); err == nil {
+		t.Error("expected error for missing separator")
+	}
+}
+
+func TestInStrAndReplace(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func(args ...interface{}) (interface{}, error)
+		args     []interface{}
+		expected interface{}
+	}{
+		{"instr", InStr, []interface{}{"hello world", "o"}, 5},
+		{"instr start", InStr, []interface{}{"hello world", "o", 6}, 8},
+		{"instr at start", InStr, []interface{}{"hello", "h", 1}, 1},
+		{"instr absent", InStr, []interface{}{"hello", "z"}, 0},
+		{"instr past end", InStr, []interface{}{"hello", "o", 9}, 0},
+		{"instr unicode", InStr, []interface{}{"héllo wörld", "wö"}, 7},
+		{"instr unicode start", InStr, []interface{}{"ééé", "é", 2}, 2},
+		{"instr empty needle", InStr, []interface{}{"abc", "", 3}, 3},
+		{"replace", Replace, []interface{}{"a-b-c", "-", "+"}, "a+b+c"},
+		{"replace count", Replace, []interface{}{"a-b-c", "-", "+", 1}, "a+b-c"},
+		{"replace none", Replace, []interface{}{"a-b-c", "-", "+", 0}, "a-b-c"},
+		{"replace unicode", Replace, []interface{}{"naïve café", "é", "e"}, "naïve cafe"},
+		{"replace delete", Replace, []interface{}{"ümlaut ü", "ü", ""}, "mlaut "},
+	}
+
+	for _, tt := range tests {
+		result, err := tt.fn(tt.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, result)
+		}
+	}
+
+	if _, err := InStr("abc", "a", 0); err == nil {
+		t.Error("expected error for start below 1")
+	}
+	if _, err := InStr("abc", 1); err == nil {
+		t.Error("expected error for non-string needle")
+	}
+	if _, err := Replace("abc", "", "x"); err == nil {
+		t.Error("expected error for empty old")
+	}
+	if _, err := Replace("abc", "a", "x", -1); err == nil {
+		t.Error("expected error for negative count")
+	}
+	if _, err := Replace("abc", "a"); err == nil {
+		t.Error("expected error for missing argument")
+	}
+}
+
+func TestChrAsc(t *testing.T) {
+	tests := []struct {
+		code int
+		char string
+	}{
+		{65, "A"},
+		{10, "\n"},
+		{233, "é"},
+		{0x1F600, "😀"},
+	}
+
+	for _, tt := range tests {
+		char, err := Chr(tt.code)
+		if err != nil {
+			t.Errorf("chr(%d): unexpected error: %v", tt.code, err)
+			continue
+		}
+		if char != tt.char {
+			t.Errorf("chr(%d): expected %q, got %q", tt.code, tt.char, char)
+		}
+
+		code, err := Asc(tt.char + "xyz")
+		if err != nil {
+			t.Errorf("asc(%q): unexpected error: %v", tt.char, err)
+			continue
+		}
+		if code != tt.code {
+			t.Errorf("asc(%q): expected %d, got %v", tt.char, tt.code, code)
+		}
+	}
+
+	for _, code := range []int{-1, 0xD800, 0x110000, 1 << 40} {
+		if _, err := Chr(code); err == nil {
+			t.Errorf("chr(%d): expected error for invalid code", code)
+		}
+	}
+	if _, err := Asc(""); err == nil {
+		t.Error("expected error for empty string")
+	}
+	if _, err := Asc(65); err == nil {
+		t.Error("expected error for non-string argument")
+	}
+}
+
+func TestVal(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected interface{}
+	}{
+		{"42", 42},
+		{"-7", -7},
+		{" 12 ", 12},
+		{"2.5", 2.5},
+		{"1e3", 1000.0},
+		{"-.5", -0.5},
+	}
+
+	for _, tt := range tests {
+		result, err := Val(tt.arg)
+		if err != nil {
+			t.Errorf("val(%q): unexpected error: %v", tt.arg, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("val(%q): expected %v (%T), got %v (%T)", tt.arg, tt.expected, tt.expected, result, result)
+		}
+	}
+
+	for _, bad := range []string{"", "abc", "12abc", "1,000", "NaN", "inf", "1e400"} {
+		if _, err := Val(bad); err == nil {
+			t.Errorf("val(%q): expected error", bad)
+		}
+	}
+	if _, err := Val(42); err == nil {
+		t.Error("expected error for non-string argument")
+	}
+}
+
+func TestStr(t *testing.T) {
+	str := Str(basic.ToString)
+	tests := []struct {
+		arg      interface{}
+		expected string
+	}{
+		{42, "42"},
+		{2.5, "2.5"},
+		{true, "true"},
+		{"hi", "hi"},
+	}
+
+	for _, tt := range tests {
+		result, err := str(tt.arg)
+		if err != nil {
+			t.Errorf("str(%v): unexpected error: %v", tt.arg, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("str(%v): expected %q, got %q", tt.arg, tt.expected, result)
+		}
+	}
+
+	fixed := Str(func(value interface{}) string { return "formatted" })
+	if result, _ := fixed(1.5); result != "formatted" {
+		t.Errorf("expected the given conversion to be used, got %q", result)
+	}
+
+	if _, err := str([]interface{}{1}); err == nil {
+		t.Error("expected error for array argument")
+	}
+	if _, err := str(); err == nil {
+		t.Error("expected error for missing argument")
+	}
+}
@@ -0,0 +1,165 @@
+// Package ast parses MechanicalBasic scripts into syntax trees, for tools
+// such as linters, formatters and editor plugins that work on scripts without
+// running them.
+//
+// The node and token types are the ones the interpreter runs, so a tree is
+// exactly what a script means. Within a major version of the module, node
+// types, their fields and the token constants are not removed or renamed,
+// and positions keep their meaning; new node types and fields may be added.
+// The interfaces can't be implemented outside the module, so a type switch
+// over nodes should have a default case.
+package ast
+
+import (
+	"io/fs"
+
+	"github.com/mechanical-lich/mechanical-basic/internal/basic"
+)
+
+// ParseError is the error returned when a script doesn't tokenize or parse.
+// It is the same type as ParseError in pkg/basic.
+type ParseError = basic.ParseError
+
+// Tokenize splits a script into tokens, ending with TOKEN_EOF. Comments are
+// dropped, except that ## doc comments are attached to the next token.
+func Tokenize(code string) ([]Token, error) {
+	return basic.Tokenize(code)
+}
+
+// Comment is a # or REM comment of a script. Text is the whole comment,
+// starting with the # or REM; Line and Column locate its first character.
+type Comment struct {
+	Text   string
+	Line   int
+	Column int
+}
+
+// Comments returns the comments of a script in source order, which Tokenize
+// and Parse leave out
+func Comments(code string) ([]Comment, error) {
+	var comments []Comment
+	t := basic.NewTokenizer(code)
+	for {
+		tok, err := t.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.Type {
+		case TOKEN_EOF:
+			return comments, nil
+		case TOKEN_COMMENT:
+			comments = append(comments, Comment{Text: tok.Value, Line: tok.Line, Column: tok.Column})
+		}
+	}
+}
+
+// Parse parses a script. INCLUDE statements are left in the tree; use
+// ParseFS to replace them with the files they name. A script that doesn't
+// parse fails with a *ParseError.
+func Parse(code string) (*Program, error) {
+	tokens, err := basic.Tokenize(code)
+	if err != nil {
+		return nil, err
+	}
+	return basic.Parse(tokens)
+}
+
+// ParseFS parses a script as the interpreter does before running it: each
+// INCLUDE is replaced by the statements of the file it names, read from fsys,
+// and the program is checked for errors such as GOTO to an undefined label.
+// Nodes from included files name the file in their SourceFile. fsys may be
+// nil for a script that includes nothing.
+func ParseFS(fsys fs.FS, code string) (*Program, error) {
+	interp := basic.NewInterpreter()
+	interp.SetSourceFS(fsys)
+	return interp.Compile(code)
+}
+
+// Inspect walks the tree under node, parents before their children, calling
+// visit for each node. Children are skipped when visit returns false. Nil
+// nodes, such as the value of a bare RETURN, aren't visited.
+func Inspect(node Node, visit func(Node) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+	block := func(stmts []Statement) {
+		for _, stmt := range stmts {
+			Inspect(stmt, visit)
+		}
+	}
+	exprs := func(list ...Expression) {
+		for _, expr := range list {
+			Inspect(expr, visit)
+		}
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		block(n.Statements)
+	case *LetStatement:
+		exprs(n.Value)
+	case *LocalStatement:
+		exprs(n.Value)
+	case *ConstStatement:
+		exprs(n.Value)
+	case *DimStatement:
+		exprs(n.Sizes...)
+	case *AssignStatement:
+		exprs(n.Indices...)
+		exprs(n.Value)
+	case *MultiAssignStatement:
+		for _, target := range n.Targets {
+			Inspect(target, visit)
+		}
+		exprs(n.Values...)
+	case *IfStatement:
+		exprs(n.Condition)
+		block(n.ThenBlock)
+		for _, clause := range n.ElseIfClauses {
+			exprs(clause.Condition)
+			block(clause.Block)
+		}
+		block(n.ElseBlock)
+	case *ForStatement:
+		exprs(n.Start, n.End)
+		block(n.Body)
+	case *DoLoopStatement:
+		exprs(n.Condition)
+		block(n.Body)
+	case *TryStatement:
+		block(n.Body)
+		block(n.Handler)
+	case *FunctionStatement:
+		exprs(n.Defaults...)
+		block(n.Body)
+	case *ThrowStatement:
+		exprs(n.Message)
+	case *ReturnStatement:
+		exprs(n.Value)
+	case *PrintStatement:
+		exprs(n.Using)
+		exprs(n.Values...)
+	case *ExpressionStatement:
+		exprs(n.Expr)
+
+	case *BinaryExpr:
+		exprs(n.Left, n.Right)
+	case *UnaryExpr:
+		exprs(n.Operand)
+	case *CallExpr:
+		exprs(n.Args...)
+		for _, arg := range n.Named {
+			exprs(arg.Value)
+		}
+	case *ConditionalExpr:
+		exprs(n.Condition, n.Then, n.Else)
+	case *IndexExpr:
+		exprs(n.Indices...)
+	case *SliceExpr:
+		exprs(n.Target, n.Start, n.End)
+	case *MemberExpr:
+		exprs(n.Target)
+	case *YieldExpr:
+		exprs(n.Value)
+	}
+}
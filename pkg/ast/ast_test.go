@@ -0,0 +1,129 @@
+package ast
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize("let x = 1 # set x\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []TokenType{TOKEN_LET, TOKEN_IDENTIFIER, TOKEN_EQ, TOKEN_INT, TOKEN_NEWLINE, TOKEN_EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %v", len(expected), tokens)
+	}
+	for idx, tok := range tokens {
+		if tok.Type != expected[idx] {
+			t.Errorf("token %d: expected %s, got %s", idx, expected[idx], tok.Type)
+		}
+	}
+	if tokens[3].Value != "1" || tokens[3].Line != 1 || tokens[3].Column != 9 {
+		t.Errorf("unexpected token %+v", tokens[3])
+	}
+}
+
+func TestComments(t *testing.T) {
+	comments, err := Comments("## Heals.\nlet x = 1 # set x\nREM old style\nprint \"# not a comment\"\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Comment{
+		{Text: "## Heals.", Line: 1, Column: 1},
+		{Text: "# set x", Line: 2, Column: 11},
+		{Text: "REM old style", Line: 3, Column: 1},
+	}
+	if len(comments) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, comments)
+	}
+	for idx, comment := range comments {
+		if comment != expected[idx] {
+			t.Errorf("comment %d: expected %+v, got %+v", idx, expected[idx], comment)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	prog, err := Parse("include \"lib.bas\"\nif hp < 10 then\n    heal(hp, 5)\nendif\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prog.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(prog.Statements))
+	}
+	if inc, ok := prog.Statements[0].(*IncludeStatement); !ok || inc.Path != "lib.bas" {
+		t.Errorf("expected the INCLUDE to be kept, got %#v", prog.Statements[0])
+	}
+
+	stmt, ok := prog.Statements[1].(*IfStatement)
+	if !ok {
+		t.Fatalf("expected an IF statement, got %T", prog.Statements[1])
+	}
+	cond, ok := stmt.Condition.(*BinaryExpr)
+	if !ok || cond.Operator != TOKEN_LT {
+		t.Fatalf("expected hp < 10, got %#v", stmt.Condition)
+	}
+	call := stmt.ThenBlock[0].(*ExpressionStatement).Expr.(*CallExpr)
+	if line, col := call.Position(); call.Name != "heal" || len(call.Args) != 2 || line != 3 || col != 5 {
+		t.Errorf("unexpected call %s with %d arguments at %d:%d", call.Name, len(call.Args), line, col)
+	}
+
+	_, err = Parse("if x then\n")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{"lib.bas": {Data: []byte("function heal(hp, amount):\n    return hp + amount\nendfunction\n")}}
+	prog, err := ParseFS(fsys, "include \"lib.bas\"\nprint heal(1, 2)\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fn, ok := prog.Statements[0].(*FunctionStatement)
+	if !ok || fn.Name != "heal" || fn.SourceFile() != "lib.bas" {
+		t.Fatalf("expected heal from lib.bas, got %#v", prog.Statements[0])
+	}
+
+	if _, err := ParseFS(nil, "include \"lib.bas\"\n"); err == nil {
+		t.Error("expected an error including without a file system")
+	}
+	if _, err := ParseFS(nil, "goto nowhere\n"); err == nil {
+		t.Error("expected an error for GOTO to an undefined label")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	prog, err := Parse(`function f(x):
+    return x * 2
+endfunction
+for i = 1 to 3
+    print f(i) + 1
+next i
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var calls, idents []string
+	Inspect(prog, func(node Node) bool {
+		switch n := node.(type) {
+		case *FunctionStatement:
+			return false // Skip the body
+		case *CallExpr:
+			calls = append(calls, n.Name)
+		case *Identifier:
+			idents = append(idents, n.Name)
+		}
+		return true
+	})
+	if len(calls) != 1 || calls[0] != "f" {
+		t.Errorf("expected a call to f, got %v", calls)
+	}
+	if len(idents) != 1 || idents[0] != "i" {
+		t.Errorf("expected only i outside f, got %v", idents)
+	}
+}
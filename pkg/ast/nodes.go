@@ -0,0 +1,163 @@
+package ast
+
+import "github.com/mechanical-lich/mechanical-basic/internal/basic"
+
+type (
+	// Node is implemented by every statement and expression. Position
+	// returns the line and column the node starts at, and SourceFile the
+	// included file it comes from, "" for the script itself.
+	Node = basic.Node
+
+	// Statement is a node that runs, such as LET or IF
+	Statement = basic.Statement
+
+	// Expression is a node that produces a value
+	Expression = basic.Expression
+
+	// Pos is the position embedded in every node
+	Pos = basic.Pos
+
+	// Program is a parsed script: its top-level statements in source order
+	Program = basic.Program
+)
+
+// Statements
+
+type (
+	// LetStatement represents: LET x = expr
+	LetStatement = basic.LetStatement
+
+	// AssignStatement represents: x = expr, x += 1, x++, x--
+	AssignStatement = basic.AssignStatement
+
+	// MultiAssignStatement represents: [LET] a, b = expr, expr
+	// Every value is evaluated before the targets are assigned, so a, b = b, a
+	// swaps. A single array value is unpacked across the targets.
+	MultiAssignStatement = basic.MultiAssignStatement
+
+	// ConstStatement represents: CONST NAME = expr
+	ConstStatement = basic.ConstStatement
+
+	// GlobalStatement represents: GLOBAL name[, name...]
+	GlobalStatement = basic.GlobalStatement
+
+	// LocalStatement represents: LOCAL x = expr
+	LocalStatement = basic.LocalStatement
+
+	// TypeStatement represents: TYPE name, its field names one per line, ENDTYPE
+	TypeStatement = basic.TypeStatement
+
+	// IncludeStatement represents: INCLUDE "file.bas"
+	IncludeStatement = basic.IncludeStatement
+
+	// OptionStatement represents: OPTION EXPLICIT
+	OptionStatement = basic.OptionStatement
+
+	// ImportStatement represents: IMPORT "module"
+	ImportStatement = basic.ImportStatement
+
+	// DimStatement represents: DIM name(size[, size...])
+	DimStatement = basic.DimStatement
+
+	// IfStatement represents: IF cond THEN ... [ELSEIF cond THEN ...] [ELSE ...] ENDIF
+	IfStatement = basic.IfStatement
+
+	// ElseIfClause represents a single ELSEIF branch
+	ElseIfClause = basic.ElseIfClause
+
+	// ForStatement represents: FOR i = start TO end ... NEXT i
+	ForStatement = basic.ForStatement
+
+	// DoLoopStatement represents DO ... LOOP with an optional WHILE or UNTIL
+	// condition, tested either after DO (before each pass) or after LOOP (after
+	// each pass, so the body always runs at least once)
+	DoLoopStatement = basic.DoLoopStatement
+
+	// BreakStatement represents: BREAK
+	BreakStatement = basic.BreakStatement
+
+	// ExitStatement represents: EXIT FOR [var], EXIT DO, EXIT WHILE, EXIT FUNCTION
+	// or EXIT SUB
+	ExitStatement = basic.ExitStatement
+
+	// TryStatement represents: TRY ... CATCH [var] ... ENDTRY
+	TryStatement = basic.TryStatement
+
+	// OnErrorStatement represents: ON ERROR CALL handler
+	OnErrorStatement = basic.OnErrorStatement
+
+	// ThrowStatement represents: THROW message
+	ThrowStatement = basic.ThrowStatement
+
+	// LabelStatement marks a GOTO target: name:
+	LabelStatement = basic.LabelStatement
+
+	// GotoStatement represents: GOTO label
+	GotoStatement = basic.GotoStatement
+
+	// FunctionStatement represents: FUNCTION name(params): ... ENDFUNCTION
+	// or SUB name(params): ... ENDSUB
+	FunctionStatement = basic.FunctionStatement
+
+	// ReturnStatement represents: RETURN expr
+	ReturnStatement = basic.ReturnStatement
+
+	// PrintStatement represents: PRINT [USING format;] expr[, expr...][;]
+	PrintStatement = basic.PrintStatement
+
+	// ExpressionStatement wraps an expression used as a statement (e.g., function call)
+	ExpressionStatement = basic.ExpressionStatement
+)
+
+// Expressions
+
+type (
+	// IntLiteral represents an integer literal: 42
+	IntLiteral = basic.IntLiteral
+
+	// FloatLiteral represents a float literal: 3.14
+	FloatLiteral = basic.FloatLiteral
+
+	// StringLiteral represents a string literal: "hello"
+	StringLiteral = basic.StringLiteral
+
+	// BoolLiteral represents: true, false
+	BoolLiteral = basic.BoolLiteral
+
+	// NullLiteral represents: null (or nil)
+	NullLiteral = basic.NullLiteral
+
+	// Identifier represents a variable reference: x, foo
+	Identifier = basic.Identifier
+
+	// BinaryExpr represents: left op right (e.g., x + y, a < b, x AND y)
+	BinaryExpr = basic.BinaryExpr
+
+	// UnaryExpr represents: op expr (e.g., NOT x, -5)
+	UnaryExpr = basic.UnaryExpr
+
+	// CallExpr represents a function call: pow(2, 3), getX()
+	CallExpr = basic.CallExpr
+
+	// NamedArg is a call argument passed by parameter name: name = expr
+	NamedArg = basic.NamedArg
+
+	// ConditionalExpr represents iif(cond, a, b). Only the chosen branch is
+	// evaluated, so it is parsed as an expression rather than called as a function.
+	ConditionalExpr = basic.ConditionalExpr
+
+	// IndexExpr represents an element of an array declared with DIM: a(i), grid(x, y)
+	IndexExpr = basic.IndexExpr
+
+	// SliceExpr represents a character or substring of a string: s[i], s[a:b].
+	// Start and End are nil when omitted from a slice (s[:b], s[a:]).
+	SliceExpr = basic.SliceExpr
+
+	// MemberExpr represents a field of a record: v.x
+	MemberExpr = basic.MemberExpr
+
+	// YieldExpr represents: YIELD [expr]. It pauses the coroutine running it,
+	// handing the value to the host, and evaluates to the value the host
+	// resumes it with.
+	YieldExpr = basic.YieldExpr
+)
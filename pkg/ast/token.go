@@ -0,0 +1,103 @@
+package ast
+
+import "github.com/mechanical-lich/mechanical-basic/internal/basic"
+
+// Token is a lexical token: its type, its text and where it starts. Doc
+// holds the ## doc comment block on the lines directly above, if any.
+type Token = basic.Token
+
+// TokenType identifies the kind of a token. Its String method returns the
+// name of the constant without TOKEN_, e.g. "LET" or "PLUS_EQ".
+type TokenType = basic.TokenType
+
+// The token types. Operator fields of the AST hold one of the operator types.
+const (
+	// Special tokens
+	TOKEN_EOF     = basic.TOKEN_EOF
+	TOKEN_NEWLINE = basic.TOKEN_NEWLINE
+	TOKEN_COMMENT = basic.TOKEN_COMMENT
+
+	// Literals
+	TOKEN_IDENTIFIER = basic.TOKEN_IDENTIFIER
+	TOKEN_INT        = basic.TOKEN_INT
+	TOKEN_FLOAT      = basic.TOKEN_FLOAT
+	TOKEN_STRING     = basic.TOKEN_STRING
+	TOKEN_TRUE       = basic.TOKEN_TRUE
+	TOKEN_FALSE      = basic.TOKEN_FALSE
+	TOKEN_NULL       = basic.TOKEN_NULL
+
+	// Keywords
+	TOKEN_LET         = basic.TOKEN_LET
+	TOKEN_DIM         = basic.TOKEN_DIM
+	TOKEN_CONST       = basic.TOKEN_CONST
+	TOKEN_GLOBAL      = basic.TOKEN_GLOBAL
+	TOKEN_LOCAL       = basic.TOKEN_LOCAL
+	TOKEN_IF          = basic.TOKEN_IF
+	TOKEN_THEN        = basic.TOKEN_THEN
+	TOKEN_ELSE        = basic.TOKEN_ELSE
+	TOKEN_ELSEIF      = basic.TOKEN_ELSEIF
+	TOKEN_ENDIF       = basic.TOKEN_ENDIF
+	TOKEN_FOR         = basic.TOKEN_FOR
+	TOKEN_TO          = basic.TOKEN_TO
+	TOKEN_NEXT        = basic.TOKEN_NEXT
+	TOKEN_DO          = basic.TOKEN_DO
+	TOKEN_LOOP        = basic.TOKEN_LOOP
+	TOKEN_WHILE       = basic.TOKEN_WHILE
+	TOKEN_UNTIL       = basic.TOKEN_UNTIL
+	TOKEN_BREAK       = basic.TOKEN_BREAK
+	TOKEN_EXIT        = basic.TOKEN_EXIT
+	TOKEN_GOTO        = basic.TOKEN_GOTO
+	TOKEN_TRY         = basic.TOKEN_TRY
+	TOKEN_CATCH       = basic.TOKEN_CATCH
+	TOKEN_ENDTRY      = basic.TOKEN_ENDTRY
+	TOKEN_ON          = basic.TOKEN_ON
+	TOKEN_THROW       = basic.TOKEN_THROW
+	TOKEN_IMPORT      = basic.TOKEN_IMPORT
+	TOKEN_INCLUDE     = basic.TOKEN_INCLUDE
+	TOKEN_OPTION      = basic.TOKEN_OPTION
+	TOKEN_FUNCTION    = basic.TOKEN_FUNCTION
+	TOKEN_ENDFUNCTION = basic.TOKEN_ENDFUNCTION
+	TOKEN_SUB         = basic.TOKEN_SUB
+	TOKEN_ENDSUB      = basic.TOKEN_ENDSUB
+	TOKEN_TYPE        = basic.TOKEN_TYPE
+	TOKEN_ENDTYPE     = basic.TOKEN_ENDTYPE
+	TOKEN_AS          = basic.TOKEN_AS
+	TOKEN_RETURN      = basic.TOKEN_RETURN
+	TOKEN_YIELD       = basic.TOKEN_YIELD
+	TOKEN_PRINT       = basic.TOKEN_PRINT
+	TOKEN_USING       = basic.TOKEN_USING
+	TOKEN_AND         = basic.TOKEN_AND
+	TOKEN_OR          = basic.TOKEN_OR
+	TOKEN_NOT         = basic.TOKEN_NOT
+	TOKEN_XOR         = basic.TOKEN_XOR
+
+	// Operators
+	TOKEN_PLUS        = basic.TOKEN_PLUS        // +
+	TOKEN_MINUS       = basic.TOKEN_MINUS       // -
+	TOKEN_STAR        = basic.TOKEN_STAR        // *
+	TOKEN_SLASH       = basic.TOKEN_SLASH       // /
+	TOKEN_EQ          = basic.TOKEN_EQ          // =
+	TOKEN_NEQ         = basic.TOKEN_NEQ         // <> or !=
+	TOKEN_LT          = basic.TOKEN_LT          // <
+	TOKEN_GT          = basic.TOKEN_GT          // >
+	TOKEN_LTE         = basic.TOKEN_LTE         // <=
+	TOKEN_GTE         = basic.TOKEN_GTE         // >=
+	TOKEN_PLUS_EQ     = basic.TOKEN_PLUS_EQ     // +=
+	TOKEN_MINUS_EQ    = basic.TOKEN_MINUS_EQ    // -=
+	TOKEN_PLUS_PLUS   = basic.TOKEN_PLUS_PLUS   // ++
+	TOKEN_MINUS_MINUS = basic.TOKEN_MINUS_MINUS // --
+	TOKEN_AMP         = basic.TOKEN_AMP         // &
+	TOKEN_PIPE        = basic.TOKEN_PIPE        // |
+	TOKEN_SHL         = basic.TOKEN_SHL         // <<
+	TOKEN_SHR         = basic.TOKEN_SHR         // >>
+
+	// Delimiters
+	TOKEN_LPAREN    = basic.TOKEN_LPAREN    // (
+	TOKEN_RPAREN    = basic.TOKEN_RPAREN    // )
+	TOKEN_LBRACKET  = basic.TOKEN_LBRACKET  // [
+	TOKEN_RBRACKET  = basic.TOKEN_RBRACKET  // ]
+	TOKEN_COMMA     = basic.TOKEN_COMMA     // ,
+	TOKEN_COLON     = basic.TOKEN_COLON     // :
+	TOKEN_SEMICOLON = basic.TOKEN_SEMICOLON // ;
+	TOKEN_DOT       = basic.TOKEN_DOT       // .
+)
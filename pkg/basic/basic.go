@@ -1,50 +1,723 @@
 package basic
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	arraylib "github.com/mechanical-lich/mechanical-basic/internal/array_lib"
+	assertlib "github.com/mechanical-lich/mechanical-basic/internal/assert_lib"
 	"github.com/mechanical-lich/mechanical-basic/internal/basic"
+	bitlib "github.com/mechanical-lich/mechanical-basic/internal/bit_lib"
+	bufferlib "github.com/mechanical-lich/mechanical-basic/internal/buffer_lib"
+	geometrylib "github.com/mechanical-lich/mechanical-basic/internal/geometry_lib"
+	localelib "github.com/mechanical-lich/mechanical-basic/internal/locale_lib"
+	maplib "github.com/mechanical-lich/mechanical-basic/internal/map_lib"
 	mathlib "github.com/mechanical-lich/mechanical-basic/internal/math_lib"
+	matrixlib "github.com/mechanical-lich/mechanical-basic/internal/matrix_lib"
+	pathlib "github.com/mechanical-lich/mechanical-basic/internal/path_lib"
+	randomlib "github.com/mechanical-lich/mechanical-basic/internal/random_lib"
+	statslib "github.com/mechanical-lich/mechanical-basic/internal/stats_lib"
+	stringlib "github.com/mechanical-lich/mechanical-basic/internal/string_lib"
+	"github.com/mechanical-lich/mechanical-basic/pkg/functions"
+)
+
+// BenchmarkResult holds the average time per run of a benchmarked script
+type BenchmarkResult = basic.BenchmarkResult
+
+// CallOptions overrides the instance's limits for one CallWithOptions call
+type CallOptions = basic.CallOptions
+
+// Profile holds the lines executed while profiling, sorted by file and line
+type Profile = basic.Profile
+
+// LineProfile is what the profiler recorded for one source line
+type LineProfile = basic.LineProfile
+
+// ErrFunctionRegistered is returned, wrapped with the names, when strict
+// registration is on and a function is registered under a name already taken
+var ErrFunctionRegistered = basic.ErrFunctionRegistered
+
+// ErrInterrupted is returned, wrapped with the position reached, by a run
+// cancelled with Stop
+var ErrInterrupted = basic.ErrInterrupted
+
+// ErrorKind classifies a ParseError or RuntimeError
+type ErrorKind = basic.ErrorKind
+
+const (
+	ErrorSyntax      = basic.ErrorSyntax
+	ErrorCheck       = basic.ErrorCheck
+	ErrorInclude     = basic.ErrorInclude
+	ErrorRuntime     = basic.ErrorRuntime
+	ErrorHost        = basic.ErrorHost
+	ErrorThrow       = basic.ErrorThrow
+	ErrorBudget      = basic.ErrorBudget
+	ErrorMemory      = basic.ErrorMemory
+	ErrorInterrupted = basic.ErrorInterrupted
+)
+
+// ParseError is returned by Run, Eval, Load and Compile for a
+// script rejected before it runs, with the position of the problem
+type ParseError = basic.ParseError
+
+// RuntimeError is returned by Run, Eval and Call for a script that fails
+// while running, with the position reached; it wraps the cause
+type RuntimeError = basic.RuntimeError
+
+// ScriptError is the error raised by a script's THROW statement. Run, Eval
+// and Call return it wrapped with its position; use errors.As to detect it.
+type ScriptError = basic.ScriptError
+
+// BudgetExceededError is returned, wrapped with the position reached, by a
+// run that executes more statements than SetMaxStatements allows
+type BudgetExceededError = basic.BudgetExceededError
+
+// ErrMemoryLimit is returned, wrapped with the position of the assignment,
+// by a run that would store more than SetMemoryLimits allows
+var ErrMemoryLimit = basic.ErrMemoryLimit
+
+// MemoryLimits caps the variables, the size of each string and collection,
+// and the total string bytes and elements a script may hold. A zero field
+// means no limit.
+type MemoryLimits = basic.MemoryLimits
+
+// StateError is returned by MarshalState for a global variable that can't be
+// encoded as JSON; use errors.As to find which one
+type StateError = basic.StateError
+
+// Record is a value of a script's TYPE. Scripts and external functions share
+// records by reference; read fields with Field.
+type Record = basic.Record
+
+// CacheEntry describes a parsed program held in the AST cache
+type CacheEntry = basic.CacheEntry
+
+// ExternalFunc is the signature of a host function callable from scripts
+type ExternalFunc = basic.ExternalFunc
+
+// FunctionInfo documents a registered host function
+type FunctionInfo = basic.FunctionInfo
+
+// NumberFormat controls how floats are printed and concatenated into strings
+type NumberFormat = basic.NumberFormat
+
+// OverflowMode selects how integer overflow is handled
+type OverflowMode = basic.OverflowMode
+
+const (
+	OverflowWrap    = basic.OverflowWrap
+	OverflowError   = basic.OverflowError
+	OverflowPromote = basic.OverflowPromote
+)
+
+// ScopeMode selects where assignments inside functions store variables
+type ScopeMode = basic.ScopeMode
+
+const (
+	ScopeDynamic = basic.ScopeDynamic
+	ScopeLocal   = basic.ScopeLocal
+)
+
+// DebugFrame describes the statement about to execute when a debug hook runs
+type DebugFrame = basic.DebugFrame
+
+// FunctionDoc describes a script function, including its ## doc comment
+type FunctionDoc = basic.FunctionDoc
+
+// Warning is a non-fatal diagnostic about a script
+type Warning = basic.Warning
+
+// Diagnostic is an error or warning found by ValidateAll
+type Diagnostic = basic.Diagnostic
+
+// Severity says whether a Diagnostic stops the script from running
+type Severity = basic.Severity
+
+const (
+	SeverityError   = basic.SeverityError
+	SeverityWarning = basic.SeverityWarning
+)
+
+// ResultPolicy selects the numeric types returned to the host
+type ResultPolicy = basic.ResultPolicy
+
+const (
+	ResultNative  = basic.ResultNative
+	ResultInt64   = basic.ResultInt64
+	ResultFloat64 = basic.ResultFloat64
 )
 
+// NamedArgPolicy controls how CallNamed handles extra and missing arguments
+type NamedArgPolicy = basic.NamedArgPolicy
+
+// ModuleResolver returns the source code of the module a script imports
+// with IMPORT "name"
+type ModuleResolver = basic.ModuleResolver
+
+// FSResolver returns a ModuleResolver that reads IMPORT "name" from the file
+// name.bas in fsys, such as os.DirFS("scripts") or an embed.FS
+func FSResolver(fsys fs.FS) ModuleResolver {
+	return func(name string) (string, error) {
+		source, err := fs.ReadFile(fsys, name+".bas")
+		if err != nil {
+			return "", err
+		}
+		return string(source), nil
+	}
+}
+
 type MechBasic struct {
 	interpreter *basic.Interpreter
+	strings     *localelib.StringTable
+	concurrent  atomic.Bool   // Set by SetConcurrent
+	turn        chan struct{} // Held by the goroutine using the instance in concurrent mode
 }
 
-func NewMechanicalBasic() *MechBasic {
+// NewMechanicalBasic creates an instance with every built-in library
+// registered. Pass WithLibraries or WithoutLibraries to choose which.
+func NewMechanicalBasic(opts ...Option) *MechBasic {
 	mb := &MechBasic{
 		interpreter: basic.NewInterpreter(),
+		strings:     localelib.NewStringTable(),
+		turn:        make(chan struct{}, 1),
 	}
 
-	// Register built-in math functions
-	mb.RegisterMathLibrary()
+	cfg := &options{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	for _, builtin := range builtinLibraries {
+		if cfg.registers(builtin.lib) {
+			builtin.register(mb)
+		}
+	}
 
 	return mb
 }
 
-func (mb *MechBasic) RegisterFunc(name string, function func(args ...any) (any, error)) {
-	mb.interpreter.RegisterFunction(name, function)
+// FuncOption adds documentation to a function being registered
+type FuncOption func(*FunctionInfo)
+
+// WithSignature documents how to call the function, e.g. "pow(x, y)"
+func WithSignature(signature string) FuncOption {
+	return func(info *FunctionInfo) {
+		info.Signature = signature
+	}
+}
+
+// WithDescription documents what the function does
+func WithDescription(description string) FuncOption {
+	return func(info *FunctionInfo) {
+		info.Description = description
+	}
+}
+
+// RegisterFunc registers a host function that scripts can call by name,
+// replacing any function registered under the name before. After
+// SetStrictRegistration(true) it returns ErrFunctionRegistered instead.
+// Options document the function for ListFunctions and help().
+func (mb *MechBasic) RegisterFunc(name string, function func(args ...any) (any, error), opts ...FuncOption) error {
+	defer mb.lock()()
+	if err := mb.interpreter.RegisterFunction(name, function); err != nil {
+		return err
+	}
+	if len(opts) > 0 {
+		info := FunctionInfo{}
+		for _, opt := range opts {
+			opt(&info)
+		}
+		mb.interpreter.DocumentFunction(name, info.Signature, info.Description)
+	}
+	return nil
+}
+
+// RegisterConstant defines a read-only value, such as RegisterConstant("PI",
+// math.Pi), that scripts see in every scope but can't assign. It stays
+// defined when another script is run.
+func (mb *MechBasic) RegisterConstant(name string, value any) error {
+	defer mb.lock()()
+	return mb.interpreter.RegisterConstant(name, value)
+}
+
+// ListFunctions lists the registered host functions, including the built-in
+// library functions, sorted by name with any documentation they were
+// registered with
+func (mb *MechBasic) ListFunctions() []FunctionInfo {
+	defer mb.lock()()
+	return mb.interpreter.ListFunctions()
+}
+
+// RegisterNamespace registers host functions that scripts call qualified by
+// the namespace, e.g. game.spawn(x), so names can't collide across libraries
+func (mb *MechBasic) RegisterNamespace(namespace string, funcs map[string]ExternalFunc) error {
+	defer mb.lock()()
+	return mb.interpreter.RegisterNamespace(namespace, funcs)
+}
+
+// UnregisterFunc removes a registered host function, returning false if
+// none is registered under the name
+func (mb *MechBasic) UnregisterFunc(name string) bool {
+	defer mb.lock()()
+	return mb.interpreter.UnregisterFunction(name)
+}
+
+// HasExternalFunc reports whether a host function is registered under the
+// name, including the built-in library functions
+func (mb *MechBasic) HasExternalFunc(name string) bool {
+	defer mb.lock()()
+	return mb.interpreter.HasExternalFunction(name)
 }
 
-func (mb *MechBasic) Run(code string) error {
-	return mb.interpreter.Interpret(code)
+// SetStrictRegistration makes registering a function under a name already
+// taken fail with ErrFunctionRegistered rather than replace the function
+func (mb *MechBasic) SetStrictRegistration(strict bool) {
+	defer mb.lock()()
+	mb.interpreter.SetStrictRegistration(strict)
+}
+
+// RegisterGoFunc registers a plain Go function, such as
+// func(x, y int) (float64, error), converting the script's arguments to its
+// parameter types and its results back to script values. See
+// functions.Adapt for the supported signatures; RegisterGoFunc fails if fn
+// has another shape.
+func (mb *MechBasic) RegisterGoFunc(name string, fn any, opts ...FuncOption) error {
+	adapted, err := functions.Adapt(name, fn)
+	if err != nil {
+		return err
+	}
+	return mb.RegisterFunc(name, adapted, opts...)
+}
+
+// RegisterObject registers each exported method of obj as a script function
+// named prefix_method in lower case, so RegisterObject("player", p) exposes
+// p.GetHP as player_gethp(). Methods are adapted as by RegisterGoFunc and
+// called on obj itself, so pass a pointer for methods that change it. Nothing
+// is registered if any method has an unsupported signature, or with strict
+// registration on, if any name is taken.
+func (mb *MechBasic) RegisterObject(prefix string, obj any) error {
+	rv := reflect.ValueOf(obj)
+	if !rv.IsValid() || rv.NumMethod() == 0 {
+		return fmt.Errorf("%s: %T has no exported methods", prefix, obj)
+	}
+
+	adapted := make(map[string]ExternalFunc, rv.NumMethod())
+	for idx := 0; idx < rv.NumMethod(); idx++ {
+		name := prefix + "_" + strings.ToLower(rv.Type().Method(idx).Name)
+		fn, err := functions.Adapt(name, rv.Method(idx).Interface())
+		if err != nil {
+			return err
+		}
+		adapted[name] = fn
+	}
+
+	defer mb.lock()()
+	return mb.interpreter.RegisterFunctions(adapted)
+}
+
+// RunOption configures a single Run invocation
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	vars    map[string]any
+	globals map[string]any
+	caching *bool
+}
+
+// WithVars seeds the global scope with the given variables before the script runs
+func WithVars(vars map[string]any) RunOption {
+	return func(c *runConfig) {
+		c.vars = vars
+	}
+}
+
+// WithGlobalsInto copies the script's final global variables into dst once the run completes
+func WithGlobalsInto(dst map[string]any) RunOption {
+	return func(c *runConfig) {
+		c.globals = dst
+	}
+}
+
+// WithCaching overrides SetCaching for a single run, so a one-off snippet
+// can skip the AST cache on an instance that otherwise uses it
+func WithCaching(enabled bool) RunOption {
+	return func(c *runConfig) {
+		c.caching = &enabled
+	}
+}
+
+func (mb *MechBasic) Run(code string, opts ...RunOption) error {
+	defer mb.lock()()
+
+	if len(opts) == 0 {
+		return mb.interpreter.Interpret(code)
+	}
+
+	cfg := newRunConfig(opts)
+	defer mb.applyCaching(cfg)()
+	err := mb.interpreter.InterpretWithVars(code, cfg.vars)
+	mb.exportGlobals(cfg)
+	return err
+}
+
+// RunContext is Run with cancellation: once ctx is done the script stops
+// before its next statement or loop iteration and the returned error wraps
+// ctx.Err(), so errors.Is(err, context.Canceled) works as expected
+func (mb *MechBasic) RunContext(ctx context.Context, code string, opts ...RunOption) error {
+	unlock, err := mb.lockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg := newRunConfig(opts)
+	defer mb.applyCaching(cfg)()
+	err = mb.interpreter.InterpretWithVarsContext(ctx, code, cfg.vars)
+	mb.exportGlobals(cfg)
+	return err
+}
+
+func newRunConfig(opts []RunOption) *runConfig {
+	cfg := &runConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// applyCaching applies WithCaching for the run. Call the returned function
+// to restore the instance's setting.
+func (mb *MechBasic) applyCaching(cfg *runConfig) func() {
+	if cfg.caching == nil {
+		return func() {}
+	}
+	previous := mb.interpreter.Caching()
+	mb.interpreter.SetCaching(*cfg.caching)
+	return func() {
+		mb.interpreter.SetCaching(previous)
+	}
+}
+
+// exportGlobals copies the final globals into the destination requested by WithGlobalsInto
+func (mb *MechBasic) exportGlobals(cfg *runConfig) {
+	if cfg.globals == nil {
+		return
+	}
+	for name, value := range mb.interpreter.Globals() {
+		cfg.globals[name] = value
+	}
+}
+
+// Eval runs the code and returns the value of its last top-level expression
+// (or of an explicit top-level RETURN), so formulas such as "2 + damage * 3"
+// can be evaluated directly
+func (mb *MechBasic) Eval(code string, opts ...RunOption) (any, error) {
+	defer mb.lock()()
+
+	cfg := newRunConfig(opts)
+	defer mb.applyCaching(cfg)()
+	result, err := mb.interpreter.EvaluateWithVars(code, cfg.vars)
+	mb.exportGlobals(cfg)
+	return result, err
 }
 
 // Load parses the script and registers function definitions without executing top-level code
 func (mb *MechBasic) Load(code string) error {
+	defer mb.lock()()
 	return mb.interpreter.Load(code)
 }
 
 // Call invokes a script-defined function by name with the provided arguments
 // Each call starts with a fresh scope - variables do not persist between calls
 func (mb *MechBasic) Call(funcName string, args ...any) (any, error) {
+	defer mb.lock()()
 	return mb.interpreter.Call(funcName, args...)
 }
 
+// CallContext is Call with cancellation, as for RunContext
+func (mb *MechBasic) CallContext(ctx context.Context, funcName string, args ...any) (any, error) {
+	unlock, err := mb.lockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return mb.interpreter.CallContext(ctx, funcName, args...)
+}
+
+// CallWithOptions is Call with the statement budget, iteration limit or
+// timeout in opts applied for this call only, so an update handler can run
+// on a much smaller budget than init. Zero fields keep the instance's
+// settings.
+func (mb *MechBasic) CallWithOptions(funcName string, opts CallOptions, args ...any) (any, error) {
+	defer mb.lock()()
+	return mb.interpreter.CallWithOptions(funcName, opts, args...)
+}
+
+// CallNamed invokes a script-defined function, binding arguments by parameter name
+// so host event payloads keep working when a script reorders its parameters
+func (mb *MechBasic) CallNamed(funcName string, args map[string]any) (any, error) {
+	defer mb.lock()()
+	return mb.interpreter.CallNamed(funcName, args)
+}
+
+// Clone creates an independent copy of the instance with the same settings,
+// registered functions and loaded script functions, but no variables, so one
+// loaded script can be stamped out for many entities. Functions registered on
+// either afterwards are its own, as is its random source, seeded from this
+// instance's; the string table is shared.
+func (mb *MechBasic) Clone() *MechBasic {
+	defer mb.lock()()
+	return &MechBasic{
+		interpreter: mb.interpreter.Clone(),
+		strings:     mb.strings,
+		turn:        make(chan struct{}, 1),
+	}
+}
+
+// SetNamedArgPolicy sets how CallNamed treats keys that don't match the parameter list
+func (mb *MechBasic) SetNamedArgPolicy(policy NamedArgPolicy) {
+	defer mb.lock()()
+	mb.interpreter.SetNamedArgPolicy(policy)
+}
+
+// GetVariable returns the value of a global variable, or false if the script
+// hasn't defined it
+func (mb *MechBasic) GetVariable(name string) (any, bool) {
+	defer mb.lock()()
+	return mb.interpreter.GetVariable(name)
+}
+
+// SetVariable creates or replaces a global variable, so the host can hand
+// values to the next Call or Run without an external function. Constants
+// can't be replaced.
+func (mb *MechBasic) SetVariable(name string, value any) error {
+	defer mb.lock()()
+	return mb.interpreter.SetVariable(name, value)
+}
+
+// Globals returns a snapshot of the script's global variables, such as an
+// entity's state for a save file. Arrays, maps and records are copied, so
+// later runs don't change it.
+func (mb *MechBasic) Globals() map[string]any {
+	defer mb.lock()()
+	return mb.interpreter.Globals()
+}
+
+// SetGlobals replaces the script's global variables with a copy of vars,
+// restoring a snapshot taken with Globals. The script's functions and
+// constants are kept. It fails, changing nothing, if a value is an unsigned
+// number too large for int.
+func (mb *MechBasic) SetGlobals(vars map[string]any) error {
+	defer mb.lock()()
+	return mb.interpreter.SetGlobals(vars)
+}
+
+// MarshalState encodes the script's global variables as JSON for a save file.
+// The same state always gives the same bytes. Values other than numbers,
+// strings, bools, NULL, arrays and maps fail with a *StateError.
+func (mb *MechBasic) MarshalState() ([]byte, error) {
+	defer mb.lock()()
+	return mb.interpreter.MarshalState()
+}
+
+// UnmarshalState replaces the script's global variables with the JSON from
+// MarshalState, as SetGlobals does
+func (mb *MechBasic) UnmarshalState(data []byte) error {
+	defer mb.lock()()
+	return mb.interpreter.UnmarshalState(data)
+}
+
+// ResetOption selects what Reset clears besides the script state
+type ResetOption func(*basic.ResetOptions)
+
+// ResetCache makes Reset empty the AST cache as well
+func ResetCache() ResetOption {
+	return func(o *basic.ResetOptions) {
+		o.ClearCache = true
+	}
+}
+
+// ResetFunctions makes Reset drop the registered functions as well, including
+// the built-in libraries; register the ones you need again afterwards
+func ResetFunctions() ResetOption {
+	return func(o *basic.ResetOptions) {
+		o.ClearFunctions = true
+	}
+}
+
+// Reset drops the script's variables, constants, functions, record types and
+// error handler, so a long-lived instance can be reused cleanly, for example
+// between levels. Settings, the AST cache and registered functions are kept
+// unless options ask otherwise.
+func (mb *MechBasic) Reset(opts ...ResetOption) {
+	defer mb.lock()()
+
+	var options basic.ResetOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	mb.interpreter.Reset(options)
+}
+
+// HasVariable reports whether the loaded script defines the given global variable
+func (mb *MechBasic) HasVariable(name string) bool {
+	defer mb.lock()()
+	return mb.interpreter.HasVariable(name)
+}
+
+// VarType returns the type name of a global variable ("int", "float", "string",
+// "bool", "array", "map", "bytes", "null" or "object"), or false if it doesn't exist
+func (mb *MechBasic) VarType(name string) (string, bool) {
+	defer mb.lock()()
+	return mb.interpreter.VarType(name)
+}
+
+// Validate checks a script for errors without running it, returning the
+// first one found
+func (mb *MechBasic) Validate(code string) error {
+	defer mb.lock()()
+	return mb.interpreter.Validate(code)
+}
+
+// ValidateAll checks a script without running it and returns every error and
+// warning found, sorted by position, for editors that show them all at once
+func (mb *MechBasic) ValidateAll(code string) []Diagnostic {
+	defer mb.lock()()
+	return mb.interpreter.ValidateAll(code)
+}
+
+// Warnings returns the diagnostics for the script most recently run, loaded
+// or evaluated, such as function results that are discarded, variables that
+// are never read and parameters that shadow globals
+func (mb *MechBasic) Warnings() []Warning {
+	defer mb.lock()()
+	return mb.interpreter.Warnings()
+}
+
 // HasFunction checks if a function with the given name exists in the loaded script
 func (mb *MechBasic) HasFunction(funcName string) bool {
+	defer mb.lock()()
 	return mb.interpreter.HasFunction(funcName)
 }
 
+// Functions returns the names of the functions and subs defined by the loaded
+// script, in lowercase and sorted
+func (mb *MechBasic) Functions() []string {
+	defer mb.lock()()
+	return mb.interpreter.FunctionNames()
+}
+
+// DescribeFunctions documents the public functions of the loaded script in source order
+func (mb *MechBasic) DescribeFunctions() []FunctionDoc {
+	defer mb.lock()()
+	return mb.interpreter.DescribeFunctions()
+}
+
+// DescribeScript documents the public functions of a script without running it
+func (mb *MechBasic) DescribeScript(code string) ([]FunctionDoc, error) {
+	defer mb.lock()()
+	return mb.interpreter.DescribeScript(code)
+}
+
+// RunBenchmark runs the code n times without the AST cache and n times with it,
+// reporting the average time per run of each
+func (mb *MechBasic) RunBenchmark(code string, n int) (BenchmarkResult, error) {
+	defer mb.lock()()
+	return mb.interpreter.RunBenchmark(code, n)
+}
+
+// SetProfiling turns the profiler on or off. While it is on, every
+// statement run is timed and counted by source line, across runs and calls,
+// until it is turned off; turning it on again starts a fresh profile.
+// Profiling slows scripts down, so leave it off in release builds.
+func (mb *MechBasic) SetProfiling(enabled bool) {
+	defer mb.lock()()
+	mb.interpreter.SetProfiling(enabled)
+}
+
+// Profile returns the executions and time per line recorded by the profiler
+// so far, or by its last session once it is turned off
+func (mb *MechBasic) Profile() Profile {
+	defer mb.lock()()
+	return mb.interpreter.Profile()
+}
+
+// CacheKey returns the key under which the given code is cached by Run and
+// Load. Code run by Eval is cached under EvalCacheKey.
+func (mb *MechBasic) CacheKey(code string) string {
+	defer mb.lock()()
+	return mb.interpreter.CacheKey(code)
+}
+
+// EvalCacheKey returns the key under which the given code is cached by Eval
+func (mb *MechBasic) EvalCacheKey(code string) string {
+	defer mb.lock()()
+	return mb.interpreter.EvalCacheKey(code)
+}
+
+// CachedPrograms lists the programs held in the AST cache, most recently used first
+func (mb *MechBasic) CachedPrograms() []CacheEntry {
+	defer mb.lock()()
+	return mb.interpreter.CachedPrograms()
+}
+
+// PinProgram prevents a cached program from being evicted
+func (mb *MechBasic) PinProgram(hash string) bool {
+	defer mb.lock()()
+	return mb.interpreter.PinProgram(hash)
+}
+
+// UnpinProgram allows a previously pinned program to be evicted again
+func (mb *MechBasic) UnpinProgram(hash string) bool {
+	defer mb.lock()()
+	return mb.interpreter.UnpinProgram(hash)
+}
+
+// EvictProgram removes an unpinned program from the AST cache
+func (mb *MechBasic) EvictProgram(hash string) bool {
+	defer mb.lock()()
+	return mb.interpreter.EvictProgram(hash)
+}
+
+// ClearCache evicts every unpinned program and returns the number removed
+func (mb *MechBasic) ClearCache() int {
+	defer mb.lock()()
+	return mb.interpreter.ClearCache()
+}
+
+// SetCacheSize caps the number of programs held in the AST cache, evicting
+// the least recently used unpinned ones beyond it. 0, the default, means no limit.
+func (mb *MechBasic) SetCacheSize(n int) {
+	defer mb.lock()()
+	mb.interpreter.SetCacheSize(n)
+}
+
+// SetCaching turns the AST cache on or off. With it off every script is
+// parsed afresh and nothing is cached, which suits one-shot evaluation.
+func (mb *MechBasic) SetCaching(enabled bool) {
+	defer mb.lock()()
+	mb.interpreter.SetCaching(enabled)
+}
+
+// ExportCache writes the AST cache to w, so a game can ship its scripts
+// already parsed and load them with ImportCache at startup
+func (mb *MechBasic) ExportCache(w io.Writer) error {
+	defer mb.lock()()
+	return mb.interpreter.ExportCache(w)
+}
+
+// ImportCache adds the programs written by ExportCache to the AST cache
+func (mb *MechBasic) ImportCache(r io.Reader) error {
+	defer mb.lock()()
+	return mb.interpreter.ImportCache(r)
+}
+
 func (mb *MechBasic) RegisterMathLibrary() {
+	defer mb.lock()()
 	mb.interpreter.RegisterFunction("pow", mathlib.Pow)
 	mb.interpreter.RegisterFunction("abs", mathlib.Abs)
 	mb.interpreter.RegisterFunction("atn", mathlib.Atn)
@@ -52,12 +725,320 @@ func (mb *MechBasic) RegisterMathLibrary() {
 	mb.interpreter.RegisterFunction("exp", mathlib.Exp)
 	mb.interpreter.RegisterFunction("int", mathlib.Int)
 	mb.interpreter.RegisterFunction("log", mathlib.Log)
-	mb.interpreter.RegisterFunction("rnd", mathlib.Rnd)
+	mb.interpreter.RegisterBoundFunction("rnd", func(i *basic.Interpreter) basic.ExternalFunc {
+		return mathlib.RndFrom(i.Rand())
+	})
 	mb.interpreter.RegisterFunction("sin", mathlib.Sin)
 	mb.interpreter.RegisterFunction("tan", mathlib.Tan)
 	mb.interpreter.RegisterFunction("sqr", mathlib.Sqr)
+
+	for _, doc := range [][3]string{
+		{"pow", "pow(base, exponent)", "Returns base raised to the power exponent."},
+		{"abs", "abs(x)", "Returns the absolute value of x."},
+		{"atn", "atn(x)", "Returns the arctangent of x in radians."},
+		{"cos", "cos(angle)", "Returns the cosine of an angle in radians."},
+		{"exp", "exp(x)", "Returns e raised to the power x."},
+		{"int", "int(x)", "Returns x rounded down to a whole number."},
+		{"log", "log(x)", "Returns the natural logarithm of x."},
+		{"rnd", "rnd([max])", "Returns a random number from 0 up to 1, or up to max."},
+		{"sin", "sin(angle)", "Returns the sine of an angle in radians."},
+		{"tan", "tan(angle)", "Returns the tangent of an angle in radians."},
+		{"sqr", "sqr(x)", "Returns the square root of x."},
+	} {
+		mb.interpreter.DocumentFunction(doc[0], doc[1], doc[2])
+	}
+}
+
+func (mb *MechBasic) RegisterStringLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("format", stringlib.Format)
+	mb.interpreter.RegisterFunction("len", stringlib.Len)
+	mb.interpreter.RegisterFunction("mid", stringlib.Mid)
+	mb.interpreter.RegisterFunction("left", stringlib.Left)
+	mb.interpreter.RegisterFunction("right", stringlib.Right)
+	mb.interpreter.RegisterFunction("val", stringlib.Val)
+	mb.interpreter.RegisterBoundFunction("str", func(i *basic.Interpreter) basic.ExternalFunc {
+		return stringlib.Str(i.ToString)
+	})
+	mb.interpreter.RegisterFunction("chr", stringlib.Chr)
+	mb.interpreter.RegisterFunction("asc", stringlib.Asc)
+	mb.interpreter.RegisterFunction("instr", stringlib.InStr)
+	mb.interpreter.RegisterFunction("replace", stringlib.Replace)
+	mb.interpreter.RegisterFunction("split", stringlib.Split)
+	mb.interpreter.RegisterFunction("join", stringlib.Join)
+	mb.interpreter.RegisterFunction("ucase", stringlib.UCase)
+	mb.interpreter.RegisterFunction("lcase", stringlib.LCase)
+	mb.interpreter.RegisterFunction("trim", stringlib.Trim)
+	mb.interpreter.RegisterFunction("ltrim", stringlib.LTrim)
+	mb.interpreter.RegisterFunction("rtrim", stringlib.RTrim)
+}
+
+func (mb *MechBasic) RegisterArrayLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterBoundFunction("sum", func(i *basic.Interpreter) basic.ExternalFunc {
+		return arraylib.Sum(i.Add)
+	})
+	mb.interpreter.RegisterFunction("avg", arraylib.Avg)
+	mb.interpreter.RegisterFunction("min", arraylib.Min)
+	mb.interpreter.RegisterFunction("max", arraylib.Max)
+	mb.interpreter.RegisterBoundFunction("count_if", func(i *basic.Interpreter) basic.ExternalFunc {
+		return arraylib.CountIf(i.Invoke)
+	})
+	mb.interpreter.RegisterBoundFunction("filter", func(i *basic.Interpreter) basic.ExternalFunc {
+		return arraylib.Filter(i.Invoke)
+	})
+}
+
+func (mb *MechBasic) RegisterMapLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("map", maplib.Map)
+	mb.interpreter.RegisterFunction("has_key", maplib.HasKey)
+	mb.interpreter.RegisterFunction("delete_key", maplib.DeleteKey)
+	mb.interpreter.RegisterFunction("keys", maplib.Keys)
+	mb.interpreter.RegisterFunction("values", maplib.Values)
+}
+
+func (mb *MechBasic) RegisterMatrixLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("matrix", matrixlib.Matrix)
+	mb.interpreter.RegisterFunction("identity", matrixlib.Identity)
+	mb.interpreter.RegisterFunction("matmul", matrixlib.MatMul)
+	mb.interpreter.RegisterFunction("transpose", matrixlib.Transpose)
+	mb.interpreter.RegisterFunction("invert", matrixlib.Invert)
+}
+
+func (mb *MechBasic) RegisterBufferLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("buffer", bufferlib.Buffer)
+	mb.interpreter.RegisterFunction("buflen", bufferlib.BufLen)
+	mb.interpreter.RegisterFunction("readu8", bufferlib.ReadU8)
+	mb.interpreter.RegisterFunction("readi8", bufferlib.ReadI8)
+	mb.interpreter.RegisterFunction("readu16", bufferlib.ReadU16)
+	mb.interpreter.RegisterFunction("readi16", bufferlib.ReadI16)
+	mb.interpreter.RegisterFunction("readu32", bufferlib.ReadU32)
+	mb.interpreter.RegisterFunction("readi32", bufferlib.ReadI32)
+	mb.interpreter.RegisterFunction("writeu8", bufferlib.WriteU8)
+	mb.interpreter.RegisterFunction("writeu16", bufferlib.WriteU16)
+	mb.interpreter.RegisterFunction("writeu32", bufferlib.WriteU32)
+	mb.interpreter.RegisterFunction("pack", bufferlib.Pack)
+	mb.interpreter.RegisterFunction("unpack", bufferlib.Unpack)
+}
+
+func (mb *MechBasic) RegisterBitLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("setbit", bitlib.SetBit)
+	mb.interpreter.RegisterFunction("clearbit", bitlib.ClearBit)
+	mb.interpreter.RegisterFunction("togglebit", bitlib.ToggleBit)
+	mb.interpreter.RegisterFunction("testbit", bitlib.TestBit)
+	mb.interpreter.RegisterFunction("popcount", bitlib.PopCount)
+	mb.interpreter.RegisterFunction("rotl", bitlib.Rotl)
+	mb.interpreter.RegisterFunction("rotr", bitlib.Rotr)
+	mb.interpreter.RegisterFunction("bitand", bitlib.BitAnd)
+	mb.interpreter.RegisterFunction("bitor", bitlib.BitOr)
+	mb.interpreter.RegisterFunction("bitxor", bitlib.BitXor)
+	mb.interpreter.RegisterFunction("bitnot", bitlib.BitNot)
+	mb.interpreter.RegisterFunction("shl", bitlib.Shl)
+	mb.interpreter.RegisterFunction("shr", bitlib.Shr)
+}
+
+func (mb *MechBasic) RegisterStatsLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("mean", statslib.Mean)
+	mb.interpreter.RegisterFunction("median", statslib.Median)
+	mb.interpreter.RegisterFunction("variance", statslib.Variance)
+	mb.interpreter.RegisterFunction("stddev", statslib.StdDev)
+	mb.interpreter.RegisterFunction("percentile", statslib.Percentile)
+}
+
+func (mb *MechBasic) RegisterPathLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("pathfind", pathlib.Pathfind)
+}
+
+func (mb *MechBasic) RegisterGeometryLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("distance", geometrylib.Distance)
+	mb.interpreter.RegisterFunction("rect_intersects", geometrylib.RectIntersects)
+	mb.interpreter.RegisterFunction("circle_intersects", geometrylib.CircleIntersects)
+	mb.interpreter.RegisterFunction("point_in_polygon", geometrylib.PointInPolygon)
+	mb.interpreter.RegisterFunction("angle_between", geometrylib.AngleBetween)
+}
+
+func (mb *MechBasic) RegisterRandomLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterBoundFunction("gaussian", func(i *basic.Interpreter) basic.ExternalFunc {
+		return randomlib.Gaussian(i.Rand())
+	})
+	mb.interpreter.RegisterBoundFunction("weighted_choice", func(i *basic.Interpreter) basic.ExternalFunc {
+		return randomlib.WeightedChoice(i.Rand())
+	})
+	mb.interpreter.RegisterBoundFunction("shuffle", func(i *basic.Interpreter) basic.ExternalFunc {
+		return randomlib.Shuffle(i.Rand())
+	})
+}
+
+func (mb *MechBasic) RegisterLocaleLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("tr", mb.strings.Tr)
+}
+
+func (mb *MechBasic) RegisterTestLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterFunction("assert", assertlib.Assert)
+	mb.interpreter.RegisterFunction("expect_eq", assertlib.ExpectEq)
+}
+
+// RegisterHelpLibrary registers help(), which prints the documentation of a
+// function for an in-game script console
+func (mb *MechBasic) RegisterHelpLibrary() {
+	defer mb.lock()()
+	mb.interpreter.RegisterBoundFunction("help", func(i *basic.Interpreter) basic.ExternalFunc {
+		return i.Help
+	})
+	mb.interpreter.DocumentFunction("help", "help([name])", "Prints how to call the named function and what it does, or lists every function.")
+}
+
+// SetStringTable replaces the strings looked up by tr(). Call it again to
+// switch languages; scripts pick up the new table on their next tr() call.
+func (mb *MechBasic) SetStringTable(entries map[string]string) {
+	defer mb.lock()()
+	mb.strings.Set(entries)
+}
+
+// Seed reseeds the random source shared by rnd and the random library,
+// making script runs reproducible
+func (mb *MechBasic) Seed(seed int64) {
+	defer mb.lock()()
+	mb.interpreter.Seed(seed)
+}
+
+// SetMaxStatements limits how many statements a run may execute, counting
+// those in loops and function calls, so straight-line code and recursion
+// can't burn CPU unchecked. Zero, the default, means no limit.
+func (mb *MechBasic) SetMaxStatements(max int) {
+	defer mb.lock()()
+	mb.interpreter.SetMaxStatements(max)
+}
+
+// SetMemoryLimits limits the state a script may build up. The limits are
+// checked whenever the script assigns a variable, element or field.
+func (mb *MechBasic) SetMemoryLimits(limits MemoryLimits) {
+	defer mb.lock()()
+	mb.interpreter.SetMemoryLimits(limits)
+}
+
+// SetMaxExpressionDepth limits how deeply expressions may nest, including
+// through recursive function calls. Exceeding it fails with an
+// "expression too complex" error instead of overflowing the Go stack.
+func (mb *MechBasic) SetMaxExpressionDepth(max int) {
+	defer mb.lock()()
+	mb.interpreter.SetMaxExpressionDepth(max)
+}
+
+// SetNumberFormat sets how floats are formatted by PRINT and string
+// concatenation, e.g. NumberFormat{Precision: 2, Fixed: true} for "0.30"
+func (mb *MechBasic) SetNumberFormat(format NumberFormat) {
+	defer mb.lock()()
+	mb.interpreter.SetNumberFormat(format)
+}
+
+// SetScopeMode sets where a plain assignment inside a function goes:
+// ScopeDynamic (the default) updates an existing variable in any enclosing
+// scope, ScopeLocal keeps it in the function unless it is declared GLOBAL
+func (mb *MechBasic) SetScopeMode(mode ScopeMode) {
+	defer mb.lock()()
+	mb.interpreter.SetScopeMode(mode)
+}
+
+// SetCaseSensitive makes identifiers case-sensitive, so Player and player
+// are different variables and functions. The built-in libraries are
+// registered in lowercase; call it before registering functions of your own.
+func (mb *MechBasic) SetCaseSensitive(sensitive bool) {
+	defer mb.lock()()
+	mb.interpreter.SetCaseSensitive(sensitive)
+}
+
+// SetStrictVariables makes every script behave as if it began with OPTION
+// EXPLICIT, so assigning to a variable that was never declared is an error
+func (mb *MechBasic) SetStrictVariables(strict bool) {
+	defer mb.lock()()
+	mb.interpreter.SetStrictVariables(strict)
+}
+
+// SetModuleResolver sets how IMPORT finds the source of a module, e.g.
+// FSResolver(os.DirFS("scripts")). Without a resolver every IMPORT fails.
+func (mb *MechBasic) SetModuleResolver(resolver ModuleResolver) {
+	defer mb.lock()()
+	mb.interpreter.SetModuleResolver(resolver)
+}
+
+// SetSourceFS sets the file system INCLUDE "file.bas" reads from, such as
+// os.DirFS("scripts") or an embed.FS. Without one every INCLUDE fails.
+func (mb *MechBasic) SetSourceFS(fsys fs.FS) {
+	defer mb.lock()()
+	mb.interpreter.SetSourceFS(fsys)
+}
+
+// SetOverflowMode sets how integer overflow is handled: OverflowWrap (the
+// default, unchecked), OverflowError or OverflowPromote (continue as float)
+func (mb *MechBasic) SetOverflowMode(mode OverflowMode) {
+	defer mb.lock()()
+	mb.interpreter.SetOverflowMode(mode)
+}
+
+// SetResultPolicy sets the numeric types returned by Call, CallNamed, Eval
+// and WithGlobalsInto. Numbers passed in are always normalized.
+func (mb *MechBasic) SetResultPolicy(policy ResultPolicy) {
+	defer mb.lock()()
+	mb.interpreter.SetResultPolicy(policy)
+}
+
+// SetDebugHook installs a function called before every statement, which may
+// block to pause the script. Returning an error aborts the run. Pass nil to
+// remove the hook.
+func (mb *MechBasic) SetDebugHook(hook func(frame DebugFrame) error) {
+	defer mb.lock()()
+	mb.interpreter.SetDebugHook(hook)
+}
+
+// Variables returns the variables visible at the paused statement
+func (mb *MechBasic) Variables() map[string]any {
+	return mb.interpreter.Variables()
+}
+
+// CallStack returns the script functions executing at the paused statement,
+// outermost first
+func (mb *MechBasic) CallStack() []string {
+	return mb.interpreter.CallStack()
+}
+
+// EvalInFrame evaluates an expression against the variables visible at the
+// paused statement
+func (mb *MechBasic) EvalInFrame(expr string) (any, error) {
+	return mb.interpreter.EvalInFrame(expr)
+}
+
+// Stop cancels the script currently running in another goroutine. The run
+// returns an error matching ErrInterrupted before its next statement.
+func (mb *MechBasic) Stop() {
+	mb.interpreter.Stop()
 }
 
 func (mb *MechBasic) SetPrintFunc(fn func(value any)) {
+	defer mb.lock()()
 	mb.interpreter.SetPrintFunc(fn)
 }
+
+// SetRawPrintFunc sets a print handler that is also told whether the line
+// should end: newline is false after PRINT x; (trailing semicolon)
+func (mb *MechBasic) SetRawPrintFunc(fn func(value any, newline bool)) {
+	defer mb.lock()()
+	mb.interpreter.SetRawPrintFunc(fn)
+}
+
+// SetPrintSeparator sets the text placed between the values of
+// PRINT a, b, c (a single space by default)
+func (mb *MechBasic) SetPrintSeparator(sep string) {
+	defer mb.lock()()
+	mb.interpreter.SetPrintSeparator(sep)
+}
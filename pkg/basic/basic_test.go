@@ -0,0 +1,303 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWithCaching(t *testing.T) {
+	mb := NewMechanicalBasic()
+	mb.SetPrintFunc(func(value any) {})
+
+	if err := mb.Run("print 1", WithCaching(false)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries := mb.CachedPrograms(); len(entries) != 0 {
+		t.Errorf("expected the run to skip the cache, got %v", entries)
+	}
+
+	// The instance's own setting applies again afterwards
+	if err := mb.Run("print 1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries := mb.CachedPrograms(); len(entries) != 1 {
+		t.Errorf("expected the program to be cached, got %v", entries)
+	}
+
+	mb.SetCaching(false)
+	if _, err := mb.Eval("1 + 1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries := mb.CachedPrograms(); len(entries) != 1 {
+		t.Errorf("expected nothing more to be cached, got %v", entries)
+	}
+}
+
+func TestRegisterGoFunc(t *testing.T) {
+	mb := NewMechanicalBasic()
+
+	if err := mb.RegisterGoFunc("damage", func(attack int, multiplier float64) (int, error) {
+		if attack < 0 {
+			return 0, errors.New("negative attack")
+		}
+		return int(float64(attack) * multiplier), nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mb.RegisterGoFunc("total", func(values ...float32) float64 {
+		sum := 0.0
+		for _, v := range values {
+			sum += float64(v)
+		}
+		return sum
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mb.RegisterGoFunc("names", func(counts map[string]uint8) []string {
+		names := make([]string, 0, len(counts))
+		for name := range counts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mb.RegisterGoFunc("ids", func(n uint64) []uint64 {
+		return []uint64{n, n + 1}
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		code     string
+		expected any
+	}{
+		{"damage(10, 1.5)", 15},
+		{"damage(10.0, 2)", 20},
+		{"total()", 0.0},
+		{"total(1, 2.5, 3)", 6.5},
+		{`names(map("b", 1, "a", 2))`, []any{"a", "b"}},
+		{"ids(7)", []any{7, 8}},
+	}
+	for _, tt := range tests {
+		got, err := mb.Eval(tt.code)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.code, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %v (%T), got %v (%T)", tt.code, tt.expected, tt.expected, got, got)
+		}
+	}
+
+	for _, code := range []string{
+		"damage(10)",
+		`damage("10", 1)`,
+		"damage(-1, 1)",
+		`names(map("a", 300))`,
+		"ids(9223372036854775807)",
+	} {
+		if _, err := mb.Eval(code); err == nil {
+			t.Errorf("%s: expected an error", code)
+		}
+	}
+
+	if err := mb.RegisterGoFunc("bad", 42); err == nil {
+		t.Error("expected an error for a value that isn't a function")
+	}
+	if err := mb.RegisterGoFunc("bad", func() (int, string) { return 0, "" }); err == nil {
+		t.Error("expected an error for a second result that isn't an error")
+	}
+}
+
+type testPlayer struct {
+	hp int
+}
+
+func (p *testPlayer) GetHP() int { return p.hp }
+
+func (p *testPlayer) Heal(amount int) error {
+	if amount < 0 {
+		return errors.New("cannot heal a negative amount")
+	}
+	p.hp += amount
+	return nil
+}
+
+func TestRegisterObject(t *testing.T) {
+	mb := NewMechanicalBasic()
+	player := &testPlayer{hp: 10}
+	if err := mb.RegisterObject("player", player); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := mb.Eval("player_heal(5)\nreturn player_GetHP()")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 15 || player.hp != 15 {
+		t.Errorf("expected 15, got %v (player has %d)", got, player.hp)
+	}
+	if _, err := mb.Eval("player_heal(-1)"); err == nil {
+		t.Error("expected the method's error")
+	}
+
+	if err := mb.RegisterObject("nothing", struct{}{}); err == nil {
+		t.Error("expected an error for a value without methods")
+	}
+}
+
+func TestNewMechanicalBasicLibraries(t *testing.T) {
+	all := NewMechanicalBasic()
+	if !all.HasExternalFunc("pow") || !all.HasExternalFunc("len") {
+		t.Error("expected every built-in library by default")
+	}
+
+	withoutMath := NewMechanicalBasic(WithoutLibraries(MathLibrary))
+	if withoutMath.HasExternalFunc("pow") || withoutMath.HasExternalFunc("rnd") || !withoutMath.HasExternalFunc("len") {
+		t.Error("expected every library but math")
+	}
+	withoutMath.SetStrictRegistration(true)
+	if err := withoutMath.RegisterFunc("pow", func(args ...any) (any, error) { return 0, nil }); err != nil {
+		t.Errorf("expected pow to be free, got %v", err)
+	}
+
+	onlyStrings := NewMechanicalBasic(WithLibraries(StringLibrary))
+	if onlyStrings.HasExternalFunc("pow") || !onlyStrings.HasExternalFunc("len") {
+		t.Error("expected only the string library")
+	}
+	if value, err := onlyStrings.Eval(`ucase("ok")`); err != nil || value != "OK" {
+		t.Errorf("expected OK, got %v (%v)", value, err)
+	}
+
+	if none := NewMechanicalBasic(WithoutLibraries()); !none.HasExternalFunc("pow") || !none.HasExternalFunc("len") {
+		t.Error("expected WithoutLibraries() to leave every library in")
+	}
+
+	bare := NewMechanicalBasic(WithLibraries())
+	if bare.HasExternalFunc("pow") || bare.HasExternalFunc("len") {
+		t.Error("expected no built-in library")
+	}
+	if value, err := bare.Eval("1 + 2"); err != nil || value != 3 {
+		t.Errorf("expected 3, got %v (%v)", value, err)
+	}
+}
+
+func TestFunctionDocumentation(t *testing.T) {
+	mb := NewMechanicalBasic()
+	var output []any
+	mb.SetPrintFunc(func(value any) { output = append(output, value) })
+
+	err := mb.RegisterFunc("spawn", func(args ...any) (any, error) { return nil, nil },
+		WithSignature("spawn(kind)"), WithDescription("Spawns a monster."))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mb.Run(`help("spawn")
+help("pow")`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []any{"spawn(kind)", "Spawns a monster.", "pow(base, exponent)", "Returns base raised to the power exponent."}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("expected %v, got %v", expected, output)
+	}
+
+	found := false
+	for _, info := range mb.ListFunctions() {
+		if info.Name == "spawn" {
+			found = info.Description == "Spawns a monster."
+		}
+	}
+	if !found {
+		t.Error("expected ListFunctions to include spawn's documentation")
+	}
+
+	if NewMechanicalBasic(WithoutLibraries(HelpLibrary)).HasExternalFunc("help") {
+		t.Error("expected help to be left out with its library")
+	}
+}
+
+func TestRegisterConstant(t *testing.T) {
+	mb := NewMechanicalBasic()
+	if err := mb.RegisterConstant("SCREEN_W", 640); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mb.Run("let half = screen_w / 2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, ok := mb.GetVariable("half"); !ok || value != 320 {
+		t.Errorf("expected 320, got %v", value)
+	}
+	if err := mb.Run("screen_w = 800"); err == nil {
+		t.Error("expected assigning a constant to fail")
+	}
+}
+
+func TestStructuredErrors(t *testing.T) {
+	mb := NewMechanicalBasic()
+
+	var parseErr *ParseError
+	if err := mb.Run("let x = (1"); !errors.As(err, &parseErr) || parseErr.Kind != ErrorSyntax || parseErr.Line != 1 {
+		t.Errorf("expected a syntax error at line 1, got %v", err)
+	}
+
+	var runErr *RuntimeError
+	if err := mb.Run("let x = 1\nlet y = x / 0"); !errors.As(err, &runErr) || runErr.Kind != ErrorRuntime || runErr.Line != 2 {
+		t.Errorf("expected a runtime error at line 2, got %v", err)
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	mb := NewMechanicalBasic()
+	if err := mb.Validate("let x = 1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	diags := mb.ValidateAll("let = 1\nlet y = 2\nlet z = (3")
+	if len(diags) != 2 || diags[0].Line != 1 || diags[1].Line != 3 {
+		t.Errorf("expected errors at lines 1 and 3, got %v", diags)
+	}
+}
+
+func TestCallWithOptions(t *testing.T) {
+	mb := NewMechanicalBasic()
+	if err := mb.Load(`
+function busy(n):
+    let total = 0
+    for i = 1 to n
+        total += i
+    next i
+    return total
+endfunction
+function forever():
+    do
+    loop
+endfunction
+`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var budgetErr *BudgetExceededError
+	if _, err := mb.CallWithOptions("busy", CallOptions{MaxStatements: 20}, 100); !errors.As(err, &budgetErr) || budgetErr.Limit != 20 {
+		t.Errorf("expected the call's statement budget to run out, got %v", err)
+	}
+	if _, err := mb.CallWithOptions("busy", CallOptions{MaxIterations: 10}, 100); err == nil {
+		t.Error("expected the call's iteration limit to be reached")
+	}
+	if result, err := mb.Call("busy", 100); err != nil || result != 5050 {
+		t.Errorf("expected the limits to apply to one call only, got %v, %v", result, err)
+	}
+
+	start := time.Now()
+	if _, err := mb.CallWithOptions("forever", CallOptions{Timeout: 20 * time.Millisecond, MaxIterations: 1 << 40}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the call to stop after its timeout, took %v", elapsed)
+	}
+}
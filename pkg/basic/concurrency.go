@@ -0,0 +1,45 @@
+package basic
+
+import "context"
+
+// SetConcurrent makes the instance safe to share between goroutines. Every
+// method then takes its turn on the instance, so runs and calls from many
+// request handlers are serialized: one runs at a time, and settings and
+// registrations wait for the run in progress. For runs that proceed in
+// parallel, give each goroutine its own instance with Clone or
+// NewMechanicalBasic; a Program from Compile can be run on all of them.
+//
+// Host functions must not call methods that take turns on the instance
+// running them, or the run deadlocks. Only Variables, CallStack and
+// EvalInFrame, which are for use from a debug hook, Stop and SetConcurrent
+// don't take turns and stay safe to call from anywhere.
+func (mb *MechBasic) SetConcurrent(concurrent bool) {
+	mb.concurrent.Store(concurrent)
+}
+
+// lock waits for the instance in concurrent mode. Call the returned function
+// to release it.
+func (mb *MechBasic) lock() func() {
+	if !mb.concurrent.Load() {
+		return func() {}
+	}
+	mb.turn <- struct{}{}
+	return mb.release
+}
+
+// lockContext is lock, giving up with ctx's error once ctx is done
+func (mb *MechBasic) lockContext(ctx context.Context) (func(), error) {
+	if !mb.concurrent.Load() {
+		return func() {}, nil
+	}
+	select {
+	case mb.turn <- struct{}{}:
+		return mb.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (mb *MechBasic) release() {
+	<-mb.turn
+}
@@ -0,0 +1,156 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentRuns(t *testing.T) {
+	mb := NewMechanicalBasic()
+	mb.SetConcurrent(true)
+	mb.SetPrintFunc(func(value any) {})
+	const double = "function double(n):\n    return n * 2\nendfunction\n"
+	if err := mb.Load(double); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for n := 0; n < 20; n++ {
+		wg.Add(2)
+		go func(n int) {
+			defer wg.Done()
+			globals := map[string]any{}
+			err := mb.Run(double+"let total = 0\nfor i = 1 to seed\n    total = total + i\nnext i", WithVars(map[string]any{"seed": n}), WithGlobalsInto(globals))
+			if err == nil && globals["total"] != n*(n+1)/2 {
+				err = fmt.Errorf("run %d: expected total %d, got %v", n, n*(n+1)/2, globals["total"])
+			}
+			errs <- err
+		}(n)
+		go func(n int) {
+			defer wg.Done()
+			result, err := mb.Call("double", n)
+			if err == nil && result != n*2 {
+				err = fmt.Errorf("call %d: expected %d, got %v", n, n*2, result)
+			}
+			errs <- err
+		}(n)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestConcurrentRunContextWaiting(t *testing.T) {
+	mb := NewMechanicalBasic()
+	mb.SetConcurrent(true)
+	started, release := make(chan struct{}), make(chan struct{})
+	mb.RegisterFunc("hold", func(args ...any) (any, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- mb.Run("hold()")
+	}()
+	<-started
+
+	// A run waiting for its turn gives up when its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := mb.RunContext(ctx, "print 1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClonesRunInParallel(t *testing.T) {
+	base := NewMechanicalBasic()
+	err := base.Load(`
+function isEven(n):
+    return n - (n / 2) * 2 = 0
+endfunction
+
+function roll(values):
+    let total = 0
+    for i = 1 to 100
+        total = total + rnd(6)
+    next i
+    return count_if(values, "isEven")
+endfunction
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for n := 0; n < 4; n++ {
+		clone := base.Clone()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := clone.Call("roll", []any{1, 2, 3, 4})
+			if err == nil && result != 2 {
+				err = fmt.Errorf("expected 2, got %v", result)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// A clone's random source is seeded from the original's
+	base.Seed(7)
+	first, _ := base.Clone().Eval("rnd(1000)")
+	base.Seed(7)
+	second, _ := base.Clone().Eval("rnd(1000)")
+	if first != second {
+		t.Errorf("expected clones of a seeded instance to repeat, got %v and %v", first, second)
+	}
+}
+
+func TestConcurrentSettings(t *testing.T) {
+	mb := NewMechanicalBasic()
+	mb.SetConcurrent(true)
+	mb.SetPrintFunc(func(value any) {})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 20; n++ {
+			mb.Run("for i = 1 to 50\n    print rnd(6)\nnext i")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 20; n++ {
+			mb.Seed(int64(n))
+			mb.SetOverflowMode(OverflowWrap)
+			mb.SetNumberFormat(NumberFormat{})
+			mb.RegisterRandomLibrary()
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,55 @@
+package basic
+
+import "github.com/mechanical-lich/mechanical-basic/internal/basic"
+
+// ErrCoroutineDone is returned by Resume once the coroutine's function has
+// returned, failed or been closed
+var ErrCoroutineDone = basic.ErrCoroutineDone
+
+// Coroutine is a call of a script function that can pause at YIELD and be
+// resumed later, so a behavior can wait a frame in the middle of a function.
+// Start one with StartCall. While it is paused the instance can run other
+// scripts and calls; the coroutine sees the global variables as they are
+// when it resumes.
+type Coroutine struct {
+	mb *MechBasic
+	co *basic.Coroutine
+}
+
+// StartCall calls a script function as a coroutine, running it until its
+// first YIELD or its end. An error from the function before it yields is
+// returned along with the finished coroutine.
+func (mb *MechBasic) StartCall(funcName string, args ...any) (*Coroutine, error) {
+	defer mb.lock()()
+	co, err := mb.interpreter.StartCall(funcName, args...)
+	return &Coroutine{mb: mb, co: co}, err
+}
+
+// Resume continues the coroutine from its YIELD, which evaluates to value in
+// the script, and runs it until the next YIELD, returning the value yielded,
+// or until the function returns, returning its result. Each resume starts
+// with a fresh statement budget.
+func (c *Coroutine) Resume(value any) (any, error) {
+	defer c.mb.lock()()
+	return c.co.Resume(value)
+}
+
+// Close stops a paused coroutine that is no longer needed, such as the
+// behavior of a despawned entity. Close coroutines abandoned before they
+// finish, or their goroutines stay blocked.
+func (c *Coroutine) Close() {
+	defer c.mb.lock()()
+	c.co.Close()
+}
+
+// Done reports whether the coroutine's function has returned, failed or been
+// closed
+func (c *Coroutine) Done() bool {
+	return c.co.Done()
+}
+
+// Value returns the value given to the last YIELD, or the function's result
+// once it has returned
+func (c *Coroutine) Value() any {
+	return c.co.Value()
+}
@@ -0,0 +1,49 @@
+package basic
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCoroutine(t *testing.T) {
+	mb := NewMechanicalBasic()
+	mb.SetConcurrent(true)
+	if err := mb.Load(`
+function open_door(steps):
+    let dt = 0
+    for i = 1 to steps
+        dt = yield i
+    next i
+    return "open after " + dt
+endfunction
+`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	co, err := mb.StartCall("open_door", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var steps []any
+	for !co.Done() {
+		steps = append(steps, co.Value())
+		if _, err := co.Resume(0.5); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(steps) != 3 || steps[0] != 1 || steps[2] != 3 {
+		t.Errorf("expected steps 1 to 3, got %v", steps)
+	}
+	if co.Value() != "open after 0.5" {
+		t.Errorf("expected the function's result, got %v", co.Value())
+	}
+	if _, err := co.Resume(nil); !errors.Is(err, ErrCoroutineDone) {
+		t.Errorf("expected ErrCoroutineDone, got %v", err)
+	}
+
+	co, _ = mb.StartCall("open_door", 10)
+	co.Close()
+	if !co.Done() {
+		t.Error("expected Close to finish the coroutine")
+	}
+}
@@ -0,0 +1,20 @@
+package basic
+
+// Library is a bundle of host functions, such as a string, time or JSON
+// library, that RegisterLibrary adds to an instance in one call
+type Library interface {
+	// Name is the namespace of the functions, e.g. "json" for json.parse(s)
+	Name() string
+	// Functions returns the functions by name, without the namespace
+	Functions() map[string]ExternalFunc
+}
+
+// RegisterLibrary registers the functions of lib under their own names, as
+// the built-in libraries are, and qualified with the library's name, so
+// json.parse(s) still works if another library also defines parse. The
+// names must be valid identifiers. With strict registration on, nothing is
+// registered if any name is taken.
+func (mb *MechBasic) RegisterLibrary(lib Library) error {
+	defer mb.lock()()
+	return mb.interpreter.RegisterLibrary(lib.Name(), lib.Functions())
+}
@@ -0,0 +1,62 @@
+package basic
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type shoutLibrary struct{}
+
+func (shoutLibrary) Name() string { return "shout" }
+
+func (shoutLibrary) Functions() map[string]ExternalFunc {
+	return map[string]ExternalFunc{
+		"upper": func(args ...any) (any, error) {
+			return strings.ToUpper(args[0].(string)) + "!", nil
+		},
+		"len": func(args ...any) (any, error) {
+			return len(args[0].(string)) * 2, nil
+		},
+	}
+}
+
+type badLibrary struct{}
+
+func (badLibrary) Name() string { return "not valid" }
+
+func (badLibrary) Functions() map[string]ExternalFunc { return nil }
+
+func TestRegisterLibrary(t *testing.T) {
+	mb := NewMechanicalBasic()
+	if err := mb.RegisterLibrary(shoutLibrary{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// upper is free, len replaces the string library's
+	tests := map[string]any{
+		`upper("hi")`:       "HI!",
+		`shout.upper("hi")`: "HI!",
+		`shout.len("abc")`:  6,
+		`len("abc")`:        6,
+	}
+	for code, expected := range tests {
+		got, err := mb.Eval(code)
+		if err != nil || got != expected {
+			t.Errorf("%s: expected %v, got %v (%v)", code, expected, got, err)
+		}
+	}
+
+	strict := NewMechanicalBasic()
+	strict.SetStrictRegistration(true)
+	if err := strict.RegisterLibrary(shoutLibrary{}); !errors.Is(err, ErrFunctionRegistered) {
+		t.Errorf("expected ErrFunctionRegistered for len, got %v", err)
+	}
+	if strict.HasExternalFunc("upper") || strict.HasExternalFunc("shout.upper") {
+		t.Error("expected nothing to be registered")
+	}
+
+	if err := mb.RegisterLibrary(badLibrary{}); err == nil {
+		t.Error("expected an error for an invalid library name")
+	}
+}
@@ -0,0 +1,80 @@
+package basic
+
+// BuiltinLibrary names one of the libraries NewMechanicalBasic registers
+type BuiltinLibrary string
+
+const (
+	MathLibrary     BuiltinLibrary = "math"
+	StringLibrary   BuiltinLibrary = "string"
+	ArrayLibrary    BuiltinLibrary = "array"
+	MapLibrary      BuiltinLibrary = "map"
+	MatrixLibrary   BuiltinLibrary = "matrix"
+	BufferLibrary   BuiltinLibrary = "buffer"
+	BitLibrary      BuiltinLibrary = "bit"
+	StatsLibrary    BuiltinLibrary = "stats"
+	PathLibrary     BuiltinLibrary = "path"
+	GeometryLibrary BuiltinLibrary = "geometry"
+	RandomLibrary   BuiltinLibrary = "random"
+	LocaleLibrary   BuiltinLibrary = "locale"
+	TestLibrary     BuiltinLibrary = "test"
+	HelpLibrary     BuiltinLibrary = "help"
+)
+
+// builtinLibraries lists the built-in libraries in the order they are registered
+var builtinLibraries = []struct {
+	lib      BuiltinLibrary
+	register func(*MechBasic)
+}{
+	{MathLibrary, (*MechBasic).RegisterMathLibrary},
+	{StringLibrary, (*MechBasic).RegisterStringLibrary},
+	{ArrayLibrary, (*MechBasic).RegisterArrayLibrary},
+	{MapLibrary, (*MechBasic).RegisterMapLibrary},
+	{MatrixLibrary, (*MechBasic).RegisterMatrixLibrary},
+	{BufferLibrary, (*MechBasic).RegisterBufferLibrary},
+	{BitLibrary, (*MechBasic).RegisterBitLibrary},
+	{StatsLibrary, (*MechBasic).RegisterStatsLibrary},
+	{PathLibrary, (*MechBasic).RegisterPathLibrary},
+	{GeometryLibrary, (*MechBasic).RegisterGeometryLibrary},
+	{RandomLibrary, (*MechBasic).RegisterRandomLibrary},
+	{LocaleLibrary, (*MechBasic).RegisterLocaleLibrary},
+	{TestLibrary, (*MechBasic).RegisterTestLibrary},
+	{HelpLibrary, (*MechBasic).RegisterHelpLibrary},
+}
+
+// Option configures a new instance
+type Option func(*options)
+
+type options struct {
+	only map[BuiltinLibrary]bool // Built-in libraries to register, nil for all
+	skip map[BuiltinLibrary]bool // Built-in libraries not to register
+}
+
+// WithLibraries registers only the given built-in libraries, so a host can
+// provide its own pow or rnd without them being registered first. With no
+// arguments no built-in library is registered.
+func WithLibraries(libs ...BuiltinLibrary) Option {
+	return func(o *options) {
+		o.only = make(map[BuiltinLibrary]bool, len(libs))
+		for _, lib := range libs {
+			o.only[lib] = true
+		}
+	}
+}
+
+// WithoutLibraries registers every built-in library except the given ones.
+// With no arguments every built-in library is registered.
+func WithoutLibraries(libs ...BuiltinLibrary) Option {
+	return func(o *options) {
+		if o.skip == nil {
+			o.skip = make(map[BuiltinLibrary]bool, len(libs))
+		}
+		for _, lib := range libs {
+			o.skip[lib] = true
+		}
+	}
+}
+
+// registers reports whether the built-in library is to be registered
+func (o *options) registers(lib BuiltinLibrary) bool {
+	return (o.only == nil || o.only[lib]) && !o.skip[lib]
+}
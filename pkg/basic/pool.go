@@ -0,0 +1,36 @@
+package basic
+
+// Pool hands out lightweight instances for scripts that run side by side,
+// such as one per game entity. The instances share the registered functions,
+// AST cache and random source of the pool's base instance, so each one costs
+// little more than its own variables, and a script parsed by one is cached
+// for all.
+//
+// Like a single instance, a pool and the instances it hands out must be used
+// from one goroutine at a time.
+type Pool struct {
+	base *MechBasic
+}
+
+// NewPool creates a pool from base. Register functions and change settings
+// on base first: instances start with a copy of its settings, and functions
+// registered later on base or any instance are seen by all of them.
+func NewPool(base *MechBasic) *Pool {
+	return &Pool{base: base}
+}
+
+// Base returns the instance the pool's instances are made from
+func (p *Pool) Base() *MechBasic {
+	return p.base
+}
+
+// Get returns a new instance with its own variables, script functions and
+// execution state. There is nothing to give back; an instance that is no
+// longer needed is simply dropped.
+func (p *Pool) Get() *MechBasic {
+	return &MechBasic{
+		interpreter: p.base.interpreter.Spawn(),
+		strings:     p.base.strings,
+		turn:        make(chan struct{}, 1),
+	}
+}
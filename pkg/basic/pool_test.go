@@ -0,0 +1,61 @@
+package basic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPoolInstances(t *testing.T) {
+	base := NewMechanicalBasic()
+	var printed []any
+	base.SetPrintFunc(func(value any) { printed = append(printed, value) })
+	base.RegisterFunc("damage", func(args ...any) (any, error) {
+		return 7, nil
+	})
+	pool := NewPool(base)
+
+	script := "let hp = hp - damage()\nprint name + \" \" + hp"
+	goblin, orc := pool.Get(), pool.Get()
+	if err := goblin.Run(script, WithVars(map[string]any{"name": "goblin", "hp": 10})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := orc.Run(script, WithVars(map[string]any{"name": "orc", "hp": 30})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []any{"goblin 3", "orc 23"}; !reflect.DeepEqual(printed, expected) {
+		t.Errorf("expected %v, got %v", expected, printed)
+	}
+	if entries := base.CachedPrograms(); len(entries) != 1 {
+		t.Errorf("expected one shared cache entry, got %v", entries)
+	}
+	if value, err := goblin.Eval("name"); err != nil || value != "goblin" {
+		t.Errorf("expected each instance to keep its own variables, got %v (%v)", value, err)
+	}
+	if base.HasVariable("name") {
+		t.Error("expected the base instance's variables to be untouched")
+	}
+}
+
+func TestPoolInstanceCallbacks(t *testing.T) {
+	base := NewMechanicalBasic()
+	var printed []any
+	base.SetPrintFunc(func(value any) { printed = append(printed, value) })
+	pool := NewPool(base)
+
+	script := `
+function isEven(n):
+    return n - (n / 2) * 2 = 0
+endfunction
+
+print count_if(values, "isEven")
+print len(filter(values, "isEven"))
+`
+	instance := pool.Get()
+	if err := instance.Run(script, WithVars(map[string]any{"values": []any{1, 2, 3, 4, 5, 6}})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []any{3, 3}; !reflect.DeepEqual(printed, expected) {
+		t.Errorf("expected %v, got %v", expected, printed)
+	}
+}
@@ -0,0 +1,39 @@
+package basic
+
+import "github.com/mechanical-lich/mechanical-basic/internal/basic"
+
+// Program is a script parsed once by Compile, ready to run on any instance.
+// Running a program never changes it, so it can be shared by instances
+// running in parallel: one parse of goblin.bas can drive every goblin.
+type Program struct {
+	program *basic.Program
+}
+
+// Compile parses the script, with the files it INCLUDEs, without running it.
+// Instances that run the program should use the same case sensitivity
+// setting as this one.
+func (mb *MechBasic) Compile(code string) (*Program, error) {
+	defer mb.lock()()
+
+	prog, err := mb.interpreter.Compile(code)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{program: prog}, nil
+}
+
+// RunProgram is Run for a program made by Compile
+func (mb *MechBasic) RunProgram(prog *Program, opts ...RunOption) error {
+	defer mb.lock()()
+
+	cfg := newRunConfig(opts)
+	err := mb.interpreter.InterpretProgram(prog.program, cfg.vars)
+	mb.exportGlobals(cfg)
+	return err
+}
+
+// LoadProgram is Load for a program made by Compile
+func (mb *MechBasic) LoadProgram(prog *Program) error {
+	defer mb.lock()()
+	return mb.interpreter.LoadProgram(prog.program)
+}
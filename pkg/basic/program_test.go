@@ -0,0 +1,87 @@
+package basic
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestProgramSharedBetweenInstances(t *testing.T) {
+	compiler := NewMechanicalBasic()
+	goblin, err := compiler.Compile(`type stats
+    hp
+    speed
+endtype
+let me = stats(10, 2)
+dim path(10)
+let moves = 0
+function update(steps):
+    for n = 1 to steps
+        moves += 1
+        path(moves) = path(moves - 1) + me.speed
+    next
+    try
+        if moves > 5 then throw "tired"
+    catch reason
+        return reason
+    endtry
+    return path(moves)
+endfunction`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	counter, err := compiler.Compile("let total = start\nfor n = 1 to 100\n    total += n\nnext")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const instances = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, instances)
+	for idx := 0; idx < instances; idx++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			mb := NewMechanicalBasic()
+
+			if err := mb.LoadProgram(goblin); err != nil {
+				errs <- err
+				return
+			}
+			for _, expected := range []any{4, 8, "tired"} {
+				got, err := mb.Call("update", 2)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if got != expected {
+					errs <- fmt.Errorf("instance %d: expected %v, got %v", idx, expected, got)
+					return
+				}
+			}
+
+			globals := map[string]any{}
+			err := mb.RunProgram(counter, WithVars(map[string]any{"start": idx}), WithGlobalsInto(globals))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if globals["total"] != idx+5050 {
+				errs <- fmt.Errorf("instance %d: expected total %d, got %v", idx, idx+5050, globals["total"])
+			}
+		}(idx)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	mb := NewMechanicalBasic()
+	if _, err := mb.Compile("let = 1"); err == nil {
+		t.Error("expected a parse error")
+	}
+}
@@ -0,0 +1,91 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/internal/basic"
+)
+
+// REPL runs code entered a line at a time, as in a live script console.
+// Lines are collected until they form a complete entry, so a block such as
+// IF ... ENDIF or FUNCTION ... ENDFUNCTION can span several lines. The
+// variables, functions and record types an entry defines stay defined for
+// the entries after it, as does a handler set by ON ERROR CALL. Entries
+// aren't added to the AST cache.
+type REPL struct {
+	mb      *MechBasic
+	pending []string
+	output  strings.Builder
+}
+
+// REPLResult is what a complete entry produced
+type REPLResult struct {
+	Output string // Text printed by the entry, each PRINT ending with a newline unless it ends in ;
+	Value  any    // Value of the entry's last expression, nil if it has none
+}
+
+// NewREPL creates a console running entries on mb. The console takes over
+// mb's print function to collect what entries print; register functions on
+// mb as usual, before or after.
+func NewREPL(mb *MechBasic) *REPL {
+	r := &REPL{mb: mb}
+	mb.SetRawPrintFunc(func(value any, newline bool) {
+		if newline {
+			fmt.Fprintln(&r.output, value)
+		} else {
+			fmt.Fprint(&r.output, value)
+		}
+	})
+	return r
+}
+
+// Feed adds a line to the entry being entered. While the entry is
+// incomplete, such as an IF without its ENDIF or a line ending in _, Feed
+// returns nil and Pending reports true. Once it is complete the entry runs
+// and Feed returns what it printed and the value of its last expression. An
+// entry that fails is dropped, and its error is returned with the output
+// printed before it failed.
+func (r *REPL) Feed(line string) (*REPLResult, error) {
+	defer r.mb.lock()()
+
+	r.pending = append(r.pending, line)
+	code := strings.Join(r.pending, "\n")
+	r.output.Reset()
+
+	value, err := r.mb.interpreter.EvaluateSession(code)
+	if err != nil && incomplete(code, err) {
+		return nil, nil
+	}
+	r.pending = nil
+	result := &REPLResult{Output: r.output.String(), Value: value}
+	r.output.Reset()
+	return result, err
+}
+
+// Pending reports whether Feed is waiting for more lines to complete an entry
+func (r *REPL) Pending() bool {
+	return len(r.pending) > 0
+}
+
+// Cancel drops the lines of an incomplete entry, as a console does when
+// Ctrl+C is pressed at a continuation prompt
+func (r *REPL) Cancel() {
+	r.pending = nil
+}
+
+// incomplete reports whether err is a syntax error at the very end of the
+// code, which more lines could fix
+func incomplete(code string, err error) bool {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Kind != ErrorSyntax || parseErr.File != "" {
+		return false
+	}
+	tokens, tokErr := basic.Tokenize(code)
+	if tokErr != nil {
+		return false
+	}
+	eof := tokens[len(tokens)-1]
+	return parseErr.Line == eof.Line && parseErr.Column == eof.Column
+}
@@ -0,0 +1,100 @@
+package basic
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	repl := NewREPL(NewMechanicalBasic())
+
+	feed := func(line string) *REPLResult {
+		t.Helper()
+		result, err := repl.Feed(line)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", line, err)
+		}
+		return result
+	}
+
+	if result := feed("let hp = 10"); result == nil || result.Output != "" || result.Value != nil {
+		t.Errorf("expected an empty result for an assignment, got %+v", result)
+	}
+	for _, line := range []string{"function heal(n):", "if n > 0 then", "    hp += n"} {
+		if result := feed(line); result != nil || !repl.Pending() {
+			t.Fatalf("%s: expected the entry to be incomplete, got %+v", line, result)
+		}
+	}
+	feed("endif")
+	if result := feed("endfunction"); result == nil || repl.Pending() {
+		t.Fatalf("expected the function to be complete, got %+v", result)
+	}
+
+	feed("heal(5)")
+	result := feed("print \"hp:\"; : print hp: hp * 2")
+	if result.Output != "hp:15\n" || result.Value != 30 {
+		t.Errorf("expected output %q and value 30, got %+v", "hp:15\n", result)
+	}
+
+	feed("let total = hp + _")
+	if result := feed("    1"); result == nil || result.Value != nil {
+		t.Fatalf("expected the continued line to complete the entry, got %+v", result)
+	}
+	if result := feed("total"); result.Value != 16 {
+		t.Errorf("expected 16, got %v", result.Value)
+	}
+}
+
+func TestREPLErrors(t *testing.T) {
+	repl := NewREPL(NewMechanicalBasic())
+
+	result, err := repl.Feed("print 1: throw \"boom\"")
+	if err == nil || result == nil || result.Output != "1\n" {
+		t.Fatalf("expected the error with the output before it, got %+v, %v", result, err)
+	}
+
+	_, err = repl.Feed("1 / 0")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Line != 1 || runtimeErr.Column != 1 {
+		t.Fatalf("expected a runtime error at line 1, column 1, got %v", err)
+	}
+
+	_, err = repl.Feed("print )")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || repl.Pending() {
+		t.Fatalf("expected a syntax error that isn't left pending, got %v", err)
+	}
+
+	repl.Feed("for i = 1 to 3")
+	repl.Cancel()
+	if repl.Pending() {
+		t.Fatal("expected Cancel to drop the incomplete entry")
+	}
+	if result, err := repl.Feed("2 + 2"); err != nil || result.Value != 4 {
+		t.Errorf("expected 4, got %+v, %v", result, err)
+	}
+}
+
+func TestREPLSession(t *testing.T) {
+	mb := NewMechanicalBasic()
+	repl := NewREPL(mb)
+
+	for _, line := range []string{
+		"sub report(message, line):",
+		"    print \"error: \" + message",
+		"endsub",
+		"on error call report",
+	} {
+		if _, err := repl.Feed(line); err != nil {
+			t.Fatalf("%s: unexpected error: %v", line, err)
+		}
+	}
+	result, err := repl.Feed("throw \"boom\"")
+	if err != nil || result.Output != "error: boom\n" {
+		t.Errorf("expected the handler from an earlier entry to run, got %+v, %v", result, err)
+	}
+
+	if entries := mb.CachedPrograms(); len(entries) != 0 {
+		t.Errorf("expected console entries not to be cached, got %v", entries)
+	}
+}
@@ -0,0 +1,179 @@
+package format
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/ast"
+)
+
+// operators are the binary operators with their text and precedence, which
+// decides where parentheses are needed. The order matches the parser's.
+var operators = map[ast.TokenType]struct {
+	text string
+	prec int
+}{
+	ast.TOKEN_OR:    {"or", 1},
+	ast.TOKEN_AND:   {"and", 2},
+	ast.TOKEN_EQ:    {"=", 3},
+	ast.TOKEN_NEQ:   {"<>", 3},
+	ast.TOKEN_LT:    {"<", 4},
+	ast.TOKEN_GT:    {">", 4},
+	ast.TOKEN_LTE:   {"<=", 4},
+	ast.TOKEN_GTE:   {">=", 4},
+	ast.TOKEN_PIPE:  {"|", 5},
+	ast.TOKEN_XOR:   {"xor", 5},
+	ast.TOKEN_AMP:   {"&", 6},
+	ast.TOKEN_SHL:   {"<<", 7},
+	ast.TOKEN_SHR:   {">>", 7},
+	ast.TOKEN_PLUS:  {"+", 8},
+	ast.TOKEN_MINUS: {"-", 8},
+	ast.TOKEN_STAR:  {"*", 9},
+	ast.TOKEN_SLASH: {"/", 9},
+}
+
+// expr returns an expression with only the parentheses it needs
+func (p *printer) expr(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.IntLiteral:
+		if text, ok := p.literals[start(e)]; ok {
+			return text
+		}
+		return strconv.Itoa(e.Value)
+	case *ast.FloatLiteral:
+		if text, ok := p.literals[start(e)]; ok {
+			return text
+		}
+		text := strconv.FormatFloat(e.Value, 'f', -1, 64)
+		if !strings.Contains(text, ".") {
+			text += ".0"
+		}
+		return text
+	case *ast.StringLiteral:
+		return quote(e.Value)
+	case *ast.BoolLiteral:
+		return strconv.FormatBool(e.Value)
+	case *ast.NullLiteral:
+		return "null"
+	case *ast.Identifier:
+		return e.Name
+
+	case *ast.BinaryExpr:
+		op := operators[e.Operator]
+		return p.operand(e.Left, op.prec, false) + " " + op.text + " " + p.operand(e.Right, op.prec, true)
+	case *ast.UnaryExpr:
+		operand := p.expr(e.Operand)
+		switch e.Operand.(type) {
+		case *ast.BinaryExpr, *ast.YieldExpr:
+			operand = "(" + operand + ")"
+		}
+		if e.Operator == ast.TOKEN_NOT {
+			return "not " + operand
+		}
+		if strings.HasPrefix(operand, "-") {
+			operand = "(" + operand + ")" // -(-x), since --x is a decrement
+		}
+		return "-" + operand
+
+	case *ast.CallExpr:
+		args := make([]string, 0, len(e.Args)+len(e.Named))
+		for _, arg := range e.Args {
+			text := p.expr(arg)
+			if looksNamed(arg) {
+				text = "(" + text + ")"
+			}
+			args = append(args, text)
+		}
+		for _, arg := range e.Named {
+			args = append(args, arg.Name+" = "+p.expr(arg.Value))
+		}
+		return e.Name + "(" + strings.Join(args, ", ") + ")"
+	case *ast.ConditionalExpr:
+		return "iif(" + p.list([]ast.Expression{e.Condition, e.Then, e.Else}) + ")"
+	case *ast.IndexExpr:
+		return e.Name + "(" + p.list(e.Indices) + ")"
+	case *ast.SliceExpr:
+		text := p.primary(e.Target) + "["
+		if e.Start != nil {
+			text += p.expr(e.Start)
+		}
+		if e.Slice {
+			text += ":"
+			if e.End != nil {
+				text += p.expr(e.End)
+			}
+		}
+		return text + "]"
+	case *ast.MemberExpr:
+		return p.primary(e.Target) + "." + e.Field
+	case *ast.YieldExpr:
+		if e.Value == nil {
+			return "yield"
+		}
+		return "yield " + p.expr(e.Value)
+	}
+	return ""
+}
+
+// operand returns an operand of a binary operator of the given precedence.
+// Operators group left to right, so a right operand of the same precedence
+// keeps its parentheses.
+func (p *printer) operand(expr ast.Expression, prec int, right bool) string {
+	text := p.expr(expr)
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		inner := operators[e.Operator].prec
+		if inner < prec || (right && inner == prec) {
+			return "(" + text + ")"
+		}
+	case *ast.YieldExpr:
+		return "(" + text + ")" // Its value would take in the rest of the expression
+	}
+	return text
+}
+
+// primary returns the target of an index, slice or field
+func (p *printer) primary(expr ast.Expression) string {
+	switch expr.(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr, *ast.YieldExpr:
+		return "(" + p.expr(expr) + ")"
+	}
+	return p.expr(expr)
+}
+
+// value returns the value of an assignment, parenthesizing a comparison with
+// = so it isn't mistaken for a second assignment
+func (p *printer) value(expr ast.Expression) string {
+	if b, ok := expr.(*ast.BinaryExpr); ok && b.Operator == ast.TOKEN_EQ {
+		return "(" + p.expr(expr) + ")"
+	}
+	return p.expr(expr)
+}
+
+func (p *printer) list(exprs []ast.Expression) string {
+	texts := make([]string, len(exprs))
+	for idx, expr := range exprs {
+		texts[idx] = p.expr(expr)
+	}
+	return strings.Join(texts, ", ")
+}
+
+// looksNamed reports whether a call argument would print starting with
+// name =, which the parser reads as an argument passed by name
+func looksNamed(expr ast.Expression) bool {
+	for {
+		b, ok := expr.(*ast.BinaryExpr)
+		if !ok {
+			return false
+		}
+		if _, ident := b.Left.(*ast.Identifier); ident && b.Operator == ast.TOKEN_EQ {
+			return true
+		}
+		expr = b.Left
+	}
+}
+
+// quote returns a string literal
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s) + `"`
+}
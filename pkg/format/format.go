@@ -0,0 +1,464 @@
+// Package format prints MechanicalBasic scripts in a canonical layout, for
+// script editors and content pipelines that keep scripts consistent.
+//
+// Keywords are written in lower case and blocks are indented four spaces.
+// Operators and commas are spaced, redundant parentheses are removed, and a
+// comparison assigned to a variable is parenthesized: LET ok = (a = b).
+// Comments, single blank lines, the spelling of names and number literals,
+// and single-line IFs are kept. Statements separated by colons are put on
+// lines of their own, and lines continued with _ are joined.
+//
+// Minify goes the other way, shrinking a script to ship it.
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/ast"
+)
+
+// Format returns a script in the canonical layout. A script that doesn't
+// parse fails with a *ast.ParseError and isn't changed.
+func Format(code string) (string, error) {
+	tokens, err := ast.Tokenize(code)
+	if err != nil {
+		return "", err
+	}
+	comments, err := ast.Comments(code)
+	if err != nil {
+		return "", err
+	}
+	prog, err := ast.Parse(code)
+	if err != nil {
+		return "", err
+	}
+
+	p := newPrinter(tokens, comments)
+	p.blockStart = true
+	p.statements(prog.Statements, end)
+	p.flush(end)
+	if len(p.lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(p.lines, "\n") + "\n", nil
+}
+
+// pos is a position in the source, ordered line first
+type pos struct {
+	line, col int
+}
+
+// end is after every position in a script
+var end = pos{line: int(^uint(0) >> 1)}
+
+func (a pos) before(b pos) bool {
+	return a.line < b.line || (a.line == b.line && a.col < b.col)
+}
+
+func start(node ast.Node) pos {
+	line, col := node.Position()
+	return pos{line, col}
+}
+
+// comment is a comment waiting to be printed
+type comment struct {
+	ast.Comment
+	trailing bool // Follows code on its line
+}
+
+// printer writes a script while walking its syntax tree. The tree doesn't
+// hold comments, the text of literals or the positions of keywords that end
+// blocks, so those are found in the tokens.
+type printer struct {
+	lines  []string
+	indent int
+
+	tokens   []ast.Token
+	literals map[pos]string // Number literals as written
+	content  map[int]bool   // Lines holding code or a comment
+	comments []comment
+	next     int // Index of the next comment to print
+
+	blockStart bool // Nothing printed yet in the current block
+	lastCode   bool // The last line printed is code, which a comment can trail
+}
+
+func newPrinter(tokens []ast.Token, comments []ast.Comment) *printer {
+	p := &printer{
+		tokens:   tokens,
+		literals: make(map[pos]string),
+		content:  make(map[int]bool),
+	}
+
+	firstCode := make(map[int]int) // Column of the first token of each line
+	for _, tok := range tokens {
+		switch tok.Type {
+		case ast.TOKEN_NEWLINE, ast.TOKEN_EOF:
+			continue
+		case ast.TOKEN_INT, ast.TOKEN_FLOAT:
+			p.literals[pos{tok.Line, tok.Column}] = tok.Value
+		}
+		p.content[tok.Line] = true
+		if col, ok := firstCode[tok.Line]; !ok || tok.Column < col {
+			firstCode[tok.Line] = tok.Column
+		}
+	}
+	for _, c := range comments {
+		p.content[c.Line] = true
+		col, ok := firstCode[c.Line]
+		p.comments = append(p.comments, comment{Comment: c, trailing: ok && col < c.Column})
+	}
+	return p
+}
+
+// line prints a statement, comment or field that starts on the given source
+// line, keeping a blank line above it if the source has one
+func (p *printer) line(source int, text string) {
+	if !p.blockStart && source > 1 && !p.content[source-1] {
+		p.lines = append(p.lines, "")
+	}
+	p.closer(text)
+}
+
+// closer prints a line that doesn't start a statement, such as ENDIF
+func (p *printer) closer(text string) {
+	p.lines = append(p.lines, strings.Repeat("    ", p.indent)+text)
+	p.blockStart = false
+	p.lastCode = true
+}
+
+// flush prints the comments before the given position. A comment that
+// trailed code does so again if that code was the last line printed.
+func (p *printer) flush(before pos) {
+	for ; p.next < len(p.comments); p.next++ {
+		c := p.comments[p.next]
+		if !(pos{c.Line, c.Column}).before(before) {
+			return
+		}
+		if c.trailing && p.lastCode {
+			p.lines[len(p.lines)-1] += "  " + c.Text
+		} else {
+			p.line(c.Line, c.Text)
+		}
+		p.lastCode = false
+	}
+}
+
+// last returns the position of the last token of the given type before a
+// position, which is how the keywords ending blocks are found: the ENDIF of
+// an IF is the last one before the statement after it
+func (p *printer) last(typ ast.TokenType, before pos) (pos, int) {
+	idx := sort.Search(len(p.tokens), func(idx int) bool {
+		tok := p.tokens[idx]
+		return !(pos{tok.Line, tok.Column}).before(before)
+	})
+	for idx--; idx >= 0; idx-- {
+		if tok := p.tokens[idx]; tok.Type == typ {
+			return pos{tok.Line, tok.Column}, idx
+		}
+	}
+	return before, -1
+}
+
+// statements prints a list of statements ending before the given position
+func (p *printer) statements(stmts []ast.Statement, end pos) {
+	for idx, stmt := range stmts {
+		next := end
+		if idx+1 < len(stmts) {
+			next = start(stmts[idx+1])
+		}
+		p.flush(start(stmt))
+		p.statement(stmt, next)
+	}
+}
+
+// block prints the indented body of a block, ending before the keyword at
+// end, together with the comments above that keyword
+func (p *printer) block(stmts []ast.Statement, end pos) {
+	p.indent++
+	p.blockStart = true
+	p.statements(stmts, end)
+	p.flush(end)
+	p.indent--
+}
+
+// statement prints a statement, which ends before next
+func (p *printer) statement(stmt ast.Statement, next pos) {
+	line, _ := stmt.Position()
+
+	switch s := stmt.(type) {
+	case *ast.IfStatement:
+		if text, ok := p.inline(s); ok {
+			p.line(line, text)
+			return
+		}
+		endif, _ := p.last(ast.TOKEN_ENDIF, next)
+		elsePos := endif
+		if s.ElseBlock != nil {
+			bound := endif
+			if len(s.ElseBlock) > 0 {
+				bound = start(s.ElseBlock[0])
+			}
+			elsePos, _ = p.last(ast.TOKEN_ELSE, bound)
+		}
+
+		// Each branch ends where the next ELSEIF or ELSE starts
+		p.line(line, "if "+p.expr(s.Condition)+" then")
+		body := s.ThenBlock
+		for _, clause := range s.ElseIfClauses {
+			p.block(body, pos{clause.Line, clause.Column})
+			p.closer("elseif " + p.expr(clause.Condition) + " then")
+			body = clause.Block
+		}
+		p.block(body, elsePos)
+		if s.ElseBlock != nil {
+			p.closer("else")
+			p.block(s.ElseBlock, endif)
+		}
+		p.closer("endif")
+
+	case *ast.ForStatement:
+		nextPos, idx := p.last(ast.TOKEN_NEXT, next)
+		p.line(line, "for "+s.Variable+" = "+p.expr(s.Start)+" to "+p.expr(s.End))
+		p.block(s.Body, nextPos)
+		closing := "next"
+		if idx >= 0 && idx+1 < len(p.tokens) && p.tokens[idx+1].Type == ast.TOKEN_IDENTIFIER {
+			closing += " " + s.Variable
+		}
+		p.closer(closing)
+
+	case *ast.DoLoopStatement:
+		loop, _ := p.last(ast.TOKEN_LOOP, next)
+		condition := ""
+		if s.Condition != nil {
+			condition = " while " + p.expr(s.Condition)
+			if s.Until {
+				condition = " until " + p.expr(s.Condition)
+			}
+		}
+		if s.PreTest {
+			p.line(line, "do"+condition)
+			p.block(s.Body, loop)
+			p.closer("loop")
+		} else {
+			p.line(line, "do")
+			p.block(s.Body, loop)
+			p.closer("loop" + condition)
+		}
+
+	case *ast.TryStatement:
+		endtry, _ := p.last(ast.TOKEN_ENDTRY, next)
+		bound := endtry
+		if len(s.Handler) > 0 {
+			bound = start(s.Handler[0])
+		}
+		catch, _ := p.last(ast.TOKEN_CATCH, bound)
+		p.line(line, "try")
+		p.block(s.Body, catch)
+		p.closer(strings.TrimSpace("catch " + s.ErrorVar))
+		p.block(s.Handler, endtry)
+		p.closer("endtry")
+
+	case *ast.FunctionStatement:
+		kind, terminator := "function", ast.TOKEN_ENDFUNCTION
+		if s.IsSub {
+			kind, terminator = "sub", ast.TOKEN_ENDSUB
+		}
+		endPos, _ := p.last(terminator, next)
+		params := make([]string, len(s.Params))
+		for idx, param := range s.Params {
+			if s.Types[idx] != "" {
+				param += " as " + s.Types[idx]
+			}
+			if s.Defaults[idx] != nil {
+				param += " = " + p.expr(s.Defaults[idx])
+			}
+			params[idx] = param
+		}
+		p.line(line, kind+" "+s.Name+"("+strings.Join(params, ", ")+"):")
+		p.block(s.Body, endPos)
+		p.closer("end" + kind)
+
+	case *ast.TypeStatement:
+		endtype, idx := p.last(ast.TOKEN_ENDTYPE, next)
+		p.line(line, "type "+s.Name)
+		p.indent++
+		p.blockStart = true
+		fields := p.fieldPositions(start(s), idx)
+		for n, field := range s.Fields {
+			if n < len(fields) {
+				p.flush(fields[n])
+				p.line(fields[n].line, field)
+			} else {
+				p.closer(field)
+			}
+		}
+		p.flush(endtype)
+		p.indent--
+		p.closer("endtype")
+
+	default:
+		p.line(line, p.simple(stmt))
+	}
+}
+
+// fieldPositions returns the positions of the field names of the TYPE at
+// start, whose ENDTYPE is the token at index end
+func (p *printer) fieldPositions(start pos, end int) []pos {
+	var fields []pos
+	named := false
+	for idx := 0; idx < end; idx++ {
+		tok := p.tokens[idx]
+		if tok.Type != ast.TOKEN_IDENTIFIER || (pos{tok.Line, tok.Column}).before(start) {
+			continue
+		}
+		if named {
+			fields = append(fields, pos{tok.Line, tok.Column})
+		}
+		named = true // The first identifier is the type's name
+	}
+	return fields
+}
+
+// inline returns a statement that fits on one line, as the statements of a
+// single-line IF do, or false for a block
+func (p *printer) inline(stmt ast.Statement) (string, bool) {
+	switch s := stmt.(type) {
+	case *ast.IfStatement:
+		line, _ := s.Position()
+		if len(s.ThenBlock) == 0 || len(s.ElseIfClauses) > 0 {
+			return "", false
+		}
+		if thenLine, _ := s.ThenBlock[0].Position(); thenLine != line {
+			return "", false
+		}
+		then, ok := p.inlineList(s.ThenBlock)
+		if !ok {
+			return "", false
+		}
+		text := "if " + p.expr(s.Condition) + " then " + then
+		if len(s.ElseBlock) > 0 {
+			otherwise, ok := p.inlineList(s.ElseBlock)
+			if !ok {
+				return "", false
+			}
+			text += " else " + otherwise
+		}
+		return text, true
+	case *ast.ForStatement, *ast.DoLoopStatement, *ast.TryStatement, *ast.FunctionStatement, *ast.TypeStatement:
+		return "", false
+	}
+	return p.simple(stmt), true
+}
+
+func (p *printer) inlineList(stmts []ast.Statement) (string, bool) {
+	texts := make([]string, len(stmts))
+	for idx, stmt := range stmts {
+		text, ok := p.inline(stmt)
+		if !ok {
+			return "", false
+		}
+		texts[idx] = text
+	}
+	return strings.Join(texts, ": "), true
+}
+
+// simple returns a statement that isn't a block
+func (p *printer) simple(stmt ast.Statement) string {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		return "let " + s.Name + " = " + p.value(s.Value)
+	case *ast.LocalStatement:
+		return "local " + s.Name + " = " + p.value(s.Value)
+	case *ast.ConstStatement:
+		return "const " + s.Name + " = " + p.value(s.Value)
+	case *ast.GlobalStatement:
+		return "global " + strings.Join(s.Names, ", ")
+	case *ast.DimStatement:
+		return "dim " + s.Name + "(" + p.list(s.Sizes) + ")"
+	case *ast.AssignStatement:
+		return p.assign(s)
+	case *ast.MultiAssignStatement:
+		targets := make([]string, len(s.Targets))
+		for idx, target := range s.Targets {
+			targets[idx] = p.target(target)
+		}
+		values := make([]string, len(s.Values))
+		for idx, value := range s.Values {
+			values[idx] = p.value(value)
+		}
+		text := strings.Join(targets, ", ") + " = " + strings.Join(values, ", ")
+		if s.Let {
+			text = "let " + text
+		}
+		return text
+	case *ast.IncludeStatement:
+		return "include " + quote(s.Path)
+	case *ast.ImportStatement:
+		return "import " + quote(s.Module)
+	case *ast.OptionStatement:
+		return "option " + strings.ToLower(s.Name)
+	case *ast.BreakStatement:
+		return "break"
+	case *ast.ExitStatement:
+		text := "exit " + strings.ToLower(s.Kind.String())
+		if s.Variable != "" {
+			text += " " + s.Variable
+		}
+		return text
+	case *ast.OnErrorStatement:
+		return "on error call " + s.Handler
+	case *ast.ThrowStatement:
+		return "throw " + p.expr(s.Message)
+	case *ast.LabelStatement:
+		return s.Name + ":"
+	case *ast.GotoStatement:
+		return "goto " + s.Label
+	case *ast.ReturnStatement:
+		if s.Value == nil {
+			return "return"
+		}
+		return "return " + p.expr(s.Value)
+	case *ast.PrintStatement:
+		text := "print "
+		if s.Using != nil {
+			text += "using " + p.expr(s.Using) + "; "
+		}
+		text += p.list(s.Values)
+		if s.NoNewline {
+			text += ";"
+		}
+		return text
+	case *ast.ExpressionStatement:
+		return p.expr(s.Expr)
+	}
+	return ""
+}
+
+func (p *printer) assign(s *ast.AssignStatement) string {
+	target := p.target(s)
+	switch s.Operator {
+	case ast.TOKEN_PLUS_PLUS:
+		return target + "++"
+	case ast.TOKEN_MINUS_MINUS:
+		return target + "--"
+	case ast.TOKEN_PLUS_EQ:
+		return target + " += " + p.expr(s.Value)
+	case ast.TOKEN_MINUS_EQ:
+		return target + " -= " + p.expr(s.Value)
+	}
+	return target + " = " + p.value(s.Value)
+}
+
+// target returns the variable, element or field an assignment sets
+func (p *printer) target(s *ast.AssignStatement) string {
+	text := s.Name
+	if len(s.Indices) > 0 {
+		text += "(" + p.list(s.Indices) + ")"
+	}
+	for _, field := range s.Fields {
+		text += "." + field
+	}
+	return text
+}
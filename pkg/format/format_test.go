@@ -0,0 +1,253 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/ast"
+	"github.com/mechanical-lich/mechanical-basic/pkg/basic"
+)
+
+func TestFormat(t *testing.T) {
+	code := `REM inventory helpers
+OPTION EXPLICIT
+## Heals a unit.
+FUNCTION Heal(hp AS integer,amount=5):   # clamps at 100
+  IF hp<0 THEN RETURN 0
+  LET total=(hp+amount)*1
+  IF total>100 THEN
+      total=100
+  ELSEIF total = 50 THEN
+    # exactly half
+    total  +=  1
+  ELSE
+     total--
+  ENDIF
+  RETURN total
+ENDFUNCTION
+
+
+TYPE Point
+  x   # across
+  y
+ENDTYPE
+let same=1=2
+let mask = 0xFF & 0b1010
+FOR i=1 TO 2
+  PRINT USING "##";i;
+NEXT i
+x = 1: y = 2
+`
+	expected := `REM inventory helpers
+option explicit
+## Heals a unit.
+function Heal(hp as integer, amount = 5):  # clamps at 100
+    if hp < 0 then return 0
+    let total = (hp + amount) * 1
+    if total > 100 then
+        total = 100
+    elseif total = 50 then
+        # exactly half
+        total += 1
+    else
+        total--
+    endif
+    return total
+endfunction
+
+type Point
+    x  # across
+    y
+endtype
+let same = (1 = 2)
+let mask = 0xFF & 0b1010
+for i = 1 to 2
+    print using "##"; i;
+next i
+x = 1
+y = 2
+`
+	got, err := Format(code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestFormatExpressions(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"print (a + b) * c", "print (a + b) * c"},
+		{"print a - (b - c)", "print a - (b - c)"},
+		{"print (a - b) - c", "print a - b - c"},
+		{"print - (- x)", "print -(-x)"},
+		{"print NOT (a AND b) OR c XOR d", "print not (a and b) or c xor d"},
+		{"print a != b", "print a <> b"},
+		{"print s[1:], s[:2], s[0], (a+b)[1]", "print s[1:], s[:2], s[0], (a + b)[1]"},
+		{"print IIF(a, \"say \\\"hi\\\"\\n\", nil)", "print iif(a, \"say \\\"hi\\\"\\n\", null)"},
+		{"print f((a = b), c = 1)", "print f((a = b), c = 1)"},
+		{"print game.spawn(\"orc\", 1.50)", "print game.spawn(\"orc\", 1.50)"},
+		{"a(1).pos.x += 2", "a(1).pos.x += 2"},
+		{"let a , b = b , a", "let a, b = b, a"},
+		{"let x = (YIELD a) + YIELD b*2", "let x = (yield a) + (yield b * 2)"},
+		{"YIELD", "yield"},
+	}
+	for _, test := range tests {
+		got, err := Format(test.code)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.code, err)
+			continue
+		}
+		if got != test.expected+"\n" {
+			t.Errorf("%s: expected %q, got %q", test.code, test.expected+"\n", got)
+		}
+	}
+}
+
+func TestFormatComments(t *testing.T) {
+	code := `# leading
+
+# after a blank line
+do   # forever
+    # inside
+    exit do
+    # still inside
+loop   # after loop
+try
+    throw "x"
+    # end of try
+catch
+    # handler
+endtry
+# at the end
+`
+	expected := `# leading
+
+# after a blank line
+do  # forever
+    # inside
+    exit do
+    # still inside
+loop  # after loop
+try
+    throw "x"
+    # end of try
+catch
+    # handler
+endtry
+# at the end
+`
+	got, err := Format(code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestFormatIsStable(t *testing.T) {
+	code := `function f(n):
+  if n<=1 then
+   return 1
+  endif
+  return n*f(n-1)
+endfunction
+dim grid(2, 2)
+for i = 0 to 1
+for j = 0 to 1
+grid(i, j) = i * 10 + j
+next
+next
+let total = 0
+do while total < 50
+total += grid(1, 1)
+loop
+if total > 40 then print "big" else print "small"
+print f(5), total, iif(total = 55, "yes", "no"), -(-total), (1 + 2) * 3 - (4 - 1)
+print 6 & 3, 6 / (2 * 3), 2 - (3 - 4), not (1 = 2)
+`
+	once, err := Format(code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	twice, err := Format(once)
+	if err != nil {
+		t.Fatalf("formatted script doesn't parse: %v\n%s", err, once)
+	}
+	if once != twice {
+		t.Errorf("formatting again changed the script:\n%s\nthen:\n%s", once, twice)
+	}
+
+	if run(t, code) != run(t, once) {
+		t.Errorf("formatting changed the output from %v to %v", run(t, code), run(t, once))
+	}
+}
+
+// run returns the printed output of a script
+func run(t *testing.T, code string) string {
+	t.Helper()
+	var output []interface{}
+	mb := basic.NewMechanicalBasic()
+	mb.SetPrintFunc(func(value any) { output = append(output, value) })
+	if err := mb.Run(code); err != nil {
+		t.Fatalf("script failed: %v\n%s", err, code)
+	}
+	return fmt.Sprint(output)
+}
+
+func TestFormatParseError(t *testing.T) {
+	_, err := Format("if x then\n")
+	var parseErr *ast.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+
+	if got, err := Format(""); err != nil || got != "" {
+		t.Errorf("expected an empty script to stay empty, got %q, %v", got, err)
+	}
+}
+
+func TestMinify(t *testing.T) {
+	code := `## Doubles n.
+function double(n AS integer):   # comment
+    return n * 2
+endfunction
+
+REM spacing
+let total = double(3) + _
+    double(4)
+let msg = "say \"hi\""
+if total <> 14 then print "bad" else print msg, - -total, total - -1
+for i = 0 to 0x2
+    total += i
+next i
+print total
+`
+	expected := "function double(n AS integer):\nreturn n*2\nendfunction\n" +
+		"let total=double(3)+double(4)\nlet msg=\"say \\\"hi\\\"\"\n" +
+		"if total<>14then print\"bad\"else print msg,- -total,total- -1\n" +
+		"for i=0to 0x2\ntotal+=i\nnext i\nprint total\n"
+	got, err := Minify(code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if run(t, code) != run(t, got) {
+		t.Errorf("minifying changed the output from %v to %v", run(t, code), run(t, got))
+	}
+	if again, _ := Minify(got); again != got {
+		t.Errorf("minifying again changed the script:\n%s", again)
+	}
+
+	if _, err := Minify("for i = 1\n"); err == nil {
+		t.Error("expected an error for a script that doesn't parse")
+	}
+}
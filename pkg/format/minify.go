@@ -0,0 +1,67 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/ast"
+)
+
+// Minify returns a script without its comments, blank lines, indentation or
+// any space that doesn't separate tokens, for shipping scripts in game
+// assets. Lines continued with _ or after an operator are joined. The script
+// runs as before, but errors report the lines of the minified script, and
+// its functions lose their ## doc comments. A script that doesn't parse
+// fails with a *ast.ParseError.
+func Minify(code string) (string, error) {
+	if _, err := ast.Parse(code); err != nil {
+		return "", err
+	}
+	tokens, err := ast.Tokenize(code)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	prev := ""
+	for _, tok := range tokens {
+		switch tok.Type {
+		case ast.TOKEN_EOF:
+			continue
+		case ast.TOKEN_NEWLINE:
+			if prev != "" && prev != "\n" {
+				out.WriteString("\n")
+				prev = "\n"
+			}
+			continue
+		}
+
+		text := tok.Value
+		if tok.Type == ast.TOKEN_STRING {
+			text = quote(tok.Value)
+		}
+		if prev != "" && prev != "\n" && !separate(prev, text) {
+			out.WriteString(" ")
+		}
+		out.WriteString(text)
+		prev = text
+	}
+	if prev != "" && prev != "\n" {
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
+
+// separate reports whether two tokens written without a space between them
+// still read as the same two tokens, unlike LET x or - -1
+func separate(first, second string) bool {
+	tokens, err := ast.Tokenize(first + second)
+	if err != nil || len(tokens) != 3 { // The two tokens and EOF
+		return false
+	}
+	want, err := ast.Tokenize(first + " " + second)
+	if err != nil || len(want) != 3 {
+		return false
+	}
+	return tokens[0].Type == want[0].Type && tokens[1].Type == want[1].Type &&
+		tokens[0].Value == want[0].Value && tokens[1].Value == want[1].Value
+}
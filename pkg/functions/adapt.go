@@ -0,0 +1,220 @@
+package functions
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Adapt wraps a plain Go function, such as func(int, float64) (string, error),
+// as a host function named name. Arguments are converted to the parameter
+// types: numbers to any integer or float type, arrays to slices and maps to
+// maps with string keys, converting their elements in turn. Parameters of
+// interface types take the value as it is. The function may return nothing,
+// a value, an error, or a value and an error; slices it returns become
+// arrays. An unsigned result too large for int is an out of range error
+// rather than a float. Adapt fails if fn isn't a function of that shape.
+func Adapt(name string, fn interface{}) (func(args ...interface{}) (interface{}, error), error) {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func || rv.IsNil() {
+		return nil, fmt.Errorf("%s: expected a function, got %T", name, fn)
+	}
+
+	t := rv.Type()
+	switch {
+	case t.NumOut() > 2:
+		return nil, fmt.Errorf("%s: functions may return at most a value and an error, got %s", name, t)
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("%s: the second result must be an error, got %s", name, t)
+	}
+
+	fixed := t.NumIn()
+	if t.IsVariadic() {
+		fixed--
+	}
+
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) < fixed || (!t.IsVariadic() && len(args) > fixed) {
+			return nil, argCountError(name, t, len(args))
+		}
+
+		in := make([]reflect.Value, len(args))
+		for idx, arg := range args {
+			param := t.In(min(idx, fixed))
+			if idx >= fixed {
+				param = param.Elem() // One of the variadic arguments
+			}
+			value, err := convertArg(arg, param)
+			if err != nil {
+				return nil, fmt.Errorf("%s: argument %d must be %s: %v", name, idx+1, param, err)
+			}
+			in[idx] = value
+		}
+
+		return results(name, rv.Call(in))
+	}, nil
+}
+
+// argCountError reports a call with the wrong number of arguments
+func argCountError(name string, t reflect.Type, count int) error {
+	if t.IsVariadic() {
+		return fmt.Errorf("%s requires at least %d arguments, got %d", name, t.NumIn()-1, count)
+	}
+	return fmt.Errorf("%s requires %d arguments, got %d", name, t.NumIn(), count)
+}
+
+// convertArg converts a script value to the Go type t
+func convertArg(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("got NULL")
+	}
+	if reflect.TypeOf(value).AssignableTo(t) {
+		return reflect.ValueOf(value), nil
+	}
+
+	out := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := EnsureInt(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if out.OverflowInt(int64(n)) {
+			return reflect.Value{}, fmt.Errorf("%d is out of range", n)
+		}
+		out.SetInt(int64(n))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := EnsureInt(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if n < 0 || out.OverflowUint(uint64(n)) {
+			return reflect.Value{}, fmt.Errorf("%d is out of range", n)
+		}
+		out.SetUint(uint64(n))
+
+	case reflect.Float32, reflect.Float64:
+		f, err := EnsureFloat(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out.SetFloat(f)
+
+	case reflect.String:
+		s, err := EnsureString(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out.SetString(s)
+
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("got %s", TypeName(value))
+		}
+		out.SetBool(b)
+
+	case reflect.Slice:
+		arr, err := EnsureArray(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out = reflect.MakeSlice(t, len(arr), len(arr))
+		for idx, elem := range arr {
+			converted, err := convertArg(elem, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %v", idx, err)
+			}
+			out.Index(idx).Set(converted)
+		}
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("got %s", TypeName(value))
+		}
+		m, err := EnsureMap(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out = reflect.MakeMapWithSize(t, len(m))
+		for key, elem := range m {
+			converted, err := convertArg(elem, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %q: %v", key, err)
+			}
+			out.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), converted)
+		}
+
+	default:
+		return reflect.Value{}, fmt.Errorf("got %s", TypeName(value))
+	}
+	return out, nil
+}
+
+// results converts the values returned by an adapted function
+func results(name string, out []reflect.Value) (interface{}, error) {
+	if len(out) > 0 && out[len(out)-1].Type() == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	result, err := exportResult(out[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return result, nil
+}
+
+// exportResult converts a returned value to a script value. Typed slices
+// and maps become arrays and maps; numbers are normalized by the
+// interpreter, except unsigned ones too large for int, which are an error.
+func exportResult(rv reflect.Value) (interface{}, error) {
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		if err := checkUnsigned(rv.Uint()); err != nil {
+			return nil, err
+		}
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		arr := make([]interface{}, rv.Len())
+		for idx := range arr {
+			elem, err := exportResult(rv.Index(idx))
+			if err != nil {
+				return nil, err
+			}
+			arr[idx] = elem
+		}
+		return arr, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String || rv.IsNil() {
+			break
+		}
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			elem, err := exportResult(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			m[iter.Key().String()] = elem
+		}
+		return m, nil
+	case reflect.Interface:
+		if !rv.IsNil() {
+			return exportResult(rv.Elem())
+		}
+	}
+	return rv.Interface(), nil
+}
@@ -0,0 +1,121 @@
+package functions
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Normalize converts host-supplied numbers to the interpreter's canonical
+// types: every integer type becomes int and float32 becomes float64.
+// Unsigned values too large for int are an out of range error. Typed maps
+// with string keys, such as map[string]int, become map[string]interface{}.
+// Arrays and maps are normalized recursively and copied only when an element
+// changes, so already-canonical values are returned untouched.
+func Normalize(value interface{}) (interface{}, error) {
+	normalized, _, err := normalize(value)
+	return normalized, err
+}
+
+// normalize implements Normalize, also reporting whether the value changed
+func normalize(value interface{}) (interface{}, bool, error) {
+	switch v := value.(type) {
+	case int8:
+		return int(v), true, nil
+	case int16:
+		return int(v), true, nil
+	case int32:
+		return int(v), true, nil
+	case int64:
+		return int(v), true, nil
+	case uint8:
+		return int(v), true, nil
+	case uint16:
+		return int(v), true, nil
+	case uint32:
+		return int(v), true, nil
+	case uint:
+		return normalizeUnsigned(uint64(v))
+	case uint64:
+		return normalizeUnsigned(v)
+	case float32:
+		return float64(v), true, nil
+	case []interface{}:
+		var copied []interface{}
+		for idx, elem := range v {
+			normalized, changed, err := normalize(elem)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed && copied == nil {
+				copied = make([]interface{}, len(v))
+				copy(copied, v)
+			}
+			if copied != nil {
+				copied[idx] = normalized
+			}
+		}
+		if copied == nil {
+			return v, false, nil
+		}
+		return copied, true, nil
+	case map[string]interface{}:
+		var copied map[string]interface{}
+		for key, elem := range v {
+			normalized, changed, err := normalize(elem)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed && copied == nil {
+				copied = make(map[string]interface{}, len(v))
+				for k, e := range v {
+					copied[k] = e
+				}
+			}
+			if copied != nil {
+				copied[key] = normalized
+			}
+		}
+		if copied == nil {
+			return v, false, nil
+		}
+		return copied, true, nil
+	default:
+		return normalizeTypedMap(value)
+	}
+}
+
+// normalizeTypedMap copies a map with string keys and a non-interface element
+// type into a map[string]interface{}
+func normalizeTypedMap(value interface{}) (interface{}, bool, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return value, false, nil
+	}
+
+	copied := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		normalized, _, err := normalize(iter.Value().Interface())
+		if err != nil {
+			return nil, false, err
+		}
+		copied[iter.Key().String()] = normalized
+	}
+	return copied, true, nil
+}
+
+func normalizeUnsigned(v uint64) (interface{}, bool, error) {
+	if err := checkUnsigned(v); err != nil {
+		return nil, false, err
+	}
+	return int(v), true, nil
+}
+
+// checkUnsigned fails for an unsigned number too large for int
+func checkUnsigned(v uint64) error {
+	if v > math.MaxInt {
+		return fmt.Errorf("%d is out of range", v)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package functions
+
+// TypeNamer is implemented by values that name their own type, such as
+// records of a script's TYPE
+type TypeNamer interface {
+	TypeName() string
+}
+
+// TypeName returns the script-level name of a value's type: "int", "float",
+// "string", "bool", "array", "map", "bytes" or "null", or the name a TypeNamer
+// gives. Values of any other Go type are reported as "object".
+func TypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case int:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "map"
+	case []byte:
+		return "bytes"
+	case TypeNamer:
+		return v.TypeName()
+	default:
+		return "object"
+	}
+}
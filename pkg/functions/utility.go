@@ -1,6 +1,9 @@
 package functions
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 func EnsureFloat(input interface{}) (float64, error) {
 	var out float64
@@ -40,3 +43,69 @@ func EnsureString(input interface{}) (string, error) {
 		return "", errors.New("invalid argument type: expected string")
 	}
 }
+
+func EnsureArray(input interface{}) ([]interface{}, error) {
+	switch v := input.(type) {
+	case []interface{}:
+		return v, nil
+	default:
+		return nil, errors.New("invalid argument type: expected array")
+	}
+}
+
+func EnsureMap(input interface{}) (map[string]interface{}, error) {
+	switch v := input.(type) {
+	case map[string]interface{}:
+		return v, nil
+	default:
+		return nil, errors.New("invalid argument type: expected map")
+	}
+}
+
+func EnsureBytes(input interface{}) ([]byte, error) {
+	switch v := input.(type) {
+	case []byte:
+		return v, nil
+	default:
+		return nil, errors.New("invalid argument type: expected bytes")
+	}
+}
+
+// IsTruthy reports whether a value counts as true in a condition
+func IsTruthy(input interface{}) bool {
+	switch v := input.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+// ToString converts a value to the text the interpreter prints for it
+func ToString(input interface{}) string {
+	switch v := input.(type) {
+	case string:
+		return v
+	case int:
+		return fmt.Sprintf("%d", v)
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
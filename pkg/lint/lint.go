@@ -0,0 +1,175 @@
+// Package lint checks MechanicalBasic scripts for likely mistakes without
+// running them, for editors and for CI over a game's scripts. Each check is a
+// rule that can be turned off by name.
+package lint
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/ast"
+	mbasic "github.com/mechanical-lich/mechanical-basic/pkg/basic"
+)
+
+// The rules Lint checks, by name
+const (
+	// A call to a function that isn't built in, registered by the host,
+	// defined by the script or imported from a module
+	RuleUndefinedFunction = "undefined-function"
+
+	// A statement after RETURN, EXIT, THROW, GOTO or BREAK in the same block,
+	// which can never run
+	RuleUnreachableCode = "unreachable-code"
+
+	// An IF, ELSEIF, iif or loop condition made only of literals, so it is
+	// always true or always false. DO WHILE TRUE and DO UNTIL FALSE are
+	// accepted as infinite loops.
+	RuleConstantCondition = "constant-condition"
+
+	// A comparison assigned to a variable, as in LET ok = a = b, where = is
+	// easily misread, or == where BASIC uses =
+	RuleAssignCompare = "assign-compare"
+)
+
+// Rules lists every rule
+var Rules = []string{RuleUndefinedFunction, RuleUnreachableCode, RuleConstantCondition, RuleAssignCompare}
+
+// Issue is a likely mistake found by Lint
+type Issue struct {
+	Rule    string
+	Message string
+	File    string // Included file the issue is in, "" for the script itself
+	Line    int
+	Column  int
+}
+
+func (i Issue) String() string {
+	position := fmt.Sprintf("line %d, column %d", i.Line, i.Column)
+	if i.File != "" {
+		position = i.File + ": " + position
+	}
+	return fmt.Sprintf("%s: %s (%s)", position, i.Message, i.Rule)
+}
+
+// Config selects the rules and describes the environment scripts run in
+type Config struct {
+	// Rules to skip, by name
+	Disable []string
+
+	// Functions the host registers, which scripts may call besides the
+	// built-in library functions; namespaced ones are written game.spawn
+	Functions []string
+
+	// Where INCLUDE finds files and IMPORT finds modules. Without
+	// SourceFS a script that includes a file can't be checked; without
+	// ModuleResolver, calls in a script that imports a module aren't checked
+	// against the functions it defines.
+	SourceFS       fs.FS
+	ModuleResolver mbasic.ModuleResolver
+
+	// Function names are case-sensitive, as after SetCaseSensitive(true)
+	CaseSensitive bool
+}
+
+// Lint checks a script and returns the issues found, sorted by position. A
+// script that doesn't parse fails with a *ast.ParseError; the issues found
+// in its tokens, such as ==, are returned with it.
+func Lint(code string, cfg Config) ([]Issue, error) {
+	l := &linter{
+		cfg:     cfg,
+		enabled: make(map[string]bool),
+		known:   make(map[string]bool),
+	}
+	for _, rule := range Rules {
+		l.enabled[rule] = true
+	}
+	for _, rule := range cfg.Disable {
+		delete(l.enabled, rule)
+	}
+
+	tokens, err := ast.Tokenize(code)
+	if err != nil {
+		return nil, err
+	}
+	l.indexTokens(tokens)
+
+	prog, err := ast.ParseFS(cfg.SourceFS, code)
+	if err != nil {
+		return l.sorted(), err
+	}
+
+	if l.enabled[RuleUndefinedFunction] {
+		l.checkCalls(prog)
+	}
+	l.checkBlocks(prog.Statements)
+	ast.Inspect(prog, func(node ast.Node) bool {
+		l.checkNode(node)
+		return true
+	})
+	return l.sorted(), nil
+}
+
+// linter holds the state of one Lint call
+type linter struct {
+	cfg     Config
+	enabled map[string]bool
+	issues  []Issue
+
+	// Tokens of the script by position, to see what precedes an expression
+	before map[[2]int]ast.TokenType
+
+	known map[string]bool // Functions and variables a call may name
+}
+
+func (l *linter) report(rule string, node ast.Node, format string, args ...interface{}) {
+	line, col := node.Position()
+	l.issues = append(l.issues, Issue{
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+		File:    node.SourceFile(),
+		Line:    line,
+		Column:  col,
+	})
+}
+
+func (l *linter) sorted() []Issue {
+	sort.SliceStable(l.issues, func(x, y int) bool {
+		a, b := l.issues[x], l.issues[y]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.issues
+}
+
+func (l *linter) ident(name string) string {
+	if l.cfg.CaseSensitive {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// indexTokens records the token before each token of the script, and
+// reports == written where BASIC compares with =
+func (l *linter) indexTokens(tokens []ast.Token) {
+	l.before = make(map[[2]int]ast.TokenType, len(tokens))
+	for idx := 1; idx < len(tokens); idx++ {
+		prev, tok := tokens[idx-1], tokens[idx]
+		l.before[[2]int{tok.Line, tok.Column}] = prev.Type
+		if l.enabled[RuleAssignCompare] && prev.Type == ast.TOKEN_EQ && tok.Type == ast.TOKEN_EQ &&
+			prev.Line == tok.Line && prev.Column+1 == tok.Column {
+			l.issues = append(l.issues, Issue{
+				Rule:    RuleAssignCompare,
+				Message: "== is not an operator; compare with =",
+				Line:    prev.Line,
+				Column:  prev.Column,
+			})
+		}
+	}
+}
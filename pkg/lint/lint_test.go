@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/ast"
+)
+
+// positions formats issues as rule@line:column for comparison
+func positions(issues []Issue) []string {
+	out := make([]string, len(issues))
+	for idx, issue := range issues {
+		out[idx] = fmt.Sprintf("%s@%d:%d", issue.Rule, issue.Line, issue.Column)
+	}
+	return out
+}
+
+func expectIssues(t *testing.T, code string, cfg Config, expected ...string) []Issue {
+	t.Helper()
+	issues, err := Lint(code, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := positions(issues)
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	return issues
+}
+
+func TestUndefinedFunction(t *testing.T) {
+	code := `function heal(hp):
+    return min(hp + 10, 100)
+endfunction
+dim grid(3)
+let a = heal(5)
+let b = grid(1)
+let c = hael(5)
+spawn("orc")
+game.log("hi")
+`
+	issues := expectIssues(t, code, Config{},
+		"undefined-function@7:9", "undefined-function@8:1", "undefined-function@9:1")
+	if issues[0].Message != "call to undefined function hael" {
+		t.Errorf("unexpected message %q", issues[0].Message)
+	}
+
+	expectIssues(t, code, Config{Functions: []string{"hael", "SPAWN", "game.log"}})
+	expectIssues(t, code, Config{Disable: []string{RuleUndefinedFunction}})
+}
+
+func TestUndefinedFunctionWithImports(t *testing.T) {
+	code := `import "util"
+let x = clamp(5)
+let y = _helper(5)
+`
+	// Without a resolver the module's functions are unknown, so calls aren't checked
+	expectIssues(t, code, Config{})
+
+	resolver := func(name string) (string, error) {
+		return "function clamp(x):\n    return x\nendfunction\nfunction _helper(x):\n    return x\nendfunction\n", nil
+	}
+	expectIssues(t, code, Config{ModuleResolver: resolver}, "undefined-function@3:9")
+}
+
+func TestUnreachableCode(t *testing.T) {
+	code := `function f(x):
+    return x
+    print "never"
+    print "reported once"
+endfunction
+for i = 1 to 3
+    break
+    print i
+next i
+goto done
+print "skipped"
+done:
+print "reached"
+throw "stop"
+function g():
+endfunction
+`
+	issues := expectIssues(t, code, Config{},
+		"unreachable-code@3:5", "unreachable-code@8:5", "unreachable-code@11:1")
+	if issues[0].Message != "unreachable code after RETURN" {
+		t.Errorf("unexpected message %q", issues[0].Message)
+	}
+}
+
+func TestConstantCondition(t *testing.T) {
+	code := `let x = 1
+if 1 = 1 then
+    print "always"
+elseif x > 1 then
+    print "sometimes"
+elseif not false then
+    print "never"
+endif
+do while true
+    exit do
+loop
+do
+    exit do
+loop until 2 > 1
+let y = iif(true, 1, 2)
+`
+	expectIssues(t, code, Config{},
+		"constant-condition@2:4", "constant-condition@6:8", "constant-condition@14:12", "constant-condition@15:13")
+}
+
+func TestAssignCompare(t *testing.T) {
+	code := `let a = 1
+let b = 2
+let same = a = b
+let ok = (a = b)
+same = a = 1
+if a == b then
+    print "equal"
+endif
+`
+	issues, err := Lint(code, Config{})
+	var parseErr *ast.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a parse error for ==, got %v", err)
+	}
+	if fmt.Sprint(positions(issues)) != "[assign-compare@6:6]" {
+		t.Errorf("unexpected issues %v", positions(issues))
+	}
+
+	expectIssues(t, code[:len(code)-len("if a == b then\n    print \"equal\"\nendif\n")], Config{},
+		"assign-compare@3:12", "assign-compare@5:8")
+}
+
+func TestLintIncludes(t *testing.T) {
+	fsys := fstest.MapFS{"lib.bas": {Data: []byte("function f():\n    return 1\n    print \"dead\"\nendfunction\n")}}
+	issues := expectIssues(t, "include \"lib.bas\"\nprint f()\n", Config{SourceFS: fsys}, "unreachable-code@3:5")
+	if issues[0].File != "lib.bas" {
+		t.Errorf("expected the issue in lib.bas, got %q", issues[0].File)
+	}
+	if issues[0].String() != "lib.bas: line 3, column 5: unreachable code after RETURN (unreachable-code)" {
+		t.Errorf("unexpected string %q", issues[0].String())
+	}
+}
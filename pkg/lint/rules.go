@@ -0,0 +1,203 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/mechanical-lich/mechanical-basic/pkg/ast"
+	mbasic "github.com/mechanical-lich/mechanical-basic/pkg/basic"
+)
+
+// checkCalls reports calls to functions that nothing defines. A call may
+// also index an array held in a variable, so names the script assigns count
+// as defined.
+func (l *linter) checkCalls(prog *ast.Program) {
+	for _, info := range mbasic.NewMechanicalBasic().ListFunctions() {
+		l.known[l.ident(info.Name)] = true
+	}
+	for _, name := range l.cfg.Functions {
+		l.known[l.ident(name)] = true
+	}
+
+	imports := false
+	ast.Inspect(prog, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FunctionStatement:
+			l.known[l.ident(n.Name)] = true
+			for _, param := range n.Params {
+				l.known[l.ident(param)] = true
+			}
+		case *ast.TypeStatement:
+			l.known[l.ident(n.Name)] = true
+		case *ast.ImportStatement:
+			if !l.importModule(n.Module) {
+				imports = true
+			}
+		default:
+			for _, name := range assignedNames(node) {
+				l.known[l.ident(name)] = true
+			}
+		}
+		return true
+	})
+	if imports {
+		return // The functions of a module that can't be read are unknown
+	}
+
+	ast.Inspect(prog, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok && !l.known[l.ident(call.Name)] {
+			l.report(RuleUndefinedFunction, call, "call to undefined function %s", call.Name)
+		}
+		return true
+	})
+}
+
+// importModule adds the public functions of a module to the known names,
+// reporting whether its source could be read
+func (l *linter) importModule(name string) bool {
+	if l.cfg.ModuleResolver == nil {
+		return false
+	}
+	source, err := l.cfg.ModuleResolver(name)
+	if err != nil {
+		return false
+	}
+	prog, err := ast.Parse(source)
+	if err != nil {
+		return false
+	}
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*ast.FunctionStatement); ok && !strings.HasPrefix(fn.Name, "_") {
+			l.known[l.ident(fn.Name)] = true
+		}
+	}
+	return true
+}
+
+// assignedNames returns the variables a statement declares or assigns
+func assignedNames(node ast.Node) []string {
+	switch s := node.(type) {
+	case *ast.LetStatement:
+		return []string{s.Name}
+	case *ast.LocalStatement:
+		return []string{s.Name}
+	case *ast.ConstStatement:
+		return []string{s.Name}
+	case *ast.DimStatement:
+		return []string{s.Name}
+	case *ast.AssignStatement:
+		return []string{s.Name}
+	case *ast.ForStatement:
+		return []string{s.Variable}
+	case *ast.GlobalStatement:
+		return s.Names
+	case *ast.TryStatement:
+		return []string{s.ErrorVar}
+	}
+	return nil
+}
+
+// checkBlocks reports the first unreachable statement of each block
+func (l *linter) checkBlocks(stmts []ast.Statement) {
+	if !l.enabled[RuleUnreachableCode] {
+		return
+	}
+	forEachBlock(stmts, func(block []ast.Statement) {
+		var jump ast.Statement
+		for _, stmt := range block {
+			switch stmt.(type) {
+			case *ast.LabelStatement:
+				jump = nil // GOTO can reach the code after a label
+				continue
+			case *ast.FunctionStatement, *ast.TypeStatement:
+				continue // Definitions don't run in place
+			}
+			if jump != nil {
+				l.report(RuleUnreachableCode, stmt, "unreachable code after %s", jumpName(jump))
+				return
+			}
+			switch stmt.(type) {
+			case *ast.ReturnStatement, *ast.ExitStatement, *ast.ThrowStatement,
+				*ast.GotoStatement, *ast.BreakStatement:
+				jump = stmt
+			}
+		}
+	})
+}
+
+func jumpName(stmt ast.Statement) string {
+	switch s := stmt.(type) {
+	case *ast.ReturnStatement:
+		return "RETURN"
+	case *ast.ExitStatement:
+		return "EXIT " + s.Kind.String()
+	case *ast.ThrowStatement:
+		return "THROW"
+	case *ast.GotoStatement:
+		return "GOTO " + s.Label
+	}
+	return "BREAK"
+}
+
+// checkNode applies the rules that look at a single statement or expression
+func (l *linter) checkNode(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.IfStatement:
+		l.checkCondition(n.Condition, "IF")
+		for _, clause := range n.ElseIfClauses {
+			l.checkCondition(clause.Condition, "ELSEIF")
+		}
+	case *ast.DoLoopStatement:
+		if lit, ok := n.Condition.(*ast.BoolLiteral); ok && lit.Value != n.Until {
+			return // DO WHILE TRUE or DO UNTIL FALSE: an intended infinite loop
+		}
+		if n.Condition != nil {
+			l.checkCondition(n.Condition, "loop")
+		}
+	case *ast.ConditionalExpr:
+		l.checkCondition(n.Condition, "iif")
+
+	case *ast.LetStatement:
+		l.checkAssignedValue(n.Name, n.Value)
+	case *ast.LocalStatement:
+		l.checkAssignedValue(n.Name, n.Value)
+	case *ast.AssignStatement:
+		if n.Operator == ast.TOKEN_EQ && n.Value != nil {
+			l.checkAssignedValue(n.Name, n.Value)
+		}
+	}
+}
+
+func (l *linter) checkCondition(cond ast.Expression, kind string) {
+	if l.enabled[RuleConstantCondition] && isConstant(cond) {
+		l.report(RuleConstantCondition, cond, "%s condition is constant", kind)
+	}
+}
+
+// isConstant reports whether an expression is made only of literals
+func isConstant(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.IntLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BoolLiteral, *ast.NullLiteral:
+		return true
+	case *ast.UnaryExpr:
+		return isConstant(e.Operand)
+	case *ast.BinaryExpr:
+		return isConstant(e.Left) && isConstant(e.Right)
+	case *ast.ConditionalExpr:
+		return isConstant(e.Condition) && isConstant(e.Then) && isConstant(e.Else)
+	}
+	return false
+}
+
+// checkAssignedValue reports a comparison with = assigned to a variable
+// without parentheses, as in LET ok = a = b
+func (l *linter) checkAssignedValue(name string, value ast.Expression) {
+	cmp, ok := value.(*ast.BinaryExpr)
+	if !l.enabled[RuleAssignCompare] || !ok || cmp.Operator != ast.TOKEN_EQ || cmp.SourceFile() != "" {
+		return // Tokens are only indexed for the script itself
+	}
+	line, col := cmp.Position()
+	if l.before[[2]int{line, col}] == ast.TOKEN_LPAREN {
+		return
+	}
+	l.report(RuleAssignCompare, cmp, "%s is assigned the result of comparing with =; add parentheses if that is intended", name)
+}
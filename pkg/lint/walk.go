@@ -0,0 +1,28 @@
+package lint
+
+import "github.com/mechanical-lich/mechanical-basic/pkg/ast"
+
+// forEachBlock calls fn for the statement list of the script and of every
+// block and function body in it
+func forEachBlock(stmts []ast.Statement, fn func([]ast.Statement)) {
+	fn(stmts)
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.IfStatement:
+			forEachBlock(s.ThenBlock, fn)
+			for _, clause := range s.ElseIfClauses {
+				forEachBlock(clause.Block, fn)
+			}
+			forEachBlock(s.ElseBlock, fn)
+		case *ast.ForStatement:
+			forEachBlock(s.Body, fn)
+		case *ast.DoLoopStatement:
+			forEachBlock(s.Body, fn)
+		case *ast.TryStatement:
+			forEachBlock(s.Body, fn)
+			forEachBlock(s.Handler, fn)
+		case *ast.FunctionStatement:
+			forEachBlock(s.Body, fn)
+		}
+	}
+}